@@ -0,0 +1,120 @@
+// Package addressbook は電話帳のレコードをSQLiteに保存する
+//
+// エラーはすべて*errs.OpErrorで返すので、errors.Asで失敗した操作とテーブルがわかる
+// 電話番号はerrsのSensitiveKeysに含まれるため、%+vで出力しても隠される
+package addressbook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"golang/recipe-golang/6.error/errs"
+)
+
+// Table はレコードを保存するテーブル名
+const Table = "addressbook"
+
+// Record は電話帳の1件
+type Record struct {
+	ID    int64
+	Name  string
+	Phone string
+}
+
+// Store は電話帳のレコードを読み書きする
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore はStoreを作り、テーブルがなければ作成する
+func NewStore(db *sql.DB) (*Store, error) {
+	const sql = `
+	CREATE TABLE IF NOT EXISTS addressbook (
+			id    INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+			name  TEXT NOT NULL,
+			phone TEXT NOT NULL
+	);`
+	if _, err := db.Exec(sql); err != nil {
+		return nil, errs.Op("create", errs.Table, Table, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Add はレコードを追加してr.IDを設定する
+// NameかPhoneが空の場合はerrs.Invalidのエラーを返す
+func (s *Store) Add(ctx context.Context, r *Record) error {
+	if strings.TrimSpace(r.Name) == "" || strings.TrimSpace(r.Phone) == "" {
+		return errs.Invalid.Op("insert", errs.Table, Table, errors.New("name and phone are required"),
+			"name", r.Name, "phone", r.Phone)
+	}
+	const sql = "INSERT INTO addressbook(name, phone) values (?,?)"
+	res, err := s.db.ExecContext(ctx, sql, r.Name, r.Phone)
+	if err != nil {
+		return errs.Op("insert", errs.Table, Table, err, "name", r.Name, "phone", r.Phone)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return errs.Op("insert", errs.Table, Table, err, "name", r.Name, "phone", r.Phone)
+	}
+	r.ID = id
+	return nil
+}
+
+// Get はidのレコードを返す
+// 見つからない場合はerrs.NotFoundのエラーを返す
+func (s *Store) Get(ctx context.Context, id int64) (*Record, error) {
+	const sql = "SELECT id, name, phone FROM addressbook WHERE id = ?"
+	var r Record
+	err := s.db.QueryRowContext(ctx, sql, id).Scan(&r.ID, &r.Name, &r.Phone)
+	if err != nil {
+		return nil, s.selectError(err, "id", id)
+	}
+	return &r, nil
+}
+
+// List はすべてのレコードをID順に返す
+func (s *Store) List(ctx context.Context) ([]*Record, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, name, phone FROM addressbook ORDER BY id")
+	if err != nil {
+		return nil, s.selectError(err)
+	}
+	defer rows.Close()
+	var rs []*Record
+	for rows.Next() {
+		var r Record
+		if err := rows.Scan(&r.ID, &r.Name, &r.Phone); err != nil {
+			return nil, s.selectError(err)
+		}
+		rs = append(rs, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, s.selectError(err)
+	}
+	return rs, nil
+}
+
+// Delete はidのレコードを削除する
+// 見つからない場合はerrs.NotFoundのエラーを返す
+func (s *Store) Delete(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM addressbook WHERE id = ?", id)
+	if err != nil {
+		return errs.Op("delete", errs.Table, Table, err, "id", id)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return errs.Op("delete", errs.Table, Table, err, "id", id)
+	} else if n == 0 {
+		return errs.NotFound.Op("delete", errs.Table, Table, sql.ErrNoRows, "id", id)
+	}
+	return nil
+}
+
+// selectError はsql.ErrNoRowsをerrs.NotFoundにする
+func (s *Store) selectError(err error, kv ...interface{}) error {
+	code := errs.Unknown
+	if errors.Is(err, sql.ErrNoRows) {
+		code = errs.NotFound
+	}
+	return code.Op("select", errs.Table, Table, err, kv...)
+}
@@ -0,0 +1,98 @@
+package addressbook_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"example.com/mod/addressbook"
+	"golang/recipe-golang/6.error/errs"
+
+	// jobqueueのテストと同じくcgo版のドライバを使う
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestStore(t *testing.T) (*addressbook.Store, *sql.DB) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "addressbook.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	s, err := addressbook.NewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, db
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+	for _, r := range []*addressbook.Record{{Name: "tenntenn", Phone: "090-0000-0001"}, {Name: "Gopher", Phone: "090-0000-0002"}} {
+		if err := s.Add(ctx, r); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if r.ID == 0 {
+			t.Error("want ID to be set")
+		}
+	}
+	r, err := s.Get(ctx, 2)
+	if err != nil || r.Name != "Gopher" {
+		t.Errorf("want Gopher, got %v, %v", r, err)
+	}
+	if err := s.Delete(ctx, 1); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	rs, err := s.List(ctx)
+	if err != nil || len(rs) != 1 || rs[0].ID != 2 {
+		t.Errorf("want only record 2, got %v, %v", rs, err)
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+	t.Parallel()
+	s, db := newTestStore(t)
+	ctx := context.Background()
+	cases := map[string]struct {
+		do   func() error
+		op   string
+		code errs.Code
+	}{
+		"invalid":          {func() error { return s.Add(ctx, &addressbook.Record{Name: "x", Phone: " "}) }, "insert", errs.Invalid},
+		"get not found":    {func() error { _, err := s.Get(ctx, 100); return err }, "select", errs.NotFound},
+		"delete not found": {func() error { return s.Delete(ctx, 100) }, "delete", errs.NotFound},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			err := tt.do()
+			var oe *errs.OpError
+			if !errors.As(err, &oe) {
+				t.Fatalf("want *errs.OpError, got %v", err)
+			}
+			if oe.Op != tt.op || oe.Resource != addressbook.Table {
+				t.Errorf("want %s %s, got %s %s", tt.op, addressbook.Table, oe.Op, oe.Resource)
+			}
+			if !errors.Is(err, tt.code) {
+				t.Errorf("want %v, got %v", tt.code, errs.CodeOf(err))
+			}
+		})
+	}
+
+	// DBのエラーも操作とテーブルつきで返す
+	db.Close()
+	err := s.Add(ctx, &addressbook.Record{Name: "Gopher", Phone: "090-1234-5678"})
+	if !errors.Is(err, &errs.OpError{Op: "insert", Resource: addressbook.Table}) {
+		t.Fatalf("want insert error, got %v", err)
+	}
+	if detail := fmt.Sprintf("%+v", err); strings.Contains(detail, "1234") || !strings.Contains(detail, "name=Gopher") {
+		t.Errorf("want the phone number to be redacted, got %q", detail)
+	}
+}
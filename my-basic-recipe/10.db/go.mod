@@ -1,5 +1,23 @@
 module example.com/mod
 
-go 1.16
+go 1.21
 
-require github.com/tenntenn/sqlite v1.0.2 // indirect
+require (
+	github.com/mattn/go-sqlite3 v1.14.22
+	github.com/tenntenn/sqlite v1.0.2
+)
+
+require golang/recipe-golang/6.error v0.0.0
+
+require (
+	github.com/remyoudompheng/bigfft v0.0.0-20170806203942-52369c62f446 // indirect
+	golang.org/x/net v0.0.0-20181201002055-351d144fa1fc // indirect
+	modernc.org/ccgo v1.0.0 // indirect
+	modernc.org/ccir v1.0.0 // indirect
+	modernc.org/internal v1.0.0 // indirect
+	modernc.org/mathutil v1.0.0 // indirect
+	modernc.org/memory v1.0.0 // indirect
+	modernc.org/sqlite v1.0.0 // indirect
+)
+
+replace golang/recipe-golang/6.error => ../6.error
@@ -0,0 +1,252 @@
+package jobqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ListOptions はListの絞り込み条件
+type ListOptions struct {
+	State  State
+	Kind   string
+	Limit  int // 0のときは100件
+	Offset int
+}
+
+// List はジョブを新しい順に返す
+func (q *Queue) List(ctx context.Context, opts ListOptions) ([]*Job, error) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+	if opts.State != "" {
+		conds = append(conds, "state = ?")
+		args = append(args, opts.State)
+	}
+	if opts.Kind != "" {
+		conds = append(conds, "kind = ?")
+		args = append(args, opts.Kind)
+	}
+	sql := "SELECT " + jobColumns + " FROM jobs"
+	if len(conds) > 0 {
+		sql += " WHERE " + strings.Join(conds, " AND ")
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	sql += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, opts.Offset)
+
+	rows, err := q.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var jobs []*Job
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Get はIDを指定してジョブを取得する
+func (q *Queue) Get(ctx context.Context, id int64) (*Job, error) {
+	return scanJob(q.db.QueryRowContext(ctx, "SELECT "+jobColumns+" FROM jobs WHERE id = ?", id))
+}
+
+// Retry はdeadかcanceledのジョブを試行回数を0に戻して実行待ちにする
+func (q *Queue) Retry(ctx context.Context, id int64) (*Job, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	j, err := scanJob(tx.QueryRowContext(ctx, "SELECT "+jobColumns+" FROM jobs WHERE id = ?", id))
+	if err != nil {
+		return nil, err
+	}
+	if j.State != StateDead && j.State != StateCanceled {
+		return nil, fmt.Errorf("%w: cannot retry %s job", ErrInvalidState, j.State)
+	}
+	if j.UniqueKey != "" {
+		var n int
+		const sql = "SELECT COUNT(*) FROM jobs WHERE unique_key = ? AND state IN ('pending', 'running')"
+		if err := tx.QueryRowContext(ctx, sql, j.UniqueKey).Scan(&n); err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateJob, j.UniqueKey)
+		}
+	}
+
+	now := q.now()
+	const sql = `UPDATE jobs SET state = 'pending', attempts = 0, run_at = ?, last_error = '', updated_at = ?
+		WHERE id = ?`
+	if _, err := tx.ExecContext(ctx, sql, unixNano(now), unixNano(now), id); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	j.State, j.Attempts, j.RunAt, j.LastError, j.UpdatedAt = StatePending, 0, now, "", now
+	return j, nil
+}
+
+// Cancel は実行待ちか実行中のジョブをキャンセルする
+// 実行中のジョブは次のHeartbeatでErrLeaseLostになり、ワーカー側の処理がキャンセルされる
+func (q *Queue) Cancel(ctx context.Context, id int64) (*Job, error) {
+	const sql = `UPDATE jobs SET state = 'canceled', lease_token = NULL, lease_until = NULL, updated_at = ?
+		WHERE id = ? AND state IN ('pending', 'running')`
+	r, err := q.db.ExecContext(ctx, sql, unixNano(q.now()), id)
+	if err != nil {
+		return nil, err
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	j, err := q.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("%w: cannot cancel %s job", ErrInvalidState, j.State)
+	}
+	return j, nil
+}
+
+// NewAdminHandler はジョブを管理するHTTPハンドラを返す
+//
+//	GET  /jobs?state=dead&kind=import&limit=20&offset=0
+//	GET  /jobs/{id}
+//	POST /jobs/{id}/retry
+//	POST /jobs/{id}/cancel
+func NewAdminHandler(q *Queue) http.Handler {
+	return &adminHandler{q: q}
+}
+
+type adminHandler struct{ q *Queue }
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	if parts[0] != "jobs" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		h.list(w, r)
+		return
+	}
+
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid job id", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case len(parts) == 2:
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		j, err := h.q.Get(r.Context(), id)
+		writeJob(w, j, err)
+	case len(parts) == 3 && (parts[2] == "retry" || parts[2] == "cancel"):
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		var j *Job
+		if parts[2] == "retry" {
+			j, err = h.q.Retry(r.Context(), id)
+		} else {
+			j, err = h.q.Cancel(r.Context(), id)
+		}
+		writeJob(w, j, err)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *adminHandler) list(w http.ResponseWriter, r *http.Request) {
+	opts := ListOptions{
+		State: State(r.FormValue("state")),
+		Kind:  r.FormValue("kind"),
+	}
+	for name, p := range map[string]*int{"limit": &opts.Limit, "offset": &opts.Offset} {
+		v := r.FormValue(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid "+name, http.StatusBadRequest)
+			return
+		}
+		*p = n
+	}
+	jobs, err := h.q.List(r.Context(), opts)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if jobs == nil {
+		jobs = []*Job{}
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func writeJob(w http.ResponseWriter, j *Job, err error) {
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, j)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, ErrInvalidState), errors.Is(err, ErrDuplicateJob):
+		code = http.StatusConflict
+	}
+	writeJSON(w, code, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error:", err)
+	}
+}
+
+func methodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
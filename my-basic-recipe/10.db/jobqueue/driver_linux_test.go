@@ -0,0 +1,8 @@
+package jobqueue_test
+
+// tenntenn/sqliteがLinuxで使うmodernc.org/sqlite v1.0.0は最近のGoではCloseで落ち、
+// rootで動かすとfchownで止まるため、Linuxのテストに限りmacOSでtenntenn/sqliteが使うのと同じcgo版のドライバを使う
+import _ "github.com/mattn/go-sqlite3"
+
+// testDriver はテストで使うドライバ
+const testDriver = "sqlite3"
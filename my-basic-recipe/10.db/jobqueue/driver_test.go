@@ -0,0 +1,8 @@
+//go:build !linux
+
+package jobqueue_test
+
+import "github.com/tenntenn/sqlite"
+
+// testDriver はテストで使うドライバ。main.goと同じtenntenn/sqliteを使う
+const testDriver = sqlite.DriverName
@@ -0,0 +1,389 @@
+// Package jobqueue はdatabase/sqlとSQLiteを使った永続的なジョブキュー
+//
+// SQLiteは同時書き込みに弱いため、*sql.DBはSetMaxOpenConns(1)で使う
+package jobqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoJob        = errors.New("jobqueue: no job available")
+	ErrNotFound     = errors.New("jobqueue: job not found")
+	ErrDuplicateJob = errors.New("jobqueue: duplicate unique key")
+	ErrLeaseLost    = errors.New("jobqueue: lease lost")
+	ErrInvalidState = errors.New("jobqueue: invalid state")
+)
+
+// State はジョブの状態
+type State string
+
+const (
+	StatePending   State = "pending"   // 実行待ち（run_atになるまで待つ）
+	StateRunning   State = "running"   // ワーカーがリースしている
+	StateSucceeded State = "succeeded" // 正常に終了した
+	StateDead      State = "dead"      // リトライ回数を使い切った
+	StateCanceled  State = "canceled"  // 管理APIからキャンセルされた
+)
+
+// Job はキューに積まれた1つのジョブ
+type Job struct {
+	ID          int64     `json:"id"`
+	Kind        string    `json:"kind"`
+	Payload     []byte    `json:"payload"`
+	State       State     `json:"state"`
+	UniqueKey   string    `json:"unique_key,omitempty"`
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"max_attempts"`
+	RunAt       time.Time `json:"run_at"`
+	LeaseUntil  time.Time `json:"lease_until,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	token string // リースしたワーカーだけが知っているトークン
+}
+
+// Queue はジョブキュー
+type Queue struct {
+	db          *sql.DB
+	now         func() time.Time
+	backoff     func(attempt int) time.Duration
+	maxAttempts int
+}
+
+// Option はQueueの設定
+type Option func(*Queue)
+
+// WithClock は現在時刻を返す関数を差し替える
+func WithClock(now func() time.Time) Option {
+	return func(q *Queue) { q.now = now }
+}
+
+// WithBackoff はリトライまでの待ち時間を決める関数を指定する
+func WithBackoff(f func(attempt int) time.Duration) Option {
+	return func(q *Queue) { q.backoff = f }
+}
+
+// WithMaxAttempts はデフォルトの最大試行回数を指定する
+func WithMaxAttempts(n int) Option {
+	return func(q *Queue) { q.maxAttempts = n }
+}
+
+// ExponentialBackoff はbaseから倍々に増え、maxで頭打ちになる待ち時間を返す
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt; i++ {
+			d *= 2
+			if d >= max || d <= 0 {
+				return max
+			}
+		}
+		return d
+	}
+}
+
+// New はQueueを作り、必要なテーブルを作成する
+func New(db *sql.DB, opts ...Option) (*Queue, error) {
+	q := &Queue{
+		db:          db,
+		now:         time.Now,
+		backoff:     ExponentialBackoff(time.Second, time.Hour),
+		maxAttempts: 5,
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	if err := q.createTable(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *Queue) createTable() error {
+	stmts := []string{`
+	CREATE TABLE IF NOT EXISTS jobs (
+			id           INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+			kind         TEXT NOT NULL,
+			payload      BLOB,
+			state        TEXT NOT NULL,
+			unique_key   TEXT,
+			attempts     INTEGER NOT NULL DEFAULT 0,
+			max_attempts INTEGER NOT NULL,
+			run_at       INTEGER NOT NULL,
+			lease_token  TEXT,
+			lease_until  INTEGER,
+			last_error   TEXT NOT NULL DEFAULT '',
+			created_at   INTEGER NOT NULL,
+			updated_at   INTEGER NOT NULL
+	);`,
+		`CREATE INDEX IF NOT EXISTS jobs_ready ON jobs(state, run_at);`,
+		// 実行待ちと実行中のジョブの中でだけユニークにする
+		`CREATE UNIQUE INDEX IF NOT EXISTS jobs_unique_key ON jobs(unique_key)
+		WHERE unique_key IS NOT NULL AND state IN ('pending', 'running');`,
+	}
+	for _, sql := range stmts {
+		if _, err := q.db.Exec(sql); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnqueueOption はEnqueueの設定
+type EnqueueOption func(*Job)
+
+// RunAt は実行開始時刻を指定する
+func RunAt(t time.Time) EnqueueOption {
+	return func(j *Job) { j.RunAt = t }
+}
+
+// UniqueKey は同じキーのジョブが実行待ちか実行中の間は重複して積まないようにする
+func UniqueKey(key string) EnqueueOption {
+	return func(j *Job) { j.UniqueKey = key }
+}
+
+// MaxAttempts は最大試行回数を指定する
+func MaxAttempts(n int) EnqueueOption {
+	return func(j *Job) { j.MaxAttempts = n }
+}
+
+// Enqueue はジョブを積む
+// UniqueKeyが重複している場合は既存のジョブとErrDuplicateJobを返す
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload []byte, opts ...EnqueueOption) (*Job, error) {
+	now := q.now()
+	j := &Job{
+		Kind:        kind,
+		Payload:     payload,
+		State:       StatePending,
+		MaxAttempts: q.maxAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	if j.Payload == nil {
+		j.Payload = []byte{}
+	}
+
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if j.UniqueKey != "" {
+		const sql = "SELECT " + jobColumns + " FROM jobs WHERE unique_key = ? AND state IN ('pending', 'running')"
+		dup, err := scanJob(tx.QueryRowContext(ctx, sql, j.UniqueKey))
+		switch {
+		case err == nil:
+			return dup, fmt.Errorf("%w: %q", ErrDuplicateJob, j.UniqueKey)
+		case !errors.Is(err, ErrNotFound):
+			return nil, err
+		}
+	}
+
+	// ドライバによってはnilを渡せないのでNULLIFで空文字列をNULLにする
+	const sql = `INSERT INTO jobs(kind, payload, state, unique_key, max_attempts, run_at, created_at, updated_at)
+		values (?,?,?,NULLIF(?, ''),?,?,?,?)`
+	r, err := tx.ExecContext(ctx, sql, j.Kind, j.Payload, j.State, j.UniqueKey,
+		j.MaxAttempts, unixNano(j.RunAt), unixNano(now), unixNano(now))
+	if err != nil {
+		return nil, err
+	}
+	if j.ID, err = r.LastInsertId(); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Lease は実行可能なジョブを1つ取り出し、visibilityの間だけ他のワーカーから見えなくする
+// 期限内にHeartbeatかComplete/Failを呼ばないと他のワーカーに再度渡される
+// kindsを指定するとその種類のジョブだけを対象にする
+func (q *Queue) Lease(ctx context.Context, visibility time.Duration, kinds ...string) (*Job, error) {
+	now := unixNano(q.now())
+
+	// リースが切れたまま試行回数を使い切ったジョブはdeadにする
+	const reap = `UPDATE jobs SET state = 'dead', lease_token = NULL, lease_until = NULL,
+		last_error = 'lease expired', updated_at = ?
+		WHERE state = 'running' AND lease_until <= ? AND attempts >= max_attempts`
+	if _, err := q.db.ExecContext(ctx, reap, now, now); err != nil {
+		return nil, err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	where := "((state = 'pending' AND run_at <= ?) OR (state = 'running' AND lease_until <= ?))"
+	args := []interface{}{token, now + int64(visibility), now, now, now}
+	if len(kinds) > 0 {
+		where += " AND kind IN (?" + strings.Repeat(",?", len(kinds)-1) + ")"
+		for _, k := range kinds {
+			args = append(args, k)
+		}
+	}
+	// 1つのUPDATE文で取り出すので複数のワーカーが同じジョブを取ることはない
+	sql := `UPDATE jobs SET state = 'running', lease_token = ?, lease_until = ?,
+		attempts = attempts + 1, updated_at = ?
+		WHERE id = (SELECT id FROM jobs WHERE ` + where + ` ORDER BY run_at, id LIMIT 1)`
+	r, err := q.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := r.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrNoJob
+	}
+
+	j, err := scanJob(q.db.QueryRowContext(ctx, "SELECT "+jobColumns+" FROM jobs WHERE lease_token = ?", token))
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Heartbeat はリースの期限をextendだけ延長する
+// キャンセルされた場合や他のワーカーに渡った場合はErrLeaseLostを返す
+func (q *Queue) Heartbeat(ctx context.Context, j *Job, extend time.Duration) error {
+	now := q.now()
+	const sql = `UPDATE jobs SET lease_until = ?, updated_at = ?
+		WHERE id = ? AND lease_token = ? AND state = 'running'`
+	if err := q.execLeased(ctx, sql, unixNano(now.Add(extend)), unixNano(now), j.ID, j.token); err != nil {
+		return err
+	}
+	j.LeaseUntil = now.Add(extend)
+	return nil
+}
+
+// Complete はジョブを正常終了にする
+func (q *Queue) Complete(ctx context.Context, j *Job) error {
+	const sql = `UPDATE jobs SET state = 'succeeded', lease_token = NULL, lease_until = NULL, updated_at = ?
+		WHERE id = ? AND lease_token = ? AND state = 'running'`
+	if err := q.execLeased(ctx, sql, unixNano(q.now()), j.ID, j.token); err != nil {
+		return err
+	}
+	j.State = StateSucceeded
+	return nil
+}
+
+// Fail はジョブを失敗にする
+// 試行回数が残っていればバックオフ後に再実行され、使い切るかPermanentなエラーの場合はdeadになる
+func (q *Queue) Fail(ctx context.Context, j *Job, cause error) error {
+	now := q.now()
+	state, runAt := StatePending, now.Add(q.backoff(j.Attempts))
+	if j.Attempts >= j.MaxAttempts || IsPermanent(cause) {
+		state, runAt = StateDead, j.RunAt
+	}
+	msg := ""
+	if cause != nil {
+		msg = cause.Error()
+	}
+	const sql = `UPDATE jobs SET state = ?, run_at = ?, last_error = ?, lease_token = NULL, lease_until = NULL, updated_at = ?
+		WHERE id = ? AND lease_token = ? AND state = 'running'`
+	if err := q.execLeased(ctx, sql, state, unixNano(runAt), msg, unixNano(now), j.ID, j.token); err != nil {
+		return err
+	}
+	j.State, j.RunAt, j.LastError = state, runAt, msg
+	return nil
+}
+
+// release は試行回数を戻してジョブを実行待ちに戻す
+// ワーカーの停止で処理を中断した場合に使う
+func (q *Queue) release(ctx context.Context, j *Job) error {
+	const sql = `UPDATE jobs SET state = 'pending', attempts = attempts - 1, lease_token = NULL, lease_until = NULL, updated_at = ?
+		WHERE id = ? AND lease_token = ? AND state = 'running'`
+	return q.execLeased(ctx, sql, unixNano(q.now()), j.ID, j.token)
+}
+
+func (q *Queue) execLeased(ctx context.Context, sql string, args ...interface{}) error {
+	r, err := q.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// permanentError はリトライしても意味がないエラー
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent はリトライせずにdeadにするエラーを作る
+func Permanent(err error) error {
+	return &permanentError{err}
+}
+
+// IsPermanent はPermanentで作られたエラーかどうかを判定する
+func IsPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+const jobColumns = `id, kind, payload, state, unique_key, attempts, max_attempts, run_at,
+	lease_token, lease_until, last_error, created_at, updated_at`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanJob(row scanner) (*Job, error) {
+	var (
+		j                                   Job
+		uniqueKey, token                    sql.NullString
+		runAt, leaseUntil, created, updated sql.NullInt64
+	)
+	err := row.Scan(&j.ID, &j.Kind, &j.Payload, &j.State, &uniqueKey, &j.Attempts, &j.MaxAttempts,
+		&runAt, &token, &leaseUntil, &j.LastError, &created, &updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	j.UniqueKey, j.token = uniqueKey.String, token.String
+	j.RunAt = fromUnixNano(runAt)
+	j.LeaseUntil = fromUnixNano(leaseUntil)
+	j.CreatedAt = fromUnixNano(created)
+	j.UpdatedAt = fromUnixNano(updated)
+	return &j, nil
+}
+
+func newToken() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+func unixNano(t time.Time) int64 { return t.UnixNano() }
+
+func fromUnixNano(n sql.NullInt64) time.Time {
+	if !n.Valid {
+		return time.Time{}
+	}
+	return time.Unix(0, n.Int64)
+}
@@ -0,0 +1,406 @@
+package jobqueue_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/mod/jobqueue"
+)
+
+// testClock はテストから時刻を進められる時計
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestQueue(t *testing.T, opts ...jobqueue.Option) (*jobqueue.Queue, *testClock) {
+	t.Helper()
+	db, err := sql.Open(testDriver, filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatalf("err %s", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	clock := &testClock{now: time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)}
+	opts = append([]jobqueue.Option{
+		jobqueue.WithClock(clock.Now),
+		jobqueue.WithBackoff(jobqueue.ExponentialBackoff(time.Second, time.Minute)),
+	}, opts...)
+	q, err := jobqueue.New(db, opts...)
+	if err != nil {
+		t.Fatalf("err %s", err)
+	}
+	return q, clock
+}
+
+func mustEnqueue(t *testing.T, q *jobqueue.Queue, kind string, opts ...jobqueue.EnqueueOption) *jobqueue.Job {
+	t.Helper()
+	j, err := q.Enqueue(context.Background(), kind, []byte(`{"name":"tenntenn"}`), opts...)
+	if err != nil {
+		t.Fatalf("err %s", err)
+	}
+	return j
+}
+
+func TestQueue_LeaseComplete(t *testing.T) {
+	t.Parallel()
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+	want := mustEnqueue(t, q, "import")
+
+	j, err := q.Lease(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.ID != want.ID || j.State != jobqueue.StateRunning || j.Attempts != 1 {
+		t.Errorf("unexpected job: %+v", j)
+	}
+	if string(j.Payload) != `{"name":"tenntenn"}` {
+		t.Errorf("want payload %q, got %q", `{"name":"tenntenn"}`, j.Payload)
+	}
+	if _, err := q.Lease(ctx, time.Minute); !errors.Is(err, jobqueue.ErrNoJob) {
+		t.Errorf("want %v, got %v", jobqueue.ErrNoJob, err)
+	}
+	if err := q.Complete(ctx, j); err != nil {
+		t.Fatal(err)
+	}
+	got, err := q.Get(ctx, j.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.State != jobqueue.StateSucceeded {
+		t.Errorf("want %s, got %s", jobqueue.StateSucceeded, got.State)
+	}
+}
+
+func TestQueue_RunAtAndKinds(t *testing.T) {
+	t.Parallel()
+	q, clock := newTestQueue(t)
+	ctx := context.Background()
+	mustEnqueue(t, q, "import", jobqueue.RunAt(clock.Now().Add(time.Hour)))
+	mail := mustEnqueue(t, q, "mail")
+
+	if _, err := q.Lease(ctx, time.Minute, "import"); !errors.Is(err, jobqueue.ErrNoJob) {
+		t.Errorf("want %v, got %v", jobqueue.ErrNoJob, err)
+	}
+	j, err := q.Lease(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.ID != mail.ID {
+		t.Errorf("want job %d, got %d", mail.ID, j.ID)
+	}
+	clock.Advance(time.Hour)
+	if j, err := q.Lease(ctx, time.Minute, "import"); err != nil || j.Kind != "import" {
+		t.Errorf("want import job, got %+v, %v", j, err)
+	}
+}
+
+func TestQueue_VisibilityTimeout(t *testing.T) {
+	t.Parallel()
+	q, clock := newTestQueue(t)
+	ctx := context.Background()
+	mustEnqueue(t, q, "import")
+
+	first, err := q.Lease(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(40 * time.Second)
+	if err := q.Heartbeat(ctx, first, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(40 * time.Second)
+	if _, err := q.Lease(ctx, time.Minute); !errors.Is(err, jobqueue.ErrNoJob) {
+		t.Errorf("heartbeat did not extend the lease: %v", err)
+	}
+
+	clock.Advance(time.Minute)
+	second, err := q.Lease(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ID != first.ID || second.Attempts != 2 {
+		t.Errorf("want job %d on attempt 2, got %+v", first.ID, second)
+	}
+	if err := q.Complete(ctx, first); !errors.Is(err, jobqueue.ErrLeaseLost) {
+		t.Errorf("want %v, got %v", jobqueue.ErrLeaseLost, err)
+	}
+	if err := q.Heartbeat(ctx, first, time.Minute); !errors.Is(err, jobqueue.ErrLeaseLost) {
+		t.Errorf("want %v, got %v", jobqueue.ErrLeaseLost, err)
+	}
+}
+
+func TestQueue_RetryAndDeadLetter(t *testing.T) {
+	t.Parallel()
+	q, clock := newTestQueue(t)
+	ctx := context.Background()
+	mustEnqueue(t, q, "import", jobqueue.MaxAttempts(3))
+	boom := errors.New("boom")
+
+	for attempt, backoff := range []time.Duration{time.Second, 2 * time.Second} {
+		j, err := q.Lease(ctx, time.Minute)
+		if err != nil {
+			t.Fatalf("attempt %d: %v", attempt+1, err)
+		}
+		if err := q.Fail(ctx, j, boom); err != nil {
+			t.Fatal(err)
+		}
+		if j.State != jobqueue.StatePending || !j.RunAt.Equal(clock.Now().Add(backoff)) {
+			t.Errorf("attempt %d: want pending at %v, got %s at %v", attempt+1, clock.Now().Add(backoff), j.State, j.RunAt)
+		}
+		if _, err := q.Lease(ctx, time.Minute); !errors.Is(err, jobqueue.ErrNoJob) {
+			t.Errorf("attempt %d: leased before backoff: %v", attempt+1, err)
+		}
+		clock.Advance(backoff)
+	}
+
+	j, err := q.Lease(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Fail(ctx, j, boom); err != nil {
+		t.Fatal(err)
+	}
+	dead, err := q.List(ctx, jobqueue.ListOptions{State: jobqueue.StateDead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].LastError != "boom" || dead[0].Attempts != 3 {
+		t.Fatalf("want one dead job, got %+v", dead)
+	}
+
+	if _, err := q.Retry(ctx, j.ID); err != nil {
+		t.Fatal(err)
+	}
+	if j, err := q.Lease(ctx, time.Minute); err != nil || j.Attempts != 1 {
+		t.Errorf("want retried job on attempt 1, got %+v, %v", j, err)
+	}
+}
+
+func TestQueue_Permanent(t *testing.T) {
+	t.Parallel()
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+	mustEnqueue(t, q, "import")
+	j, err := q.Lease(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Fail(ctx, j, jobqueue.Permanent(errors.New("bad payload"))); err != nil {
+		t.Fatal(err)
+	}
+	if j.State != jobqueue.StateDead {
+		t.Errorf("want %s, got %s", jobqueue.StateDead, j.State)
+	}
+}
+
+func TestQueue_ExpiredLeaseWithoutAttemptsLeft(t *testing.T) {
+	t.Parallel()
+	q, clock := newTestQueue(t)
+	ctx := context.Background()
+	j := mustEnqueue(t, q, "import", jobqueue.MaxAttempts(1))
+	if _, err := q.Lease(ctx, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(2 * time.Minute)
+	if _, err := q.Lease(ctx, time.Minute); !errors.Is(err, jobqueue.ErrNoJob) {
+		t.Errorf("want %v, got %v", jobqueue.ErrNoJob, err)
+	}
+	got, err := q.Get(ctx, j.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.State != jobqueue.StateDead {
+		t.Errorf("want %s, got %s", jobqueue.StateDead, got.State)
+	}
+}
+
+func TestQueue_UniqueKey(t *testing.T) {
+	t.Parallel()
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+	first := mustEnqueue(t, q, "export", jobqueue.UniqueKey("nightly"))
+	dup, err := q.Enqueue(ctx, "export", nil, jobqueue.UniqueKey("nightly"))
+	if !errors.Is(err, jobqueue.ErrDuplicateJob) {
+		t.Fatalf("want %v, got %v", jobqueue.ErrDuplicateJob, err)
+	}
+	if dup.ID != first.ID {
+		t.Errorf("want existing job %d, got %d", first.ID, dup.ID)
+	}
+
+	j, err := q.Lease(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Complete(ctx, j); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Enqueue(ctx, "export", nil, jobqueue.UniqueKey("nightly")); err != nil {
+		t.Errorf("want no error after completion, got %v", err)
+	}
+}
+
+func TestQueue_Cancel(t *testing.T) {
+	t.Parallel()
+	q, _ := newTestQueue(t)
+	ctx := context.Background()
+	mustEnqueue(t, q, "import")
+	j, err := q.Lease(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Cancel(ctx, j.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Heartbeat(ctx, j, time.Minute); !errors.Is(err, jobqueue.ErrLeaseLost) {
+		t.Errorf("want %v, got %v", jobqueue.ErrLeaseLost, err)
+	}
+	if _, err := q.Cancel(ctx, j.ID); !errors.Is(err, jobqueue.ErrInvalidState) {
+		t.Errorf("want %v, got %v", jobqueue.ErrInvalidState, err)
+	}
+	if _, err := q.Cancel(ctx, 999); !errors.Is(err, jobqueue.ErrNotFound) {
+		t.Errorf("want %v, got %v", jobqueue.ErrNotFound, err)
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	t.Parallel()
+	q, _ := newTestQueue(t)
+	j := mustEnqueue(t, q, "import")
+	h := jobqueue.NewAdminHandler(q)
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+		state  jobqueue.State
+	}{
+		{"get", "GET", "/jobs/1", http.StatusOK, jobqueue.StatePending},
+		{"retry pending", "POST", "/jobs/1/retry", http.StatusConflict, ""},
+		{"cancel", "POST", "/jobs/1/cancel", http.StatusOK, jobqueue.StateCanceled},
+		{"retry canceled", "POST", "/jobs/1/retry", http.StatusOK, jobqueue.StatePending},
+		{"not found", "GET", "/jobs/2", http.StatusNotFound, ""},
+		{"bad id", "GET", "/jobs/abc", http.StatusBadRequest, ""},
+		{"bad method", "GET", "/jobs/1/cancel", http.StatusMethodNotAllowed, ""},
+	}
+	// 状態が変わるので順番に実行する
+	for _, tt := range cases {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		res := w.Result()
+		if res.StatusCode != tt.code {
+			t.Errorf("%s: want status %d, got %d", tt.name, tt.code, res.StatusCode)
+			continue
+		}
+		if tt.state == "" {
+			continue
+		}
+		var got jobqueue.Job
+		if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got.ID != j.ID || got.State != tt.state {
+			t.Errorf("%s: want job %d %s, got %d %s", tt.name, j.ID, tt.state, got.ID, got.State)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/jobs?state=pending&kind=import", nil))
+	var jobs []jobqueue.Job
+	if err := json.NewDecoder(w.Result().Body).Decode(&jobs); err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != j.ID {
+		t.Errorf("want [job %d], got %+v", j.ID, jobs)
+	}
+}
+
+func TestWorker(t *testing.T) {
+	t.Parallel()
+	q, _ := newTestQueue(t, jobqueue.WithClock(time.Now))
+	ok := mustEnqueue(t, q, "import")
+	bad := mustEnqueue(t, q, "import", jobqueue.MaxAttempts(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := jobqueue.NewWorker(q, jobqueue.Concurrency(2), jobqueue.PollInterval(10*time.Millisecond))
+	w.Handle("import", func(ctx context.Context, j *jobqueue.Job) error {
+		if j.ID == bad.ID {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	errc := make(chan error, 1)
+	go func() { errc <- w.Run(ctx) }()
+
+	want := map[int64]jobqueue.State{ok.ID: jobqueue.StateSucceeded, bad.ID: jobqueue.StateDead}
+	deadline := time.Now().Add(5 * time.Second)
+	for id, state := range want {
+		for {
+			got, err := q.Get(context.Background(), id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.State == state {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("job %d: want %s, got %s", id, state, got.State)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestWorker_Visibility(t *testing.T) {
+	t.Parallel()
+	for _, d := range []time.Duration{0, -time.Second} {
+		q, _ := newTestQueue(t, jobqueue.WithClock(time.Now))
+		j := mustEnqueue(t, q, "import")
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		w := jobqueue.NewWorker(q, jobqueue.Visibility(d), jobqueue.PollInterval(10*time.Millisecond))
+		w.Handle("import", func(ctx context.Context, j *jobqueue.Job) error {
+			close(done)
+			return nil
+		})
+		errc := make(chan error, 1)
+		go func() { errc <- w.Run(ctx) }()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Visibility(%v): job %d was not processed", d, j.ID)
+		}
+		cancel()
+		if err := <-errc; !errors.Is(err, context.Canceled) {
+			t.Errorf("want %v, got %v", context.Canceled, err)
+		}
+	}
+}
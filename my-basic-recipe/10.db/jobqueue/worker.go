@@ -0,0 +1,174 @@
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Handler はジョブを処理する関数
+// エラーを返すとリトライされる。リトライさせたくない場合はPermanentで包む
+type Handler func(ctx context.Context, j *Job) error
+
+// Worker はキューからジョブを取り出して処理する
+type Worker struct {
+	q            *Queue
+	handlers     map[string]Handler
+	concurrency  int
+	pollInterval time.Duration
+	visibility   time.Duration
+	logger       *log.Logger
+}
+
+// WorkerOption はWorkerの設定
+type WorkerOption func(*Worker)
+
+// Concurrency は同時に処理するジョブの数を指定する
+func Concurrency(n int) WorkerOption {
+	return func(w *Worker) { w.concurrency = n }
+}
+
+// PollInterval はジョブがない場合に次に確認するまでの間隔を指定する
+func PollInterval(d time.Duration) WorkerOption {
+	return func(w *Worker) { w.pollInterval = d }
+}
+
+// Visibility はリースの期間を指定する。0以下は無視して初期値の30秒のままにする
+// 処理中はこの1/3ごとにHeartbeatで延長する
+func Visibility(d time.Duration) WorkerOption {
+	return func(w *Worker) {
+		if d > 0 {
+			w.visibility = d
+		}
+	}
+}
+
+// Logger はエラーの出力先を指定する
+func Logger(l *log.Logger) WorkerOption {
+	return func(w *Worker) { w.logger = l }
+}
+
+// NewWorker はWorkerを作る
+func NewWorker(q *Queue, opts ...WorkerOption) *Worker {
+	w := &Worker{
+		q:            q,
+		handlers:     map[string]Handler{},
+		concurrency:  1,
+		pollInterval: time.Second,
+		visibility:   30 * time.Second,
+		logger:       log.Default(),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Handle はkindのジョブを処理するHandlerを登録する
+// Runを呼ぶ前に登録する
+func (w *Worker) Handle(kind string, h Handler) {
+	w.handlers[kind] = h
+}
+
+// Run はctxが終わるまでジョブを処理する
+// 停止時に処理中だったジョブは試行回数を戻して実行待ちに戻す
+func (w *Worker) Run(ctx context.Context) error {
+	if len(w.handlers) == 0 {
+		return errors.New("jobqueue: no handlers registered")
+	}
+	kinds := make([]string, 0, len(w.handlers))
+	for k := range w.handlers {
+		kinds = append(kinds, k)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx, kinds)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (w *Worker) loop(ctx context.Context, kinds []string) {
+	for ctx.Err() == nil {
+		j, err := w.q.Lease(ctx, w.visibility, kinds...)
+		if err != nil {
+			if !errors.Is(err, ErrNoJob) && ctx.Err() == nil {
+				w.logger.Println("jobqueue: lease:", err)
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(w.pollInterval):
+			}
+			continue
+		}
+		w.process(ctx, j)
+	}
+}
+
+// process はHeartbeatでリースを延長しながらHandlerを実行する
+func (w *Worker) process(ctx context.Context, j *Job) {
+	hctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// 3ns未満の期間でも間隔が0にならないようにする
+		ticker := time.NewTicker(max(w.visibility/3, time.Nanosecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := w.q.Heartbeat(context.Background(), j, w.visibility); err != nil {
+					// キャンセルされたか他のワーカーに渡ったので処理を止める
+					w.logger.Printf("jobqueue: heartbeat job %d: %v", j.ID, err)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err := w.call(hctx, j)
+	close(stop)
+	wg.Wait()
+
+	// ctxが終わっていてもジョブの状態は記録する
+	bg := context.Background()
+	switch {
+	case ctx.Err() != nil && err != nil:
+		err = w.q.release(bg, j)
+	case err != nil:
+		err = w.q.Fail(bg, j, err)
+	default:
+		err = w.q.Complete(bg, j)
+	}
+	if err != nil && !errors.Is(err, ErrLeaseLost) {
+		w.logger.Printf("jobqueue: job %d: %v", j.ID, err)
+	}
+}
+
+func (w *Worker) call(ctx context.Context, j *Job) (rerr error) {
+	h, ok := w.handlers[j.Kind]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler for kind %q", j.Kind))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			rerr = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h(ctx, j)
+}
@@ -28,10 +28,13 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
 
+	"example.com/mod/addressbook"
+
 	"github.com/tenntenn/sqlite"
 )
 
@@ -167,64 +170,57 @@ func main() {
 // ロールバック
 // func (tx *Tx) Rollback() error
 
-// ** Q. 電話帳を作ろう */
-type Record struct {
-	ID    int64
-	Name  string
-	Phone string
-}
+// ** ジョブキュー */ ・・jobqueueパッケージ
+// 一括インポートのような時間のかかる処理はHTTPリクエストの中で実行しない
+// SQLiteのテーブルにジョブを積んでワーカーが取り出して処理する
+// - リースとハートビート
+// 取り出したジョブは一定時間だけ他のワーカーから見えなくなる
+// 処理中はハートビートで期限を延長し、ワーカーが落ちたら他のワーカーに渡る
+// - リトライ
+// 失敗したら指数バックオフで再実行し、回数を使い切るとdeadになる
+// q, err := jobqueue.New(db)
+// q.Enqueue(ctx, "import", payload, jobqueue.UniqueKey("import:addressbook"))
+// w := jobqueue.NewWorker(q)
+// w.Handle("import", func(ctx context.Context, j *jobqueue.Job) error { /* 処理 */ })
+// w.Run(ctx)
+// 管理API: http.Handle("/admin/", http.StripPrefix("/admin", jobqueue.NewAdminHandler(q)))
 
+// ** Q. 電話帳を作ろう */
+// テーブルの読み書きはaddressbook.Storeにまとめた
+// エラーは*errs.OpErrorなので、errors.Asで失敗した操作とテーブルを取り出せる
+// var oe *errs.OpError
+// if errors.As(err, &oe) { fmt.Println(oe.Op, oe.Resource) } // insert addressbook
 func run() error {
 	db, err := sql.Open(sqlite.DriverName, "addressbook.db")
 	if err != nil {
 		return err
 	}
 
-	if err := createTable(db); err != nil {
+	store, err := addressbook.NewStore(db)
+	if err != nil {
 		return err
 	}
 
+	ctx := context.Background()
 	for {
-		if err := showRecords(db); err != nil {
+		if err := showRecords(ctx, store); err != nil {
 			return err
 		}
 
-		if err := inputRecord(db); err != nil {
+		if err := inputRecord(ctx, store); err != nil {
 			return err
 		}
 	}
-
-	return nil
-}
-
-// テーブルの作成
-func createTable(db *sql.DB) error {
-	const sql = `
-	CREATE TABLE IF NOT EXISTS addressbook (
-			id    INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
-			name  TEXT NOT NULL,
-			phone TEXT NOT NULL
-	);`
-
-	if _, err := db.Exec(sql); err != nil {
-		return err
-	}
-
-	return nil
 }
 
 // テーブルの中身全件取得
-func showRecords(db *sql.DB) error {
+func showRecords(ctx context.Context, store *addressbook.Store) error {
 	fmt.Println("全件表示")
-	rows, err := db.Query("SELECT * FROM addressbook")
+	rs, err := store.List(ctx)
 	if err != nil {
 		return err
 	}
-	for rows.Next() {
-		var r Record
-		if err := rows.Scan(&r.ID, &r.Name, &r.Phone); err != nil {
-			return err
-		}
+	for _, r := range rs {
 		fmt.Printf("[%d] Name:%s TEL:%s\n", r.ID, r.Name, r.Phone)
 	}
 	fmt.Println("--------")
@@ -233,8 +229,8 @@ func showRecords(db *sql.DB) error {
 }
 
 // テーブルにデータ挿入
-func inputRecord(db *sql.DB) error {
-	var r Record
+func inputRecord(ctx context.Context, store *addressbook.Store) error {
+	var r addressbook.Record
 
 	fmt.Print("Name >")
 	fmt.Scan(&r.Name)
@@ -242,13 +238,7 @@ func inputRecord(db *sql.DB) error {
 	fmt.Print("TEL >")
 	fmt.Scan(&r.Phone)
 
-	const sql = "INSERT INTO addressbook(name, phone) values (?,?)"
-	_, err := db.Exec(sql, r.Name, r.Phone)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return store.Add(ctx, &r)
 }
 
 // ** Q. 電話帳を作ろう ここまで */
@@ -0,0 +1,83 @@
+// jadate は文章の中の日本語の日付を探して、指定したレイアウトに揃えて出力する
+//
+//	jadate < minutes.txt
+//	jadate -layout '{era}{eraYear}年1月2日（{week}）' a.txt
+//
+// 正しくない日付（平成32年1月1日など）はそのまま出力し、位置と理由を標準エラーに出力する
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang/recipe-golang/11.text/jadate"
+	"golang/recipe-golang/11.text/textpipe"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("jadate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	layout := fs.String("layout", "2006-01-02", "日付のレイアウト（time.Formatのレイアウトに{era}{eraYear}{week}{ampm}{hour}を加えたもの）")
+	dtLayout := fs.String("datetime-layout", "2006-01-02 15:04:05", "時刻も書かれている日付のレイアウト")
+	strict := fs.Bool("strict", false, "正しくない日付があれば終了コードを1にする")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	names := fs.Args()
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	invalid := false
+	for _, name := range names {
+		r := stdin
+		if name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				return 1
+			}
+			defer f.Close()
+			r = f
+		}
+
+		lineNum := 0
+		normalize := textpipe.LineFunc(func(line string) string {
+			lineNum++
+			var b strings.Builder
+			last := 0
+			for _, m := range jadate.FindAll(line, time.UTC) {
+				if m.Err != nil {
+					invalid = true
+					fmt.Fprintf(stderr, "%s:%d:%d: %s\n", name, lineNum, m.Err.Column(), m.Err.Msg)
+					continue
+				}
+				l := *layout
+				if m.HasTime {
+					l = *dtLayout
+				}
+				b.WriteString(line[last:m.Start])
+				b.WriteString(jadate.Format(m.Time, l))
+				last = m.End
+			}
+			b.WriteString(line[last:])
+			return b.String()
+		})
+		if err := textpipe.New(normalize).Run(stdout, r); err != nil {
+			fmt.Fprintln(stderr, "jadate:", err)
+			return 1
+		}
+	}
+	if *strict && invalid {
+		return 1
+	}
+	return 0
+}
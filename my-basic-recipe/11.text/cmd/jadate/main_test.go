@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	in := "会議は令和6年1月2日（火）午後3時から\n締切は二〇二四年一月三十一日、平成32年1月1日は誤り\n"
+	cases := map[string]struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		"default": {
+			stdout: "会議は2024-01-02 15:00:00から\n締切は2024-01-31、平成32年1月1日は誤り\n",
+			stderr: "-:2:16: 2020-01-01 is after 平成 ended (令和 started on 2019-05-01)\n",
+		},
+		"wareki": {
+			args:   []string{"-layout", "{era}{eraYear}年1月2日", "-datetime-layout", "{era}{eraYear}年1月2日 {ampm}{hour}時"},
+			stdout: "会議は令和6年1月2日 午後3時から\n締切は令和6年1月31日、平成32年1月1日は誤り\n",
+		},
+		"strict": {args: []string{"-strict"}, code: 1},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, strings.NewReader(in), &stdout, &stderr); code != tt.code {
+				t.Fatalf("want exit code %d, got %d", tt.code, code)
+			}
+			if tt.stdout != "" && stdout.String() != tt.stdout {
+				t.Errorf("want %q, got %q", tt.stdout, stdout.String())
+			}
+			if tt.stderr != "" && stderr.String() != tt.stderr {
+				t.Errorf("want %q, got %q", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// logextract はYAMLで定義した名前付きグループの正規表現でログを読み、JSON LinesかCSVで出力する
+//
+//	logextract -patterns patterns.yaml access.log
+//	logextract -patterns patterns.yaml -format csv -unmatched rest.log < app.log
+//
+// どのパターンにもマッチしなかった行は-unmatchedのファイルにそのまま書き出す
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"golang/recipe-golang/11.text/logextract"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("logextract", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	patterns := fs.String("patterns", "", "パターンを定義したYAMLファイル（必須）")
+	format := fs.String("format", "json", "出力形式 `json|csv`")
+	unmatched := fs.String("unmatched", "", "マッチしなかった行を書き出すファイル")
+	workers := fs.Int("workers", 0, "並行して処理するゴールーチンの数（0はCPUの数）")
+	chunk := fs.Int("chunk", 0, "まとめて処理する行数（0は1024）")
+	verbose := fs.Bool("v", false, "処理した行数を標準エラーに出力する")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *patterns == "" {
+		fmt.Fprintln(stderr, "logextract: -patterns is required")
+		fs.Usage()
+		return 2
+	}
+
+	lib, err := loadLibrary(*patterns)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	opts := []logextract.Option{logextract.WithWorkers(*workers), logextract.WithChunkLines(*chunk)}
+	if *unmatched != "" {
+		f, err := os.Create(*unmatched)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		defer f.Close()
+		opts = append(opts, logextract.WithUnmatched(f))
+	}
+	x, err := logextract.New(lib, opts...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	var w logextract.Writer
+	switch *format {
+	case "json":
+		w = logextract.NewJSONWriter(stdout)
+	case "csv":
+		w = logextract.NewCSVWriter(stdout, x.Fields())
+	default:
+		fmt.Fprintf(stderr, "logextract: unknown format %q\n", *format)
+		return 2
+	}
+
+	names := fs.Args()
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	code := 0
+	for _, name := range names {
+		stats, err := extract(ctx, x, w, name, stdin)
+		if *verbose {
+			fmt.Fprintf(stderr, "%s: %d lines, %d matched, %d unmatched (%d invalid)\n",
+				name, stats.Lines, stats.Matched, stats.Unmatched, stats.Invalid)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			code = 1
+			break
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return code
+}
+
+func loadLibrary(name string) (*logextract.Library, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return logextract.LoadLibrary(f)
+}
+
+// extract はnameのファイルを読んでwに書き出す。nameが-なら標準入力を読む
+func extract(ctx context.Context, x *logextract.Extractor, w logextract.Writer, name string, stdin io.Reader) (logextract.Stats, error) {
+	r := stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return logextract.Stats{}, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return x.Run(ctx, r, w.Write)
+}
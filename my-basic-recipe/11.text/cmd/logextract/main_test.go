@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		args   []string
+		stdin  string
+		code   int
+		stderr string
+	}{
+		"json":        {args: []string{"-patterns", "testdata/patterns.yaml", "testdata/input.log"}},
+		"csv":         {args: []string{"-patterns", "testdata/patterns.yaml", "-format", "csv", "-chunk", "1", "-workers", "4", "testdata/input.log"}},
+		"stdin":       {args: []string{"-patterns", "testdata/patterns.yaml", "-v"}, stdin: "x\n2024-01-12T10:00:00Z INFO ok\n", stderr: "-: 2 lines, 1 matched, 1 unmatched (0 invalid)"},
+		"no patterns": {args: nil, code: 2, stderr: "-patterns is required"},
+		"bad format":  {args: []string{"-patterns", "testdata/patterns.yaml", "-format", "xml"}, code: 2, stderr: `unknown format "xml"`},
+		"bad library": {args: []string{"-patterns", "testdata/input.log"}, code: 2, stderr: "logextract: load library: "},
+		"no file":     {args: []string{"-patterns", "testdata/patterns.yaml", "testdata/missing.log"}, code: 1, stderr: "open testdata/missing.log: "},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var stdout, stderr bytes.Buffer
+			code := run(context.Background(), tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.code {
+				t.Fatalf("want exit code %d, got %d (%s)", tt.code, code, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("want %q in stderr, got %q", tt.stderr, stderr.String())
+			}
+			if tt.code != 0 {
+				return
+			}
+
+			golden := filepath.Join("testdata", strings.ReplaceAll(name, " ", "_")+".golden")
+			if *update {
+				if err := os.WriteFile(golden, stdout.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stdout.String() != string(want) {
+				t.Errorf("want %q, got %q", want, stdout.String())
+			}
+		})
+	}
+}
+
+func TestRun_Unmatched(t *testing.T) {
+	t.Parallel()
+	out := filepath.Join(t.TempDir(), "rest.log")
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"-patterns", "testdata/patterns.yaml", "-unmatched", out, "testdata/input.log"}, nil, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("want exit code 0, got %d (%s)", code, stderr.String())
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "not a log line\n2024-01-12T10:00:04+09:00 INFO slow took=forever\n"
+	if string(got) != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
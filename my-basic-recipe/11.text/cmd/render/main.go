@@ -0,0 +1,157 @@
+// render はJSON・YAML・CSV・環境変数のデータをtext/templateのテンプレートに当てはめて出力する
+//
+//	render -data config.yaml nginx.conf.tmpl > nginx.conf
+//	render -data users=users.csv -set title=名簿 -strict list.tmpl
+//	render -env APP_ -o app.env app.env.tmpl
+//	curl -s https://example.com/api | render -data - -format json report.tmpl
+//	render -manifest site.yaml -out public
+//
+// -dataのkey=を省略するとトップレベルにマージする。テンプレートの後ろのファイルは{{ template }}で呼び出せる部品になる
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang/recipe-golang/11.text/render"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// listFlag は何度でも指定できるフラグ
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var dataFiles, sets listFlag
+	fs.Var(&dataFiles, "data", "データのファイル `[key=]file`（-は標準入力。何度でも指定できる）")
+	fs.Var(&sets, "set", "データに文字列を設定する `key=value`（何度でも指定できる）")
+	format := fs.String("format", "", "標準入力のデータの形式 `json|yaml|csv`")
+	env := fs.String("env", "", "このprefixで始まる環境変数をenvに入れる")
+	strict := fs.Bool("strict", false, "データにないキーを参照したらエラーにする")
+	output := fs.String("o", "", "出力するファイル（省略すると標準出力）")
+	manifest := fs.String("manifest", "", "複数のファイルを出力するマニフェスト")
+	outDir := fs.String("out", ".", "-manifestで出力するディレクトリ")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: render [flags] template [partials...]\n       render -manifest manifest.yaml [-out dir] [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *manifest == "" && fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	data := make(render.Data)
+	var m *render.Manifest
+	if *manifest != "" {
+		var err error
+		if m, err = loadManifest(*manifest); err != nil {
+			fmt.Fprintln(stderr, "render:", err)
+			return 2
+		}
+		if data, err = m.LoadData(filepath.Dir(*manifest)); err != nil {
+			fmt.Fprintln(stderr, "render:", err)
+			return 2
+		}
+	}
+	for _, d := range dataFiles {
+		key, name, ok := strings.Cut(d, "=")
+		if !ok {
+			key, name = "", d
+		}
+		v, err := loadData(name, *format, stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, "render:", err)
+			return 2
+		}
+		if err := render.Merge(data, key, v); err != nil {
+			fmt.Fprintf(stderr, "render: %s: %v\n", name, err)
+			return 2
+		}
+	}
+	if *env != "" {
+		data["env"] = render.Env(*env)
+	}
+	for _, kv := range sets {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			fmt.Fprintf(stderr, "render: -set %q: want key=value\n", kv)
+			return 2
+		}
+		data[k] = v
+	}
+
+	var partials []string
+	if fs.NArg() > 1 {
+		partials = fs.Args()[1:]
+	}
+	r := render.New(render.WithStrict(*strict), render.WithPartials(partials...))
+	if m != nil {
+		written, err := r.Render(m, filepath.Dir(*manifest), *outDir, data)
+		for _, name := range written {
+			fmt.Fprintln(stdout, name)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "render:", err)
+			return 1
+		}
+		return 0
+	}
+
+	// 途中でエラーになったときに中途半端な出力を残さないよう、まとめてから書き込む
+	var buf bytes.Buffer
+	if err := r.ExecuteFile(&buf, fs.Arg(0), data); err != nil {
+		fmt.Fprintln(stderr, "render:", err)
+		return 1
+	}
+	if *output == "" {
+		if _, err := stdout.Write(buf.Bytes()); err != nil {
+			fmt.Fprintln(stderr, "render:", err)
+			return 1
+		}
+		return 0
+	}
+	if err := os.WriteFile(*output, buf.Bytes(), 0o644); err != nil {
+		fmt.Fprintln(stderr, "render:", err)
+		return 1
+	}
+	return 0
+}
+
+func loadManifest(name string) (*render.Manifest, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return render.LoadManifest(f)
+}
+
+// loadData はnameが-なら標準入力をformatで、それ以外はファイルを拡張子の形式で読み込む
+func loadData(name, format string, stdin io.Reader) (interface{}, error) {
+	if name != "-" {
+		return render.LoadFile(name)
+	}
+	if format == "" {
+		return nil, fmt.Errorf("-format is required to read data from stdin")
+	}
+	return render.Load(stdin, format)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		args   []string
+		stdin  string
+		code   int
+		stderr string
+	}{
+		"yaml":         {args: []string{"-data", "testdata/config.yaml", "testdata/nginx.conf.tmpl"}},
+		"csv":          {args: []string{"-data", "users=testdata/users.csv", "-set", "title=会員", "testdata/list.tmpl", "testdata/_header.tmpl"}},
+		"stdin":        {args: []string{"-data", "users=-", "-format", "csv", "testdata/list.tmpl", "testdata/_header.tmpl"}, stdin: "name,joined,points\n鈴木一郎,1989-01-08,1234567\n"},
+		"not strict":   {args: []string{"-data", "testdata/config.yaml", "testdata/missing.tmpl"}},
+		"strict":       {args: []string{"-strict", "-data", "testdata/config.yaml", "testdata/missing.tmpl"}, code: 1, stderr: `map has no entry for key "host"`},
+		"required":     {args: []string{"testdata/nginx.conf.tmpl"}, code: 1, stderr: "server.nameは必須です"},
+		"no template":  {args: nil, code: 2, stderr: "usage:"},
+		"no format":    {args: []string{"-data", "-", "testdata/list.tmpl"}, code: 2, stderr: "-format is required"},
+		"bad set":      {args: []string{"-set", "title", "testdata/list.tmpl"}, code: 2, stderr: "want key=value"},
+		"merge list":   {args: []string{"-data", "testdata/users.csv", "testdata/list.tmpl"}, code: 2, stderr: "want an object"},
+		"bad manifest": {args: []string{"-manifest", "testdata/config.yaml"}, code: 2, stderr: "field server not found"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.code {
+				t.Fatalf("want exit code %d, got %d (%s)", tt.code, code, stderr.String())
+			}
+			if tt.code != 0 {
+				if !strings.Contains(stderr.String(), tt.stderr) {
+					t.Errorf("want %q in stderr, got %q", tt.stderr, stderr.String())
+				}
+				return
+			}
+
+			golden := filepath.Join("testdata", strings.ReplaceAll(name, " ", "_")+".golden")
+			if *update {
+				if err := os.WriteFile(golden, stdout.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stdout.String() != string(want) {
+				t.Errorf("want %q, got %q", want, stdout.String())
+			}
+		})
+	}
+}
+
+func TestRun_Env(t *testing.T) {
+	t.Setenv("RENDER_TEST_PORT", "8080")
+	tmpl := filepath.Join(t.TempDir(), "env.tmpl")
+	if err := os.WriteFile(tmpl, []byte("PORT={{ .env.PORT }}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "app.env")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-env", "RENDER_TEST_", "-o", out, tmpl}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("want exit code 0, got %d (%s)", code, stderr.String())
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "PORT=8080\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestRun_Manifest(t *testing.T) {
+	t.Parallel()
+	out := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-manifest", "testdata/site/manifest.yaml", "-out", out, "-strict"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("want exit code 0, got %d (%s)", code, stderr.String())
+	}
+	if got := strings.Count(stdout.String(), "\n"); got != 3 {
+		t.Errorf("want 3 files written, got %q", stdout.String())
+	}
+	want := map[string]string{
+		"index.txt":   "1 山田太郎\n2 佐藤花子\n",
+		"users/1.txt": "山田太郎: 12,000pt (1/2)\n",
+		"users/2.txt": "佐藤花子: 800pt (2/2)\n",
+	}
+	for name, content := range want {
+		b, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Errorf("%s: want %q, got %q", name, content, b)
+		}
+	}
+}
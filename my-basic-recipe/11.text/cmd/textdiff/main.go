@@ -0,0 +1,342 @@
+// textdiff はファイルやディレクトリの差分を出力し、unified diffのパッチを当てる
+//
+//	textdiff diff old.txt new.txt
+//	textdiff diff -format side -width 120 old.txt new.txt
+//	textdiff diff -format json olddir newdir
+//	textdiff diff olddir newdir > fix.patch
+//	textdiff patch -p 1 -fuzz 2 < fix.patch
+//
+// diffの終了コードは差分がなければ0、あれば1、エラーなら2
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang/recipe-golang/11.text/diff"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+const usage = `usage:
+  textdiff diff [flags] old new
+  textdiff patch [flags] [patchfile]
+`
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	switch args[0] {
+	case "diff":
+		return runDiff(args[1:], stdout, stderr)
+	case "patch":
+		return runPatch(args[1:], stdin, stdout, stderr)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	}
+	fmt.Fprintf(stderr, "textdiff: unknown command %q\n%s", args[0], usage)
+	return 2
+}
+
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("textdiff diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	context := fs.Int("u", 3, "前後に出力する文脈の行数")
+	format := fs.String("format", "unified", "出力形式 `unified|side|json`")
+	width := fs.Int("width", 80, "sideで出力するときの全体の表示幅")
+	patience := fs.Bool("patience", false, "patience diffを使う")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintln(stderr, "textdiff diff: want old and new")
+		return 2
+	}
+	var opts []diff.Option
+	if *patience {
+		opts = append(opts, diff.WithAlgorithm(diff.Patience))
+	}
+	// jsonではファイルごとの差分を集めて最後に1つの配列で出力する
+	files := []jsonFile{}
+	var write func(oldName, newName string, edits []diff.Edit) error
+	switch *format {
+	case "unified":
+		write = func(oldName, newName string, edits []diff.Edit) error {
+			return diff.WriteUnified(stdout, oldName, newName, diff.Hunks(edits, *context))
+		}
+	case "side":
+		write = func(oldName, newName string, edits []diff.Edit) error {
+			fmt.Fprintf(stdout, "=== %s %s\n", oldName, newName)
+			return diff.WriteSideBySide(stdout, edits, *width)
+		}
+	case "json":
+		write = func(oldName, newName string, edits []diff.Edit) error {
+			files = append(files, jsonFile{OldName: oldName, NewName: newName, Hunks: diff.Hunks(edits, *context)})
+			return nil
+		}
+	default:
+		fmt.Fprintf(stderr, "textdiff diff: unknown format %q\n", *format)
+		return 2
+	}
+
+	pairs, err := filePairs(fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(stderr, "textdiff diff:", err)
+		return 2
+	}
+	changed := false
+	for _, p := range pairs {
+		a, err := readOrEmpty(p.old)
+		if err != nil {
+			fmt.Fprintln(stderr, "textdiff diff:", err)
+			return 2
+		}
+		b, err := readOrEmpty(p.new)
+		if err != nil {
+			fmt.Fprintln(stderr, "textdiff diff:", err)
+			return 2
+		}
+		edits := diff.Lines(a, b, opts...)
+		if !diff.HasChanges(edits) {
+			continue
+		}
+		changed = true
+		if err := write(p.oldName(), p.newName(), edits); err != nil {
+			fmt.Fprintln(stderr, "textdiff diff:", err)
+			return 2
+		}
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(files); err != nil {
+			fmt.Fprintln(stderr, "textdiff diff:", err)
+			return 2
+		}
+	}
+	if changed {
+		return 1
+	}
+	return 0
+}
+
+// jsonFile はJSONで出力する1つのファイルの差分
+type jsonFile struct {
+	OldName string      `json:"old_name"`
+	NewName string      `json:"new_name"`
+	Hunks   []diff.Hunk `json:"hunks"`
+}
+
+// pair は比べる2つのファイル。片方にしかないときはもう片方が""
+type pair struct{ old, new string }
+
+const devNull = "/dev/null"
+
+func (p pair) oldName() string {
+	if p.old == "" {
+		return devNull
+	}
+	return filepath.ToSlash(p.old)
+}
+
+func (p pair) newName() string {
+	if p.new == "" {
+		return devNull
+	}
+	return filepath.ToSlash(p.new)
+}
+
+// filePairs はoldとnewがディレクトリならfilepath.Walkでたどって同じ相対パスのファイルを組にする
+func filePairs(oldPath, newPath string) ([]pair, error) {
+	oldInfo, err := os.Stat(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	newInfo, err := os.Stat(newPath)
+	if err != nil {
+		return nil, err
+	}
+	if oldInfo.IsDir() != newInfo.IsDir() {
+		return nil, fmt.Errorf("cannot compare a file with a directory: %s, %s", oldPath, newPath)
+	}
+	if !oldInfo.IsDir() {
+		return []pair{{oldPath, newPath}}, nil
+	}
+
+	oldFiles, err := walkFiles(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := walkFiles(newPath)
+	if err != nil {
+		return nil, err
+	}
+	rels := make(map[string]bool)
+	for rel := range oldFiles {
+		rels[rel] = true
+	}
+	for rel := range newFiles {
+		rels[rel] = true
+	}
+	sorted := make([]string, 0, len(rels))
+	for rel := range rels {
+		sorted = append(sorted, rel)
+	}
+	sort.Strings(sorted)
+
+	pairs := make([]pair, 0, len(sorted))
+	for _, rel := range sorted {
+		var p pair
+		if oldFiles[rel] {
+			p.old = filepath.Join(oldPath, rel)
+		}
+		if newFiles[rel] {
+			p.new = filepath.Join(newPath, rel)
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, nil
+}
+
+// walkFiles はroot以下の通常のファイルの相対パスを返す
+func walkFiles(root string) (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[rel] = true
+		return nil
+	})
+	return files, err
+}
+
+func readOrEmpty(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	b, err := os.ReadFile(name)
+	return string(b), err
+}
+
+func runPatch(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("textdiff patch", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	strip := fs.Int("p", 0, "ファイル名の先頭から取り除くディレクトリの数")
+	fuzz := fs.Int("fuzz", 0, "Hunkが見つからないときに無視してよい文脈の行数")
+	dir := fs.String("dir", ".", "パッチを当てるディレクトリ")
+	dryRun := fs.Bool("dry-run", false, "ファイルを書き換えずに当てられるかだけを確かめる")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	r := stdin
+	if fs.NArg() > 0 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(stderr, "textdiff patch:", err)
+			return 2
+		}
+		defer f.Close()
+		r = f
+	}
+	patches, err := diff.Parse(r)
+	if err != nil {
+		fmt.Fprintln(stderr, "textdiff patch:", err)
+		return 2
+	}
+
+	code := 0
+	for _, p := range patches {
+		name, err := target(p, *strip)
+		if err != nil {
+			fmt.Fprintln(stderr, "textdiff patch:", err)
+			return 2
+		}
+		path := filepath.Join(*dir, name)
+		src, err := os.ReadFile(path)
+		if err != nil && !(errors.Is(err, os.ErrNotExist) && p.OldName == devNull) {
+			fmt.Fprintln(stderr, "textdiff patch:", err)
+			code = 1
+			continue
+		}
+		result, err := diff.Apply(string(src), p.Hunks, *fuzz)
+		if err != nil {
+			fmt.Fprintf(stderr, "textdiff patch: %s: %v\n", name, err)
+			code = 1
+			continue
+		}
+		fmt.Fprintf(stdout, "patching %s\n", name)
+		if *dryRun {
+			continue
+		}
+		if p.NewName == devNull {
+			err = os.Remove(path)
+		} else {
+			err = writeFile(path, result)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "textdiff patch:", err)
+			code = 1
+		}
+	}
+	return code
+}
+
+// target はパッチを当てるファイルの名前を返す
+// -dirの外を書き換えないように、絶対パスと..で外に出る名前はエラーにする
+func target(p diff.FilePatch, strip int) (string, error) {
+	name := p.NewName
+	if name == devNull {
+		name = p.OldName
+	}
+	parts := strings.Split(name, "/")
+	if strip >= len(parts) {
+		return "", fmt.Errorf("cannot strip %d components from %s", strip, name)
+	}
+	rel := strings.Join(parts[strip:], "/")
+	if rel == "" || strings.HasPrefix(rel, "/") || filepath.IsAbs(filepath.FromSlash(rel)) || filepath.VolumeName(filepath.FromSlash(rel)) != "" {
+		return "", fmt.Errorf("refusing to patch %q outside the target directory", name)
+	}
+	clean := filepath.Clean(filepath.FromSlash(rel))
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing to patch %q outside the target directory", name)
+	}
+	return clean, nil
+}
+
+func writeFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	return os.WriteFile(path, []byte(content), mode)
+}
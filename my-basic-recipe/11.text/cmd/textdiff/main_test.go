@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		"unified":     {args: []string{"diff", "testdata/old", "testdata/new"}, code: 1},
+		"side":        {args: []string{"diff", "-format", "side", "-width", "30", "testdata/old/greet.txt", "testdata/new/greet.txt"}, code: 1},
+		"json":        {args: []string{"diff", "-format", "json", "-u", "0", "testdata/old", "testdata/new"}, code: 1},
+		"same":        {args: []string{"diff", "testdata/old/same.txt", "testdata/new/same.txt"}},
+		"no command":  {args: nil, code: 2, stderr: "usage:"},
+		"bad command": {args: []string{"merge"}, code: 2, stderr: `unknown command "merge"`},
+		"one file":    {args: []string{"diff", "testdata/old"}, code: 2, stderr: "want old and new"},
+		"file vs dir": {args: []string{"diff", "testdata/old", "testdata/new/greet.txt"}, code: 2, stderr: "cannot compare a file with a directory"},
+		"bad format":  {args: []string{"diff", "-format", "html", "testdata/old", "testdata/new"}, code: 2, stderr: `unknown format "html"`},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, strings.NewReader(""), &stdout, &stderr)
+			if code != tt.code {
+				t.Fatalf("want exit code %d, got %d (%s)", tt.code, code, stderr.String())
+			}
+			if tt.code == 2 {
+				if !strings.Contains(stderr.String(), tt.stderr) {
+					t.Errorf("want %q in stderr, got %q", tt.stderr, stderr.String())
+				}
+				return
+			}
+
+			golden := filepath.Join("testdata", strings.ReplaceAll(name, " ", "_")+".golden")
+			if *update {
+				if err := os.WriteFile(golden, stdout.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stdout.String() != string(want) {
+				t.Errorf("want %q, got %q", want, stdout.String())
+			}
+		})
+	}
+}
+
+// TestRun_Patch はディレクトリの差分をパッチとして当てると新しい方と同じになることを確かめる
+func TestRun_Patch(t *testing.T) {
+	t.Parallel()
+	var patch, stderr bytes.Buffer
+	if code := run([]string{"diff", "testdata/old", "testdata/new"}, nil, &patch, &stderr); code != 1 {
+		t.Fatalf("want exit code 1, got %d (%s)", code, stderr.String())
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"greet.txt", "same.txt", filepath.Join("sub", "gone.txt")} {
+		b, err := os.ReadFile(filepath.Join("testdata", "old", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writeFile(filepath.Join(dir, name), string(b)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout bytes.Buffer
+	// testdata/old/greet.txtのtestdata/oldを取り除く
+	code := run([]string{"patch", "-p", "2", "-dir", dir}, strings.NewReader(patch.String()), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("want exit code 0, got %d (%s)", code, stderr.String())
+	}
+	if code := run([]string{"diff", dir, "testdata/new"}, nil, &stdout, &stderr); code != 0 {
+		t.Errorf("want no differences after patching, got exit code %d", code)
+	}
+
+	// もう一度当てると失敗する
+	stderr.Reset()
+	code = run([]string{"patch", "-p", "2", "-dir", dir, "-dry-run"}, strings.NewReader(patch.String()), &stdout, &stderr)
+	if code != 1 || !strings.Contains(stderr.String(), "hunk does not apply") {
+		t.Errorf("want hunk failure, got exit code %d (%s)", code, stderr.String())
+	}
+}
+
+func TestRun_PatchOutsideDir(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"parent":   "--- a/x\n+++ ../../x\n@@ -0,0 +1 @@\n+evil\n",
+		"absolute": "--- a/x\n+++ /tmp/x\n@@ -0,0 +1 @@\n+evil\n",
+		"delete":   "--- sub/../../x\n+++ /dev/null\n@@ -1 +0,0 @@\n-x\n",
+	}
+	for name, patch := range cases {
+		name, patch := name, patch
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			root := t.TempDir()
+			dir := filepath.Join(root, "a", "b")
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(root, "x"), []byte("x\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"patch", "-dir", dir}, strings.NewReader(patch), &stdout, &stderr)
+			if code != 2 || !strings.Contains(stderr.String(), "outside the target directory") {
+				t.Errorf("want exit code 2, got %d (%s)", code, stderr.String())
+			}
+			if b, err := os.ReadFile(filepath.Join(root, "x")); err != nil || string(b) != "x\n" {
+				t.Errorf("want file outside -dir untouched, got %q (%v)", b, err)
+			}
+		})
+	}
+}
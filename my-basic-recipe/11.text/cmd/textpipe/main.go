@@ -0,0 +1,140 @@
+// textpipe は標準入力かファイルを1行ずつ読み込み、フラグかYAMLで指定した段を順に通して出力する
+//
+//	textpipe -replace 郷=Go -upper -grep GO -head 10 a.txt b.txt
+//	textpipe -config pipe.yaml < a.txt
+//
+// 段はフラグを指定した順につながる
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang/recipe-golang/11.text/textpipe"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var c textpipe.Config
+	fs := newFlagSet(&c)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	p, err := c.Pipeline()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	var rs []io.Reader
+	for _, name := range fs.Args() {
+		if name == "-" {
+			rs = append(rs, stdin)
+			continue
+		}
+		// ファイルは最後まで開いたままになるが、読み込みは順に1行ずつ行う
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		defer f.Close()
+		rs = append(rs, f)
+	}
+	if len(rs) == 0 {
+		rs = append(rs, stdin)
+	}
+
+	if err := p.Run(stdout, rs...); err != nil {
+		fmt.Fprintln(stderr, "textpipe:", err)
+		return 1
+	}
+	return 0
+}
+
+// newFlagSet は指定した順にc.Stagesに段を追加するフラグを定義する
+func newFlagSet(c *textpipe.Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("textpipe", flag.ContinueOnError)
+	add := func(sc textpipe.StageConfig) { c.Stages = append(c.Stages, sc) }
+	// boolStage は-upper=falseのように指定された場合は追加しない
+	boolStage := func(sc textpipe.StageConfig) func(string) error {
+		return func(s string) error {
+			b, err := strconv.ParseBool(s)
+			if b {
+				add(sc)
+			}
+			return err
+		}
+	}
+
+	fs.Func("config", "YAMLで定義した段を追加する", func(name string) error {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		loaded, err := textpipe.LoadConfig(f)
+		if err != nil {
+			return err
+		}
+		c.Stages = append(c.Stages, loaded.Stages...)
+		return nil
+	})
+	fs.Func("replace", "`old=new` で置換する（複数指定できる）", func(s string) error {
+		from, to, ok := strings.Cut(s, "=")
+		if !ok {
+			return errors.New("want old=new")
+		}
+		add(textpipe.StageConfig{Type: "replace", Pairs: []string{from, to}})
+		return nil
+	})
+	fs.Func("regex", "`pattern=>template` で正規表現にマッチした部分を置換する（$nameでキャプチャを参照）", func(s string) error {
+		pattern, template, ok := strings.Cut(s, "=>")
+		if !ok {
+			return errors.New("want pattern=>template")
+		}
+		add(textpipe.StageConfig{Type: "regex", Pattern: pattern, Template: template})
+		return nil
+	})
+	fs.BoolFunc("upper", "大文字にする", boolStage(textpipe.StageConfig{Type: "upper"}))
+	fs.BoolFunc("lower", "小文字にする", boolStage(textpipe.StageConfig{Type: "lower"}))
+	fs.BoolFunc("trim", "前後の空白を取り除く", boolStage(textpipe.StageConfig{Type: "trim"}))
+	fs.Func("width", "`narrow|wide|fold` で文字幅を揃える", func(s string) error {
+		add(textpipe.StageConfig{Type: "width", Mode: s})
+		return nil
+	})
+	fs.BoolFunc("normalize", "全角英数字・半角カナ・長音・空白をそろえる", boolStage(textpipe.StageConfig{Type: "normalize"}))
+	fs.Func("grep", "正規表現にマッチする行だけを出力する", func(s string) error {
+		add(textpipe.StageConfig{Type: "grep", Pattern: s})
+		return nil
+	})
+	fs.Func("grepv", "正規表現にマッチしない行だけを出力する", func(s string) error {
+		add(textpipe.StageConfig{Type: "grep", Pattern: s, Invert: true})
+		return nil
+	})
+	fs.BoolFunc("uniq", "連続した重複行を取り除く", boolStage(textpipe.StageConfig{Type: "dedupe", Adjacent: true}))
+	fs.BoolFunc("dedupe", "重複行をすべて取り除く", boolStage(textpipe.StageConfig{Type: "dedupe"}))
+	lines := func(typ string) func(string) error {
+		return func(s string) error {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return err
+			}
+			add(textpipe.StageConfig{Type: typ, N: n})
+			return nil
+		}
+	}
+	fs.Func("head", "最初の`n`行だけを出力する", lines("head"))
+	fs.Func("tail", "最後の`n`行だけを出力する", lines("tail"))
+	return fs
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		args   []string
+		stdin  string
+		code   int
+		stderr string
+	}{
+		"flags": {
+			args: []string{"-replace", "郷=Go", "-replace", "入れば=入っては", "-regex", `(?P<Y>\d+)年(?P<M>\d+)月(?P<D>\d+)日=>$Y/$M/$D`,
+				"-trim", "-config", "testdata/pipe.yaml", "-upper", "testdata/input.txt"},
+		},
+		"stdin":       {args: []string{"-grepv", "^#", "-head", "1", "-"}, stdin: "# comment\nbody\nrest\n"},
+		"bool false":  {args: []string{"-upper=false"}, stdin: "keep\n"},
+		"bad regex":   {args: []string{"-grep", "("}, code: 2, stderr: "textpipe: stage 1: grep: "},
+		"bad flag":    {args: []string{"-replace", "nosep"}, code: 2, stderr: `invalid value "nosep" for flag -replace: want old=new`},
+		"no file":     {args: []string{"testdata/missing.txt"}, code: 1, stderr: "open testdata/missing.txt: "},
+		"bad config":  {args: []string{"-config", "testdata/input.txt"}, code: 2, stderr: "textpipe: load config: "},
+		"bad head":    {args: []string{"-head", "x"}, code: 2, stderr: `invalid value "x" for flag -head`},
+		"width fold":  {args: []string{"-width", "fold"}, stdin: "ＡＢＣ１２３ｶﾞｲﾄﾞ\n"},
+		"tail uniq":   {args: []string{"-dedupe", "-tail", "2"}, stdin: "a\nb\na\nc\n"},
+		"bad width":   {args: []string{"-width", "half"}, code: 2, stderr: "textpipe: stage 1: width: unknown mode"},
+		"lower trims": {args: []string{"-lower", "-trim"}, stdin: "  ABC \n"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.code {
+				t.Fatalf("want exit code %d, got %d (%s)", tt.code, code, stderr.String())
+			}
+			if tt.code != 0 {
+				if !strings.Contains(stderr.String(), tt.stderr) {
+					t.Errorf("want %q in stderr, got %q", tt.stderr, stderr.String())
+				}
+				return
+			}
+
+			golden := filepath.Join("testdata", strings.ReplaceAll(name, " ", "_")+".golden")
+			if *update {
+				if err := os.WriteFile(golden, stdout.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stdout.String() != string(want) {
+				t.Errorf("want %q, got %q", want, stdout.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,397 @@
+// Package diff はテキストの差分を求め、unified diffなどで出力したりパッチを当てたりする
+//
+// 行ごとの差分はLines、文字（rune）ごとの差分はRunesで求める
+// 差分はMyersのアルゴリズムで求め、差分が大きすぎるときはpatience diffに切り替える
+//
+//	edits := diff.Lines(oldText, newText)
+//	diff.WriteUnified(os.Stdout, "a.txt", "b.txt", diff.Hunks(edits, 3))
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op は編集の種類
+type Op int8
+
+const (
+	Equal  Op = iota // 両方にある
+	Delete           // 古い方だけにある
+	Insert           // 新しい方だけにある
+)
+
+var opNames = [...]string{Equal: "equal", Delete: "delete", Insert: "insert"}
+
+func (op Op) String() string {
+	if int(op) < len(opNames) {
+		return opNames[op]
+	}
+	return fmt.Sprintf("Op(%d)", int(op))
+}
+
+// MarshalText はJSONで"equal"などの文字列にする
+func (op Op) MarshalText() ([]byte, error) {
+	return []byte(op.String()), nil
+}
+
+// UnmarshalText は"equal"などの文字列を読み込む
+func (op *Op) UnmarshalText(b []byte) error {
+	for i, name := range opNames {
+		if string(b) == name {
+			*op = Op(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("diff: unknown op %q", b)
+}
+
+// Edit は1つの編集
+// Linesでは1行（改行を含む）、Runesでは同じ種類の編集が続く部分の文字列
+type Edit struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// Algorithm は差分を求めるアルゴリズム
+type Algorithm int
+
+const (
+	// Myers は最短の差分を求める。編集が多すぎるとPatienceに切り替える
+	Myers Algorithm = iota
+	// Patience は両方に1度だけ現れる行を目印にして分けてから差分を求める
+	// 最短とは限らないが、関数の移動などで読みやすい差分になりやすい
+	Patience
+)
+
+// DefaultMaxCost はMyersで探す編集数の上限のデフォルト
+const DefaultMaxCost = 1000
+
+type config struct {
+	alg     Algorithm
+	maxCost int
+}
+
+// Option は差分の求め方の設定
+type Option func(*config)
+
+// WithAlgorithm はアルゴリズムを指定する
+func WithAlgorithm(alg Algorithm) Option {
+	return func(c *config) { c.alg = alg }
+}
+
+// WithMaxCost はMyersで探す編集数の上限を指定する
+// 上限を超えるとpatience diffに切り替える。探索のメモリは上限の2乗に比例する
+func WithMaxCost(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.maxCost = n
+		}
+	}
+}
+
+// Lines は行ごとの差分を求める
+// 各Editの文字列は改行を含む（最後の行に改行がなければ含まない）
+func Lines(a, b string, opts ...Option) []Edit {
+	return Strings(SplitLines(a), SplitLines(b), opts...)
+}
+
+// Runes は文字（rune）ごとの差分を求め、同じ種類の編集が続く部分をまとめる
+func Runes(a, b string, opts ...Option) []Edit {
+	edits := Strings(splitRunes(a), splitRunes(b), opts...)
+	var merged []Edit
+	for _, e := range edits {
+		if n := len(merged); n > 0 && merged[n-1].Op == e.Op {
+			merged[n-1].Text += e.Text
+			continue
+		}
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+// Strings は要素ごとの差分を求める
+func Strings(a, b []string, opts ...Option) []Edit {
+	c := config{maxCost: DefaultMaxCost}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	// 文字列を番号に置き換えて比較を速くする
+	ids := make(map[string]int)
+	toIDs := func(ss []string) []int {
+		out := make([]int, len(ss))
+		for i, s := range ss {
+			id, ok := ids[s]
+			if !ok {
+				id = len(ids)
+				ids[s] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+	d := &differ{config: c, a: a, b: b}
+	d.diff(toIDs(a), toIDs(b), 0, 0)
+	return d.edits
+}
+
+// SplitLines は改行を含めたまま行に分ける
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func splitRunes(s string) []string {
+	out := make([]string, 0, len(s))
+	for _, r := range s {
+		out = append(out, string(r))
+	}
+	return out
+}
+
+// HasChanges はeditsにEqual以外の編集があるかを返す
+func HasChanges(edits []Edit) bool {
+	for _, e := range edits {
+		if e.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// Inline は文字ごとの差分を[-削除-]{+追加+}の形式で表す
+func Inline(edits []Edit) string {
+	var b strings.Builder
+	for _, e := range edits {
+		switch e.Op {
+		case Equal:
+			b.WriteString(e.Text)
+		case Delete:
+			b.WriteString("[-" + e.Text + "-]")
+		case Insert:
+			b.WriteString("{+" + e.Text + "+}")
+		}
+	}
+	return b.String()
+}
+
+type differ struct {
+	config
+	a, b  []string
+	edits []Edit
+}
+
+func (d *differ) emit(op Op, ai, bi int) {
+	if op == Insert {
+		d.edits = append(d.edits, Edit{Op: op, Text: d.b[bi]})
+		return
+	}
+	d.edits = append(d.edits, Edit{Op: op, Text: d.a[ai]})
+}
+
+// diff はa（d.aのaOffから）とb（d.bのbOffから）の差分をd.editsに追加する
+func (d *differ) diff(a, b []int, aOff, bOff int) {
+	// 共通の先頭と末尾は探索しない
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		d.emit(Equal, aOff+pre, 0)
+		pre++
+	}
+	a, b, aOff, bOff = a[pre:], b[pre:], aOff+pre, bOff+pre
+	suf := 0
+	for suf < len(a) && suf < len(b) && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	a, b = a[:len(a)-suf], b[:len(b)-suf]
+
+	switch {
+	case len(a) == 0 || len(b) == 0:
+		d.replace(len(a), len(b), aOff, bOff)
+	case d.alg == Patience:
+		d.patience(a, b, aOff, bOff)
+	default:
+		if ops, ok := myers(a, b, d.maxCost); ok {
+			ai, bi := aOff, bOff
+			for _, op := range ops {
+				d.emit(op, ai, bi)
+				if op != Insert {
+					ai++
+				}
+				if op != Delete {
+					bi++
+				}
+			}
+		} else {
+			d.patience(a, b, aOff, bOff)
+		}
+	}
+
+	for i := 0; i < suf; i++ {
+		d.emit(Equal, aOff+len(a)+i, 0)
+	}
+}
+
+// replace はaの範囲をすべて削除してbの範囲をすべて追加する
+func (d *differ) replace(na, nb, aOff, bOff int) {
+	for i := 0; i < na; i++ {
+		d.emit(Delete, aOff+i, 0)
+	}
+	for i := 0; i < nb; i++ {
+		d.emit(Insert, 0, bOff+i)
+	}
+}
+
+// myers はMyersのO(ND)のアルゴリズムでaからbへの最短の編集を求める
+// 編集数がmaxを超えるとfalseを返す
+func myers(a, b []int, max int) ([]Op, bool) {
+	n, m := len(a), len(b)
+	limit := n + m
+	if max < limit {
+		limit = max
+	}
+	off := limit + 1
+	v := make([]int, 2*limit+3)
+	// trace[d]はd回の編集でkの対角線ごとに到達できる最も遠いx（添字はk+d）
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m), true
+			}
+		}
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+	}
+	return nil, false
+}
+
+// backtrack はtraceを終点からたどって編集の列を作る
+func backtrack(trace [][]int, x, y int) []Op {
+	var ops []Op
+	for d := len(trace); d > 0; d-- {
+		prev := trace[d-1]
+		get := func(k int) int { return prev[k+d-1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && get(k-1) < get(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := get(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, Equal)
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, Insert)
+		} else {
+			ops = append(ops, Delete)
+		}
+		x, y = prevX, prevY
+	}
+	for ; x > 0; x-- {
+		ops = append(ops, Equal)
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// patience は両方に1度だけ現れる要素のうち、順序が保たれる最長の列を目印にして分ける
+// 目印の間はd.diffで再帰的に求め、目印がなければすべて置き換える
+// 目印の間は元より必ず短くなるので再帰は終わる
+func (d *differ) patience(a, b []int, aOff, bOff int) {
+	anchors := uniqueLCS(a, b)
+	if len(anchors) == 0 {
+		d.replace(len(a), len(b), aOff, bOff)
+		return
+	}
+	ai, bi := 0, 0
+	for _, m := range anchors {
+		d.diff(a[ai:m[0]], b[bi:m[1]], aOff+ai, bOff+bi)
+		d.emit(Equal, aOff+m[0], 0)
+		ai, bi = m[0]+1, m[1]+1
+	}
+	d.diff(a[ai:], b[bi:], aOff+ai, bOff+bi)
+}
+
+// uniqueLCS はaとbにそれぞれ1度だけ現れる要素の組のうち、両方で順序が保たれる最長の列を返す
+func uniqueLCS(a, b []int) [][2]int {
+	type count struct{ na, nb, ia, ib int }
+	counts := make(map[int]*count)
+	for i, x := range a {
+		c := counts[x]
+		if c == nil {
+			c = &count{}
+			counts[x] = c
+		}
+		c.na++
+		c.ia = i
+	}
+	for i, x := range b {
+		if c := counts[x]; c != nil {
+			c.nb++
+			c.ib = i
+		}
+	}
+	// aの順に並べたbの位置
+	var pairs [][2]int
+	for i, x := range a {
+		if c := counts[x]; c.na == 1 && c.nb == 1 {
+			pairs = append(pairs, [2]int{i, c.ib})
+		}
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	// patience sortingでbの位置の最長増加部分列を求める
+	var tops []int // 各山の一番上のpairsの添字
+	prev := make([]int, len(pairs))
+	for i, p := range pairs {
+		lo, hi := 0, len(tops)
+		for lo < hi {
+			mid := (lo + hi) / 2
+			if pairs[tops[mid]][1] < p[1] {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+		prev[i] = -1
+		if lo > 0 {
+			prev[i] = tops[lo-1]
+		}
+		if lo == len(tops) {
+			tops = append(tops, i)
+		} else {
+			tops[lo] = i
+		}
+	}
+	lcs := make([][2]int, len(tops))
+	for i, j := len(tops)-1, tops[len(tops)-1]; i >= 0; i, j = i-1, prev[j] {
+		lcs[i] = pairs[j]
+	}
+	return lcs
+}
@@ -0,0 +1,296 @@
+package diff_test
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"golang/recipe-golang/11.text/diff"
+)
+
+// rebuild は編集から古い方と新しい方のテキストを組み立て直す
+func rebuild(edits []diff.Edit) (a, b string) {
+	var sa, sb strings.Builder
+	for _, e := range edits {
+		if e.Op != diff.Insert {
+			sa.WriteString(e.Text)
+		}
+		if e.Op != diff.Delete {
+			sb.WriteString(e.Text)
+		}
+	}
+	return sa.String(), sb.String()
+}
+
+func countChanges(edits []diff.Edit) int {
+	n := 0
+	for _, e := range edits {
+		if e.Op != diff.Equal {
+			n++
+		}
+	}
+	return n
+}
+
+// lcsChanges は動的計画法で求めた最小の編集数
+func lcsChanges(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+	return len(a) + len(b) - 2*dp[0][0]
+}
+
+// randomText は少ない種類の行からなるテキストを作る
+func randomText(rng *rand.Rand, n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteString(string(rune('a'+rng.Intn(4))) + "\n")
+	}
+	return b.String()
+}
+
+func TestLines_Minimal(t *testing.T) {
+	t.Parallel()
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		a, b := randomText(rng, rng.Intn(20)), randomText(rng, rng.Intn(20))
+		algs := map[string][]diff.Option{
+			"myers":    nil,
+			"patience": {diff.WithAlgorithm(diff.Patience)},
+			"fallback": {diff.WithMaxCost(2)},
+		}
+		for name, opts := range algs {
+			edits := diff.Lines(a, b, opts...)
+			if ga, gb := rebuild(edits); ga != a || gb != b {
+				t.Fatalf("%s: edits do not rebuild %q -> %q: %v", name, a, b, edits)
+			}
+			if name != "myers" {
+				continue
+			}
+			if got, want := countChanges(edits), lcsChanges(diff.SplitLines(a), diff.SplitLines(b)); got != want {
+				t.Fatalf("%q -> %q: want %d changes, got %d", a, b, want, got)
+			}
+		}
+	}
+}
+
+func TestLines_Patience(t *testing.T) {
+	t.Parallel()
+	// 関数の間の}や空行に引きずられず、関数ごとの差分になる
+	a := "func a() {\n\treturn 1\n}\n\nfunc b() {\n\treturn 2\n}\n"
+	b := "func b() {\n\treturn 2\n}\n\nfunc a() {\n\treturn 1\n}\n"
+	edits := diff.Lines(a, b, diff.WithAlgorithm(diff.Patience))
+	if ga, gb := rebuild(edits); ga != a || gb != b {
+		t.Fatalf("edits do not rebuild: %v", edits)
+	}
+	if edits[0].Op != diff.Delete || edits[0].Text != "func a() {\n" {
+		t.Errorf("want func a to be deleted first, got %v", edits[0])
+	}
+}
+
+func TestRunes(t *testing.T) {
+	t.Parallel()
+	edits := diff.Runes("今日は晴れです", "今日も晴れでした")
+	if got, want := diff.Inline(edits), "今日[-は-]{+も+}晴れで[-す-]{+した+}"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if diff.HasChanges(diff.Runes("同じ", "同じ")) {
+		t.Error("want no changes")
+	}
+}
+
+func TestUnified(t *testing.T) {
+	t.Parallel()
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\nend"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\nend\n"
+	want := `--- a.txt
++++ b.txt
+@@ -2,3 +2,3 @@
+ 2
+-3
++three
+ 4
+@@ -10,2 +10,3 @@
+ 10
+-end
+\ No newline at end of file
++11
++end
+`
+	if got := diff.Unified("a.txt", "b.txt", a, b, 1); got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+	// 文脈が2*context行以下なら1つにまとめる
+	if got := diff.Hunks(diff.Lines(a, b), 4); len(got) != 1 {
+		t.Errorf("want 1 hunk, got %d", len(got))
+	}
+	if got := diff.Unified("a", "b", a, a, 3); got != "" {
+		t.Errorf("want empty diff, got %q", got)
+	}
+	if got := diff.Unified("/dev/null", "b", "", "x\n", 3); !strings.Contains(got, "@@ -0,0 +1 @@\n+x\n") {
+		t.Errorf("unexpected diff for a new file: %q", got)
+	}
+}
+
+func TestWriteSideBySide(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	edits := diff.Lines("同じ\n変更前\n消す\n", "同じ\n変更後のとても長い行\n")
+	if err := diff.WriteSideBySide(&buf, edits, 23); err != nil {
+		t.Fatal(err)
+	}
+	want := "同じ         同じ\n" +
+		"変更前     | 変更後のと\n" +
+		"消す       <\n"
+	if buf.String() != want {
+		t.Errorf("want\n%s\ngot\n%s", want, buf.String())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	if err := diff.WriteJSON(&buf, diff.Hunks(diff.Lines("a\n", "b\n"), 3)); err != nil {
+		t.Fatal(err)
+	}
+	want := `[
+  {
+    "old_start": 1,
+    "old_lines": 1,
+    "new_start": 1,
+    "new_lines": 1,
+    "lines": [
+      {
+        "op": "delete",
+        "text": "a\n"
+      },
+      {
+        "op": "insert",
+        "text": "b\n"
+      }
+    ]
+  }
+]
+`
+	if buf.String() != want {
+		t.Errorf("want %s, got %s", want, buf.String())
+	}
+}
+
+func TestParseApply_RoundTrip(t *testing.T) {
+	t.Parallel()
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 200; i++ {
+		a, b := randomText(rng, rng.Intn(30)), randomText(rng, rng.Intn(30))
+		if rng.Intn(3) == 0 {
+			a = strings.TrimSuffix(a, "\n")
+		}
+		patch := diff.Unified("a", "b", a, b, rng.Intn(4))
+		files, err := diff.Parse(strings.NewReader("diff -u a b\n" + patch))
+		if err != nil {
+			t.Fatalf("%q: %v", patch, err)
+		}
+		if patch == "" {
+			if len(files) != 0 {
+				t.Fatalf("want no files, got %v", files)
+			}
+			continue
+		}
+		got, err := diff.Apply(a, files[0].Hunks, 0)
+		if err != nil {
+			t.Fatalf("%q: %v", patch, err)
+		}
+		if got != b {
+			t.Fatalf("applying %q to %q: want %q, got %q", patch, a, b, got)
+		}
+	}
+}
+
+func TestApply_OffsetAndFuzz(t *testing.T) {
+	t.Parallel()
+	a := "a\nb\nc\nd\ne\n"
+	b := "a\nb\nC\nd\ne\n"
+	files, err := diff.Parse(strings.NewReader(diff.Unified("x", "x", a, b, 2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hunks := files[0].Hunks
+
+	// 先頭に行が増えていても近くを探して当てる
+	got, err := diff.Apply("new\nnew\n"+a, hunks, 0)
+	if err != nil || got != "new\nnew\n"+b {
+		t.Errorf("want offset applied, got %q (%v)", got, err)
+	}
+
+	// 文脈が変わっているとfuzzなしでは当たらない
+	changed := "A\nb\nc\nd\nE\n"
+	if _, err := diff.Apply(changed, hunks, 0); !errors.Is(err, diff.ErrHunkFailed) {
+		t.Errorf("want %v, got %v", diff.ErrHunkFailed, err)
+	}
+	got, err = diff.Apply(changed, hunks, 1)
+	if err != nil || got != "A\nb\nC\nd\nE\n" {
+		t.Errorf("want fuzzy applied, got %q (%v)", got, err)
+	}
+	// 削除する行が違うときはfuzzがあっても当てない
+	if _, err := diff.Apply("a\nb\nX\nd\ne\n", hunks, 2); !errors.Is(err, diff.ErrHunkFailed) {
+		t.Errorf("want %v, got %v", diff.ErrHunkFailed, err)
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		in   string
+		line int
+	}{
+		"no plus":     {"--- a\nfoo\n", 2},
+		"bad header":  {"--- a\n+++ b\n@@ -x +1 @@\n", 3},
+		"short hunk":  {"--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n", 4},
+		"bad line":    {"--- a\n+++ b\n@@ -1 +1 @@\n*a\n", 4},
+		"long hunk":   {"--- a\n+++ b\n@@ -1 +1 @@\n-a\n-b\n", 5},
+		"lone marker": {"--- a\n+++ b\n@@ -0,0 +1 @@\n\\ No newline at end of file\n", 4},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := diff.Parse(strings.NewReader(tt.in))
+			var pe *diff.ParseError
+			if !errors.As(err, &pe) || pe.Line != tt.line {
+				t.Errorf("want error on line %d, got %v", tt.line, err)
+			}
+		})
+	}
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add("a\nb\nc\n", "a\nc\nd")
+	f.Add("", "x\n")
+	f.Add("日本\n語\n", "日本\n")
+	f.Fuzz(func(t *testing.T, a, b string) {
+		edits := diff.Lines(a, b, diff.WithMaxCost(8))
+		if ga, gb := rebuild(edits); ga != a || gb != b {
+			t.Fatalf("edits do not rebuild %q -> %q", a, b)
+		}
+		files, err := diff.Parse(strings.NewReader(diff.Unified("a", "b", a, b, 1)))
+		if err != nil || len(files) == 0 {
+			return
+		}
+		got, err := diff.Apply(a, files[0].Hunks, 0)
+		if err != nil || got != b {
+			t.Fatalf("applying to %q: want %q, got %q (%v)", a, b, got, err)
+		}
+	})
+}
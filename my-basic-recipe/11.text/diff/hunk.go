@@ -0,0 +1,216 @@
+package diff
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/width"
+)
+
+// Hunk は変更のある部分と前後の文脈の行
+// OldStartとNewStartは1から始まる行番号。行数が0のときは直前の行の番号になる
+type Hunk struct {
+	OldStart int    `json:"old_start"`
+	OldLines int    `json:"old_lines"`
+	NewStart int    `json:"new_start"`
+	NewLines int    `json:"new_lines"`
+	Lines    []Edit `json:"lines"`
+}
+
+// Header は@@ -1,3 +1,4 @@の形式のヘッダを返す
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+func hunkRange(start, n int) string {
+	if n == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+// Hunks は行ごとの差分を前後context行の文脈を付けたHunkに分ける
+// 変更の間の文脈が2*context行以下なら1つのHunkにまとめる
+func Hunks(edits []Edit, context int) []Hunk {
+	if context < 0 {
+		context = 0
+	}
+	// 各編集の前までの行数
+	oldPos := make([]int, len(edits)+1)
+	newPos := make([]int, len(edits)+1)
+	for i, e := range edits {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if e.Op != Insert {
+			oldPos[i+1]++
+		}
+		if e.Op != Delete {
+			newPos[i+1]++
+		}
+	}
+
+	var hunks []Hunk
+	for i := 0; i < len(edits); {
+		if edits[i].Op == Equal {
+			i++
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		last := i
+		for j := i + 1; j < len(edits); j++ {
+			if edits[j].Op != Equal {
+				last = j
+			} else if j-last > 2*context {
+				break
+			}
+		}
+		end := last + context + 1
+		if end > len(edits) {
+			end = len(edits)
+		}
+		h := Hunk{
+			OldStart: oldPos[start] + 1,
+			OldLines: oldPos[end] - oldPos[start],
+			NewStart: newPos[start] + 1,
+			NewLines: newPos[end] - newPos[start],
+			Lines:    edits[start:end],
+		}
+		if h.OldLines == 0 {
+			h.OldStart--
+		}
+		if h.NewLines == 0 {
+			h.NewStart--
+		}
+		hunks = append(hunks, h)
+		i = end
+	}
+	return hunks
+}
+
+// noNewline は最後の行に改行がないことを表す行
+const noNewline = `\ No newline at end of file`
+
+// WriteUnified はhunksをunified diffの形式でwに書き出す
+// hunksが空なら何も書かない
+func WriteUnified(w io.Writer, oldName, newName string, hunks []Hunk) error {
+	if len(hunks) == 0 {
+		return nil
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks {
+		bw.WriteString(h.Header() + "\n")
+		for _, e := range h.Lines {
+			bw.WriteByte(" -+"[e.Op])
+			bw.WriteString(e.Text)
+			if !strings.HasSuffix(e.Text, "\n") {
+				bw.WriteString("\n" + noNewline + "\n")
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// Unified はaとbの行ごとの差分をunified diffの文字列で返す。差分がなければ""を返す
+func Unified(oldName, newName, a, b string, context int, opts ...Option) string {
+	var sb strings.Builder
+	WriteUnified(&sb, oldName, newName, Hunks(Lines(a, b, opts...), context))
+	return sb.String()
+}
+
+// WriteJSON はhunksをJSONの配列でwに書き出す
+func WriteJSON(w io.Writer, hunks []Hunk) error {
+	if hunks == nil {
+		hunks = []Hunk{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(hunks)
+}
+
+// WriteSideBySide は行ごとの差分を左右に並べてwに書き出す
+// 列の間の記号は、変更が|、削除が<、追加が>になる。widthは全体の表示幅
+func WriteSideBySide(w io.Writer, edits []Edit, totalWidth int) error {
+	col := (totalWidth - 3) / 2
+	if col < 1 {
+		col = 1
+	}
+	bw := bufio.NewWriter(w)
+	row := func(left, mark, right string) {
+		left = fit(strings.TrimSuffix(left, "\n"), col)
+		right = strings.TrimSuffix(right, "\n")
+		if right == "" {
+			bw.WriteString(strings.TrimRight(left+" "+mark, " ") + "\n")
+			return
+		}
+		bw.WriteString(left + " " + mark + " " + truncate(right, col) + "\n")
+	}
+	for i := 0; i < len(edits); {
+		if edits[i].Op == Equal {
+			row(edits[i].Text, " ", edits[i].Text)
+			i++
+			continue
+		}
+		// 続く削除と追加を組にする
+		var dels, inss []string
+		for ; i < len(edits) && edits[i].Op != Equal; i++ {
+			if edits[i].Op == Delete {
+				dels = append(dels, edits[i].Text)
+			} else {
+				inss = append(inss, edits[i].Text)
+			}
+		}
+		for j := 0; j < len(dels) || j < len(inss); j++ {
+			switch {
+			case j < len(dels) && j < len(inss):
+				row(dels[j], "|", inss[j])
+			case j < len(dels):
+				row(dels[j], "<", "")
+			default:
+				row("", ">", inss[j])
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// fit はsを表示幅nに切り詰めるか空白で埋める
+func fit(s string, n int) string {
+	s = truncate(s, n)
+	return s + strings.Repeat(" ", n-displayWidth(s))
+}
+
+// truncate はsを表示幅n以下に切り詰める
+func truncate(s string, n int) string {
+	w := 0
+	for i, r := range s {
+		rw := runeWidth(r)
+		if w+rw > n {
+			return s[:i]
+		}
+		w += rw
+	}
+	return s
+}
+
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+// runeWidth は全角の文字を2、それ以外を1とする
+func runeWidth(r rune) int {
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
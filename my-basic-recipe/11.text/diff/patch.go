@@ -0,0 +1,321 @@
+package diff
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrHunkFailed はパッチのHunkを当てる場所が見つからないときのエラー
+var ErrHunkFailed = errors.New("diff: hunk does not apply")
+
+// FilePatch は1つのファイルへのパッチ
+type FilePatch struct {
+	OldName string
+	NewName string
+	Hunks   []Hunk
+}
+
+// ParseError はunified diffを解析できないときのエラー
+type ParseError struct {
+	Line int // 1から始まる行番号
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("diff: parsing patch: line %d: %s", e.Line, e.Msg)
+}
+
+// Parse はunified diffを読み込む
+// ---の行より前の行（diffコマンドの行など）は読み飛ばす
+func Parse(r io.Reader) ([]FilePatch, error) {
+	p := &parser{r: bufio.NewReader(r)}
+	var patches []FilePatch
+	for {
+		line, ok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return patches, nil
+		}
+		if !strings.HasPrefix(line, "--- ") {
+			continue
+		}
+		next, ok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok || !strings.HasPrefix(next, "+++ ") {
+			return nil, p.errorf("want +++ after ---")
+		}
+		fp := FilePatch{OldName: fileName(line[4:]), NewName: fileName(next[4:])}
+		for {
+			h, ok, err := p.hunk()
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				break
+			}
+			fp.Hunks = append(fp.Hunks, h)
+		}
+		patches = append(patches, fp)
+	}
+}
+
+// fileName は---や+++の後ろからファイル名を取り出す（タブの後ろの日時は除く）
+func fileName(s string) string {
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "\n"), "\r")
+	name, _, _ := strings.Cut(s, "\t")
+	return name
+}
+
+type parser struct {
+	r      *bufio.Reader
+	lineNo int
+	peeked *string
+}
+
+// next は次の行を改行を含めて返す
+func (p *parser) next() (string, bool, error) {
+	if p.peeked != nil {
+		line := *p.peeked
+		p.peeked = nil
+		p.lineNo++
+		return line, true, nil
+	}
+	line, err := p.r.ReadString('\n')
+	if line == "" {
+		if err == io.EOF {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+	}
+	p.lineNo++
+	return line, true, nil
+}
+
+// unread は読んだ行を戻す
+func (p *parser) unread(line string) {
+	p.peeked = &line
+	p.lineNo--
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &ParseError{Line: p.lineNo, Msg: fmt.Sprintf(format, args...)}
+}
+
+// hunk は@@で始まるHunkを1つ読む。次の行が@@でなければfalseを返す
+func (p *parser) hunk() (Hunk, bool, error) {
+	line, ok, err := p.next()
+	if err != nil || !ok {
+		return Hunk{}, false, err
+	}
+	if !strings.HasPrefix(line, "@@ ") {
+		p.unread(line)
+		return Hunk{}, false, nil
+	}
+	var h Hunk
+	if h, err = parseHeader(line); err != nil {
+		return Hunk{}, false, p.errorf("%v", err)
+	}
+
+	oldLeft, newLeft := h.OldLines, h.NewLines
+	for oldLeft > 0 || newLeft > 0 {
+		line, ok, err := p.next()
+		if err != nil {
+			return Hunk{}, false, err
+		}
+		if !ok {
+			return Hunk{}, false, p.errorf("unexpected end of hunk")
+		}
+		var e Edit
+		switch line[0] {
+		case ' ':
+			e = Edit{Op: Equal, Text: line[1:]}
+			oldLeft--
+			newLeft--
+		case '\n':
+			// 空の文脈の行の空白が取り除かれていることがある
+			e = Edit{Op: Equal, Text: line}
+			oldLeft--
+			newLeft--
+		case '-':
+			e = Edit{Op: Delete, Text: line[1:]}
+			oldLeft--
+		case '+':
+			e = Edit{Op: Insert, Text: line[1:]}
+			newLeft--
+		case '\\':
+			if err := p.noNewline(&h); err != nil {
+				return Hunk{}, false, err
+			}
+			continue
+		default:
+			return Hunk{}, false, p.errorf("unexpected line %q in hunk", strings.TrimSuffix(line, "\n"))
+		}
+		if oldLeft < 0 || newLeft < 0 {
+			return Hunk{}, false, p.errorf("hunk is longer than its header")
+		}
+		h.Lines = append(h.Lines, e)
+	}
+	// 最後の行の後ろの\ No newline at end of file
+	if line, ok, err := p.next(); err != nil {
+		return Hunk{}, false, err
+	} else if ok {
+		if strings.HasPrefix(line, "\\") {
+			if err := p.noNewline(&h); err != nil {
+				return Hunk{}, false, err
+			}
+		} else {
+			p.unread(line)
+		}
+	}
+	return h, true, nil
+}
+
+// noNewline は直前の行の改行を取り除く
+func (p *parser) noNewline(h *Hunk) error {
+	if len(h.Lines) == 0 {
+		return p.errorf("%s before any line", noNewline)
+	}
+	last := &h.Lines[len(h.Lines)-1]
+	last.Text = strings.TrimSuffix(last.Text, "\n")
+	return nil
+}
+
+// parseHeader は@@ -1,3 +1,4 @@を読む
+func parseHeader(line string) (Hunk, error) {
+	var h Hunk
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[3] != "@@" || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return h, fmt.Errorf("invalid hunk header %q", strings.TrimSpace(line))
+	}
+	var err error
+	if h.OldStart, h.OldLines, err = parseRange(fields[1][1:]); err != nil {
+		return h, err
+	}
+	if h.NewStart, h.NewLines, err = parseRange(fields[2][1:]); err != nil {
+		return h, err
+	}
+	return h, nil
+}
+
+func parseRange(s string) (start, n int, err error) {
+	startStr, nStr, ok := strings.Cut(s, ",")
+	if start, err = strconv.Atoi(startStr); err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q", s)
+	}
+	n = 1
+	if ok {
+		if n, err = strconv.Atoi(nStr); err != nil {
+			return 0, 0, fmt.Errorf("invalid range %q", s)
+		}
+	}
+	return start, n, nil
+}
+
+// Apply はsrcにhunksを当てた結果を返す
+// Hunkは書かれた行番号の近くから探すので、行がずれていても当てられる
+// fuzzを指定すると、見つからないときにHunkの前後の文脈をfuzz行まで無視して探す
+func Apply(src string, hunks []Hunk, fuzz int) (string, error) {
+	lines := SplitLines(src)
+	var out strings.Builder
+	pos := 0    // srcのまだ出力していない最初の行
+	offset := 0 // 前のHunkで見つかった位置と書かれた位置のずれ
+	for i, h := range hunks {
+		var old []string
+		for _, e := range h.Lines {
+			if e.Op != Insert {
+				old = append(old, e.Text)
+			}
+		}
+		want := h.OldStart - 1
+		if h.OldLines == 0 {
+			want = h.OldStart
+		}
+		want += offset
+
+		lead, trail := contextLines(h.Lines)
+		at, skip, skipEnd := -1, 0, 0
+		for f := 0; f <= fuzz && at < 0; f++ {
+			skip, skipEnd = min(f, lead), min(f, trail)
+			at = search(lines, old[skip:len(old)-skipEnd], want+skip, pos)
+		}
+		if at < 0 {
+			return "", fmt.Errorf("hunk #%d %s: %w", i+1, h.Header(), ErrHunkFailed)
+		}
+		offset = at - skip - (want - offset)
+
+		for _, l := range lines[pos:at] {
+			out.WriteString(l)
+		}
+		pos = at
+		// 無視した文脈の行は当てる側の行をそのまま使う
+		for _, e := range trimContext(h.Lines, skip, skipEnd) {
+			switch e.Op {
+			case Equal:
+				out.WriteString(lines[pos])
+				pos++
+			case Delete:
+				pos++
+			case Insert:
+				out.WriteString(e.Text)
+			}
+		}
+	}
+	for _, l := range lines[pos:] {
+		out.WriteString(l)
+	}
+	return out.String(), nil
+}
+
+// contextLines は先頭と末尾の文脈の行数を返す
+func contextLines(edits []Edit) (lead, trail int) {
+	for lead < len(edits) && edits[lead].Op == Equal {
+		lead++
+	}
+	for trail < len(edits)-lead && edits[len(edits)-1-trail].Op == Equal {
+		trail++
+	}
+	return lead, trail
+}
+
+// trimContext は先頭と末尾の文脈の行を取り除く
+func trimContext(edits []Edit, lead, trail int) []Edit {
+	return edits[lead : len(edits)-trail]
+}
+
+// search はlinesの中でpatternと一致する位置をwantに近い順に探す。from より前は探さない
+func search(lines, pattern []string, want, from int) int {
+	last := len(lines) - len(pattern)
+	if want < from {
+		want = from
+	}
+	if want > last {
+		want = last
+	}
+	for d := 0; want-d >= from || want+d <= last; d++ {
+		for _, at := range [2]int{want - d, want + d} {
+			if at >= from && at <= last && matchAt(lines, pattern, at) {
+				return at
+			}
+		}
+	}
+	return -1
+}
+
+func matchAt(lines, pattern []string, at int) bool {
+	for i, p := range pattern {
+		if lines[at+i] != p {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,8 @@
+module golang/recipe-golang/11.text
+
+go 1.21
+
+require (
+	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,56 @@
+package iox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// Checksum は書き込まれたデータのSHA-256とCRC32を同時に計算するio.Writer
+// io.TeeReaderやio.MultiWriterと組み合わせて、読み書きしながら計算する
+type Checksum struct {
+	sha hash.Hash
+	crc hash.Hash32
+	n   int64
+}
+
+// NewChecksum はChecksumを作る
+func NewChecksum() *Checksum {
+	return &Checksum{sha: sha256.New(), crc: crc32.NewIEEE()}
+}
+
+// Write はpをハッシュに加える。エラーは返さない
+func (c *Checksum) Write(p []byte) (int, error) {
+	c.sha.Write(p)
+	c.crc.Write(p)
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// N はこれまでに書き込まれたバイト数を返す
+func (c *Checksum) N() int64 { return c.n }
+
+// SHA256 はSHA-256を16進数の文字列で返す
+func (c *Checksum) SHA256() string {
+	return hex.EncodeToString(c.sha.Sum(nil))
+}
+
+// CRC32 はCRC32（IEEE）を返す
+func (c *Checksum) CRC32() uint32 {
+	return c.crc.Sum32()
+}
+
+// ChecksumReader はrから読み込んだデータのChecksumを計算するio.Readerを返す
+// 最後まで読み込んでからChecksumの値を使う
+func ChecksumReader(r io.Reader) (io.Reader, *Checksum) {
+	c := NewChecksum()
+	return io.TeeReader(r, c), c
+}
+
+// ChecksumWriter はwに書き込んだデータのChecksumを計算するio.Writerを返す
+func ChecksumWriter(w io.Writer) (io.Writer, *Checksum) {
+	c := NewChecksum()
+	return io.MultiWriter(w, c), c
+}
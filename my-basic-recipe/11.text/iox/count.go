@@ -0,0 +1,62 @@
+// Package iox はio.Readerやio.Writerを包んで機能を足すユーティリティ
+//
+// どれも標準のインターフェースを満たすので、io.Copyやio.TeeReaderなどと組み合わせられる
+//
+//	f, _ := os.Open("large.log")
+//	lim := iox.NewLimiter(1 << 20) // 1MB/秒
+//	r, sum := iox.ChecksumReader(lim.Reader(ctx, f))
+//	r = iox.ProgressReader(r, size, func(p iox.Progress) { fmt.Printf("\r%.0f%%", p.Percent()) })
+//	io.Copy(dst, r)
+//	fmt.Println(sum.SHA256())
+package iox
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// CountingReader は読み込んだバイト数を数えるio.Reader
+// Nは他のゴールーチンから呼んでもよい
+type CountingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+// NewCountingReader はrから読み込むCountingReaderを作る
+func NewCountingReader(r io.Reader) *CountingReader {
+	return &CountingReader{r: r}
+}
+
+func (cr *CountingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n.Add(int64(n))
+	return n, err
+}
+
+// N はこれまでに読み込んだバイト数を返す
+func (cr *CountingReader) N() int64 {
+	return cr.n.Load()
+}
+
+// CountingWriter は書き込んだバイト数を数えるio.Writer
+// Nは他のゴールーチンから呼んでもよい
+type CountingWriter struct {
+	w io.Writer
+	n atomic.Int64
+}
+
+// NewCountingWriter はwに書き込むCountingWriterを作る
+func NewCountingWriter(w io.Writer) *CountingWriter {
+	return &CountingWriter{w: w}
+}
+
+func (cw *CountingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n.Add(int64(n))
+	return n, err
+}
+
+// N はこれまでに書き込んだバイト数を返す
+func (cw *CountingWriter) N() int64 {
+	return cw.n.Load()
+}
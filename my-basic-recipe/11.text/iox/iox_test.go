@@ -0,0 +1,277 @@
+package iox_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"golang/recipe-golang/11.text/iox"
+)
+
+func TestCounting(t *testing.T) {
+	t.Parallel()
+	cr := iox.NewCountingReader(strings.NewReader("Hello, 世界"))
+	var buf bytes.Buffer
+	cw := iox.NewCountingWriter(&buf)
+	if _, err := io.Copy(cw, iotest.OneByteReader(cr)); err != nil {
+		t.Fatal(err)
+	}
+	if cr.N() != 13 || cw.N() != 13 {
+		t.Errorf("want 13 bytes, got read %d, written %d", cr.N(), cw.N())
+	}
+}
+
+func TestProgressReader(t *testing.T) {
+	t.Parallel()
+	var got []iox.Progress
+	r := iox.ProgressReader(iotest.HalfReader(strings.NewReader("abcdefgh")), 8, func(p iox.Progress) {
+		got = append(got, p)
+	})
+	if _, err := io.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) < 2 {
+		t.Fatalf("want several progress reports, got %v", got)
+	}
+	last := got[len(got)-1]
+	if !last.Done || last.N != 8 || last.Percent() != 100 {
+		t.Errorf("want done at 100%%, got %+v", last)
+	}
+	for i, p := range got[:len(got)-1] {
+		if p.Done || p.N > got[i+1].N {
+			t.Errorf("unexpected progress %+v before %+v", p, got[i+1])
+		}
+	}
+	if p := (iox.Progress{N: 10, Total: -1}); p.Percent() != -1 {
+		t.Errorf("want -1 for unknown total, got %v", p.Percent())
+	}
+}
+
+func TestProgressWriter_Interval(t *testing.T) {
+	t.Parallel()
+	var got []iox.Progress
+	w := iox.ProgressWriter(io.Discard, 100, func(p iox.Progress) {
+		got = append(got, p)
+	}, iox.WithInterval(time.Hour))
+	for i := 0; i < 10; i++ {
+		w.Write(make([]byte, 10))
+	}
+	// 最初と最後だけ知らせる
+	if len(got) != 2 || got[0].N != 10 || !got[1].Done || got[1].N != 100 {
+		t.Errorf("want first and last progress, got %+v", got)
+	}
+}
+
+func TestProgressChan(t *testing.T) {
+	t.Parallel()
+	fn, ch, done := iox.ProgressChan()
+	data := strings.Repeat("x", 1<<16)
+	go func() {
+		defer done()
+		io.Copy(io.Discard, iox.ProgressReader(iotest.OneByteReader(strings.NewReader(data)), int64(len(data)), fn))
+	}()
+	var last iox.Progress
+	for p := range ch {
+		if p.N < last.N {
+			t.Errorf("progress went back from %d to %d", last.N, p.N)
+		}
+		last = p
+	}
+	if !last.Done || last.N != int64(len(data)) {
+		t.Errorf("want done progress, got %+v", last)
+	}
+}
+
+func TestProgressChan_Done(t *testing.T) {
+	t.Parallel()
+	errBoom := errors.New("boom")
+	cases := map[string]struct {
+		copy func(fn func(iox.Progress)) error
+		err  error
+	}{
+		"read error": {func(fn func(iox.Progress)) error {
+			r := io.MultiReader(strings.NewReader("abc"), iotest.ErrReader(errBoom))
+			_, err := io.Copy(io.Discard, iox.ProgressReader(r, 10, fn))
+			return err
+		}, errBoom},
+		"unknown total": {func(fn func(iox.Progress)) error {
+			_, err := io.Copy(iox.ProgressWriter(io.Discard, -1, fn), strings.NewReader("abc"))
+			return err
+		}, nil},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			fn, ch, done := iox.ProgressChan()
+			errc := make(chan error, 1)
+			go func() {
+				defer done()
+				errc <- tt.copy(fn)
+			}()
+			var last iox.Progress
+			for p := range ch {
+				last = p
+			}
+			if last.Done || last.N != 3 {
+				t.Errorf("want 3 bytes without done, got %+v", last)
+			}
+			if err := <-errc; !errors.Is(err, tt.err) {
+				t.Errorf("want %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+	const rate = 20000
+	data := bytes.Repeat([]byte("x"), rate/4)
+	start := time.Now()
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, iox.RateLimitReader(context.Background(), bytes.NewReader(data), rate)); err != nil {
+		t.Fatal(err)
+	}
+	// 最初のバーストの分（0.1秒）だけ早く終わる
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("want at least 100ms, got %v", elapsed)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Error("data was changed")
+	}
+
+	start = time.Now()
+	w := iox.RateLimitWriter(context.Background(), io.Discard, rate)
+	if n, err := w.Write(data); err != nil || n != len(data) {
+		t.Fatalf("want %d bytes, got %d (%v)", len(data), n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("want at least 100ms, got %v", elapsed)
+	}
+}
+
+func TestLimiter_Cancel(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	// 1バイト/秒なので最後まで書き込む前にctxが終わる
+	w := iox.RateLimitWriter(ctx, io.Discard, 1)
+	start := time.Now()
+	n, err := w.Write([]byte("hello"))
+	if !errors.Is(err, context.DeadlineExceeded) || n >= 5 {
+		t.Errorf("want %v before all bytes, got %d (%v)", context.DeadlineExceeded, n, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancellation took %v", elapsed)
+	}
+	if _, err := iox.RateLimitReader(ctx, strings.NewReader("x"), 1).Read(make([]byte, 1)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	t.Parallel()
+	const (
+		wantSHA = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+		wantCRC = 0x3610a686
+	)
+	r, rsum := iox.ChecksumReader(strings.NewReader("hello"))
+	var buf bytes.Buffer
+	w, wsum := iox.ChecksumWriter(&buf)
+	if _, err := io.Copy(w, r); err != nil {
+		t.Fatal(err)
+	}
+	for name, sum := range map[string]*iox.Checksum{"reader": rsum, "writer": wsum} {
+		if sum.SHA256() != wantSHA || sum.CRC32() != wantCRC || sum.N() != 5 {
+			t.Errorf("%s: want %s %08x, got %s %08x (%d bytes)", name, wantSHA, wantCRC, sum.SHA256(), sum.CRC32(), sum.N())
+		}
+	}
+	if buf.String() != "hello" {
+		t.Errorf("want hello, got %q", buf.String())
+	}
+}
+
+func TestSections(t *testing.T) {
+	t.Parallel()
+	data := "0123456789"
+	cases := map[string]struct {
+		n    int
+		want []string
+	}{
+		"three":    {3, []string{"012", "345", "6789"}},
+		"one":      {1, []string{data}},
+		"zero":     {0, []string{data}},
+		"too many": {20, strings.Split(data, "")},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got := readAll(t, iox.Sections(strings.NewReader(data), int64(len(data)), tt.n))
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLineSections(t *testing.T) {
+	t.Parallel()
+	data := "a\nbb\nccc\ndddddddddddddddddddd\ne\nf"
+	cases := map[string]struct {
+		n    int
+		want []string
+	}{
+		"two":       {2, []string{"a\nbb\nccc\ndddddddddddddddddddd\n", "e\nf"}},
+		"four":      {4, []string{"a\nbb\nccc\n", "dddddddddddddddddddd\n", "e\nf"}},
+		"many":      {100, []string{"a\n", "bb\n", "ccc\n", "dddddddddddddddddddd\n", "e\n", "f"}},
+		"one":       {1, []string{data}},
+		"no breaks": {3, nil},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			in := data
+			if tt.want == nil {
+				in = strings.Repeat("x", 10000)
+				tt.want = []string{in}
+			}
+			sections, err := iox.LineSections(strings.NewReader(in), int64(len(in)), tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := readAll(t, sections)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// readAll はセクションを並行に読み込む
+func readAll(t *testing.T, sections []*io.SectionReader) []string {
+	t.Helper()
+	got := make([]string, len(sections))
+	errs := make([]error, len(sections))
+	var wg sync.WaitGroup
+	for i, s := range sections {
+		wg.Add(1)
+		go func(i int, s *io.SectionReader) {
+			defer wg.Done()
+			b, err := io.ReadAll(s)
+			got[i], errs[i] = string(b), err
+		}(i, s)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
@@ -0,0 +1,150 @@
+package iox
+
+import (
+	"io"
+	"time"
+)
+
+// Progress は進捗
+type Progress struct {
+	N     int64 // これまでに処理したバイト数
+	Total int64 // 全体のバイト数。わからないときは-1
+	Done  bool  // 最後まで処理した
+}
+
+// Percent は進捗を0から100で返す。Totalがわからないときは-1
+func (p Progress) Percent() float64 {
+	switch {
+	case p.Total < 0:
+		return -1
+	case p.Total == 0:
+		return 100
+	}
+	return float64(p.N) * 100 / float64(p.Total)
+}
+
+// ProgressOption はProgressReaderとProgressWriterの設定
+type ProgressOption func(*progress)
+
+// WithInterval は進捗を知らせる間隔を指定する
+// 指定しないと読み書きのたびに知らせる。最後の進捗は間隔によらず必ず知らせる
+func WithInterval(d time.Duration) ProgressOption {
+	return func(p *progress) { p.interval = d }
+}
+
+type progress struct {
+	n, total int64
+	fn       func(Progress)
+	interval time.Duration
+	last     time.Time
+	done     bool
+}
+
+func newProgress(total int64, fn func(Progress), opts []ProgressOption) *progress {
+	p := &progress{total: total, fn: fn}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *progress) add(n int, done bool) {
+	if p.done {
+		return
+	}
+	p.n += int64(n)
+	if n == 0 && !done {
+		return
+	}
+	now := time.Now()
+	if !done && p.interval > 0 && now.Sub(p.last) < p.interval {
+		return
+	}
+	p.last = now
+	p.done = done
+	p.fn(Progress{N: p.n, Total: p.total, Done: done})
+}
+
+type progressReader struct {
+	r io.Reader
+	p *progress
+}
+
+// ProgressReader は読み込むたびにfnで進捗を知らせるio.Readerを返す
+// totalは全体のバイト数で、わからないときは-1を渡す
+// rがio.EOFを返すとDoneがtrueの進捗を知らせる
+func ProgressReader(r io.Reader, total int64, fn func(Progress), opts ...ProgressOption) io.Reader {
+	return &progressReader{r: r, p: newProgress(total, fn, opts)}
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	pr.p.add(n, err == io.EOF)
+	return n, err
+}
+
+type progressWriter struct {
+	w io.Writer
+	p *progress
+}
+
+// ProgressWriter は書き込むたびにfnで進捗を知らせるio.Writerを返す
+// totalバイト書き込むとDoneがtrueの進捗を知らせる
+func ProgressWriter(w io.Writer, total int64, fn func(Progress), opts ...ProgressOption) io.Writer {
+	return &progressWriter{w: w, p: newProgress(total, fn, opts)}
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	n, err := pw.w.Write(b)
+	pw.p.add(n, pw.p.total >= 0 && pw.p.n+int64(n) >= pw.p.total)
+	return n, err
+}
+
+// ProgressChan はProgressReaderなどに渡す関数と、進捗を受け取るチャネルと、読み書きを終えたときに呼ぶ関数を返す
+// 受信が間に合わないときは古い進捗を捨てて最新の進捗だけを残すので、読み書きを待たせない
+// Doneの進捗を送るか、doneを呼ぶとチャネルを閉じる。エラーで終わったときや
+// 全体の大きさがわからないProgressWriterではDoneの進捗を送らないので、doneを必ず呼ぶ
+// 返す関数は同時に複数のゴールーチンから呼ばない
+//
+//	fn, ch, done := iox.ProgressChan()
+//	errc := make(chan error, 1)
+//	go func() {
+//		defer done()
+//		_, err := io.Copy(dst, iox.ProgressReader(src, size, fn))
+//		errc <- err
+//	}()
+//	for p := range ch {
+//		bar.Set(p.Percent())
+//	}
+//	err := <-errc
+func ProgressChan() (fn func(Progress), ch <-chan Progress, done func()) {
+	c := make(chan Progress, 1)
+	closed := false
+	done = func() {
+		if !closed {
+			close(c)
+			closed = true
+		}
+	}
+	fn = func(p Progress) {
+		if closed {
+			return
+		}
+		for {
+			select {
+			case c <- p:
+				if p.Done {
+					done()
+				}
+				return
+			default:
+				// 受信されていない古い進捗を捨てる
+				select {
+				case <-c:
+				default:
+				}
+			}
+		}
+	}
+	return fn, c, done
+}
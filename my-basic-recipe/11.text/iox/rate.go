@@ -0,0 +1,136 @@
+package iox
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Limiter は1秒あたりのバイト数を制限する
+// 1つのLimiterから作ったReaderとWriterは帯域を共有する
+//
+//	lim := iox.NewLimiter(512 << 10) // 512KB/秒
+//	io.Copy(lim.Writer(ctx, conn), f)
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64 // 1秒あたりのバイト数
+	burst  int     // 一度に読み書きするバイト数の上限
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter はbytesPerSecバイト/秒に制限するLimiterを作る
+// bytesPerSecが0以下のときはパニックになる
+func NewLimiter(bytesPerSec int) *Limiter {
+	if bytesPerSec <= 0 {
+		panic("iox: non-positive rate")
+	}
+	// 0.1秒分ずつ読み書きして、なるべく一定の速さにする
+	burst := bytesPerSec / 10
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{rate: float64(bytesPerSec), burst: burst, tokens: float64(burst), last: time.Now()}
+}
+
+// wait はnバイト分の時間が経つまで待つ
+func (l *Limiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+	l.last = now
+	// 先に差し引くので、同時に待つ他のReaderやWriterはさらに後ろで待つ
+	l.tokens -= float64(n)
+	var d time.Duration
+	if l.tokens < 0 {
+		d = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if d == 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Reader はrからの読み込みを制限するio.Readerを返す
+// ctxが終わるとctx.Err()を返す
+func (l *Limiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	return &limitedReader{ctx: ctx, r: r, l: l}
+}
+
+// Writer はwへの書き込みを制限するio.Writerを返す
+// ctxが終わるとctx.Err()を返す
+func (l *Limiter) Writer(ctx context.Context, w io.Writer) io.Writer {
+	return &limitedWriter{ctx: ctx, w: w, l: l}
+}
+
+// RateLimitReader はrからの読み込みをbytesPerSecバイト/秒に制限する
+func RateLimitReader(ctx context.Context, r io.Reader, bytesPerSec int) io.Reader {
+	return NewLimiter(bytesPerSec).Reader(ctx, r)
+}
+
+// RateLimitWriter はwへの書き込みをbytesPerSecバイト/秒に制限する
+func RateLimitWriter(ctx context.Context, w io.Writer, bytesPerSec int) io.Writer {
+	return NewLimiter(bytesPerSec).Writer(ctx, w)
+}
+
+type limitedReader struct {
+	ctx context.Context
+	r   io.Reader
+	l   *Limiter
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if err := lr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(p) > lr.l.burst {
+		p = p[:lr.l.burst]
+	}
+	// 読み込んだ分だけ待つ
+	n, err := lr.r.Read(p)
+	if n > 0 {
+		if werr := lr.l.wait(lr.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+type limitedWriter struct {
+	ctx context.Context
+	w   io.Writer
+	l   *Limiter
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > lw.l.burst {
+			chunk = chunk[:lw.l.burst]
+		}
+		if err := lw.l.wait(lw.ctx, len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := lw.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
@@ -0,0 +1,72 @@
+package iox
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// Sections はsizeバイトのrをn個のほぼ同じ大きさの*io.SectionReaderに分ける
+// それぞれ別のゴールーチンから並行に読み込める
+// sizeがnより小さいときは1バイトずつ、sizeが0のときは空のスライスを返す
+func Sections(r io.ReaderAt, size int64, n int) []*io.SectionReader {
+	if n < 1 {
+		n = 1
+	}
+	if int64(n) > size {
+		n = int(size)
+	}
+	sections := make([]*io.SectionReader, 0, n)
+	for i := 0; i < n; i++ {
+		off := size * int64(i) / int64(n)
+		end := size * int64(i+1) / int64(n)
+		sections = append(sections, io.NewSectionReader(r, off, end-off))
+	}
+	return sections
+}
+
+// LineSections はSectionsと同じようにrを分けるが、区切りを行の途中にしない
+// 各セクションは行の先頭から始まり、\nの直後か末尾で終わる
+// 長い行があると指定より少ないセクションになる
+func LineSections(r io.ReaderAt, size int64, n int) ([]*io.SectionReader, error) {
+	if n < 1 {
+		n = 1
+	}
+	var sections []*io.SectionReader
+	start := int64(0)
+	for i := 1; i <= n && start < size; i++ {
+		end := size * int64(i) / int64(n)
+		if end <= start {
+			// 前のセクションが長い行でここを越えた
+			continue
+		}
+		if end < size {
+			var err error
+			if end, err = nextLine(r, end-1, size); err != nil {
+				return nil, err
+			}
+		}
+		sections = append(sections, io.NewSectionReader(r, start, end-start))
+		start = end
+	}
+	return sections, nil
+}
+
+// nextLine はoff以降で最初の\nの次の位置を返す。\nがなければsizeを返す
+func nextLine(r io.ReaderAt, off, size int64) (int64, error) {
+	buf := make([]byte, 4096)
+	for off < size {
+		n, err := r.ReadAt(buf, off)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return off + int64(i) + 1, nil
+		}
+		off += int64(n)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return size, nil
+}
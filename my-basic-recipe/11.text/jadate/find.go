@@ -0,0 +1,72 @@
+package jadate
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Match は文章の中で見つかった日付
+type Match struct {
+	Start, End int // 日付の範囲（バイト）。Errがあるときは読めたところまで
+	Time       time.Time
+	HasTime    bool // 時刻も書かれていたか
+	// Err は日付として書かれているが正しくない場合のエラー
+	// 平成32年1月1日や2024年2月30日など。このときTimeはゼロ値
+	Err *ParseError
+}
+
+// FindAll はsの中の日付をすべて探す
+// 元号があるか月まで読めたのに解析に失敗したものは、Errを持つMatchとして返す
+// 2024年度や03-1234-5678のように月まで読めないものは日付ではないとして飛ばす
+func FindAll(s string, loc *time.Location) []Match {
+	var ms []Match
+	prev := rune(0)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !canStart(r) || isDigit(prev) {
+			prev = r
+			i += size
+			continue
+		}
+		p := &parser{s: s, pos: i, loc: loc}
+		t, err := p.parse()
+		var pe *ParseError
+		switch {
+		case err == nil:
+			ms = append(ms, Match{Start: i, End: p.pos, Time: t, HasTime: p.hasClock})
+		case p.committed && errors.As(err, &pe):
+			// 同じ日付の途中から探し直さないように、読めたところまで進める
+			ms = append(ms, Match{Start: i, End: p.pos, Err: pe})
+		default:
+			prev = r
+			i += size
+			continue
+		}
+		if p.pos <= i {
+			p.pos = i + size
+		}
+		prev, _ = utf8.DecodeLastRuneInString(s[:p.pos])
+		i = p.pos
+	}
+	return ms
+}
+
+// canStart は日付の最初の文字になりうるかを返す
+func canStart(r rune) bool {
+	if isDigit(r) {
+		return true
+	}
+	for _, e := range Eras {
+		if first, _ := utf8.DecodeRuneInString(e.Name); r == first {
+			return true
+		}
+	}
+	return false
+}
+
+// isDigit は半角・全角の数字か漢数字かを返す
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9' || '０' <= r && r <= '９' || strings.ContainsRune(kanjiNumerals, r)
+}
@@ -0,0 +1,81 @@
+package jadate
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// よく使うレイアウト
+// time.Formatのレイアウトに次の置き換えを加えたもの
+//
+//	{era}     元号（令和）
+//	{eraYear} 元号での年（元年は「元」）
+//	{week}    曜日（火）
+//	{ampm}    午前・午後
+//	{hour}    午前・午後と使う0から11の時（time.Formatの3は0時を12と出力する）
+const (
+	Seireki         = "2006年1月2日"
+	Wareki          = "{era}{eraYear}年1月2日"
+	WarekiWeekday   = "{era}{eraYear}年1月2日（{week}）"
+	WarekiDateTime  = "{era}{eraYear}年1月2日 {ampm}{hour}時4分"
+	SeirekiDateTime = "2006年1月2日 15時4分"
+)
+
+// Format はlayoutに従ってtを整形する
+// 明治より前の日付では{era}は空、{eraYear}は西暦の年になる
+//
+//	jadate.Format(t, jadate.WarekiWeekday) // 令和6年1月2日（火）
+func Format(t time.Time, layout string) string {
+	var b strings.Builder
+	for layout != "" {
+		i := strings.IndexByte(layout, '{')
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(layout[i:], '}')
+		if j < 0 {
+			break
+		}
+		token := layout[i+1 : i+j]
+		v, ok := formatToken(t, token)
+		if !ok {
+			// 知らないものはそのまま出力する
+			b.WriteString(t.Format(layout[:i+j+1]))
+			layout = layout[i+j+1:]
+			continue
+		}
+		b.WriteString(t.Format(layout[:i]))
+		b.WriteString(v)
+		layout = layout[i+j+1:]
+	}
+	b.WriteString(t.Format(layout))
+	return b.String()
+}
+
+func formatToken(t time.Time, token string) (string, bool) {
+	switch token {
+	case "era":
+		era, _, _ := EraOf(t)
+		return era.Name, true
+	case "eraYear":
+		_, year, ok := EraOf(t)
+		switch {
+		case !ok:
+			return strconv.Itoa(t.Year()), true
+		case year == 1:
+			return "元", true
+		}
+		return strconv.Itoa(year), true
+	case "week":
+		return weekdays[t.Weekday()], true
+	case "ampm":
+		if t.Hour() < 12 {
+			return "午前", true
+		}
+		return "午後", true
+	case "hour":
+		return strconv.Itoa(t.Hour() % 12), true
+	}
+	return "", false
+}
@@ -0,0 +1,424 @@
+// Package jadate は日本語の日付を解析・整形する
+//
+// 次のような表記をtime.Timeにする
+//
+//	2024年1月2日
+//	令和6年1月2日（火）
+//	平成元年一月八日 午後3時半
+//	二〇二四年十二月三十一日 23:59:59
+//	２０２４／０１／０２
+//
+// 解析できない場合は入力のどこで失敗したかを持つ*ParseErrorを返す
+package jadate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang/recipe-golang/11.text/number"
+)
+
+// Era は元号
+type Era struct {
+	Name  string
+	Start time.Time // 元年の最初の日（その日の0時、UTC）
+}
+
+// Eras は対応する元号を新しい順に並べたもの
+var Eras = []Era{
+	{"令和", date(2019, 5, 1)},
+	{"平成", date(1989, 1, 8)},
+	{"昭和", date(1926, 12, 25)},
+	{"大正", date(1912, 7, 30)},
+	{"明治", date(1868, 1, 25)},
+}
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// EraOf はtの日付の元号と元号での年を返す
+// 明治より前の日付のときはokがfalseになる
+func EraOf(t time.Time) (era Era, year int, ok bool) {
+	d := date(t.Year(), t.Month(), t.Day())
+	for _, e := range Eras {
+		if !d.Before(e.Start) {
+			return e, t.Year() - e.Start.Year() + 1, true
+		}
+	}
+	return Era{}, 0, false
+}
+
+// ParseError は解析に失敗した位置と理由を持つ
+type ParseError struct {
+	Input  string
+	Offset int // 失敗した位置（バイト）
+	Msg    string
+}
+
+// Column は失敗した位置を1から始まる文字（rune）の位置で返す
+func (e *ParseError) Column() int {
+	return utf8.RuneCountInString(e.Input[:e.Offset]) + 1
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("jadate: parsing %q: column %d: %s", e.Input, e.Column(), e.Msg)
+}
+
+// Parse はsを日付として解析する
+// 時刻がなければ0時、タイムゾーンはUTCになる
+func Parse(s string) (time.Time, error) {
+	return ParseInLocation(s, time.UTC)
+}
+
+// ParseInLocation はlocのタイムゾーンでsを日付として解析する
+func ParseInLocation(s string, loc *time.Location) (time.Time, error) {
+	p := &parser{s: s, loc: loc}
+	t, err := p.parse()
+	if err != nil {
+		return time.Time{}, err
+	}
+	p.spaces()
+	if p.pos < len(s) {
+		return time.Time{}, p.errorf(p.pos, "unexpected %q", s[p.pos:])
+	}
+	return t, nil
+}
+
+// parser はsのposの位置から日付を読む
+type parser struct {
+	s   string
+	pos int
+	loc *time.Location
+	// committed は月まで読めた後、または元号を読んだ後にtrueになる
+	// 文章から日付を探すときに、2024年度や03-1234-5678のような日付ではない数字と
+	// 書き間違えた日付を区別するのに使う
+	committed bool
+	// hasClock は時刻を読んだときにtrueになる
+	hasClock bool
+}
+
+func (p *parser) errorf(off int, format string, args ...interface{}) error {
+	return &ParseError{Input: p.s, Offset: off, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) consume(lits ...string) bool {
+	for _, lit := range lits {
+		if strings.HasPrefix(p.s[p.pos:], lit) {
+			p.pos += len(lit)
+			return true
+		}
+	}
+	return false
+}
+
+// spaces は半角と全角の空白を読み飛ばす
+func (p *parser) spaces() {
+	for p.consume(" ", "\t", "　") {
+	}
+}
+
+func (p *parser) parse() (time.Time, error) {
+	start := p.pos
+	var era *Era
+	for i := range Eras {
+		if p.consume(Eras[i].Name) {
+			era = &Eras[i]
+			p.committed = true
+			break
+		}
+	}
+
+	yearOff := p.pos
+	var year, digits int
+	if era != nil && p.consume("元") {
+		year, digits = 1, 1
+	} else {
+		var err error
+		if year, digits, err = p.number(); err != nil {
+			return time.Time{}, err
+		}
+		if digits == 0 {
+			return time.Time{}, p.errorf(p.pos, "expected year")
+		}
+	}
+
+	var (
+		month, day       int
+		monthOff, dayOff int
+		err              error
+	)
+	sepOff := p.pos
+	switch {
+	case p.consume("年"):
+		monthOff = p.pos
+		if month, err = p.field("月", "month"); err != nil {
+			return time.Time{}, err
+		}
+		dayOff = p.pos
+		if day, err = p.field("日", "day"); err != nil {
+			return time.Time{}, p.commitDay(dayOff, err)
+		}
+	case era == nil && digits == 4 && p.sep() != "":
+		// 2024/01/02のように区切る場合は同じ区切り文字を使う
+		sep := p.sep()
+		p.consume(sep)
+		monthOff = p.pos
+		if month, err = p.field(sep, "month"); err != nil {
+			return time.Time{}, err
+		}
+		dayOff = p.pos
+		if day, err = p.field("", "day"); err != nil {
+			return time.Time{}, p.commitDay(dayOff, err)
+		}
+	default:
+		return time.Time{}, p.errorf(sepOff, "expected 年 after year")
+	}
+	p.committed = true
+
+	if era != nil {
+		year += era.Start.Year() - 1
+	} else if year < 1000 {
+		return time.Time{}, p.errorf(yearOff, "year %d must have 4 digits without an era name", year)
+	}
+	if month < 1 || month > 12 {
+		return time.Time{}, p.errorf(monthOff, "month %d out of range", month)
+	}
+	if n := daysIn(year, time.Month(month)); day < 1 || day > n {
+		return time.Time{}, p.errorf(dayOff, "day %d out of range (%d-%02d has %d days)", day, year, month, n)
+	}
+	d := date(year, time.Month(month), day)
+	if era != nil {
+		if err := p.checkEra(start, *era, d); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if err := p.weekday(d); err != nil {
+		return time.Time{}, err
+	}
+	hour, min, sec, err := p.clock()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(year, time.Month(month), day, hour, min, sec, 0, p.loc), nil
+}
+
+// commitDay は日を読めなかったときのエラーを返す
+// 2024年4月のように日の数字がないものは年月なので、元号がなければ日付の誤りにしない
+func (p *parser) commitDay(off int, err error) error {
+	p.committed = p.committed || p.pos > off
+	return err
+}
+
+// checkEra はdがeraの期間にあるかを確かめる
+func (p *parser) checkEra(off int, era Era, d time.Time) error {
+	if d.Before(era.Start) {
+		return p.errorf(off, "%s is before %s started on %s", d.Format("2006-01-02"), era.Name, era.Start.Format("2006-01-02"))
+	}
+	for i, e := range Eras {
+		if e.Name == era.Name && i > 0 && !d.Before(Eras[i-1].Start) {
+			return p.errorf(off, "%s is after %s ended (%s started on %s)", d.Format("2006-01-02"), era.Name, Eras[i-1].Name, Eras[i-1].Start.Format("2006-01-02"))
+		}
+	}
+	return nil
+}
+
+// sep は年月日の区切り文字を返す
+func (p *parser) sep() string {
+	for _, sep := range []string{"/", "-", ".", "／", "－", "．"} {
+		if strings.HasPrefix(p.s[p.pos:], sep) {
+			return sep
+		}
+	}
+	return ""
+}
+
+// field は数字とその後のunitを読む
+func (p *parser) field(unit, name string) (int, error) {
+	n, digits, err := p.number()
+	if err != nil {
+		return 0, err
+	}
+	if digits == 0 {
+		return 0, p.errorf(p.pos, "expected %s", name)
+	}
+	if unit != "" && !p.consume(unit) {
+		return 0, p.errorf(p.pos, "expected %s after %s", unit, name)
+	}
+	return n, nil
+}
+
+var weekdays = []string{"日", "月", "火", "水", "木", "金", "土"}
+
+// weekday は（火）のような曜日を読み、dの曜日と一致するかを確かめる
+func (p *parser) weekday(d time.Time) error {
+	save := p.pos
+	p.spaces()
+	if !p.consume("（", "(") {
+		p.pos = save
+		return nil
+	}
+	off := p.pos
+	w := -1
+	for i, name := range weekdays {
+		if p.consume(name) {
+			w = i
+			break
+		}
+	}
+	if w < 0 {
+		return p.errorf(off, "expected weekday")
+	}
+	p.consume("曜日", "曜")
+	if !p.consume("）", ")") {
+		return p.errorf(p.pos, "expected ）")
+	}
+	if time.Weekday(w) != d.Weekday() {
+		return p.errorf(off, "weekday %s does not match %s (%s)", weekdays[w], d.Format("2006-01-02"), weekdays[d.Weekday()])
+	}
+	return nil
+}
+
+// clock は午後3時半や15:04:05のような時刻を読む
+// 時刻がなければ0時を返す
+func (p *parser) clock() (hour, min, sec int, err error) {
+	save := p.pos
+	p.spaces()
+	ampm := ""
+	if p.consume("午前") {
+		ampm = "午前"
+	} else if p.consume("午後") {
+		ampm = "午後"
+	}
+	hourOff := p.pos
+	hour, digits, err := p.number()
+	if err != nil && ampm == "" {
+		// 時刻ではないので読まなかったことにする
+		p.pos = save
+		return 0, 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	switch {
+	case digits > 0 && p.consume("時"):
+		if p.consume("半") {
+			min = 30
+			break
+		}
+		if min, err = p.optional("分", "minute", 59); err != nil {
+			return 0, 0, 0, err
+		}
+		if sec, err = p.optional("秒", "second", 59); err != nil {
+			return 0, 0, 0, err
+		}
+	case digits > 0 && ampm == "" && p.consume(":", "："):
+		if min, err = p.colonField("minute"); err != nil {
+			return 0, 0, 0, err
+		}
+		if p.consume(":", "：") {
+			if sec, err = p.colonField("second"); err != nil {
+				return 0, 0, 0, err
+			}
+		}
+	case ampm != "":
+		return 0, 0, 0, p.errorf(p.pos, "expected hour after %s", ampm)
+	default:
+		p.pos = save
+		return 0, 0, 0, nil
+	}
+
+	if ampm == "" && hour > 23 || ampm == "午前" && hour > 12 || ampm == "午後" && hour > 11 {
+		return 0, 0, 0, p.errorf(hourOff, "hour %d out of range", hour)
+	}
+	if ampm == "午後" {
+		hour += 12
+	}
+	p.hasClock = true
+	return hour, min, sec, nil
+}
+
+// colonField は15:04:05の分か秒を読む
+func (p *parser) colonField(name string) (int, error) {
+	off := p.pos
+	n, err := p.field("", name)
+	if err != nil {
+		return 0, err
+	}
+	if n > 59 {
+		return 0, p.errorf(off, "%s %d out of range", name, n)
+	}
+	return n, nil
+}
+
+// optional は数字とunitがあれば読む
+// 数字の後にunitがなければ読まなかったことにする
+func (p *parser) optional(unit, name string, max int) (int, error) {
+	save := p.pos
+	n, digits, err := p.number()
+	if err != nil || digits == 0 || !p.consume(unit) {
+		p.pos = save
+		return 0, nil
+	}
+	if n > max {
+		return 0, p.errorf(save, "%s %d out of range", name, n)
+	}
+	return n, nil
+}
+
+// number は半角・全角の数字か漢数字を読む
+// 数字がなければdigitsは0になる
+func (p *parser) number() (n, digits int, err error) {
+	start := p.pos
+	for p.pos < len(p.s) {
+		r, size := utf8.DecodeRuneInString(p.s[p.pos:])
+		switch {
+		case '0' <= r && r <= '9':
+			n = n*10 + int(r-'0')
+		case '０' <= r && r <= '９':
+			n = n*10 + int(r-'０')
+		default:
+			if digits == 0 {
+				return p.kanji()
+			}
+			return n, digits, nil
+		}
+		if digits++; digits > 9 {
+			return 0, 0, p.errorf(start, "number too large")
+		}
+		p.pos += size
+	}
+	return n, digits, nil
+}
+
+// kanjiNumerals は日付に使う漢数字
+// 東京の京のような文字を数字として扱わないように万より上の位は含めない
+const kanjiNumerals = "〇一二三四五六七八九十百千"
+
+// kanji は二〇二四のような位取りの漢数字か、二千二十四のような位を使う漢数字を読む
+func (p *parser) kanji() (n, digits int, err error) {
+	start := p.pos
+	for p.pos < len(p.s) {
+		r, size := utf8.DecodeRuneInString(p.s[p.pos:])
+		if !strings.ContainsRune(kanjiNumerals, r) {
+			break
+		}
+		digits++
+		p.pos += size
+	}
+	if digits == 0 {
+		return 0, 0, nil
+	}
+	run := p.s[start:p.pos]
+	if n, err = number.ParseInt[int](run); err != nil {
+		return 0, 0, p.errorf(start, "invalid kanji numeral %q", run)
+	}
+	return n, digits, nil
+}
+
+func daysIn(year int, m time.Month) int {
+	return time.Date(year, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
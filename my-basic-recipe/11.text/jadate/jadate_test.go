@@ -0,0 +1,195 @@
+package jadate_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang/recipe-golang/11.text/jadate"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		in   string
+		want time.Time
+	}{
+		"seireki":      {"1986年01月12日", date(1986, 1, 12)},
+		"slash":        {"2020/03/24", date(2020, 3, 24)},
+		"hyphen":       {"2020-3-24", date(2020, 3, 24)},
+		"full width":   {"２０２４／０１／０２", date(2024, 1, 2)},
+		"reiwa":        {"令和6年1月2日", date(2024, 1, 2)},
+		"gannen":       {"平成元年1月8日", date(1989, 1, 8)},
+		"showa":        {"昭和64年1月7日", date(1989, 1, 7)},
+		"kanji":        {"二〇二四年十二月三十一日", date(2024, 12, 31)},
+		"kanji units":  {"二千二十四年一月十日", date(2024, 1, 10)},
+		"weekday":      {"令和6年1月2日（火）", date(2024, 1, 2)},
+		"weekday long": {"2024年1月2日 (火曜日)", date(2024, 1, 2)},
+		"pm":           {"2024年1月2日 午後3時", time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)},
+		"am":           {"2024年1月2日午前0時5分", time.Date(2024, 1, 2, 0, 5, 0, 0, time.UTC)},
+		"half":         {"平成元年一月八日　午後三時半", time.Date(1989, 1, 8, 15, 30, 0, 0, time.UTC)},
+		"seconds":      {"2024年1月2日（火）15時4分5秒", time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)},
+		"colon":        {"2024/01/02 23:59:59", time.Date(2024, 1, 2, 23, 59, 59, 0, time.UTC)},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := jadate.Parse(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		in     string
+		column int
+		msg    string
+	}{
+		"empty":          {"", 1, "expected year"},
+		"no year unit":   {"2024", 5, "expected 年 after year"},
+		"no month":       {"2024年", 6, "expected month"},
+		"no day unit":    {"2024年1月2", 9, "expected 日 after day"},
+		"short year":     {"24年1月2日", 1, "year 24 must have 4 digits without an era name"},
+		"month":          {"2024年13月1日", 6, "month 13 out of range"},
+		"day":            {"2023年2月29日", 8, "day 29 out of range (2023-02 has 28 days)"},
+		"era ended":      {"平成32年1月1日", 1, "2020-01-01 is after 平成 ended (令和 started on 2019-05-01)"},
+		"era not begun":  {"令和元年4月30日", 1, "2019-04-30 is before 令和 started on 2019-05-01"},
+		"weekday":        {"2024年1月2日（月）", 11, "weekday 月 does not match 2024-01-02 (火)"},
+		"no weekday":     {"2024年1月2日（x）", 11, "expected weekday"},
+		"hour":           {"2024年1月2日 午後12時", 13, "hour 12 out of range"},
+		"minute":         {"2024年1月2日 10:60", 14, "minute 60 out of range"},
+		"kanji numeral":  {"二〇二四年十十月一日", 6, `invalid kanji numeral "十十"`},
+		"trailing":       {"2024年1月2日です", 10, `unexpected "です"`},
+		"no hour":        {"2024年1月2日 午後", 13, "expected hour after 午後"},
+		"sep mismatch":   {"2024/01-02", 8, "expected / after month"},
+		"era with slash": {"令和6/1/2", 4, "expected 年 after year"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := jadate.Parse(tt.in)
+			var pe *jadate.ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("want *ParseError, got %v", err)
+			}
+			if pe.Column() != tt.column || pe.Msg != tt.msg {
+				t.Errorf("want column %d %q, got column %d %q", tt.column, tt.msg, pe.Column(), pe.Msg)
+			}
+		})
+	}
+}
+
+func TestParseInLocation(t *testing.T) {
+	t.Parallel()
+	jst := time.FixedZone("JST", 9*60*60)
+	got, err := jadate.ParseInLocation("2024年1月2日 9時", jst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		t      time.Time
+		layout string
+		want   string
+	}{
+		"seireki":      {date(2024, 1, 2), jadate.Seireki, "2024年1月2日"},
+		"wareki":       {date(2024, 1, 2), jadate.Wareki, "令和6年1月2日"},
+		"gannen":       {date(2019, 5, 1), jadate.Wareki, "令和元年5月1日"},
+		"era boundary": {date(2019, 4, 30), jadate.Wareki, "平成31年4月30日"},
+		"weekday":      {date(2024, 1, 2), jadate.WarekiWeekday, "令和6年1月2日（火）"},
+		"midnight":     {time.Date(2024, 1, 2, 0, 5, 0, 0, time.UTC), jadate.WarekiDateTime, "令和6年1月2日 午前0時5分"},
+		"afternoon":    {time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC), jadate.WarekiDateTime, "令和6年1月2日 午後3時4分"},
+		"before meiji": {date(1800, 1, 1), jadate.Wareki, "1800年1月1日"},
+		"unknown":      {date(2024, 1, 2), "{x}2006", "{x}2024"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := jadate.Format(tt.t, tt.layout); got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestFormat_RoundTrip は整形した日付を解析すると元に戻ることを確かめる
+func TestFormat_RoundTrip(t *testing.T) {
+	t.Parallel()
+	for d := date(1989, 1, 1); d.Before(date(2025, 1, 1)); d = d.Add(97*time.Hour + 13*time.Minute) {
+		d := d.Truncate(time.Minute)
+		for _, layout := range []string{jadate.WarekiWeekday, jadate.WarekiDateTime, jadate.SeirekiDateTime} {
+			s := jadate.Format(d, layout)
+			got, err := jadate.Parse(s)
+			if err != nil {
+				t.Fatalf("%s: %v", s, err)
+			}
+			if layout == jadate.WarekiWeekday {
+				got = got.Add(time.Duration(d.Hour())*time.Hour + time.Duration(d.Minute())*time.Minute)
+			}
+			if !got.Equal(d) {
+				t.Fatalf("%s: want %v, got %v", s, d, got)
+			}
+		}
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	t.Parallel()
+	s := "会議は令和6年1月2日（火）午後3時から。締切は2024/01/31、2024年2月30日や平成32年1月1日は誤り。価格は1000円。"
+	ms := jadate.FindAll(s, time.UTC)
+	if len(ms) != 4 {
+		t.Fatalf("want 4 matches, got %+v", ms)
+	}
+	if got := s[ms[0].Start:ms[0].End]; got != "令和6年1月2日（火）午後3時" || !ms[0].HasTime || ms[0].Time.Hour() != 15 {
+		t.Errorf("want the first date with time, got %q %+v", got, ms[0])
+	}
+	if got := s[ms[1].Start:ms[1].End]; got != "2024/01/31" || ms[1].HasTime {
+		t.Errorf("want the second date, got %q", got)
+	}
+	if ms[2].Err == nil || s[ms[2].Start:ms[2].End] != "2024年2月30日" {
+		t.Errorf("want error for 2024年2月30日, got %+v", ms[2])
+	}
+	if ms[3].Err == nil || s[ms[3].Start:ms[3].End] != "平成32年1月1日" {
+		t.Errorf("want error for 平成32年1月1日, got %+v", ms[3])
+	}
+}
+
+func TestFindAll_NotDate(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"fiscal year": "2024年度の予算",
+		"years ago":   "3年前に引っ越した",
+		"phone":       "電話は03-1234-5678まで",
+		"year month":  "2024年4月から始める",
+		"five digits": "12345年",
+	}
+	for name, s := range cases {
+		name, s := name, s
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if ms := jadate.FindAll(s, time.UTC); len(ms) != 0 {
+				t.Errorf("want no matches in %q, got %+v", s, ms)
+			}
+		})
+	}
+}
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
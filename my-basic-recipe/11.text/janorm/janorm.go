@@ -0,0 +1,243 @@
+// Package janorm は日本語の文字列の表記ゆれをそろえる
+//
+// 全角英数字と半角カナの幅、ひらがなとカタカナ、長音、空白をそろえる
+// NFKCに近いが、①や㈱のような互換文字は変換しない
+//
+//	janorm.String("ﾃﾞｰﾀﾍﾞｰｽ　ＡＢＣ－１２３") // データベース ABC-123
+//
+// transform.Transformerとして使えるので、ファイルなどを少しずつ変換できる
+//
+//	r := transform.NewReader(f, janorm.New().Transformer())
+package janorm
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// Kana はひらがなとカタカナのどちらにそろえるか
+type Kana int
+
+const (
+	KanaAsIs Kana = iota // そろえない
+	Hiragana             // ひらがなにする
+	Katakana             // カタカナにする
+)
+
+// Normalizer は設定に従って文字列をそろえる
+type Normalizer struct {
+	width     bool
+	kana      Kana
+	longVowel bool
+	spaces    bool
+	except    string
+}
+
+// Option はNormalizerの設定
+type Option func(*Normalizer)
+
+// WithWidth は全角英数字・記号を半角に、半角カナを全角にするかを設定する（デフォルトはtrue）
+// 半角カナの濁点・半濁点は前の文字と合わせて1文字にする（ｶﾞ → ガ）
+func WithWidth(on bool) Option {
+	return func(n *Normalizer) { n.width = on }
+}
+
+// WithKana はひらがなとカタカナのどちらにそろえるかを設定する（デフォルトはKanaAsIs）
+func WithKana(k Kana) Option {
+	return func(n *Normalizer) { n.kana = k }
+}
+
+// WithLongVowel は長音をそろえるかを設定する（デフォルトはtrue）
+// かなの後のダッシュやハイフンに似た文字を「ー」にし、続いた「ー」を1つにする
+func WithLongVowel(on bool) Option {
+	return func(n *Normalizer) { n.longVowel = on }
+}
+
+// WithSpaces は空白をそろえるかを設定する（デフォルトはtrue）
+// 全角空白やタブを半角空白にし、続いた空白を1つにする。改行はそのまま
+func WithSpaces(on bool) Option {
+	return func(n *Normalizer) { n.spaces = on }
+}
+
+// WithExcept は変換しない文字を設定する
+//
+//	janorm.New(janorm.WithExcept("￥～")) // 全角の円記号と波ダッシュはそのまま
+func WithExcept(chars string) Option {
+	return func(n *Normalizer) { n.except = chars }
+}
+
+// New はNormalizerを作る
+func New(opts ...Option) *Normalizer {
+	n := &Normalizer{width: true, longVowel: true, spaces: true}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+// Default はデフォルトの設定のNormalizer
+var Default = New()
+
+// String はDefaultでsをそろえる
+func String(s string) string { return Default.String(s) }
+
+// String はsをそろえる
+func (n *Normalizer) String(s string) string {
+	out, _, err := transform.String(n.Transformer(), s)
+	if err != nil {
+		// 変換でエラーになることはない
+		panic(err)
+	}
+	return out
+}
+
+// Transformer はnの設定でそろえるtransform.Transformerを返す
+// 直前の文字を覚えておくので、ゴルーチンごとに作る
+func (n *Normalizer) Transformer() transform.Transformer {
+	return &transformer{n: n}
+}
+
+type transformer struct {
+	n    *Normalizer
+	prev rune // 直前に出力した文字
+}
+
+func (t *transformer) Reset() { t.prev = 0 }
+
+func (t *transformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		r, size := utf8.DecodeRune(src[nSrc:])
+		if r == utf8.RuneError && !atEOF && !utf8.FullRune(src[nSrc:]) {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+		consumed := size
+
+		if t.n.width && isHalfKana(r) && !t.excepted(r) {
+			// 次の文字が濁点・半濁点かを見るまで変換できない
+			rest := src[nSrc+size:]
+			if !atEOF && !utf8.FullRune(rest) {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			if mark, msize := utf8.DecodeRune(rest); mark == 'ﾞ' || mark == 'ﾟ' {
+				if c, ok := compose(r, mark); ok {
+					r = c
+					consumed += msize
+				}
+			}
+		}
+
+		out := t.n.mapRune(r, t.prev, t.excepted(r))
+		if out >= 0 {
+			if nDst+utf8.RuneLen(out) > len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			nDst += utf8.EncodeRune(dst[nDst:], out)
+			t.prev = out
+		}
+		nSrc += consumed
+	}
+	return nDst, nSrc, nil
+}
+
+func (t *transformer) excepted(r rune) bool {
+	return t.n.except != "" && strings.ContainsRune(t.n.except, r)
+}
+
+// mapRune はrを変換した文字を返す。出力しない場合は-1を返す
+func (n *Normalizer) mapRune(r, prev rune, except bool) rune {
+	if except {
+		return r
+	}
+	switch {
+	case n.longVowel && isKana(prev) && isDash(r):
+		r = 'ー'
+	case n.width:
+		r = fold(r)
+	}
+	switch n.kana {
+	case Hiragana:
+		r = toHiragana(r)
+	case Katakana:
+		r = toKatakana(r)
+	}
+	if n.longVowel && r == 'ー' && prev == 'ー' {
+		return -1
+	}
+	if n.spaces && r != '\n' && r != '\r' && unicode.IsSpace(r) {
+		if prev == ' ' {
+			return -1
+		}
+		r = ' '
+	}
+	return r
+}
+
+// fold は全角英数字・記号を半角に、半角カナを全角にする
+func fold(r rune) rune {
+	switch r {
+	case 'ﾞ':
+		return '゛' // 結合文字ではなく単独の濁点にする
+	case 'ﾟ':
+		return '゜'
+	}
+	p := width.LookupRune(r)
+	switch p.Kind() {
+	case width.EastAsianFullwidth:
+		if n := p.Narrow(); n != 0 {
+			return n
+		}
+	case width.EastAsianHalfwidth:
+		if isHalfKana(r) {
+			return p.Wide()
+		}
+	}
+	return r
+}
+
+// compose は半角カナと濁点・半濁点を合わせた全角の1文字を返す
+func compose(base, mark rune) (rune, bool) {
+	combining := '゙'
+	if mark == 'ﾟ' {
+		combining = '゚'
+	}
+	s := norm.NFC.String(string([]rune{fold(base), combining}))
+	c, size := utf8.DecodeRuneInString(s)
+	return c, size == len(s)
+}
+
+// isHalfKana は半角カナと半角の句読点かを返す
+func isHalfKana(r rune) bool { return '｡' <= r && r <= 'ﾟ' }
+
+// isKana はひらがな・カタカナ・長音かを返す
+func isKana(r rune) bool {
+	return 'ぁ' <= r && r <= 'ヿ' && r != '・' && r != '゠'
+}
+
+// isDash は長音の代わりに使われやすい文字かを返す
+// 半角のハイフンマイナスは数字や英語で使うので含めない
+func isDash(r rune) bool {
+	switch r {
+	case 'ｰ', '－', '‐', '‑', '‒', '–', '—', '―', '−', '─', '━':
+		return true
+	}
+	return false
+}
+
+func toKatakana(r rune) rune {
+	if 'ぁ' <= r && r <= 'ゖ' || r == 'ゝ' || r == 'ゞ' {
+		return r + 0x60
+	}
+	return r
+}
+
+func toHiragana(r rune) rune {
+	if 'ァ' <= r && r <= 'ヶ' || r == 'ヽ' || r == 'ヾ' {
+		return r - 0x60
+	}
+	return r
+}
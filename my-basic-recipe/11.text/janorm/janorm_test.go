@@ -0,0 +1,95 @@
+package janorm_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"golang.org/x/text/transform"
+
+	"golang/recipe-golang/11.text/janorm"
+)
+
+func TestString(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		in   string
+		opts []janorm.Option
+		want string
+	}{
+		"alnum":         {"ＡＢＣ－１２３", nil, "ABC-123"},
+		"half kana":     {"ﾃﾞｰﾀﾍﾞｰｽ", nil, "データベース"},
+		"handakuten":    {"ﾊﾟﾋﾟﾌﾟ", nil, "パピプ"},
+		"vu":            {"ｳﾞｧｲｵﾘﾝ", nil, "ヴァイオリン"},
+		"lone dakuten":  {"ｱﾞ", nil, "ア゛"},
+		"punctuation":   {"｢ｶﾅ｣､｡", nil, "「カナ」、。"},
+		"long vowel":    {"カ－ド", nil, "カード"},
+		"long dashes":   {"すご――い", nil, "すごーい"},
+		"collapse":      {"ラーーメン", nil, "ラーメン"},
+		"hyphen kept":   {"ABC－123", nil, "ABC-123"},
+		"spaces":        {"a　 \tb\nc", nil, "a b\nc"},
+		"compat kept":   {"①㈱", nil, "①㈱"},
+		"katakana":      {"ひらがなとカタカナ", []janorm.Option{janorm.WithKana(janorm.Katakana)}, "ヒラガナトカタカナ"},
+		"hiragana":      {"ﾃﾞｰﾀとカタカナ", []janorm.Option{janorm.WithKana(janorm.Hiragana)}, "でーたとかたかな"},
+		"iteration":     {"ゝゞ", []janorm.Option{janorm.WithKana(janorm.Katakana)}, "ヽヾ"},
+		"except":        {"￥１００～", []janorm.Option{janorm.WithExcept("￥～")}, "￥100～"},
+		"except kana":   {"ｶﾞｶﾞ", []janorm.Option{janorm.WithExcept("ｶ")}, "ｶ゛ｶ゛"},
+		"no width":      {"ＡＢＣ ｶﾞ", []janorm.Option{janorm.WithWidth(false)}, "ＡＢＣ ｶﾞ"},
+		"no long vowel": {"カ－ドーー", []janorm.Option{janorm.WithLongVowel(false)}, "カ-ドーー"},
+		"no spaces":     {"a　 b", []janorm.Option{janorm.WithSpaces(false)}, "a  b"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := janorm.New(tt.opts...).String(tt.in); got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestTransformer は1バイトずつ読み込んでも同じ結果になることを確かめる
+func TestTransformer(t *testing.T) {
+	t.Parallel()
+	in := strings.Repeat("ﾃﾞｰﾀﾍﾞｰｽ　ＡＢＣ－１２３  カ――ド\n", 100)
+	want := janorm.String(in)
+	r := transform.NewReader(iotest.OneByteReader(strings.NewReader(in)), janorm.New().Transformer())
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if !strings.HasPrefix(want, "データベース ABC-123 カード\n") {
+		t.Errorf("want normalized text, got %q", want[:40])
+	}
+}
+
+func TestTransformer_ShortDst(t *testing.T) {
+	t.Parallel()
+	tr := janorm.New().Transformer()
+	dst := make([]byte, 4)
+	nDst, nSrc, err := tr.Transform(dst, []byte("ｶﾞｷﾞ"), true)
+	if err != transform.ErrShortDst {
+		t.Fatalf("want %v, got %v", transform.ErrShortDst, err)
+	}
+	if string(dst[:nDst]) != "ガ" || nSrc != len("ｶﾞ") {
+		t.Errorf("want ガ from %d bytes, got %q from %d bytes", len("ｶﾞ"), dst[:nDst], nSrc)
+	}
+	// 次の文字が濁点かわからない場合は続きを待つ
+	tr.Reset()
+	if _, nSrc, err := tr.Transform(make([]byte, 16), []byte("ｶ"), false); err != transform.ErrShortSrc || nSrc != 0 {
+		t.Errorf("want %v with no progress, got %v after %d bytes", transform.ErrShortSrc, err, nSrc)
+	}
+}
+
+func BenchmarkString(b *testing.B) {
+	s := strings.Repeat("ﾃﾞｰﾀﾍﾞｰｽ　ＡＢＣ－１２３ ひらがなとカタカナ\n", 100)
+	b.SetBytes(int64(len(s)))
+	for i := 0; i < b.N; i++ {
+		janorm.String(s)
+	}
+}
@@ -0,0 +1,269 @@
+package logextract
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+)
+
+// Record は1行から取り出したレコード
+type Record struct {
+	Line    int    // 1から始まる行番号
+	Pattern string // マッチしたパターンの名前
+	Fields  []Field
+}
+
+// Field はグループ名と変換した値
+// 値はstring、int64、float64、bool、time.Duration、time.Timeのどれか
+// 任意のグループがマッチしなかったときはnil
+type Field struct {
+	Name  string
+	Value interface{}
+}
+
+// Get はnameのフィールドの値を返す
+func (r Record) Get(name string) (interface{}, bool) {
+	for _, f := range r.Fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Stats はRunで処理した行数
+type Stats struct {
+	Lines     int // 読み込んだ行
+	Matched   int // レコードにした行
+	Unmatched int // どのパターンでもレコードにできなかった行
+	Invalid   int // Unmatchedのうち、マッチしたが値を変換できなかった行
+}
+
+// Extractor はパターンの一覧を使ってログからレコードを取り出す
+type Extractor struct {
+	patterns  []*compiled
+	workers   int
+	chunk     int
+	unmatched io.Writer
+}
+
+// Option はExtractorの設定
+type Option func(*Extractor)
+
+// WithWorkers は並行して処理するゴールーチンの数を指定する（デフォルトはCPUの数）
+func WithWorkers(n int) Option {
+	return func(x *Extractor) {
+		if n > 0 {
+			x.workers = n
+		}
+	}
+}
+
+// WithChunkLines は1つのゴールーチンにまとめて渡す行数を指定する（デフォルトは1024）
+func WithChunkLines(n int) Option {
+	return func(x *Extractor) {
+		if n > 0 {
+			x.chunk = n
+		}
+	}
+}
+
+// WithUnmatched はレコードにできなかった行をそのままwに書き出す
+// 新しいパターンを作るときの入力に使える
+func WithUnmatched(w io.Writer) Option {
+	return func(x *Extractor) { x.unmatched = w }
+}
+
+// New はlibのパターンをコンパイルしてExtractorを作る
+func New(lib *Library, opts ...Option) (*Extractor, error) {
+	if len(lib.Patterns) == 0 {
+		return nil, ErrNoPatterns
+	}
+	x := &Extractor{workers: runtime.NumCPU(), chunk: 1024}
+	for i, p := range lib.Patterns {
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("pattern%d", i+1)
+		}
+		c, err := compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("logextract: pattern %s: %w", p.Name, err)
+		}
+		x.patterns = append(x.patterns, c)
+	}
+	for _, opt := range opts {
+		opt(x)
+	}
+	return x, nil
+}
+
+// Fields はすべてのパターンのグループ名を最初に現れた順に返す
+// CSVの列などに使う
+func (x *Extractor) Fields() []string {
+	var fields []string
+	seen := make(map[string]bool)
+	for _, c := range x.patterns {
+		for _, name := range c.names {
+			if name != "" && !seen[name] {
+				seen[name] = true
+				fields = append(fields, name)
+			}
+		}
+	}
+	return fields
+}
+
+// Extract は1行をレコードにする
+// パターンを上から順に試し、マッチして値も変換できた最初のパターンを使う
+// どのパターンでもレコードにできなければ、最後に変換できなかったエラーかnilとfalseを返す
+func (x *Extractor) Extract(lineNo int, line string) (Record, bool, error) {
+	var lastErr error
+	for _, c := range x.patterns {
+		fields, ok, err := c.match(line)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if ok {
+			return Record{Line: lineNo, Pattern: c.name, Fields: fields}, true, nil
+		}
+	}
+	return Record{}, false, lastErr
+}
+
+// chunk はまとめて処理する行
+type chunk struct {
+	first int // 最初の行の行番号
+	lines []string
+	out   chan<- result
+}
+
+// result はchunkを処理した結果
+type result struct {
+	records   []Record
+	unmatched []string
+	invalid   int
+}
+
+// Run はrを1行ずつ読み込み、レコードにできた行をemitに渡す
+// 行はまとめて並行に処理するが、emitとWithUnmatchedの出力は入力と同じ順になる
+// emitがエラーを返すかctxが終わると処理をやめる
+func (x *Extractor) Run(ctx context.Context, r io.Reader, emit func(Record) error) (Stats, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chunks := make(chan chunk)
+	// orderには読み込んだ順にchunkの結果を受け取るチャネルを送る
+	// バッファの分だけ先の行を処理しておける
+	order := make(chan chan result, x.workers)
+	var readErr error
+	go func() {
+		defer close(order)
+		defer close(chunks)
+		readErr = x.read(ctx, r, chunks, order)
+	}()
+	for i := 0; i < x.workers; i++ {
+		go func() {
+			for c := range chunks {
+				c.out <- x.process(c)
+			}
+		}()
+	}
+
+	var stats Stats
+	for out := range order {
+		var res result
+		select {
+		case res = <-out:
+		case <-ctx.Done():
+			return stats, ctx.Err()
+		}
+		for _, rec := range res.records {
+			if err := emit(rec); err != nil {
+				return stats, err
+			}
+			stats.Matched++
+		}
+		if x.unmatched != nil {
+			for _, line := range res.unmatched {
+				if _, err := io.WriteString(x.unmatched, line+"\n"); err != nil {
+					return stats, fmt.Errorf("logextract: write unmatched: %w", err)
+				}
+			}
+		}
+		stats.Unmatched += len(res.unmatched)
+		stats.Invalid += res.invalid
+		stats.Lines = stats.Matched + stats.Unmatched
+	}
+	// orderが閉じた後なのでreadErrを読んでよい
+	if readErr != nil {
+		return stats, readErr
+	}
+	return stats, ctx.Err()
+}
+
+// read はx.chunk行ずつchunksとorderに送る
+func (x *Extractor) read(ctx context.Context, r io.Reader, chunks chan<- chunk, order chan<- chan result) error {
+	br := bufio.NewReader(r)
+	lineNo := 0
+	var lines []string
+	send := func() bool {
+		out := make(chan result, 1)
+		c := chunk{first: lineNo - len(lines) + 1, lines: lines, out: out}
+		lines = nil
+		select {
+		case chunks <- c:
+		case <-ctx.Done():
+			return false
+		}
+		select {
+		case order <- out:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			lineNo++
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			lines = append(lines, line)
+			if len(lines) == x.chunk && !send() {
+				return nil
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if len(lines) > 0 {
+				send()
+			}
+			return fmt.Errorf("logextract: read line %d: %w", lineNo+1, err)
+		}
+	}
+	if len(lines) > 0 {
+		send()
+	}
+	return nil
+}
+
+func (x *Extractor) process(c chunk) result {
+	var res result
+	for i, line := range c.lines {
+		rec, ok, err := x.Extract(c.first+i, line)
+		if ok {
+			res.records = append(res.records, rec)
+			continue
+		}
+		if err != nil {
+			res.invalid++
+		}
+		res.unmatched = append(res.unmatched, line)
+	}
+	return res
+}
@@ -0,0 +1,209 @@
+package logextract_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/recipe-golang/11.text/logextract"
+)
+
+const library = `
+patterns:
+  - name: req
+    regex: '^(?P<time>\S+) (?P<method>[A-Z]+) (?P<path>\S+) (?P<status>\d+) (?P<took>\S+)$'
+    types:
+      time: time:2006-01-02T15:04:05
+      status: int
+      took: duration
+  - name: msg
+    regex: '^(?P<level>[A-Z]+): (?P<msg>.+)$'
+`
+
+func newExtractor(t *testing.T, opts ...logextract.Option) *logextract.Extractor {
+	t.Helper()
+	lib, err := logextract.LoadLibrary(strings.NewReader(library))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := logextract.New(lib, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return x
+}
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+	x := newExtractor(t)
+	rec, ok, err := x.Extract(7, "2024-01-12T10:00:00 GET /users 200 15ms")
+	if !ok || err != nil {
+		t.Fatalf("want match, got %v (%v)", ok, err)
+	}
+	if rec.Line != 7 || rec.Pattern != "req" {
+		t.Errorf("want line 7 of req, got line %d of %s", rec.Line, rec.Pattern)
+	}
+	want := map[string]interface{}{
+		"time":   time.Date(2024, 1, 12, 10, 0, 0, 0, time.UTC),
+		"method": "GET",
+		"path":   "/users",
+		"status": int64(200),
+		"took":   15 * time.Millisecond,
+	}
+	for name, w := range want {
+		if got, _ := rec.Get(name); got != w {
+			t.Errorf("%s: want %v (%T), got %v (%T)", name, w, w, got, got)
+		}
+	}
+
+	// 変換できないときは次のパターンを試す
+	if _, ok, err := x.Extract(1, "2024-01-12T10:00:00 GET /users 200 slow"); ok || err == nil {
+		t.Errorf("want conversion error, got %v (%v)", ok, err)
+	}
+	if _, ok, err := x.Extract(1, "plain text"); ok || err != nil {
+		t.Errorf("want no match, got %v (%v)", ok, err)
+	}
+	if got, want := x.Fields(), []string{"time", "method", "path", "status", "took", "level", "msg"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestNew_Error(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		lib  logextract.Library
+		want string
+	}{
+		"empty":      {logextract.Library{}, logextract.ErrNoPatterns.Error()},
+		"bad regex":  {logextract.Library{Patterns: []logextract.Pattern{{Name: "a", Regex: "("}}}, "pattern a: error parsing regexp"},
+		"no groups":  {logextract.Library{Patterns: []logextract.Pattern{{Regex: `\d+`}}}, "pattern pattern1: " + logextract.ErrNoGroups.Error()},
+		"bad type":   {logextract.Library{Patterns: []logextract.Pattern{{Name: "a", Regex: `(?P<n>\d+)`, Types: map[string]string{"n": "uint"}}}}, `field n: unknown type "uint"`},
+		"type group": {logextract.Library{Patterns: []logextract.Pattern{{Name: "a", Regex: `(?P<n>\d+)`, Types: map[string]string{"m": "int"}}}}, `no group named "m"`},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := logextract.New(&tt.lib)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("want %q, got %v", tt.want, err)
+			}
+		})
+	}
+	if _, err := logextract.LoadLibrary(strings.NewReader("pattern: []")); err == nil {
+		t.Error("want error for unknown field")
+	}
+}
+
+// TestRun_Order は小さなchunkで並行に処理しても入力と同じ順で出力されることを確かめる
+func TestRun_Order(t *testing.T) {
+	t.Parallel()
+	var in strings.Builder
+	const n = 1000
+	for i := 1; i <= n; i++ {
+		if i%10 == 0 {
+			fmt.Fprintf(&in, "garbage %d\r\n", i)
+			continue
+		}
+		fmt.Fprintf(&in, "INFO: message %d\n", i)
+	}
+
+	var unmatched bytes.Buffer
+	x := newExtractor(t, logextract.WithWorkers(8), logextract.WithChunkLines(3), logextract.WithUnmatched(&unmatched))
+	var lines []int
+	stats, err := x.Run(context.Background(), strings.NewReader(in.String()), func(rec logextract.Record) error {
+		msg, _ := rec.Get("msg")
+		if want := fmt.Sprintf("message %d", rec.Line); msg != want {
+			t.Errorf("want %q, got %q", want, msg)
+		}
+		lines = append(lines, rec.Line)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (logextract.Stats{Lines: n, Matched: n - n/10, Unmatched: n / 10}); stats != want {
+		t.Errorf("want %+v, got %+v", want, stats)
+	}
+	for i := 1; i < len(lines); i++ {
+		if lines[i] <= lines[i-1] {
+			t.Fatalf("out of order at %d: %v", i, lines[i-1:i+1])
+		}
+	}
+	got := strings.Split(strings.TrimSuffix(unmatched.String(), "\n"), "\n")
+	if len(got) != n/10 || got[0] != "garbage 10" || got[len(got)-1] != "garbage 1000" {
+		t.Errorf("unexpected unmatched lines: %q", got)
+	}
+}
+
+func TestRun_Stop(t *testing.T) {
+	t.Parallel()
+	in := strings.Repeat("INFO: x\n", 10000)
+	x := newExtractor(t, logextract.WithWorkers(4), logextract.WithChunkLines(10))
+	errStop := errors.New("stop")
+	count := 0
+	_, err := x.Run(context.Background(), strings.NewReader(in), func(logextract.Record) error {
+		if count++; count == 25 {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) || count != 25 {
+		t.Errorf("want %v after 25 records, got %v after %d", errStop, err, count)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := x.Run(ctx, strings.NewReader(in), func(logextract.Record) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestWriters(t *testing.T) {
+	t.Parallel()
+	x := newExtractor(t)
+	rec1, _, _ := x.Extract(1, "2024-01-12T10:00:00 GET /a?b=<c>&d 404 1m30s")
+	rec2, _, _ := x.Extract(2, `WARN: "quoted", comma`)
+
+	var j bytes.Buffer
+	jw := logextract.NewJSONWriter(&j)
+	for _, rec := range []logextract.Record{rec1, rec2} {
+		if err := jw.Write(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := jw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := `{"_pattern":"req","_line":1,"time":"2024-01-12T10:00:00Z","method":"GET","path":"/a?b=<c>&d","status":404,"took":"1m30s"}
+{"_pattern":"msg","_line":2,"level":"WARN","msg":"\"quoted\", comma"}
+`
+	if j.String() != wantJSON {
+		t.Errorf("want %q, got %q", wantJSON, j.String())
+	}
+
+	var c bytes.Buffer
+	cw := logextract.NewCSVWriter(&c, []string{"status", "msg"})
+	for _, rec := range []logextract.Record{rec1, rec2} {
+		if err := cw.Write(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "_pattern,_line,status,msg\nreq,1,404,\nmsg,2,,\"\"\"quoted\"\", comma\"\n"
+	if c.String() != wantCSV {
+		t.Errorf("want %q, got %q", wantCSV, c.String())
+	}
+
+	// レコードがなくてもヘッダは書く
+	c.Reset()
+	if err := logextract.NewCSVWriter(&c, []string{"a"}).Flush(); err != nil || c.String() != "_pattern,_line,a\n" {
+		t.Errorf("want header only, got %q (%v)", c.String(), err)
+	}
+}
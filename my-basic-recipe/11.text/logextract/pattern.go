@@ -0,0 +1,156 @@
+// Package logextract は名前付きグループの正規表現でログの行を構造化したレコードにする
+//
+// 正規表現の(?P<name>...)の名前がレコードのフィールド名になり、
+// Typesで指定したフィールドは数値や時刻に変換する
+//
+//	lib, _ := logextract.LoadLibrary(f)
+//	x, _ := logextract.New(lib, logextract.WithWorkers(4))
+//	stats, err := x.Run(ctx, logFile, func(rec logextract.Record) error {
+//		return w.Write(rec)
+//	})
+package logextract
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrNoPatterns はパターンが1つもないときのエラー
+	ErrNoPatterns = errors.New("logextract: no patterns")
+	// ErrNoGroups は名前付きグループのないパターンのエラー
+	ErrNoGroups = errors.New("logextract: pattern has no named groups")
+)
+
+// Library はパターンの一覧。上から順に試し、最初にマッチしたパターンを使う
+//
+//	patterns:
+//	  - name: access
+//	    regex: '^(?P<host>\S+) \[(?P<time>[^\]]+)\] "(?P<method>\S+) (?P<path>\S+)" (?P<status>\d+)'
+//	    types:
+//	      status: int
+//	      time: time:02/Jan/2006:15:04:05 -0700
+type Library struct {
+	Patterns []Pattern `yaml:"patterns"`
+}
+
+// Pattern は1つの正規表現とフィールドの型
+type Pattern struct {
+	Name  string `yaml:"name"`
+	Regex string `yaml:"regex"`
+	// Types はグループ名と型。string（省略時）、int、float、bool、duration、
+	// time（RFC3339）、time:レイアウト のどれか
+	Types map[string]string `yaml:"types,omitempty"`
+}
+
+// LoadLibrary はYAMLのパターン一覧を読み込む
+// 知らないフィールドがあるとエラーにする
+func LoadLibrary(r io.Reader) (*Library, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	var lib Library
+	if err := dec.Decode(&lib); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("logextract: load library: %w", err)
+	}
+	return &lib, nil
+}
+
+// compiled はコンパイル済みのパターン
+type compiled struct {
+	name  string
+	re    *regexp.Regexp
+	names []string    // グループの番号ごとの名前（名前のないグループは""）
+	convs []converter // グループの番号ごとの変換（名前のないグループはnil）
+}
+
+// converter はマッチした文字列をフィールドの値にする
+type converter func(s string) (interface{}, error)
+
+func compile(p Pattern) (*compiled, error) {
+	re, err := regexp.Compile(p.Regex)
+	if err != nil {
+		return nil, err
+	}
+	c := &compiled{
+		name:  p.Name,
+		re:    re,
+		names: re.SubexpNames(),
+		convs: make([]converter, re.NumSubexp()+1),
+	}
+	used := 0
+	for i, name := range c.names {
+		if name == "" {
+			continue
+		}
+		conv, err := newConverter(p.Types[name])
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		c.convs[i] = conv
+		used++
+	}
+	if used == 0 {
+		return nil, ErrNoGroups
+	}
+	for name := range p.Types {
+		if re.SubexpIndex(name) < 0 {
+			return nil, fmt.Errorf("types: no group named %q", name)
+		}
+	}
+	return c, nil
+}
+
+// match はlineにマッチしたら各フィールドの値を返す
+// マッチしても変換できないフィールドがあればエラーを返す
+func (c *compiled) match(line string) ([]Field, bool, error) {
+	m := c.re.FindStringSubmatchIndex(line)
+	if m == nil {
+		return nil, false, nil
+	}
+	fields := make([]Field, 0, len(c.names))
+	for i, name := range c.names {
+		if name == "" {
+			continue
+		}
+		f := Field{Name: name}
+		// マッチしなかった任意のグループはnilのまま
+		if m[2*i] >= 0 {
+			v, err := c.convs[i](line[m[2*i]:m[2*i+1]])
+			if err != nil {
+				return nil, true, fmt.Errorf("%s: field %s: %w", c.name, name, err)
+			}
+			f.Value = v
+		}
+		fields = append(fields, f)
+	}
+	return fields, true, nil
+}
+
+func newConverter(typ string) (converter, error) {
+	typ, layout, _ := strings.Cut(typ, ":")
+	switch typ {
+	case "", "string":
+		return func(s string) (interface{}, error) { return s, nil }, nil
+	case "int":
+		return func(s string) (interface{}, error) { return strconv.ParseInt(s, 10, 64) }, nil
+	case "float":
+		return func(s string) (interface{}, error) { return strconv.ParseFloat(s, 64) }, nil
+	case "bool":
+		return func(s string) (interface{}, error) { return strconv.ParseBool(s) }, nil
+	case "duration":
+		return func(s string) (interface{}, error) { return time.ParseDuration(s) }, nil
+	case "time":
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		return func(s string) (interface{}, error) { return time.Parse(layout, s) }, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
@@ -0,0 +1,153 @@
+package logextract
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Writer はレコードを書き出す
+type Writer interface {
+	Write(rec Record) error
+	Flush() error
+}
+
+// メタデータの列名。グループ名と重ならないように_で始める
+const (
+	PatternKey = "_pattern"
+	LineKey    = "_line"
+)
+
+// JSONWriter はレコードを1行に1つのJSONオブジェクトで書き出す（JSON Lines）
+// キーは_pattern、_line、グループ名の順になる
+//
+//	{"_pattern":"access","_line":1,"host":"127.0.0.1","status":200}
+type JSONWriter struct {
+	w   *bufio.Writer
+	buf bytes.Buffer
+}
+
+// NewJSONWriter はwに書き出すJSONWriterを作る
+func NewJSONWriter(w io.Writer) *JSONWriter {
+	return &JSONWriter{w: bufio.NewWriter(w)}
+}
+
+// Write はrecを1行で書き出す
+// time.Durationは"1.5s"のような文字列、time.TimeはRFC3339になる
+func (jw *JSONWriter) Write(rec Record) error {
+	b := &jw.buf
+	b.Reset()
+	b.WriteString(`{"` + PatternKey + `":`)
+	if err := writeJSON(b, rec.Pattern); err != nil {
+		return err
+	}
+	b.WriteString(`,"` + LineKey + `":` + strconv.Itoa(rec.Line))
+	for _, f := range rec.Fields {
+		b.WriteByte(',')
+		if err := writeJSON(b, f.Name); err != nil {
+			return err
+		}
+		b.WriteByte(':')
+		v := f.Value
+		if d, ok := v.(time.Duration); ok {
+			v = d.String()
+		}
+		if err := writeJSON(b, v); err != nil {
+			return err
+		}
+	}
+	b.WriteString("}\n")
+	_, err := jw.w.Write(b.Bytes())
+	return err
+}
+
+// Flush はバッファに残っている出力を書き出す
+func (jw *JSONWriter) Flush() error {
+	return jw.w.Flush()
+}
+
+// writeJSON はvをJSONでbに追加する
+// ログにはURLなどが多いので<>&はエスケープしない
+func writeJSON(b *bytes.Buffer, v interface{}) error {
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	// Encodeが付ける改行を取り除く
+	b.Truncate(b.Len() - 1)
+	return nil
+}
+
+// CSVWriter はレコードをCSVで書き出す
+// 列は_pattern、_line、fieldsの順で、最初のWriteの前にヘッダを書く
+// レコードにないフィールドは空になる
+type CSVWriter struct {
+	w      *csv.Writer
+	fields []string
+	header bool
+	row    []string
+}
+
+// NewCSVWriter はfieldsを列にしてwに書き出すCSVWriterを作る
+// fieldsにはExtractor.Fieldsを使うとすべてのグループが列になる
+func NewCSVWriter(w io.Writer, fields []string) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w), fields: fields}
+}
+
+// Write はrecを1行で書き出す
+func (cw *CSVWriter) Write(rec Record) error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	row := append(cw.row[:0], rec.Pattern, strconv.Itoa(rec.Line))
+	for _, name := range cw.fields {
+		v, _ := rec.Get(name)
+		row = append(row, formatValue(v))
+	}
+	cw.row = row
+	return cw.w.Write(row)
+}
+
+// Flush はバッファに残っている出力を書き出す
+// レコードが1つもなくてもヘッダは書く
+func (cw *CSVWriter) Flush() error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *CSVWriter) writeHeader() error {
+	if cw.header {
+		return nil
+	}
+	cw.header = true
+	return cw.w.Write(append([]string{PatternKey, LineKey}, cw.fields...))
+}
+
+// formatValue はフィールドの値をCSVの文字列にする
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Duration:
+		return v.String()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return ""
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"math"
@@ -11,6 +12,18 @@ import (
 	"strconv"
 	"strings"
 	"unicode"
+
+	"golang.org/x/text/language"
+
+	"golang/recipe-golang/11.text/diff"
+	"golang/recipe-golang/11.text/iox"
+	"golang/recipe-golang/11.text/jadate"
+	"golang/recipe-golang/11.text/janorm"
+	"golang/recipe-golang/11.text/logextract"
+	"golang/recipe-golang/11.text/number"
+	"golang/recipe-golang/11.text/render"
+	"golang/recipe-golang/11.text/splitfunc"
+	"golang/recipe-golang/11.text/textpipe"
 )
 
 func main() {
@@ -39,6 +52,23 @@ func main() {
 	scanner2.Split(bufio.ScanRunes) // コードポイントごと
 	scanner2.Split(bufio.ScanWords) // 1単語ごと
 
+	// ** 独自のSplitFunc */ ・・splitfuncパッケージを使う
+	// 日本語の文ごと: "今日は晴れ。" "「本当？」" "と聞いた。"
+	scanner3 := bufio.NewScanner(strings.NewReader("今日は晴れ。「本当？」と聞いた。"))
+	scanner3.Split(splitfunc.Sentences)
+	for scanner3.Scan() {
+		fmt.Printf("%q\n", scanner3.Text())
+	}
+	// 空行で区切られた段落ごとやJSONの値ごとにも分けられる
+	scanner4 := bufio.NewScanner(strings.NewReader(`{"id":1}{"id":2} [3]`))
+	scanner4.Split(splitfunc.JSONValues)
+	for scanner4.Scan() {
+		fmt.Println(scanner4.Text())
+	}
+	if err := scanner4.Err(); err != nil {
+		fmt.Println(err)
+	}
+
 	// ** strconvパッケージ */・・文字列と他の型の変換を行うパッケージ
 	// 文字列をint型に変換: 100 <nil>
 	fmt.Println(strconv.Atoi("100"))
@@ -66,6 +96,15 @@ func main() {
 	if int16(n) < 0 { // オーバーフロー
 		fmt.Println(n) // 32768 が表示される
 	}
+	// numberパッケージのParseIntは変換先の型の範囲を超えるとエラーにする
+	// number.ParseInt: parsing "32768": value out of range
+	if _, err := number.ParseInt[int16](s); err != nil {
+		fmt.Println(err)
+	}
+	// 桁区切り・全角数字・漢数字も受け付ける: 1234 <nil>
+	fmt.Println(number.ParseInt[int16]("千二百三十四"))
+	// 言語に合わせて桁区切りを入れる: 1,234,567円
+	fmt.Println(number.NewFormatter(language.Japanese).Yen(1234567))
 
 	// ** stringsパッケージ */ ・・文字列関連の処理を行うパッケージ
 	// スペースで分割してスライスにする: [a b c]
@@ -138,6 +177,14 @@ func main() {
 	// hello, world
 	fmt.Println(strings.ToLower("Hello, World"))
 
+	// ** 全角・半角やひらがな・カタカナをそろえる */ ・・janormパッケージを使う
+	// strings.Mapでは半角カナの濁点（ｶﾞ → ガ）のように2文字を1文字にする変換ができない
+	// transform.Transformerとしても使えるので、transform.NewReaderで少しずつ変換できる
+	// データベース ABC-123
+	fmt.Println(janorm.String("ﾃﾞｰﾀﾍﾞｰｽ　ＡＢＣ－１２３"))
+	// ヒラガナトカタカナ
+	fmt.Println(janorm.New(janorm.WithKana(janorm.Katakana)).String("ひらがなとカタカナ"))
+
 	// ** bytesパッケージ */ ・・[]byte型向けの処理を提供する
 	// stringsパッケージにある関数や型に似たものが多い
 	// []byte型からstring型へのキャスト省く
@@ -198,6 +245,19 @@ func main() {
 	// Hellooooo, 世界
 	fmt.Print(buf3.String())
 
+	// ** 読み書きに機能を足す */ ・・ioxパッケージを使う
+	// 数える・進捗を知らせる・速さを制限する・ハッシュを計算するio.Readerやio.Writerを組み合わせる
+	r9, sum := iox.ChecksumReader(strings.NewReader("hello"))
+	r9 = iox.ProgressReader(r9, 5, func(p iox.Progress) {
+		if p.Done {
+			// 5/5 bytes (100%)
+			fmt.Printf("%d/%d bytes (%.0f%%)\n", p.N, p.Total, p.Percent())
+		}
+	})
+	io.Copy(io.Discard, r9)
+	// 2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824 3610a686
+	fmt.Printf("%s %08x\n", sum.SHA256(), sum.CRC32())
+
 	// **12.3. 正規表現*/
 	// ** 正規表現のコンパイル
 	// - regexp.Compile関数を用いる
@@ -287,6 +347,79 @@ func main() {
 		result = re5.ExpandString(result, template, content, submatches)
 	}
 	// "1986/01/12\n2020/03/24\n"
-	fmt.Printf("%q", result)
+	fmt.Printf("%q\n", result)
+
+	// ** 変換をつなげる */ ・・textpipeパッケージを使う
+	// 置換・大文字変換・正規表現の置換を段としてつなげ、1行ずつ流して変換する
+	// ファイル全体をメモリに載せない
+	// コマンドはcmd/textpipe（例: textpipe -replace 郷=Go -upper -head 10 a.txt）
+	p := textpipe.New(
+		textpipe.Replace("郷", "Go"),
+		textpipe.MapRunes(unicode.ToUpper),
+		textpipe.Regexp(re5, "$Y/$M/$D"),
+	)
+	// GOに入ってはGOに従え
+	// 1986/01/12
+	if err := p.Run(os.Stdout, strings.NewReader("郷に入っては郷に従え\n1986年01月12日\n")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	// ** 日本語の日付を解析する */ ・・jadateパッケージを使う
+	// 正規表現では元号・漢数字・全角数字・曜日・午前午後などに対応しきれない
+	// 解析できない場合は位置（Column）と理由を持つ*jadate.ParseErrorが返る
+	t, err := jadate.Parse("令和元年五月一日（水）午後３時半")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	// 2019-05-01 15:30:00 +0000 UTC
+	fmt.Println(t)
+	// 令和元年5月1日（水）
+	fmt.Println(jadate.Format(t, jadate.WarekiWeekday))
+	// jadate: parsing "平成32年1月1日": column 1: 2020-01-01 is after 平成 ended (令和 started on 2019-05-01)
+	_, err = jadate.Parse("平成32年1月1日")
+	fmt.Println(err)
+
+	// ** ログからレコードを取り出す */ ・・logextractパッケージを使う
+	// 名前付きグループの名前がフィールド名になり、typesで数値や時間に変換する
+	// コマンドはcmd/logextract（例: logextract -patterns patterns.yaml -format csv access.log）
+	x, err := logextract.New(&logextract.Library{Patterns: []logextract.Pattern{{
+		Name:  "req",
+		Regex: `(?P<method>[A-Z]+) (?P<path>\S+) (?P<status>\d+) (?P<took>\S+)`,
+		Types: map[string]string{"status": "int", "took": "duration"},
+	}}})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	// {"_pattern":"req","_line":1,"method":"GET","path":"/","status":200,"took":"15ms"}
+	jw := logextract.NewJSONWriter(os.Stdout)
+	if _, err := x.Run(context.Background(), strings.NewReader("GET / 200 15ms\n"), jw.Write); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	jw.Flush()
+
+	// ** テキストの差分 */ ・・diffパッケージを使う
+	// 行ごとの差分をunified diffで出力したり、文字ごとの差分を求めたりできる
+	// コマンドはcmd/textdiff（例: textdiff diff olddir newdir > fix.patch、textdiff patch -p 1 < fix.patch）
+	// --- old.txt
+	// +++ new.txt
+	// @@ -1,2 +1,2 @@
+	//  郷に入っては
+	// -郷に従え
+	// +Goに従え
+	fmt.Print(diff.Unified("old.txt", "new.txt", "郷に入っては\n郷に従え\n", "郷に入っては\nGoに従え\n", 3))
+	// [-郷-]{+Go+}に従え
+	fmt.Println(diff.Inline(diff.Runes("郷に従え", "Goに従え")))
+
+	// ** テンプレートでテキストを作る */ ・・renderパッケージを使う
+	// JSON・YAML・CSV・環境変数のデータをtext/templateに当てはめる。WithStrictでデータにないキーをエラーにする
+	// コマンドはcmd/render（例: render -data config.yaml nginx.conf.tmpl、render -manifest site.yaml -out public）
+	rd, err := render.Load(strings.NewReader("name: gopher\npoints: 1234567\n"), "yaml")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	// GOPHERさん 1,234,567pt 令和元年5月1日
+	if err := render.New(render.WithStrict(true)).Execute(os.Stdout, `{{ .name | upper }}さん {{ comma .points }}pt {{ wareki "2019-05-01" }}`+"\n", rd); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
 
 }
@@ -0,0 +1,96 @@
+package number
+
+import (
+	"strings"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// Formatter は言語の桁区切りで数値を整形する
+//
+//	f := number.NewFormatter(language.Japanese)
+//	f.Decimal(1234567) // 1,234,567
+//	f.Yen(100)         // 100円
+//	f.Percent(0.256)   // 26%
+type Formatter struct {
+	tag language.Tag
+	p   *message.Printer
+}
+
+// NewFormatter はtagの言語で整形するFormatterを作る
+func NewFormatter(tag language.Tag) *Formatter {
+	return &Formatter{tag: tag, p: message.NewPrinter(tag)}
+}
+
+// Decimal は桁区切りを入れて整形する
+// optsで小数点以下の桁数などを指定できる
+func (f *Formatter) Decimal(v interface{}, opts ...number.Option) string {
+	return f.p.Sprint(number.Decimal(v, opts...))
+}
+
+// Percent はvを百分率で整形する（0.256は26%）
+// fractionDigitsは小数点以下の桁数
+func (f *Formatter) Percent(v float64, fractionDigits int) string {
+	return f.p.Sprint(number.Percent(v, number.MaxFractionDigits(fractionDigits), number.MinFractionDigits(fractionDigits)))
+}
+
+// Yen は金額を整形する
+// 日本語では1,234円、それ以外の言語では¥ 1,234のように通貨記号を付ける
+func (f *Formatter) Yen(v int64) string {
+	if base, _ := f.tag.Base(); base.String() == "ja" {
+		return f.Decimal(v) + "円"
+	}
+	return f.p.Sprint(currency.Symbol(currency.JPY.Amount(v)))
+}
+
+var (
+	kanjiDigitNames = []string{"〇", "一", "二", "三", "四", "五", "六", "七", "八", "九"}
+	smallUnitNames  = []string{"千", "百", "十", ""}
+	bigUnitNames    = []string{"京", "兆", "億", "万", ""}
+)
+
+// FormatKanji はvを漢数字（千二百三十四）で表す
+// 一千や一百のようには書かず、十・百・千の前の一は省く
+func FormatKanji[T Signed | Unsigned](v T) string {
+	if v == 0 {
+		return kanjiDigitNames[0]
+	}
+	var b strings.Builder
+	mag := uint64(v)
+	if v < 0 {
+		b.WriteString("マイナス")
+		mag = -uint64(v)
+	}
+	// 4桁ずつ大きい位から書く
+	div := uint64(1e16)
+	for _, big := range bigUnitNames {
+		// uint64の最大値は1844京なので京の位も4桁に収まる
+		section := mag / div % 1e4
+		if section > 0 {
+			writeSection(&b, section)
+			b.WriteString(big)
+		}
+		mag %= div
+		div /= 1e4
+	}
+	return b.String()
+}
+
+// writeSection は1から9999を書く
+func writeSection(b *strings.Builder, n uint64) {
+	div := uint64(1000)
+	for _, unit := range smallUnitNames {
+		d := n / div % 10
+		switch {
+		case d == 0:
+		case d == 1 && unit != "":
+			b.WriteString(unit)
+		default:
+			b.WriteString(kanjiDigitNames[d] + unit)
+		}
+		div /= 10
+	}
+}
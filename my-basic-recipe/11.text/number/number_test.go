@@ -0,0 +1,199 @@
+package number_test
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+	xnumber "golang.org/x/text/number"
+
+	"golang/recipe-golang/11.text/number"
+)
+
+func TestParseInt(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		in   string
+		want int64
+		err  error
+	}{
+		"ascii":          {"100", 100, nil},
+		"sign":           {"-42", -42, nil},
+		"plus":           {"+7", 7, nil},
+		"full width":     {"－１２３", -123, nil},
+		"comma":          {"1,234,567", 1234567, nil},
+		"underscore":     {"1_000", 1000, nil},
+		"underscore any": {"1_0_0", 100, nil},
+		"spaces":         {"　12 ", 12, nil},
+		"kanji":          {"千二百三十四", 1234, nil},
+		"kanji ten":      {"十二", 12, nil},
+		"positional":     {"二〇二四", 2024, nil},
+		"big units":      {"一億二千三百四十五万六千七百八十九", 123456789, nil},
+		"mixed":          {"1億2345万6789", 123456789, nil},
+		"man":            {"3万", 30000, nil},
+		"daiji":          {"壱万弐千", 12000, nil},
+		"max":            {"9223372036854775807", math.MaxInt64, nil},
+		"min":            {"-9223372036854775808", math.MinInt64, nil},
+		"overflow":       {"9223372036854775808", 0, strconv.ErrRange},
+		"huge":           {"99999999999999999999", 0, strconv.ErrRange},
+		"empty":          {"", 0, strconv.ErrSyntax},
+		"sign only":      {"-", 0, strconv.ErrSyntax},
+		"letters":        {"12a", 0, strconv.ErrSyntax},
+		"double comma":   {"1,,000", 0, strconv.ErrSyntax},
+		"leading comma":  {",100", 0, strconv.ErrSyntax},
+		"comma groups":   {"1,2,3", 0, strconv.ErrSyntax},
+		"short group":    {"12,34", 0, strconv.ErrSyntax},
+		"long first":     {"1234,567", 0, strconv.ErrSyntax},
+		"comma man":      {"1,234万", 12340000, nil},
+		"full comma":     {"１，２３４", 1234, nil},
+		"unit order":     {"百千", 0, strconv.ErrSyntax},
+		"big order":      {"一万一億", 0, strconv.ErrSyntax},
+		"two digits":     {"12百", 0, strconv.ErrSyntax},
+		"lone man":       {"万", 0, strconv.ErrSyntax},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := number.ParseInt[int64](tt.in)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("want %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("want %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestParseInt_Width は変換先の型の範囲を超えるとエラーになることを確かめる
+func TestParseInt_Width(t *testing.T) {
+	t.Parallel()
+	// strconv.Atoiでは32768をint16にキャストすると-32768になってしまう
+	s := strconv.FormatInt(math.MaxInt16+1, 10)
+	if _, err := number.ParseInt[int16](s); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("want %v, got %v", strconv.ErrRange, err)
+	}
+	if got, err := number.ParseInt[int16]("-32768"); err != nil || got != math.MinInt16 {
+		t.Errorf("want %d, got %d (%v)", math.MinInt16, got, err)
+	}
+	if _, err := number.ParseInt[int8]("百二十八"); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("want %v, got %v", strconv.ErrRange, err)
+	}
+	type Level int8
+	if got, err := number.ParseInt[Level]("一二七"); err != nil || got != 127 {
+		t.Errorf("want 127, got %d (%v)", got, err)
+	}
+
+	var ne *number.Error
+	_, err := number.ParseInt[int32]("3,000,000,000")
+	if !errors.As(err, &ne) || ne.Func != "ParseInt" {
+		t.Fatalf("want *number.Error, got %v", err)
+	}
+	if want := `number.ParseInt: parsing "3,000,000,000": value out of range`; err.Error() != want {
+		t.Errorf("want %q, got %q", want, err.Error())
+	}
+}
+
+func TestParseUint(t *testing.T) {
+	t.Parallel()
+	if got, err := number.ParseUint[uint8]("２５５"); err != nil || got != 255 {
+		t.Errorf("want 255, got %d (%v)", got, err)
+	}
+	if _, err := number.ParseUint[uint8]("256"); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("want %v, got %v", strconv.ErrRange, err)
+	}
+	if _, err := number.ParseUint[uint]("-1"); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("want %v, got %v", strconv.ErrRange, err)
+	}
+	if got, err := number.ParseUint[uint64]("千八百四十四京六千七百四十四兆七百三十七億九百五十五万千六百十五"); err != nil || got != math.MaxUint64 {
+		t.Errorf("want %d, got %d (%v)", uint64(math.MaxUint64), got, err)
+	}
+	if _, err := number.ParseUint[uint64]("18446744073709551616"); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("want %v, got %v", strconv.ErrRange, err)
+	}
+}
+
+func TestFormatKanji(t *testing.T) {
+	t.Parallel()
+	cases := map[int64]string{
+		0:             "〇",
+		1:             "一",
+		10:            "十",
+		12:            "十二",
+		1234:          "千二百三十四",
+		10000:         "一万",
+		10000000:      "千万",
+		123456789:     "一億二千三百四十五万六千七百八十九",
+		100000001:     "一億一",
+		-305:          "マイナス三百五",
+		math.MaxInt64: "九百二十二京三千三百七十二兆三百六十八億五千四百七十七万五千八百七",
+	}
+	for in, want := range cases {
+		in, want := in, want
+		t.Run(want, func(t *testing.T) {
+			t.Parallel()
+			got := number.FormatKanji(in)
+			if got != want {
+				t.Errorf("want %q, got %q", want, got)
+			}
+			// 漢数字で書いたものを解析すると元に戻る
+			if in >= 0 {
+				if back, err := number.ParseInt[int64](got); err != nil || back != in {
+					t.Errorf("want %d, got %d (%v)", in, back, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatter(t *testing.T) {
+	t.Parallel()
+	ja := number.NewFormatter(language.Japanese)
+	de := number.NewFormatter(language.German)
+	cases := map[string]struct {
+		got, want string
+	}{
+		"decimal":    {ja.Decimal(1234567), "1,234,567"},
+		"fraction":   {ja.Decimal(1234.5, xnumber.MinFractionDigits(2)), "1,234.50"},
+		"german":     {de.Decimal(1234567.5), "1.234.567,5"},
+		"yen":        {ja.Yen(100), "100円"},
+		"yen group":  {ja.Yen(-1234567), "-1,234,567円"},
+		"yen german": {de.Yen(1234), "¥ 1.234"},
+		"percent":    {ja.Percent(0.256, 0), "26%"},
+		"percent .1": {ja.Percent(0.2564, 1), "25.6%"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if tt.got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, tt.got)
+			}
+		})
+	}
+}
+
+func TestScanner(t *testing.T) {
+	t.Parallel()
+	sc := number.NewScanner[int16](strings.NewReader("1 2\n三　1,000\n\n32768 5\n"))
+	var got []int16
+	for sc.Scan() {
+		got = append(got, sc.Value())
+	}
+	if len(got) != 4 || got[3] != 1000 {
+		t.Errorf("want [1 2 3 1000], got %v", got)
+	}
+	if err := sc.Err(); !errors.Is(err, strconv.ErrRange) || !strings.HasPrefix(err.Error(), "line 4: ") {
+		t.Errorf("want range error on line 4, got %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+// Package number は数値の解析と整形を扱う
+//
+// strconv.Atoiの結果をint16などにキャストするとオーバーフローしても気づけない
+// ParseIntは変換先の型の範囲を超えるとstrconv.ErrRangeを返す
+//
+//	n, err := number.ParseInt[int16]("32768") // strconv.ErrRange
+//	n, err := number.ParseInt[int64]("千二百三十四") // 1234
+//
+// 桁区切り（1,234や1_234。,は3桁ごと、_はどこに置いてもよい）、全角数字、漢数字（千二百三十四、二〇二四、1億2345万）を受け付ける
+package number
+
+import (
+	"math/bits"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// Signed は符号付き整数型
+type Signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+// Unsigned は符号なし整数型
+type Unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Error は解析に失敗したときのエラー
+// Errはstrconv.ErrSyntaxかstrconv.ErrRangeなので、errors.Isで判定できる
+type Error struct {
+	Func string // ParseIntかParseUint
+	Num  string // 入力
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return "number." + e.Func + ": parsing " + strconv.Quote(e.Num) + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// ParseInt はsをTの範囲の整数として解析する
+func ParseInt[T Signed](s string) (T, error) {
+	neg, mag, err := parse(s)
+	if err != nil {
+		return 0, &Error{Func: "ParseInt", Num: s, Err: err}
+	}
+	size := uint(unsafe.Sizeof(T(0))) * 8
+	limit := uint64(1) << (size - 1)
+	if !neg && mag >= limit || neg && mag > limit {
+		return 0, &Error{Func: "ParseInt", Num: s, Err: strconv.ErrRange}
+	}
+	if neg {
+		// mag == limitのときも-int64(mag)で最小値になる
+		return T(-int64(mag)), nil
+	}
+	return T(mag), nil
+}
+
+// ParseUint はsをTの範囲の符号なし整数として解析する
+// 負の数はstrconv.ErrRangeになる
+func ParseUint[T Unsigned](s string) (T, error) {
+	neg, mag, err := parse(s)
+	if err != nil {
+		return 0, &Error{Func: "ParseUint", Num: s, Err: err}
+	}
+	size := uint(unsafe.Sizeof(T(0))) * 8
+	if neg && mag != 0 || size < 64 && mag >= uint64(1)<<size {
+		return 0, &Error{Func: "ParseUint", Num: s, Err: strconv.ErrRange}
+	}
+	return T(mag), nil
+}
+
+// 漢数字の値と位
+var (
+	kanjiDigits = map[rune]uint64{
+		'〇': 0, '零': 0, '一': 1, '二': 2, '三': 3, '四': 4,
+		'五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+		// 大字
+		'壱': 1, '弐': 2, '参': 3,
+	}
+	smallUnits = map[rune]uint64{'十': 10, '百': 100, '千': 1000, '拾': 10}
+	bigUnits   = map[rune]uint64{'万': 1e4, '億': 1e8, '兆': 1e12, '京': 1e16}
+)
+
+// IsKanjiNumeral はrが漢数字（数字か位）かを返す
+func IsKanjiNumeral(r rune) bool {
+	_, d := kanjiDigits[r]
+	_, s := smallUnits[r]
+	_, b := bigUnits[r]
+	return d || s || b
+}
+
+// parse はsの符号と絶対値を返す
+func parse(s string) (neg bool, mag uint64, err error) {
+	s = strings.TrimSpace(strings.Trim(s, "　"))
+	switch r, size := utf8.DecodeRuneInString(s); r {
+	case '-', '−', '－':
+		neg, s = true, s[size:]
+	case '+', '＋':
+		s = s[size:]
+	}
+	if s == "" {
+		return false, 0, strconv.ErrSyntax
+	}
+
+	var (
+		total, section uint64
+		cur            uint64 // 位の前の数
+		hasCur         bool
+		lastSmall      = uint64(1e4)
+		lastBig        = uint64(1e17)
+		prevDigit      bool // 直前が数字か（桁区切りは数字の間にだけ置ける）
+		run            int  // 最後の,からの数字の数
+		commas         bool // 続いている数字に,があるか
+	)
+	// groups は,で区切った数字が終わったときに、最初以外が3桁ずつになっているかを返す
+	groups := func() bool {
+		ok := !commas || run == 3
+		run, commas = 0, false
+		return ok
+	}
+	for i, r := range s {
+		d, isDigit := digitValue(r)
+		switch {
+		case isDigit:
+			hi, lo := bits.Mul64(cur, 10)
+			v, carry := bits.Add64(lo, d, 0)
+			if hi != 0 || carry != 0 {
+				return false, 0, strconv.ErrRange
+			}
+			cur, hasCur, prevDigit = v, true, true
+			run++
+			continue
+		case r == ',' || r == '，' || r == '_':
+			next, _ := utf8.DecodeRuneInString(s[i+utf8.RuneLen(r):])
+			if _, ok := digitValue(next); !prevDigit || !ok {
+				return false, 0, strconv.ErrSyntax
+			}
+			if r != '_' {
+				// 1,234,567のように最初は1〜3桁、後は3桁ずつ区切る
+				if commas && run != 3 || run > 3 {
+					return false, 0, strconv.ErrSyntax
+				}
+				run, commas = 0, true
+			}
+			continue
+		case smallUnits[r] != 0:
+			if !groups() {
+				return false, 0, strconv.ErrSyntax
+			}
+			// 十・百・千の前は1桁の数だけ
+			u := smallUnits[r]
+			if u >= lastSmall || hasCur && (cur == 0 || cur > 9) {
+				return false, 0, strconv.ErrSyntax
+			}
+			if !hasCur {
+				cur = 1 // 十は一十と同じ
+			}
+			section += cur * u
+			lastSmall = u
+		case bigUnits[r] != 0:
+			if !groups() {
+				return false, 0, strconv.ErrSyntax
+			}
+			u := bigUnits[r]
+			section += cur
+			if u >= lastBig || section == 0 {
+				return false, 0, strconv.ErrSyntax
+			}
+			hi, lo := bits.Mul64(section, u)
+			v, carry := bits.Add64(total, lo, 0)
+			if hi != 0 || carry != 0 {
+				return false, 0, strconv.ErrRange
+			}
+			total, section, lastBig, lastSmall = v, 0, u, 1e4
+		default:
+			return false, 0, strconv.ErrSyntax
+		}
+		cur, hasCur, prevDigit = 0, false, false
+	}
+	if !groups() {
+		return false, 0, strconv.ErrSyntax
+	}
+	v, c1 := bits.Add64(total, section, 0)
+	v, c2 := bits.Add64(v, cur, 0)
+	if c1 != 0 || c2 != 0 {
+		return false, 0, strconv.ErrRange
+	}
+	return neg, v, nil
+}
+
+// digitValue は半角・全角の数字と漢数字の値を返す
+func digitValue(r rune) (uint64, bool) {
+	switch {
+	case '0' <= r && r <= '9':
+		return uint64(r - '0'), true
+	case '０' <= r && r <= '９':
+		return uint64(r - '０'), true
+	}
+	d, ok := kanjiDigits[r]
+	return d, ok
+}
@@ -0,0 +1,60 @@
+package number
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Scanner はio.Readerから空白区切りの整数を1つずつ読む
+// ファイル全体をメモリに載せない
+//
+//	sc := number.NewScanner[int16](f)
+//	for sc.Scan() {
+//		sum += sc.Value()
+//	}
+//	if err := sc.Err(); err != nil { ... }
+type Scanner[T Signed] struct {
+	sc     *bufio.Scanner
+	line   int
+	fields []string
+	v      T
+	err    error
+}
+
+// NewScanner はrから読むScannerを作る
+func NewScanner[T Signed](r io.Reader) *Scanner[T] {
+	return &Scanner[T]{sc: bufio.NewScanner(r)}
+}
+
+// Scan は次の整数を読む
+// 読めなかったとき、または解析に失敗したときはfalseを返す
+func (s *Scanner[T]) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+	for len(s.fields) == 0 {
+		if !s.sc.Scan() {
+			s.err = s.sc.Err()
+			return false
+		}
+		s.line++
+		// 全角空白でも区切る
+		s.fields = strings.Fields(s.sc.Text())
+	}
+	v, err := ParseInt[T](s.fields[0])
+	s.fields = s.fields[1:]
+	if err != nil {
+		s.err = fmt.Errorf("line %d: %w", s.line, err)
+		return false
+	}
+	s.v = v
+	return true
+}
+
+// Value は最後に読んだ整数を返す
+func (s *Scanner[T]) Value() T { return s.v }
+
+// Err は最初に起きたエラーを返す
+func (s *Scanner[T]) Err() error { return s.err }
@@ -0,0 +1,126 @@
+// Package render はtext/templateのテンプレートにJSON・YAML・CSV・環境変数のデータを当てはめてテキストを作る
+//
+//	data, _ := render.LoadFile("config.yaml")
+//	r := render.New(render.WithStrict(true))
+//	r.ExecuteFile(os.Stdout, "nginx.conf.tmpl", data)
+//
+// テンプレートではFuncsの関数（upper、add、date、default、requiredなど）が使える
+// Manifestを使うと、1つのデータから複数のファイルを出力できる
+package render
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnknownFormat はデータの形式がわからないときのエラー
+var ErrUnknownFormat = errors.New("render: unknown data format")
+
+// Data はテンプレートに渡すデータ
+type Data = map[string]interface{}
+
+// Load はformat（json、yaml、csv）のデータを読み込む
+// JSONとYAMLは任意の値、CSVは1行目を列名にした行ごとのmapのスライスになる
+func Load(r io.Reader, format string) (interface{}, error) {
+	var v interface{}
+	switch format {
+	case "json":
+		dec := json.NewDecoder(r)
+		// 大きな整数がfloat64で丸められないようにする
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return nil, fmt.Errorf("render: load json: %w", err)
+		}
+		return v, nil
+	case "yaml", "yml":
+		if err := yaml.NewDecoder(r).Decode(&v); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("render: load yaml: %w", err)
+		}
+		return v, nil
+	case "csv":
+		rows, err := loadCSV(r)
+		if err != nil {
+			return nil, fmt.Errorf("render: load csv: %w", err)
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+}
+
+func loadCSV(r io.Reader) ([]interface{}, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return []interface{}{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rows := []interface{}{}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(Data, len(header))
+		for i, name := range header {
+			row[name] = rec[i]
+		}
+		rows = append(rows, row)
+	}
+}
+
+// LoadFile は拡張子（.json、.yaml、.yml、.csv）から形式を決めてファイルを読み込む
+func LoadFile(name string) (interface{}, error) {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	v, err := Load(f, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return v, nil
+}
+
+// Env はprefixで始まる環境変数をprefixを取り除いた名前のmapで返す
+func Env(prefix string) Data {
+	env := make(Data)
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		if name, ok := strings.CutPrefix(k, prefix); ok && name != "" {
+			env[name] = v
+		}
+	}
+	return env
+}
+
+// Merge はvをdataに加える
+// keyが空ならvはmapでなければならず、そのキーをdataに上書きする。keyがあればdata[key]にvを入れる
+func Merge(data Data, key string, v interface{}) error {
+	if key != "" {
+		data[key] = v
+		return nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("render: want an object to merge at the top level, got %T", v)
+	}
+	for k, v := range m {
+		data[k] = v
+	}
+	return nil
+}
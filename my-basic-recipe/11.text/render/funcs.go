@@ -0,0 +1,286 @@
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+
+	"golang/recipe-golang/11.text/jadate"
+	"golang/recipe-golang/11.text/number"
+)
+
+// ErrRequired はrequiredに空の値が渡されたときのエラー
+var ErrRequired = errors.New("render: required value is empty")
+
+// Funcs はテンプレートで使える関数を返す
+//
+// 文字列: upper lower title trim trimPrefix trimSuffix replace contains hasPrefix hasSuffix
+// split join repeat quote indent nindent
+// 数値: add sub mul div mod max min seq comma
+// 日時: now date parseTime wareki
+// 値: default coalesce empty ternary required toJSON env
+//
+// パイプラインで使いやすいよう、操作する値を最後の引数にする（{{ .name | default "名無し" }}）
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      title,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"repeat":     func(n int, s string) string { return strings.Repeat(s, n) },
+		"quote":      strconv.Quote,
+		"indent":     indent,
+		"nindent":    func(n int, s string) string { return "\n" + indent(n, s) },
+
+		"add":   arith(func(a, b int64) (int64, error) { return a + b, nil }, func(a, b float64) float64 { return a + b }),
+		"sub":   arith(func(a, b int64) (int64, error) { return a - b, nil }, func(a, b float64) float64 { return a - b }),
+		"mul":   arith(func(a, b int64) (int64, error) { return a * b, nil }, func(a, b float64) float64 { return a * b }),
+		"div":   arith(intDiv, func(a, b float64) float64 { return a / b }),
+		"mod":   arith(intMod, math.Mod),
+		"max":   arith(func(a, b int64) (int64, error) { return max(a, b), nil }, math.Max),
+		"min":   arith(func(a, b int64) (int64, error) { return min(a, b), nil }, math.Min),
+		"seq":   seq,
+		"comma": comma,
+
+		"now":       time.Now,
+		"date":      date,
+		"parseTime": time.Parse,
+		"wareki":    func(v interface{}) (string, error) { return date(jadate.Wareki, v) },
+
+		"default":  func(def, v interface{}) interface{} { return coalesce(v, def) },
+		"coalesce": coalesce,
+		"empty":    empty,
+		"ternary": func(yes, no interface{}, cond bool) interface{} {
+			if cond {
+				return yes
+			}
+			return no
+		},
+		"required": required,
+		"toJSON":   toJSON,
+		"env":      os.Getenv,
+	}
+}
+
+// title は空白で区切られた単語の先頭を大文字にする
+func title(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		r, size := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToUpper(r)) + w[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// join はスライスの要素を文字列にしてsepでつなげる
+func join(sep string, v interface{}) (string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: want a slice, got %T", v)
+	}
+	parts := make([]string, rv.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(rv.Index(i).Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// indent は各行の先頭にn個の空白を入れる
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// arith は整数同士なら整数、どちらかが小数なら小数で計算する関数を作る
+// 数値の文字列（CSVの値など）も数値として扱う
+func arith(ints func(a, b int64) (int64, error), floats func(a, b float64) float64) func(a, b interface{}) (interface{}, error) {
+	return func(a, b interface{}) (interface{}, error) {
+		an, err := toNumber(a)
+		if err != nil {
+			return nil, err
+		}
+		bn, err := toNumber(b)
+		if err != nil {
+			return nil, err
+		}
+		if an.isInt && bn.isInt {
+			return ints(an.i, bn.i)
+		}
+		return floats(an.float(), bn.float()), nil
+	}
+}
+
+func intDiv(a, b int64) (int64, error) {
+	if b == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return a / b, nil
+}
+
+func intMod(a, b int64) (int64, error) {
+	if b == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return a % b, nil
+}
+
+// numeric は整数か小数の値
+// 2^53を超える整数が丸められないように、整数はfloat64を通さずにint64のまま持つ
+type numeric struct {
+	i     int64
+	f     float64
+	isInt bool
+}
+
+func (n numeric) float() float64 {
+	if n.isInt {
+		return float64(n.i)
+	}
+	return n.f
+}
+
+func intNumber(i int64) numeric     { return numeric{i: i, isInt: true} }
+func floatNumber(f float64) numeric { return numeric{f: f} }
+
+// toNumber はvを数値にする。int64に収まらない整数は小数として扱う
+func toNumber(v interface{}) (numeric, error) {
+	switch v := v.(type) {
+	case int:
+		return intNumber(int64(v)), nil
+	case int64:
+		return intNumber(v), nil
+	case float64:
+		return floatNumber(v), nil
+	case json.Number:
+		return toNumber(string(v))
+	case string:
+		s := strings.TrimSpace(v)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return intNumber(i), nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return floatNumber(f), nil
+		}
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intNumber(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := rv.Uint(); u <= math.MaxInt64 {
+			return intNumber(int64(u)), nil
+		}
+		return floatNumber(float64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return floatNumber(rv.Float()), nil
+	}
+	return numeric{}, fmt.Errorf("not a number: %v (%T)", v, v)
+}
+
+// seq は0からn-1までのスライスを返す（{{ range seq 3 }}）
+func seq(n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = i
+	}
+	return s
+}
+
+var jaFormatter = number.NewFormatter(language.Japanese)
+
+// comma は3桁ごとにカンマを入れる
+func comma(v interface{}) (string, error) {
+	n, err := toNumber(v)
+	if err != nil {
+		return "", err
+	}
+	if n.isInt {
+		return jaFormatter.Decimal(n.i), nil
+	}
+	return jaFormatter.Decimal(n.f), nil
+}
+
+// date はtをlayoutで整形する
+// tはtime.Timeか、RFC3339や2006-01-02の文字列
+// layoutにはjadate.Formatの{era}{eraYear}なども使える
+func date(layout string, v interface{}) (string, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return "", err
+	}
+	return jadate.Format(t, layout), nil
+}
+
+func toTime(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		return *v, nil
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02", "2006/01/02"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		return jadate.Parse(v)
+	}
+	return time.Time{}, fmt.Errorf("not a time: %v (%T)", v, v)
+}
+
+// empty はvがnil・ゼロ値・長さ0かを返す
+func empty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	}
+	return rv.IsZero()
+}
+
+// coalesce は最初の空でない値を返す
+func coalesce(vs ...interface{}) interface{} {
+	for _, v := range vs {
+		if !empty(v) {
+			return v
+		}
+	}
+	return nil
+}
+
+// required はvが空ならmsgのエラーにする（{{ required "nameは必須です" .name }}）
+func required(msg string, v interface{}) (interface{}, error) {
+	if empty(v) {
+		return nil, fmt.Errorf("%w: %s", ErrRequired, msg)
+	}
+	return v, nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -0,0 +1,206 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrNoOutputs はマニフェストに出力がないときのエラー
+	ErrNoOutputs = errors.New("render: manifest has no outputs")
+	// ErrOutsideDir は出力先が出力ディレクトリの外になるときのエラー
+	ErrOutsideDir = errors.New("render: output path is outside the output directory")
+)
+
+// Manifest は1つのデータから複数のファイルを出力するための設定
+//
+//	data:
+//	  - file: site.yaml
+//	  - file: users.csv
+//	    key: users
+//	  - env: APP_
+//	    key: env
+//	outputs:
+//	  - template: index.html.tmpl
+//	    path: index.html
+//	  - template: user.html.tmpl
+//	    path: users/{{ .user.id }}.html
+//	    each: users
+//	    as: user
+type Manifest struct {
+	Data    []DataSource `yaml:"data"`
+	Outputs []Output     `yaml:"outputs"`
+}
+
+// DataSource はテンプレートに渡すデータの読み込み元
+// FileかEnvのどちらかを指定する。Keyが空ならトップレベルにマージする
+type DataSource struct {
+	File   string `yaml:"file"`
+	Env    string `yaml:"env"`
+	Key    string `yaml:"key"`
+	Format string `yaml:"format"`
+}
+
+// Output は出力する1つ（Eachがあれば要素の数だけ）のファイル
+type Output struct {
+	// Template はテンプレートのファイル
+	Template string `yaml:"template"`
+	// Path は出力先。テンプレートとして展開する
+	Path string `yaml:"path"`
+	// Each はデータのリストのキー。要素ごとにファイルを出力する
+	Each string `yaml:"each"`
+	// As は要素を入れるキー。省略すると"item"。要素の番号は"index"に入る
+	As string `yaml:"as"`
+}
+
+// LoadManifest はYAMLのマニフェストを読み込む
+func LoadManifest(r io.Reader) (*Manifest, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	var m Manifest
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("render: load manifest: %w", err)
+	}
+	if len(m.Outputs) == 0 {
+		return nil, ErrNoOutputs
+	}
+	for i, o := range m.Outputs {
+		if o.Template == "" || o.Path == "" {
+			return nil, fmt.Errorf("render: outputs[%d]: template and path are required", i)
+		}
+	}
+	for i, d := range m.Data {
+		if (d.File == "") == (d.Env == "") {
+			return nil, fmt.Errorf("render: data[%d]: want either file or env", i)
+		}
+	}
+	return &m, nil
+}
+
+// LoadData はbaseDirを基準にマニフェストのデータを読み込んでまとめる
+func (m *Manifest) LoadData(baseDir string) (Data, error) {
+	data := make(Data)
+	for _, d := range m.Data {
+		var v interface{}
+		if d.Env != "" {
+			v = Env(d.Env)
+		} else {
+			name := filepath.Join(baseDir, d.File)
+			var err error
+			if d.Format == "" {
+				v, err = LoadFile(name)
+			} else {
+				v, err = loadFileAs(name, d.Format)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err := Merge(data, d.Key, v); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func loadFileAs(name, format string) (interface{}, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	v, err := Load(f, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return v, nil
+}
+
+// Render はマニフェストの出力をoutDirに書き込み、書き込んだファイルのパスを返す
+// テンプレートはbaseDirを基準に探す
+func (r *Renderer) Render(m *Manifest, baseDir, outDir string, data Data) ([]string, error) {
+	var written []string
+	for i, o := range m.Outputs {
+		tmpl, err := r.ParseFile(filepath.Join(baseDir, o.Template))
+		if err != nil {
+			return written, err
+		}
+		pathTmpl, err := r.Parse(fmt.Sprintf("outputs[%d].path", i), o.Path)
+		if err != nil {
+			return written, err
+		}
+
+		items := []Data{data}
+		if o.Each != "" {
+			items, err = eachData(data, o)
+			if err != nil {
+				return written, err
+			}
+		}
+		for _, item := range items {
+			var path bytes.Buffer
+			if err := pathTmpl.Execute(&path, item); err != nil {
+				return written, err
+			}
+			dst, err := outputPath(outDir, path.String())
+			if err != nil {
+				return written, err
+			}
+			var out bytes.Buffer
+			if err := tmpl.Execute(&out, item); err != nil {
+				return written, err
+			}
+			if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+				return written, err
+			}
+			if err := os.WriteFile(dst, out.Bytes(), 0o644); err != nil {
+				return written, err
+			}
+			written = append(written, dst)
+		}
+	}
+	return written, nil
+}
+
+// eachData はo.Eachのリストの要素ごとに、dataにo.Asと"index"を加えたデータを作る
+func eachData(data Data, o Output) ([]Data, error) {
+	list, ok := data[o.Each].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("render: each %q: want a list, got %T", o.Each, data[o.Each])
+	}
+	as := o.As
+	if as == "" {
+		as = "item"
+	}
+	items := make([]Data, len(list))
+	for i, v := range list {
+		item := make(Data, len(data)+2)
+		for k, v := range data {
+			item[k] = v
+		}
+		item[as] = v
+		item["index"] = i
+		items[i] = item
+	}
+	return items, nil
+}
+
+// outputPath はoutDirの外に出ないことを確かめて出力先のパスを返す
+func outputPath(outDir, path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" || filepath.IsAbs(path) {
+		return "", fmt.Errorf("%w: %q", ErrOutsideDir, path)
+	}
+	clean := filepath.Clean(filepath.FromSlash(path))
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q", ErrOutsideDir, path)
+	}
+	return filepath.Join(outDir, clean), nil
+}
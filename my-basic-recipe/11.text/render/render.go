@@ -0,0 +1,98 @@
+package render
+
+import (
+	"io"
+	"path/filepath"
+	"text/template"
+)
+
+// Renderer はテンプレートを実行する
+type Renderer struct {
+	strict   bool
+	funcs    template.FuncMap
+	partials []string
+}
+
+// Option はRendererの設定
+type Option func(*Renderer)
+
+// WithStrict はtrueのとき、データにないキーを参照するとエラーにする
+func WithStrict(strict bool) Option {
+	return func(r *Renderer) {
+		r.strict = strict
+	}
+}
+
+// WithFuncs はテンプレートで使える関数を追加する。同じ名前はFuncsの関数を上書きする
+func WithFuncs(funcs template.FuncMap) Option {
+	return func(r *Renderer) {
+		for name, fn := range funcs {
+			r.funcs[name] = fn
+		}
+	}
+}
+
+// WithPartials は{{ template "name" }}で呼び出せるテンプレートのファイルを追加する
+func WithPartials(files ...string) Option {
+	return func(r *Renderer) {
+		r.partials = append(r.partials, files...)
+	}
+}
+
+// New はRendererを作る
+func New(opts ...Option) *Renderer {
+	r := &Renderer{funcs: Funcs()}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Parse はテキストをnameという名前のテンプレートとして解析する
+func (r *Renderer) Parse(name, text string) (*template.Template, error) {
+	t, err := r.newTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	return t.Parse(text)
+}
+
+// ParseFile はファイルをテンプレートとして解析する。テンプレートの名前はファイル名になる
+func (r *Renderer) ParseFile(name string) (*template.Template, error) {
+	t, err := r.newTemplate(filepath.Base(name))
+	if err != nil {
+		return nil, err
+	}
+	return t.ParseFiles(name)
+}
+
+func (r *Renderer) newTemplate(name string) (*template.Template, error) {
+	t := template.New(name).Funcs(r.funcs)
+	if r.strict {
+		t.Option("missingkey=error")
+	}
+	if len(r.partials) > 0 {
+		if _, err := t.ParseFiles(r.partials...); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Execute はテキストのテンプレートにdataを当てはめてwに書き込む
+func (r *Renderer) Execute(w io.Writer, text string, data interface{}) error {
+	t, err := r.Parse("render", text)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, data)
+}
+
+// ExecuteFile はファイルのテンプレートにdataを当てはめてwに書き込む
+func (r *Renderer) ExecuteFile(w io.Writer, name string, data interface{}) error {
+	t, err := r.ParseFile(name)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, data)
+}
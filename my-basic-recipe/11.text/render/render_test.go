@@ -0,0 +1,291 @@
+package render_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"golang/recipe-golang/11.text/render"
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		in     string
+		format string
+		want   string
+	}{
+		"json": {`{"name":"太郎","age":20,"id":12345678901234567890}`, "json", "太郎 20 12345678901234567890"},
+		"yaml": {"name: 太郎\nage: 20\nid: 1\n", "yaml", "太郎 20 1"},
+		"csv":  {"name,age,id\n太郎,20,1\n花子,30,2\n", "csv", "太郎 20 1"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			v, err := render.Load(strings.NewReader(tt.in), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			text := `{{ .name }} {{ .age }} {{ .id }}`
+			if tt.format == "csv" {
+				text = `{{ with index . 0 }}{{ .name }} {{ .age }} {{ .id }}{{ end }}`
+			}
+			var buf bytes.Buffer
+			if err := render.New().Execute(&buf, text, v); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("want %q, got %q", tt.want, buf.String())
+			}
+		})
+	}
+
+	if _, err := render.Load(strings.NewReader(""), "toml"); !errors.Is(err, render.ErrUnknownFormat) {
+		t.Errorf("want %v, got %v", render.ErrUnknownFormat, err)
+	}
+}
+
+func TestEnvMerge(t *testing.T) {
+	t.Setenv("RENDER_TEST_NAME", "太郎")
+	data := render.Data{"name": "上書きされる"}
+	if err := render.Merge(data, "", map[string]interface{}(render.Env("RENDER_TEST_"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := render.Merge(data, "list", []interface{}{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	want := render.Data{"NAME": "太郎", "name": "上書きされる", "list": []interface{}{1, 2}}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("want %v, got %v", want, data)
+	}
+	if err := render.Merge(data, "", []interface{}{}); err == nil {
+		t.Error("want error merging a list at the top level")
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	t.Parallel()
+	data := render.Data{
+		"name":  "go gopher",
+		"empty": "",
+		"n":     "1234567",
+		"price": 1.5,
+		"day":   "2024-01-02",
+		"tags":  []interface{}{"a", "b"},
+		"body":  "a\nb",
+		"big":   json.Number("9007199254740993"),
+	}
+	cases := map[string]struct {
+		text string
+		want string
+	}{
+		"strings":  {`{{ .name | upper }}|{{ title .name }}|{{ .name | replace "go" "Go" }}|{{ .name | hasPrefix "go" }}`, "GO GOPHER|Go Gopher|Go Gopher|true"},
+		"split":    {`{{ .name | split " " | join "-" }}|{{ .tags | join "," }}|{{ .body | indent 2 }}`, "go-gopher|a,b|  a\n  b"},
+		"math":     {`{{ add 1 2 }} {{ sub .n 7 }} {{ mul .price 2 }} {{ div 7 2 }} {{ mod 7 2 }} {{ max 3 .price }}`, "3 1234560 3 3 1 3"},
+		"title ja": {`{{ title "日本 ábc" }}`, "日本 Ábc"},
+		"big int":  {`{{ add 1 .big }} {{ sub .big "9007199254740992" }} {{ comma .big }}`, "9007199254740994 1 9,007,199,254,740,993"},
+		"seq":      {`{{ range seq 3 }}{{ . }}{{ end }}`, "012"},
+		"comma":    {`{{ comma .n }}`, "1,234,567"},
+		"date":     {`{{ date "2006/01/02" .day }} {{ wareki .day }}`, "2024/01/02 令和6年1月2日"},
+		"default":  {`{{ .empty | default "なし" }} {{ .name | default "なし" }} {{ coalesce .empty .missing "x" }}`, "なし go gopher x"},
+		"ternary":  {`{{ ternary "yes" "no" (empty .empty) }}`, "yes"},
+		"required": {`{{ required "name" .name }}`, "go gopher"},
+		"toJSON":   {`{{ toJSON .tags }}`, `["a","b"]`},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			if err := render.New().Execute(&buf, tt.text, data); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("want %q, got %q", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestFuncs_Error(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		text string
+		want error
+	}{
+		"required":  {`{{ required "nameは必須です" .name }}`, render.ErrRequired},
+		"not a num": {`{{ add "a" 1 }}`, nil},
+		"div zero":  {`{{ div 1 0 }}`, nil},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := render.New().Execute(new(bytes.Buffer), tt.text, render.Data{})
+			if err == nil {
+				t.Fatal("want error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("want %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestStrict(t *testing.T) {
+	t.Parallel()
+	text := `Hello {{ .name }}`
+	var buf bytes.Buffer
+	if err := render.New().Execute(&buf, text, render.Data{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "Hello <no value>"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	err := render.New(render.WithStrict(true)).Execute(new(bytes.Buffer), text, render.Data{})
+	if err == nil || !strings.Contains(err.Error(), `map has no entry for key "name"`) {
+		t.Errorf("want missing key error, got %v", err)
+	}
+}
+
+func TestWithFuncsPartials(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	partial := filepath.Join(dir, "_header.tmpl")
+	if err := os.WriteFile(partial, []byte(`{{ define "header" }}# {{ . | shout }}{{ end }}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := render.New(
+		render.WithFuncs(template.FuncMap{"shout": func(s string) string { return strings.ToUpper(s) + "!" }}),
+		render.WithPartials(partial),
+	)
+	var buf bytes.Buffer
+	if err := r.Execute(&buf, `{{ template "header" .title }}`, render.Data{"title": "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "# HI!"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	t.Parallel()
+	base := t.TempDir()
+	files := map[string]string{
+		"site.yaml":  "title: 名簿\n",
+		"users.csv":  "id,name\n1,太郎\n2,花子\n",
+		"index.tmpl": "{{ .title }}: {{ len .users }}人\n",
+		"user.tmpl":  "{{ .index }} {{ .user.name }}\n",
+		"manifest.yaml": `data:
+  - file: site.yaml
+  - file: users.csv
+    key: users
+outputs:
+  - template: index.tmpl
+    path: index.txt
+  - template: user.tmpl
+    path: "users/{{ .user.id }}.txt"
+    each: users
+    as: user
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(base, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.Open(filepath.Join(base, "manifest.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	m, err := render.LoadManifest(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := m.LoadData(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	written, err := render.New(render.WithStrict(true)).Render(m, base, out, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"index.txt":   "名簿: 2人\n",
+		"users/1.txt": "0 太郎\n",
+		"users/2.txt": "1 花子\n",
+	}
+	if len(written) != len(want) {
+		t.Errorf("want %d files, got %v", len(want), written)
+	}
+	for name, content := range want {
+		b, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Errorf("%s: want %q, got %q", name, content, b)
+		}
+	}
+}
+
+func TestManifest_Error(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		manifest string
+		want     error
+	}{
+		"no outputs":    {"data: []\n", render.ErrNoOutputs},
+		"unknown field": {"outputs:\n  - template: a\n    path: b\n    mode: 644\n", nil},
+		"no path":       {"outputs:\n  - template: a\n", nil},
+		"file and env":  {"data:\n  - file: a.yaml\n    env: APP_\noutputs:\n  - template: a\n    path: b\n", nil},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			_, err := render.LoadManifest(strings.NewReader(tt.manifest))
+			if err == nil {
+				t.Fatal("want error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("want %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// 出力先がディレクトリの外になるときは書き込まない
+	base := t.TempDir()
+	if err := os.WriteFile(filepath.Join(base, "a.tmpl"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"../evil.txt", "/etc/evil.txt", "{{ .dir }}/evil.txt"} {
+		m := &render.Manifest{Outputs: []render.Output{{Template: "a.tmpl", Path: path}}}
+		_, err := render.New().Render(m, base, t.TempDir(), render.Data{"dir": ".."})
+		if !errors.Is(err, render.ErrOutsideDir) {
+			t.Errorf("%s: want %v, got %v", path, render.ErrOutsideDir, err)
+		}
+	}
+}
+
+func TestDate_Time(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	d := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	if err := render.New().Execute(&buf, `{{ wareki .d }}`, render.Data{"d": d}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "令和元年5月1日"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
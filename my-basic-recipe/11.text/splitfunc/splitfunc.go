@@ -0,0 +1,188 @@
+// Package splitfunc はbufio.Scannerで使うbufio.SplitFuncを提供する
+//
+// bufio.ScanLinesやbufio.ScanWordsでは分けられない単位でトークンにする
+//
+//	sc := bufio.NewScanner(f)
+//	sc.Split(splitfunc.Paragraphs)
+//	for sc.Scan() {
+//		fmt.Println(sc.Text()) // 空行で区切られた段落
+//	}
+//
+// どのSplitFuncも、データがどこで区切られて渡されても同じトークンを返す
+package splitfunc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+)
+
+var (
+	// ErrTooLarge はLengthPrefixedのフレームが最大の長さを超えたときのエラー
+	ErrTooLarge = errors.New("splitfunc: frame too large")
+	// ErrTruncated はLengthPrefixedのフレームの途中で入力が終わったときのエラー
+	ErrTruncated = errors.New("splitfunc: truncated frame")
+	// ErrInvalidJSON はJSONValuesで正しくないJSONを読んだときのエラー
+	ErrInvalidJSON = errors.New("splitfunc: invalid JSON value")
+	// ErrOpenQuote はCSVRecordsで引用符が閉じられずに入力が終わったときのエラー
+	ErrOpenQuote = errors.New("splitfunc: unterminated quoted field")
+)
+
+// Delimiter はdelimで区切るSplitFuncを返す
+// delimは複数バイトでもよく、トークンにdelimは含まない
+//
+//	sc.Split(splitfunc.Delimiter([]byte("\n---\n")))
+func Delimiter(delim []byte) bufio.SplitFunc {
+	if len(delim) == 0 {
+		panic("splitfunc: empty delimiter")
+	}
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		if i := bytes.Index(data, delim); i >= 0 {
+			return i + len(delim), data[:i], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		// delimの途中までしか読めていないかもしれないので続きを待つ
+		return 0, nil, nil
+	}
+}
+
+// RawLines は改行を含めたまま1行ずつに分けるSplitFunc
+// \n、\r\n、\rのどれでも行が終わる。\r\nは2回に分けて読んでも1つの改行として扱う
+func RawLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		switch {
+		case data[i] == '\n':
+			return i + 1, data[:i+1], nil
+		case i+1 < len(data) && data[i+1] == '\n':
+			return i + 2, data[:i+2], nil
+		case i+1 < len(data) || atEOF:
+			return i + 1, data[:i+1], nil
+		}
+		// \rの次が\nかどうかわからない
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// Paragraphs は空行で区切られた段落ごとに分けるSplitFunc
+// 空白だけの行も空行とみなす。トークンの最後の改行と前後の空行は含まない
+func Paragraphs(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	// 先頭の空行を読み飛ばす
+	start := 0
+	for {
+		i := bytes.IndexByte(data[start:], '\n')
+		if i < 0 {
+			if atEOF && isBlank(data[start:]) {
+				return len(data), nil, nil
+			}
+			break
+		}
+		if !isBlank(data[start : start+i]) {
+			break
+		}
+		start += i + 1
+	}
+
+	pos := start
+	for pos < len(data) {
+		i := bytes.IndexByte(data[pos:], '\n')
+		if i < 0 {
+			if !atEOF {
+				return start, nil, nil
+			}
+			if isBlank(data[pos:]) {
+				return len(data), trimEOL(data[start:pos]), nil
+			}
+			return len(data), trimEOL(data[start:]), nil
+		}
+		if isBlank(data[pos : pos+i]) {
+			return pos + i + 1, trimEOL(data[start:pos]), nil
+		}
+		pos += i + 1
+	}
+	if atEOF && pos > start {
+		return len(data), trimEOL(data[start:pos]), nil
+	}
+	return start, nil, nil
+}
+
+// isBlank はlineが空白だけかを返す
+func isBlank(line []byte) bool {
+	return len(bytes.Trim(line, " \t\r")) == 0
+}
+
+func trimEOL(b []byte) []byte {
+	b = bytes.TrimSuffix(b, []byte("\n"))
+	return bytes.TrimSuffix(b, []byte("\r"))
+}
+
+// CSVRecords はCSVの1レコードごとに分けるSplitFunc
+// 引用符で囲まれたフィールドの中の改行ではレコードを分けない
+// トークンは改行（\nか\r\n）を含まないレコードの文字列なので、encoding/csvで解析できる
+func CSVRecords(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	inQuote := false
+	for i, c := range data {
+		switch {
+		case c == '"':
+			// ""は2回切り替わるのでエスケープも正しく扱える
+			inQuote = !inQuote
+		case c == '\n' && !inQuote:
+			return i + 1, trimEOL(data[:i+1]), nil
+		}
+	}
+	if !atEOF {
+		return 0, nil, nil
+	}
+	if inQuote {
+		return 0, nil, ErrOpenQuote
+	}
+	return len(data), trimEOL(data), nil
+}
+
+// LengthPrefixed はheaderバイトのビッグエンディアンの長さが先頭に付いたフレームごとに分けるSplitFuncを返す
+// トークンは長さを除いた中身。headerは1、2、4、8のどれか
+// maxより長いフレームはErrTooLargeになる。maxはScannerのバッファより小さくする
+func LengthPrefixed(header, max int) bufio.SplitFunc {
+	switch header {
+	case 1, 2, 4, 8:
+	default:
+		panic("splitfunc: header must be 1, 2, 4 or 8 bytes")
+	}
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if len(data) < header {
+			if atEOF && len(data) > 0 {
+				return 0, nil, ErrTruncated
+			}
+			return 0, nil, nil
+		}
+		var n uint64
+		for _, b := range data[:header] {
+			n = n<<8 | uint64(b)
+		}
+		if n > uint64(max) {
+			return 0, nil, ErrTooLarge
+		}
+		end := header + int(n)
+		if len(data) < end {
+			if atEOF {
+				return 0, nil, ErrTruncated
+			}
+			return 0, nil, nil
+		}
+		return end, data[header:end], nil
+	}
+}
@@ -0,0 +1,222 @@
+package splitfunc_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang/recipe-golang/11.text/splitfunc"
+)
+
+// splits はテストするSplitFunc
+var splits = map[string]bufio.SplitFunc{
+	"delimiter":  splitfunc.Delimiter([]byte("<>")),
+	"raw lines":  splitfunc.RawLines,
+	"paragraphs": splitfunc.Paragraphs,
+	"csv":        splitfunc.CSVRecords,
+	"frames":     splitfunc.LengthPrefixed(2, 1<<10),
+	"json":       splitfunc.JSONValues,
+	"sentences":  splitfunc.Sentences,
+}
+
+// scan はrからsplitで読んだトークンとエラーを返す
+func scan(split bufio.SplitFunc, r io.Reader) ([]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Split(split)
+	// 最初のバッファを小さくして、バッファを広げる処理も通るようにする
+	sc.Buffer(make([]byte, 2), 1<<16)
+	var tokens []string
+	for sc.Scan() {
+		tokens = append(tokens, sc.Text())
+	}
+	return tokens, sc.Err()
+}
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		split string
+		in    string
+		want  []string
+		err   error
+	}{
+		"delimiter":       {"delimiter", "a<>b<><>c", []string{"a", "b", "", "c"}, nil},
+		"delimiter end":   {"delimiter", "a<>", []string{"a"}, nil},
+		"raw lines":       {"raw lines", "a\nb\r\nc\rd", []string{"a\n", "b\r\n", "c\r", "d"}, nil},
+		"raw lines cr":    {"raw lines", "a\r", []string{"a\r"}, nil},
+		"paragraphs":      {"paragraphs", "\n\na\nb\n\n \t\nc\r\n\r\nd", []string{"a\nb", "c", "d"}, nil},
+		"paragraph end":   {"paragraphs", "a\n\n\n", []string{"a"}, nil},
+		"blank only":      {"paragraphs", "\n \n", nil, nil},
+		"csv":             {"csv", "a,b\r\n\"x\ny\",\"\"\"q\"\"\"\nlast", []string{"a,b", "\"x\ny\",\"\"\"q\"\"\"", "last"}, nil},
+		"csv open quote":  {"csv", "a,\"b\nc", nil, splitfunc.ErrOpenQuote},
+		"frames":          {"frames", "\x00\x02hi\x00\x00\x00\x03abc", []string{"hi", "", "abc"}, nil},
+		"frame truncated": {"frames", "\x00\x05hi", nil, splitfunc.ErrTruncated},
+		"frame header":    {"frames", "\x00\x01a\x00", []string{"a"}, splitfunc.ErrTruncated},
+		"frame too large": {"frames", "\xff\xff", nil, splitfunc.ErrTooLarge},
+		"json":            {"json", `{"a":"}"}[1,{"b":[2]}] "s\"" 12 true null{}`, []string{`{"a":"}"}`, `[1,{"b":[2]}]`, `"s\""`, "12", "true", "null", "{}"}, nil},
+		"json invalid":    {"json", `[1] [2}`, []string{"[1]"}, splitfunc.ErrInvalidJSON},
+		"json scalar":     {"json", `tru`, nil, splitfunc.ErrInvalidJSON},
+		"json unexpected": {"json", `{"a":`, nil, io.ErrUnexpectedEOF},
+		"json delim":      {"json", `1,2`, []string{"1"}, splitfunc.ErrInvalidJSON},
+		"sentences":       {"sentences", "今日は晴れ。\n「本当？」と聞いた！！ 明日は雨", []string{"今日は晴れ。", "「本当？」", "と聞いた！！", "明日は雨"}, nil},
+		"sentences space": {"sentences", "　終わり。　\n", []string{"終わり。"}, nil},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := scan(splits[tt.split], strings.NewReader(tt.in))
+			if !errors.Is(err, tt.err) {
+				t.Errorf("want error %v, got %v", tt.err, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCSVRecords_Parse(t *testing.T) {
+	t.Parallel()
+	// トークンはencoding/csvでそのまま解析できる
+	tokens, err := scan(splitfunc.CSVRecords, strings.NewReader("name,memo\n\"Gopher\",\"1行目\n2行目\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := csv.NewReader(strings.NewReader(tokens[1])).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Gopher", "1行目\n2行目"}; !reflect.DeepEqual(rec, want) {
+		t.Errorf("want %q, got %q", want, rec)
+	}
+}
+
+// cutReader はdataをcutの位置で2回に分けて返す
+type cutReader struct {
+	data []byte
+	cut  int
+}
+
+func (r *cutReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := len(r.data)
+	if r.cut > 0 && r.cut < n {
+		n = r.cut
+	}
+	n = copy(p, r.data[:n])
+	r.data = r.data[n:]
+	r.cut -= n
+	return n, nil
+}
+
+// chunkReader はdataをsizeバイトずつ返す
+type chunkReader struct {
+	data []byte
+	size int
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := r.size
+	if n > len(p) {
+		n = len(p)
+	}
+	n = copy(p, r.data[:min(n, len(r.data))])
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// checkBoundaries はdataをすべての位置で分けて読んでも、まとめて読んだときと同じ結果になるかを確かめる
+func checkBoundaries(t *testing.T, split bufio.SplitFunc, data []byte) {
+	t.Helper()
+	want, wantErr := scan(split, bytes.NewReader(data))
+	for cut := 1; cut < len(data); cut++ {
+		got, err := scan(split, &cutReader{data: data, cut: cut})
+		if !reflect.DeepEqual(got, want) || !sameError(err, wantErr) {
+			t.Fatalf("cut at %d of %q: want %q (%v), got %q (%v)", cut, data, want, wantErr, got, err)
+		}
+	}
+	for size := 1; size <= 3; size++ {
+		got, err := scan(split, &chunkReader{data: data, size: size})
+		if !reflect.DeepEqual(got, want) || !sameError(err, wantErr) {
+			t.Fatalf("%d-byte reads of %q: want %q (%v), got %q (%v)", size, data, want, wantErr, got, err)
+		}
+	}
+}
+
+func sameError(a, b error) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Error() == b.Error()
+}
+
+func TestBoundaries(t *testing.T) {
+	t.Parallel()
+	inputs := map[string]string{
+		"delimiter":  "a<>b<<>>c<><>",
+		"raw lines":  "a\r\nb\rc\n\r\n\r",
+		"paragraphs": "\r\n a\r\nb\r\n \r\n\r\nc\n",
+		"csv":        "a,\"b\r\nc\"\r\n\"\"\"\",d\ne",
+		"frames":     "\x00\x03abc\x00\x00\x00\x01z",
+		"json":       `{"a":["}",{"b":"\\\""}]} 123 "x"true[]`,
+		"sentences":  "「はい。」と言った！？ そうか。。終わり",
+	}
+	for name, in := range inputs {
+		name, in := name, in
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			checkBoundaries(t, splits[name], []byte(in))
+		})
+	}
+}
+
+func fuzzSplit(f *testing.F, split bufio.SplitFunc, seeds ...string) {
+	for _, s := range seeds {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) > 256 {
+			t.Skip()
+		}
+		checkBoundaries(t, split, data)
+	})
+}
+
+func FuzzDelimiter(f *testing.F) {
+	fuzzSplit(f, splits["delimiter"], "a<>b", "<<>>", "<")
+}
+
+func FuzzRawLines(f *testing.F) {
+	fuzzSplit(f, splitfunc.RawLines, "a\r\nb", "\r\r\n", "\n")
+}
+
+func FuzzParagraphs(f *testing.F) {
+	fuzzSplit(f, splitfunc.Paragraphs, "a\n\nb", "\r\n \r\n", "a\n \nb\n")
+}
+
+func FuzzCSVRecords(f *testing.F) {
+	fuzzSplit(f, splitfunc.CSVRecords, "a,\"b\nc\"\nd", `"""`)
+}
+
+func FuzzLengthPrefixed(f *testing.F) {
+	fuzzSplit(f, splits["frames"], "\x00\x01a\x00\x00", "\xff\xff", "\x00")
+}
+
+func FuzzJSONValues(f *testing.F) {
+	fuzzSplit(f, splitfunc.JSONValues, `{"a":1}[2]`, `"\"" 1 true`, `[{"}":"]"}]`)
+}
+
+func FuzzSentences(f *testing.F) {
+	fuzzSplit(f, splitfunc.Sentences, "一。二！」三？", "　あ。\n")
+}
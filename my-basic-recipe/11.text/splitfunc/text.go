@@ -0,0 +1,170 @@
+package splitfunc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode"
+	"unicode/utf8"
+)
+
+// JSONValues は連結されたJSONの値を1つずつに分けるSplitFunc
+// {"a":1}{"b":2} [3] "x" 4 のように区切りがない、または空白で区切られた値を読める
+// 正しくない値はErrInvalidJSON、値の途中で入力が終わるとio.ErrUnexpectedEOFになる
+func JSONValues(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	start := 0
+	for start < len(data) && isJSONSpace(data[start]) {
+		start++
+	}
+	if start == len(data) {
+		return start, nil, nil
+	}
+
+	end := -1
+	switch data[start] {
+	case '{', '[':
+		end = endOfCompound(data, start)
+	case '"':
+		end = endOfString(data, start+1)
+	default:
+		// 数値・true・false・nullは区切りの文字か入力の終わりまで
+		for i := start; i < len(data); i++ {
+			if isJSONSpace(data[i]) || isJSONDelim(data[i]) {
+				end = i
+				break
+			}
+		}
+		if end < 0 && atEOF {
+			end = len(data)
+		}
+	}
+	if end < 0 {
+		if atEOF {
+			return 0, nil, fmt.Errorf("%w: %w", ErrInvalidJSON, io.ErrUnexpectedEOF)
+		}
+		return start, nil, nil
+	}
+	token = data[start:end]
+	if !json.Valid(token) {
+		return 0, nil, fmt.Errorf("%w at %q", ErrInvalidJSON, truncate(token, 20))
+	}
+	return end, token, nil
+}
+
+// endOfCompound はstartから始まるオブジェクトか配列の終わりの次の位置を返す
+// 終わりが見つからなければ-1を返す
+func endOfCompound(data []byte, start int) int {
+	depth := 0
+	for i := start; i < len(data); i++ {
+		switch data[i] {
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		case '"':
+			end := endOfString(data, i+1)
+			if end < 0 {
+				return -1
+			}
+			i = end - 1
+		}
+	}
+	return -1
+}
+
+// endOfString は文字列の中のiから閉じる"の次の位置を返す
+func endOfString(data []byte, i int) int {
+	for ; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+func isJSONSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' }
+
+func isJSONDelim(c byte) bool {
+	switch c {
+	case '{', '}', '[', ']', ',', ':', '"':
+		return true
+	}
+	return false
+}
+
+func truncate(b []byte, n int) string {
+	if len(b) > n {
+		return string(b[:n]) + "..."
+	}
+	return string(b)
+}
+
+// Sentences は日本語の文ごとに分けるSplitFunc
+// 。！？で文が終わり、続く終止符や」』）などの閉じ括弧も同じ文に含める
+// 文の前後の空白と改行はトークンに含まない
+func Sentences(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	start := 0
+	for start < len(data) {
+		if !atEOF && !utf8.FullRune(data[start:]) {
+			return start, nil, nil
+		}
+		r, size := utf8.DecodeRune(data[start:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += size
+	}
+
+	for i := start; i < len(data); {
+		if !atEOF && !utf8.FullRune(data[i:]) {
+			return start, nil, nil
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		i += size
+		if !isTerminator(r) {
+			continue
+		}
+		for i < len(data) {
+			if !atEOF && !utf8.FullRune(data[i:]) {
+				return start, nil, nil
+			}
+			r, size := utf8.DecodeRune(data[i:])
+			if !isTerminator(r) && !isCloser(r) {
+				return i, data[start:i], nil
+			}
+			i += size
+		}
+		if !atEOF {
+			// 閉じ括弧が続くかもしれない
+			return start, nil, nil
+		}
+		return i, data[start:i], nil
+	}
+	if atEOF && start < len(data) {
+		end := len(data)
+		for end > start {
+			r, size := utf8.DecodeLastRune(data[start:end])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			end -= size
+		}
+		return len(data), data[start:end], nil
+	}
+	return start, nil, nil
+}
+
+func isTerminator(r rune) bool { return r == '。' || r == '！' || r == '？' }
+
+func isCloser(r rune) bool {
+	switch r {
+	case '」', '』', '）', ')', '】', '〕', '”', '’':
+		return true
+	}
+	return false
+}
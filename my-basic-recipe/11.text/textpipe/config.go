@@ -0,0 +1,137 @@
+package textpipe
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+	"gopkg.in/yaml.v3"
+
+	"golang/recipe-golang/11.text/janorm"
+)
+
+// Config はYAMLで定義したパイプライン
+//
+//	stages:
+//	  - type: replace
+//	    pairs: [郷, Go]
+//	  - type: regex
+//	    pattern: '(?P<Y>\d+)年(?P<M>\d+)月(?P<D>\d+)日'
+//	    template: '$Y/$M/$D'
+//	  - type: head
+//	    n: 10
+type Config struct {
+	Stages []StageConfig `yaml:"stages"`
+}
+
+// StageConfig は1つの段の設定
+// Typeによって使うフィールドが決まる
+type StageConfig struct {
+	// replace, regex, upper, lower, trim, width, normalize, grep, dedupe, head, tail
+	Type     string   `yaml:"type"`
+	Pairs    []string `yaml:"pairs,omitempty"`    // replace: 置換前と置換後を交互に並べる
+	Pattern  string   `yaml:"pattern,omitempty"`  // regex, grep: 正規表現
+	Template string   `yaml:"template,omitempty"` // regex: 置換後のテンプレート
+	Mode     string   `yaml:"mode,omitempty"`     // trim: both, left, right / width: narrow, wide, fold / normalize: hiragana, katakana
+	Invert   bool     `yaml:"invert,omitempty"`   // grep: マッチしない行を通す
+	Adjacent bool     `yaml:"adjacent,omitempty"` // dedupe: 連続した重複だけを取り除く
+	N        int      `yaml:"n,omitempty"`        // head, tail: 行数
+}
+
+// LoadConfig はYAMLを読み込む
+// 知らないフィールドがあるとエラーにする
+func LoadConfig(r io.Reader) (*Config, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	var c Config
+	if err := dec.Decode(&c); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("textpipe: load config: %w", err)
+	}
+	return &c, nil
+}
+
+// Pipeline は設定からPipelineを作る
+func (c *Config) Pipeline() (*Pipeline, error) {
+	stages := make([]Stage, len(c.Stages))
+	for i, sc := range c.Stages {
+		s, err := sc.Build()
+		if err != nil {
+			return nil, fmt.Errorf("textpipe: stage %d: %w", i+1, err)
+		}
+		stages[i] = s
+	}
+	return New(stages...), nil
+}
+
+// Build は設定から段を作る
+func (c StageConfig) Build() (Stage, error) {
+	switch c.Type {
+	case "replace":
+		if len(c.Pairs) == 0 || len(c.Pairs)%2 != 0 {
+			return nil, fmt.Errorf("replace: want pairs of old and new, got %d strings", len(c.Pairs))
+		}
+		return Replace(c.Pairs...), nil
+	case "regex":
+		re, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("regex: %w", err)
+		}
+		return Regexp(re, c.Template), nil
+	case "upper":
+		return MapRunes(unicode.ToUpper), nil
+	case "lower":
+		return MapRunes(unicode.ToLower), nil
+	case "trim":
+		switch c.Mode {
+		case "", "both":
+			return LineFunc(strings.TrimSpace), nil
+		case "left":
+			return LineFunc(func(s string) string { return strings.TrimLeftFunc(s, unicode.IsSpace) }), nil
+		case "right":
+			return LineFunc(func(s string) string { return strings.TrimRightFunc(s, unicode.IsSpace) }), nil
+		}
+		return nil, fmt.Errorf("trim: unknown mode %q", c.Mode)
+	case "width":
+		// 全角英数字と半角カナを揃える
+		// 半角カナの濁点は全角にすると結合文字になるのでNFCで1文字にまとめる
+		switch c.Mode {
+		case "narrow":
+			return LineFunc(width.Narrow.String), nil
+		case "wide":
+			return LineFunc(func(s string) string { return norm.NFC.String(width.Widen.String(s)) }), nil
+		case "", "fold":
+			// 英数字は半角、カナは全角にする
+			return LineFunc(func(s string) string { return norm.NFC.String(width.Fold.String(s)) }), nil
+		}
+		return nil, fmt.Errorf("width: unknown mode %q", c.Mode)
+	case "normalize":
+		// 幅・長音・空白をそろえ、modeを指定するとひらがなかカタカナにそろえる
+		kana := map[string]janorm.Kana{"": janorm.KanaAsIs, "hiragana": janorm.Hiragana, "katakana": janorm.Katakana}
+		k, ok := kana[c.Mode]
+		if !ok {
+			return nil, fmt.Errorf("normalize: unknown mode %q", c.Mode)
+		}
+		return LineFunc(janorm.New(janorm.WithKana(k)).String), nil
+	case "grep":
+		re, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("grep: %w", err)
+		}
+		return Grep(re, c.Invert), nil
+	case "dedupe":
+		return Dedupe(c.Adjacent), nil
+	case "head", "tail":
+		if c.N < 0 {
+			return nil, fmt.Errorf("%s: n must not be negative, got %d", c.Type, c.N)
+		}
+		if c.Type == "head" {
+			return Head(c.N), nil
+		}
+		return Tail(c.N), nil
+	}
+	return nil, fmt.Errorf("unknown stage type %q", c.Type)
+}
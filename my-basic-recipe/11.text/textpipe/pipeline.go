@@ -0,0 +1,104 @@
+// Package textpipe は入力を1行ずつ、設定した段（Stage）の列に流して変換する
+//
+// ファイル全体をメモリに載せずに処理する
+//
+//	p := textpipe.New(
+//		textpipe.Replace("郷", "Go"),
+//		textpipe.MapRunes(unicode.ToUpper),
+//		textpipe.Head(10),
+//	)
+//	err := p.Run(os.Stdout, os.Stdin)
+package textpipe
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrStop はそれ以上入力を読む必要がないことを表す
+// Headなどの段がProcessで返すと、Pipelineは読み込みをやめて残りの段をFlushする
+var ErrStop = errors.New("textpipe: stop")
+
+// Emit は次の段に1行を渡す関数
+type Emit func(line string) error
+
+// Stage はパイプラインの1つの段
+type Stage interface {
+	// Process は1行（改行を含まない）を処理して、出力する行をemitに渡す
+	Process(line string, emit Emit) error
+	// Flush は入力の終わりで呼ばれ、溜めていた行をemitに渡す
+	Flush(emit Emit) error
+}
+
+// Pipeline は段を順につないだもの
+// 段は状態を持つことがあるので、Runは1回だけ呼ぶ
+type Pipeline struct {
+	stages []Stage
+}
+
+// New は段を順につないだPipelineを作る
+func New(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Run はrsを順に1行ずつ読み込み、段を通してwに書き込む
+// 出力する行の終わりには必ず改行を付ける
+func (p *Pipeline) Run(w io.Writer, rs ...io.Reader) error {
+	bw := bufio.NewWriter(w)
+	emits := p.chain(func(line string) error {
+		if _, err := bw.WriteString(line); err != nil {
+			return err
+		}
+		return bw.WriteByte('\n')
+	})
+
+	err := p.read(emits[0], rs)
+	if err != nil && !errors.Is(err, ErrStop) {
+		return err
+	}
+	// 前の段のFlushで出た行も後ろの段で処理されるように前から順に呼ぶ
+	for i, s := range p.stages {
+		if err := s.Flush(emits[i+1]); err != nil && !errors.Is(err, ErrStop) {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// chain はi番目の段に行を渡すEmitをemits[i]に入れて返す
+// emits[len(stages)]はoutに書き込む
+func (p *Pipeline) chain(out Emit) []Emit {
+	emits := make([]Emit, len(p.stages)+1)
+	emits[len(p.stages)] = out
+	for i := len(p.stages) - 1; i >= 0; i-- {
+		s, next := p.stages[i], emits[i+1]
+		emits[i] = func(line string) error { return s.Process(line, next) }
+	}
+	return emits
+}
+
+func (p *Pipeline) read(emit Emit, rs []io.Reader) error {
+	for _, r := range rs {
+		// bufio.Scannerは長い行を読めないのでReadStringを使う
+		br := bufio.NewReader(r)
+		for {
+			line, err := br.ReadString('\n')
+			if len(line) > 0 {
+				line = strings.TrimSuffix(line, "\n")
+				line = strings.TrimSuffix(line, "\r")
+				if err := emit(line); err != nil {
+					return err
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
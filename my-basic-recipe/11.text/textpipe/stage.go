@@ -0,0 +1,150 @@
+package textpipe
+
+import (
+	"regexp"
+	"strings"
+)
+
+// LineFunc は1行を別の1行に変換する関数をStageとして使う
+//
+//	textpipe.LineFunc(strings.TrimSpace)
+type LineFunc func(line string) string
+
+// Process はfで変換した行を渡す
+func (f LineFunc) Process(line string, emit Emit) error { return emit(f(line)) }
+
+// Flush は何もしない
+func (LineFunc) Flush(Emit) error { return nil }
+
+// Replace は複数の文字列を置換する段を作る
+// oldnewは置換前と置換後の文字列を交互に並べる（strings.NewReplacerと同じ）
+func Replace(oldnew ...string) Stage {
+	return LineFunc(strings.NewReplacer(oldnew...).Replace)
+}
+
+// Regexp は正規表現にマッチした部分をtemplateに展開して置換する段を作る
+// templateでは$1や${name}で(?P<name>...)のキャプチャを参照できる
+//
+//	textpipe.Regexp(regexp.MustCompile(`(?P<Y>\d+)年(?P<M>\d+)月(?P<D>\d+)日`), "$Y/$M/$D")
+func Regexp(re *regexp.Regexp, template string) Stage {
+	return LineFunc(func(line string) string {
+		return re.ReplaceAllString(line, template)
+	})
+}
+
+// MapRunes はコードポイントごとに変換する段を作る
+//
+//	textpipe.MapRunes(unicode.ToUpper)
+func MapRunes(mapping func(rune) rune) Stage {
+	return LineFunc(func(line string) string {
+		return strings.Map(mapping, line)
+	})
+}
+
+// Grep は正規表現にマッチする行だけを通す段を作る
+// invertがtrueのときはマッチしない行だけを通す（grep -v）
+func Grep(re *regexp.Regexp, invert bool) Stage {
+	return &grep{re: re, invert: invert}
+}
+
+type grep struct {
+	re     *regexp.Regexp
+	invert bool
+}
+
+func (g *grep) Process(line string, emit Emit) error {
+	if g.re.MatchString(line) == g.invert {
+		return nil
+	}
+	return emit(line)
+}
+
+func (*grep) Flush(Emit) error { return nil }
+
+// Dedupe は重複した行を取り除く段を作る
+// adjacentがtrueのときは連続した重複だけを取り除く（uniq）
+// falseのときはこれまでに出た行をすべて覚えておくので、行の種類が多いとメモリを使う
+func Dedupe(adjacent bool) Stage {
+	return &dedupe{adjacent: adjacent, seen: map[string]struct{}{}}
+}
+
+type dedupe struct {
+	adjacent bool
+	prev     *string
+	seen     map[string]struct{}
+}
+
+func (d *dedupe) Process(line string, emit Emit) error {
+	if d.adjacent {
+		if d.prev != nil && *d.prev == line {
+			return nil
+		}
+		d.prev = &line
+		return emit(line)
+	}
+	if _, ok := d.seen[line]; ok {
+		return nil
+	}
+	d.seen[line] = struct{}{}
+	return emit(line)
+}
+
+func (*dedupe) Flush(Emit) error { return nil }
+
+// Head は最初のn行だけを通す段を作る
+// n行を通したらErrStopを返して入力の読み込みを終わらせる
+func Head(n int) Stage {
+	return &head{n: n}
+}
+
+type head struct {
+	n, seen int
+}
+
+func (h *head) Process(line string, emit Emit) error {
+	if h.seen >= h.n {
+		return ErrStop
+	}
+	h.seen++
+	if err := emit(line); err != nil {
+		return err
+	}
+	if h.seen == h.n {
+		return ErrStop
+	}
+	return nil
+}
+
+func (*head) Flush(Emit) error { return nil }
+
+// Tail は最後のn行だけを通す段を作る
+// n行分のリングバッファだけを持つ
+func Tail(n int) Stage {
+	return &tail{buf: make([]string, 0, n)}
+}
+
+type tail struct {
+	buf   []string
+	start int
+}
+
+func (t *tail) Process(line string, _ Emit) error {
+	switch {
+	case cap(t.buf) == 0:
+	case len(t.buf) < cap(t.buf):
+		t.buf = append(t.buf, line)
+	default:
+		t.buf[t.start] = line
+		t.start = (t.start + 1) % len(t.buf)
+	}
+	return nil
+}
+
+func (t *tail) Flush(emit Emit) error {
+	for i := range t.buf {
+		if err := emit(t.buf[(t.start+i)%len(t.buf)]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
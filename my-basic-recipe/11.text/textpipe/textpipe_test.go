@@ -0,0 +1,180 @@
+package textpipe_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode"
+
+	"golang/recipe-golang/11.text/textpipe"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// TestGolden はtestdata/*.yamlの設定で*.inputを変換し、*.goldenと比較する
+func TestGolden(t *testing.T) {
+	t.Parallel()
+	configs, err := filepath.Glob("testdata/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, config := range configs {
+		config := config
+		name := strings.TrimSuffix(filepath.Base(config), ".yaml")
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			f, err := os.Open(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			c, err := textpipe.LoadConfig(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := c.Pipeline()
+			if err != nil {
+				t.Fatal(err)
+			}
+			in, err := os.Open(filepath.Join("testdata", name+".input"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer in.Close()
+			var got bytes.Buffer
+			if err := p.Run(&got, in); err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, got.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != string(want) {
+				t.Errorf("want %q, got %q", want, got.String())
+			}
+		})
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		stages []textpipe.Stage
+		in     string
+		want   string
+	}{
+		"empty":      {nil, "a\nb", "a\nb\n"},
+		"replace":    {[]textpipe.Stage{textpipe.Replace("郷", "Go")}, "郷に入っては郷に従え\n", "Goに入ってはGoに従え\n"},
+		"regexp":     {[]textpipe.Stage{textpipe.Regexp(regexp.MustCompile(`(?P<Y>\d+)年`), "${Y}/")}, "1986年\n", "1986/\n"},
+		"map":        {[]textpipe.Stage{textpipe.MapRunes(unicode.ToUpper)}, "Hello, World\n", "HELLO, WORLD\n"},
+		"grep":       {[]textpipe.Stage{textpipe.Grep(regexp.MustCompile(`^a`), false)}, "ab\nba\nac\n", "ab\nac\n"},
+		"grep -v":    {[]textpipe.Stage{textpipe.Grep(regexp.MustCompile(`^a`), true)}, "ab\nba\nac\n", "ba\n"},
+		"uniq":       {[]textpipe.Stage{textpipe.Dedupe(true)}, "a\na\nb\na\n", "a\nb\na\n"},
+		"dedupe":     {[]textpipe.Stage{textpipe.Dedupe(false)}, "a\na\nb\na\n", "a\nb\n"},
+		"head 0":     {[]textpipe.Stage{textpipe.Head(0)}, "a\nb\n", ""},
+		"tail":       {[]textpipe.Stage{textpipe.Tail(2)}, "a\nb\nc\n", "b\nc\n"},
+		"tail 0":     {[]textpipe.Stage{textpipe.Tail(0)}, "a\nb\n", ""},
+		"tail short": {[]textpipe.Stage{textpipe.Tail(5)}, "a\nb\n", "a\nb\n"},
+		"head tail":  {[]textpipe.Stage{textpipe.Head(3), textpipe.Tail(2)}, "a\nb\nc\nd\n", "b\nc\n"},
+		"tail head":  {[]textpipe.Stage{textpipe.Tail(3), textpipe.Head(1)}, "a\nb\nc\nd\n", "b\n"},
+		"crlf":       {[]textpipe.Stage{textpipe.LineFunc(strings.TrimSpace)}, " a \r\n", "a\n"},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var got bytes.Buffer
+			if err := textpipe.New(tt.stages...).Run(&got, strings.NewReader(tt.in)); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got.String())
+			}
+		})
+	}
+}
+
+// stopReader はHeadが途中で読み込みをやめることを確認するためのio.Reader
+type stopReader struct {
+	reads int
+}
+
+func (r *stopReader) Read(p []byte) (int, error) {
+	r.reads++
+	if r.reads > 1 {
+		return 0, errors.New("read after head")
+	}
+	return copy(p, "a\nb\nc\n"), nil
+}
+
+func TestHead_Stop(t *testing.T) {
+	t.Parallel()
+	var got bytes.Buffer
+	r := &stopReader{}
+	if err := textpipe.New(textpipe.Head(2)).Run(&got, r, r); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != "a\nb\n" {
+		t.Errorf("want %q, got %q", "a\nb\n", got.String())
+	}
+}
+
+func TestRun_Files(t *testing.T) {
+	t.Parallel()
+	var got bytes.Buffer
+	// 最後の行に改行がなくても次の入力とつながらない
+	err := textpipe.New().Run(&got, strings.NewReader("a"), strings.NewReader("b\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != "a\nb\n" {
+		t.Errorf("want %q, got %q", "a\nb\n", got.String())
+	}
+	if err := textpipe.New().Run(io.Discard, iotestErr{}); err == nil {
+		t.Error("want read error")
+	}
+}
+
+type iotestErr struct{}
+
+func (iotestErr) Read([]byte) (int, error) { return 0, errors.New("broken") }
+
+func TestConfig_Error(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"unknown type":  "stages:\n  - type: sort\n",
+		"odd pairs":     "stages:\n  - type: replace\n    pairs: [a]\n",
+		"bad regex":     "stages:\n  - type: regex\n    pattern: '('\n",
+		"bad mode":      "stages:\n  - type: width\n    mode: half\n",
+		"negative head": "stages:\n  - type: head\n    n: -1\n",
+	}
+	for name, yml := range cases {
+		yml := yml
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			c, err := textpipe.LoadConfig(strings.NewReader(yml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.Pipeline(); err == nil || !strings.HasPrefix(err.Error(), "textpipe: stage 1: ") {
+				t.Errorf("want error for stage 1, got %v", err)
+			}
+		})
+	}
+	if _, err := textpipe.LoadConfig(strings.NewReader("stages:\n  - type: head\n    count: 1\n")); err == nil {
+		t.Error("want error for unknown field")
+	}
+}
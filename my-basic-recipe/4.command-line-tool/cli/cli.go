@@ -0,0 +1,342 @@
+// Package cli はflag.FlagSetを使ったサブコマンドのフレームワーク
+//
+// サブコマンドごとにFlagSetを持ち、ヘルプの生成、環境変数からのフラグの値、必須のフラグ、
+// bash・zsh・fishの補完、errs.ExitCodeによる終了コードを扱う
+//
+//	app := &cli.Command{Name: "tool", Commands: []*cli.Command{repeatCmd()}}
+//	os.Exit(cli.Main(context.Background(), app, os.Args[1:]))
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"golang/recipe-golang/6.error/errs"
+)
+
+// Command はコマンドまたはサブコマンド
+type Command struct {
+	// Name はコマンド名
+	Name string
+	// Usage はフラグ以外の引数の書き方（例: "[file...]"）
+	Usage string
+	// Short はコマンド一覧に出す1行の説明
+	Short string
+	// Long はヘルプに出す詳しい説明
+	Long string
+	// Flags はFlagSetにフラグを登録する。ヘルプや補完のときにも呼ばれる
+	Flags func(fs *flag.FlagSet)
+	// Env はフラグ名から、フラグが指定されなかったときに使う環境変数名への対応
+	Env map[string]string
+	// Required は指定しなければならないフラグの名前（環境変数で指定してもよい）
+	Required []string
+	// Run はコマンドの処理。Commandsがあって引数がないときは呼ばれない
+	Run func(ctx context.Context, c *Context) error
+	// Commands はサブコマンド
+	Commands []*Command
+	// Hidden はtrueのときヘルプと補完に出さない
+	Hidden bool
+
+	parent *Command
+}
+
+// Context はRunに渡される実行時の情報
+type Context struct {
+	// Command は実行しているコマンド
+	Command *Command
+	// Flags はパースしたFlagSet
+	Flags *flag.FlagSet
+	// Args はフラグを取り除いた引数
+	Args   []string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Usagef は使い方の誤りを表すエラーを作る
+// Mainはヘルプの案内を出力し、終了コードをerrs.ExitUsageにする
+func Usagef(format string, args ...interface{}) error {
+	return errs.Invalid.Errorf(format, args...)
+}
+
+// ExitError はメッセージを出さずに終了コードだけを返すためのエラー
+// grepが一致しなかったときの1のように、エラーではない結果を終了コードで伝えるときに使う
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string { return fmt.Sprintf("exit status %d", e.Code) }
+
+// Exit は終了コードをcodeにするエラーを返す
+func Exit(code int) error {
+	return &ExitError{Code: code}
+}
+
+// Path はルートからこのコマンドまでの名前を空白でつなげたもの
+func (c *Command) Path() string {
+	if c.parent == nil {
+		return c.Name
+	}
+	return c.parent.Path() + " " + c.Name
+}
+
+// Lookup はnameのサブコマンドを返す
+func (c *Command) Lookup(name string) *Command {
+	for _, sub := range c.Commands {
+		if sub.Name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// flagSet はフラグを登録したFlagSetを作る
+// ヘルプとエラーはExecがまとめて出力するので、FlagSetには出力させない
+func (c *Command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.Path(), flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	if c.Flags != nil {
+		c.Flags(fs)
+	}
+	return fs
+}
+
+// setParents はサブコマンドに親を設定する
+func (c *Command) setParents() {
+	for _, sub := range c.Commands {
+		sub.parent = c
+		sub.setParents()
+	}
+}
+
+// Main はargsでcmdを実行し、終了コードを返す
+// helpとcompletionのサブコマンドはルートに自動で加わる
+func Main(ctx context.Context, cmd *Command, args []string) int {
+	return Exec(ctx, cmd, args, os.Stdin, os.Stdout, os.Stderr)
+}
+
+// Exec は入出力を指定してMainと同じことをする
+func Exec(ctx context.Context, cmd *Command, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	root := withBuiltins(cmd)
+	err := root.execute(ctx, args, &Context{Stdin: stdin, Stdout: stdout, Stderr: stderr})
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return errs.ExitOK
+	}
+	var ee *ExitError
+	if errors.As(err, &ee) {
+		return ee.Code
+	}
+	var ce *commandError
+	if errors.As(err, &ce) {
+		fmt.Fprintf(stderr, "%s: %v\n", ce.cmd.Path(), ce.err)
+		if errors.Is(err, errs.Invalid) {
+			fmt.Fprintf(stderr, "Run '%s -h' for usage.\n", ce.cmd.Path())
+		}
+	} else {
+		fmt.Fprintf(stderr, "%s: %v\n", root.Name, err)
+	}
+	return errs.ExitCode(err)
+}
+
+// commandError はどのコマンドでエラーになったかを持つ
+type commandError struct {
+	cmd *Command
+	err error
+}
+
+func (e *commandError) Error() string { return e.cmd.Path() + ": " + e.err.Error() }
+func (e *commandError) Unwrap() error { return e.err }
+
+// withBuiltins はcmdのコピーにhelpとcompletionを加える
+func withBuiltins(cmd *Command) *Command {
+	root := *cmd
+	root.parent = nil
+	root.Commands = append([]*Command(nil), cmd.Commands...)
+	if root.Lookup("help") == nil {
+		root.Commands = append(root.Commands, helpCommand(&root))
+	}
+	if root.Lookup("completion") == nil {
+		root.Commands = append(root.Commands, completionCommand(&root))
+	}
+	root.Commands = append(root.Commands, completeCommand(&root))
+	root.setParents()
+	return &root
+}
+
+func (c *Command) execute(ctx context.Context, args []string, base *Context) error {
+	fs, err := c.parse(args, base.Stderr)
+	if err != nil {
+		return err
+	}
+	rest := fs.Args()
+	if len(c.Commands) > 0 && len(rest) > 0 {
+		if sub := c.Lookup(rest[0]); sub != nil {
+			return sub.execute(ctx, rest[1:], base)
+		}
+		if c.Run == nil {
+			return &commandError{c, Usagef("unknown command %q", rest[0])}
+		}
+	}
+	if c.Run == nil {
+		c.WriteHelp(base.Stderr)
+		return &commandError{c, Usagef("missing command")}
+	}
+	cc := *base
+	cc.Command, cc.Flags, cc.Args = c, fs, rest
+	if err := c.Run(ctx, &cc); err != nil {
+		return &commandError{c, err}
+	}
+	return nil
+}
+
+// parse はフラグをパースし、環境変数の値と必須のフラグを確かめる
+func (c *Command) parse(args []string, stderr io.Writer) (*flag.FlagSet, error) {
+	fs := c.flagSet()
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			c.WriteHelp(stderr)
+			return nil, err
+		}
+		return nil, &commandError{c, errs.Invalid.Wrap(err, "")}
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	names := make([]string, 0, len(c.Env))
+	for name := range c.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key := c.Env[name]
+		v, ok := os.LookupEnv(key)
+		if set[name] || !ok {
+			continue
+		}
+		if err := fs.Set(name, v); err != nil {
+			return nil, &commandError{c, Usagef("invalid value %q for $%s: %v", v, key, err)}
+		}
+		set[name] = true
+	}
+	for _, name := range c.Required {
+		if !set[name] {
+			return nil, &commandError{c, Usagef("flag -%s is required", name)}
+		}
+	}
+	return fs, nil
+}
+
+// WriteHelp はコマンドのヘルプを書き込む
+func (c *Command) WriteHelp(w io.Writer) {
+	usage := c.Path()
+	fs := c.flagSet()
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		usage += " [flags]"
+	}
+	if c.Usage != "" {
+		usage += " " + c.Usage
+	} else if len(c.Commands) > 0 {
+		usage += " <command>"
+	}
+	fmt.Fprintf(w, "Usage: %s\n", usage)
+	if desc := strings.TrimSpace(c.Long); desc != "" {
+		fmt.Fprintf(w, "\n%s\n", desc)
+	} else if c.Short != "" {
+		fmt.Fprintf(w, "\n%s\n", c.Short)
+	}
+
+	if subs := c.visibleCommands(); len(subs) > 0 {
+		width := 0
+		for _, sub := range subs {
+			width = max(width, len(sub.Name))
+		}
+		fmt.Fprintln(w, "\nCommands:")
+		for _, sub := range subs {
+			fmt.Fprintf(w, "  %-*s  %s\n", width, sub.Name, sub.Short)
+		}
+	}
+
+	if hasFlags {
+		required := make(map[string]bool)
+		for _, name := range c.Required {
+			required[name] = true
+		}
+		fmt.Fprintln(w, "\nFlags:")
+		fs.VisitAll(func(f *flag.Flag) {
+			name, usage := flag.UnquoteUsage(f)
+			line := "  -" + f.Name
+			if name != "" {
+				line += " " + name
+			}
+			line += "\n    \t" + strings.ReplaceAll(usage, "\n", "\n    \t")
+			if required[f.Name] {
+				line += " (required)"
+			} else if !isZeroValue(f) {
+				line += " (default " + defaultValue(f) + ")"
+			}
+			if key, ok := c.Env[f.Name]; ok {
+				line += " [$" + key + "]"
+			}
+			fmt.Fprintln(w, line)
+		})
+	}
+}
+
+// isZeroValue はフラグの初期値がゼロ値かを返す
+func isZeroValue(f *flag.Flag) bool {
+	switch f.DefValue {
+	case "", "0", "false", "0s", "[]":
+		return true
+	}
+	return false
+}
+
+// defaultValue は初期値を返す。文字列のフラグは引用符で囲む
+func defaultValue(f *flag.Flag) string {
+	if g, ok := f.Value.(flag.Getter); ok {
+		if _, ok := g.Get().(string); ok {
+			return fmt.Sprintf("%q", f.DefValue)
+		}
+	}
+	return f.DefValue
+}
+
+func (c *Command) visibleCommands() []*Command {
+	var subs []*Command
+	for _, sub := range c.Commands {
+		if !sub.Hidden {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// helpCommand は「help [command...]」でヘルプを出力するコマンド
+func helpCommand(root *Command) *Command {
+	return &Command{
+		Name:  "help",
+		Usage: "[command...]",
+		Short: "コマンドのヘルプを表示する",
+		Run: func(ctx context.Context, c *Context) error {
+			cmd := root
+			for _, name := range c.Args {
+				sub := cmd.Lookup(name)
+				if sub == nil {
+					return Usagef("unknown command %q", strings.TrimSpace(cmd.Path()+" "+name))
+				}
+				cmd = sub
+			}
+			cmd.WriteHelp(c.Stdout)
+			return nil
+		},
+	}
+}
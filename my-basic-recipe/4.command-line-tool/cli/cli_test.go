@@ -0,0 +1,234 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang/recipe-golang/4.command-line-tool/cli"
+	"golang/recipe-golang/6.error/errs"
+)
+
+// newTestApp はテストごとに新しいコマンドを作る
+// フラグの値はクロージャの変数に入るので、並行するテストで共有しない
+func newTestApp() *cli.Command {
+	var name string
+	var loud bool
+	var count int
+	return &cli.Command{
+		Name: "app",
+		Commands: []*cli.Command{
+			{
+				Name:  "greet",
+				Usage: "[target...]",
+				Short: "挨拶する",
+				Flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&name, "name", "", "名前")
+					fs.BoolVar(&loud, "loud", false, "大きな声で")
+					fs.IntVar(&count, "count", 1, "回数")
+				},
+				Env:      map[string]string{"name": "CLI_TEST_NAME"},
+				Required: []string{"name"},
+				Run: func(ctx context.Context, c *cli.Context) error {
+					msg := "hello " + name + strings.Join(c.Args, "")
+					if loud {
+						msg = strings.ToUpper(msg)
+					}
+					for i := 0; i < count; i++ {
+						fmt.Fprintln(c.Stdout, msg)
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "remote",
+				Short: "リモートを操作する",
+				Commands: []*cli.Command{
+					{
+						Name:  "add",
+						Short: "リモートを追加する",
+						Run: func(ctx context.Context, c *cli.Context) error {
+							if len(c.Args) != 1 {
+								return cli.Usagef("want a name")
+							}
+							fmt.Fprintln(c.Stdout, "added", c.Args[0])
+							return nil
+						},
+					},
+					{
+						Name:  "rm",
+						Short: "リモートを削除する",
+						Run: func(ctx context.Context, c *cli.Context) error {
+							return errs.NotFound.Errorf("remote %q not found", strings.Join(c.Args, ""))
+						},
+					},
+					{Name: "secret", Hidden: true},
+				},
+			},
+		},
+	}
+}
+
+func TestExec(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		"run":             {args: []string{"greet", "-name", "go", "-count", "2"}, stdout: "hello go\nhello go\n"},
+		"bool flag":       {args: []string{"greet", "-name=go", "-loud", "!"}, stdout: "HELLO GO!\n"},
+		"nested":          {args: []string{"remote", "add", "origin"}, stdout: "added origin\n"},
+		"error code":      {args: []string{"remote", "rm", "x"}, code: errs.ExitNoInput, stderr: `app remote rm: remote "x" not found`},
+		"usage error":     {args: []string{"remote", "add"}, code: errs.ExitUsage, stderr: "app remote add: want a name\nRun 'app remote add -h' for usage."},
+		"required":        {args: []string{"greet"}, code: errs.ExitUsage, stderr: "flag -name is required"},
+		"bad flag":        {args: []string{"greet", "-name", "go", "-count", "x"}, code: errs.ExitUsage, stderr: `invalid value "x" for flag -count`},
+		"unknown flag":    {args: []string{"greet", "-nmae", "go"}, code: errs.ExitUsage, stderr: "flag provided but not defined: -nmae"},
+		"unknown command": {args: []string{"remote", "push"}, code: errs.ExitUsage, stderr: `app remote: unknown command "push"`},
+		"missing command": {args: []string{"remote"}, code: errs.ExitUsage, stderr: "Usage: app remote <command>"},
+		"help flag":       {args: []string{"greet", "-h"}, stderr: "Usage: app greet [flags] [target...]"},
+		"help command":    {args: []string{"help", "remote", "add"}, stdout: "Usage: app remote add\n\nリモートを追加する\n"},
+		"help unknown":    {args: []string{"help", "nope"}, code: errs.ExitUsage, stderr: `unknown command "app nope"`},
+		"completion":      {args: []string{"completion", "bash"}, stdout: "complete -o default -F _app app\n"},
+		"bad shell":       {args: []string{"completion", "tcsh"}, code: errs.ExitUsage, stderr: `unsupported shell "tcsh"`},
+		"complete":        {args: []string{"__complete", "app", "remote", ""}, stdout: "add\nrm\n"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var stdout, stderr bytes.Buffer
+			code := cli.Exec(context.Background(), newTestApp(), tt.args, strings.NewReader(""), &stdout, &stderr)
+			if code != tt.code {
+				t.Fatalf("want exit code %d, got %d (%s)", tt.code, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.stdout) {
+				t.Errorf("want %q in stdout, got %q", tt.stdout, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("want %q in stderr, got %q", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestExec_Exit(t *testing.T) {
+	t.Parallel()
+	app := &cli.Command{
+		Name: "app",
+		Run: func(ctx context.Context, c *cli.Context) error {
+			return fmt.Errorf("no match: %w", cli.Exit(3))
+		},
+	}
+	var stdout, stderr bytes.Buffer
+	if code := cli.Exec(context.Background(), app, nil, nil, &stdout, &stderr); code != 3 {
+		t.Errorf("want exit code 3, got %d", code)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("want no message, got %q", stderr.String())
+	}
+}
+
+func TestExec_Env(t *testing.T) {
+	t.Setenv("CLI_TEST_NAME", "env")
+	var stdout, stderr bytes.Buffer
+	if code := cli.Exec(context.Background(), newTestApp(), []string{"greet"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("want exit code 0, got %d (%s)", code, stderr.String())
+	}
+	if got, want := stdout.String(), "hello env\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	// フラグが環境変数より優先される
+	stdout.Reset()
+	if code := cli.Exec(context.Background(), newTestApp(), []string{"greet", "-name", "flag"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("want exit code 0, got %d (%s)", code, stderr.String())
+	}
+	if got, want := stdout.String(), "hello flag\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestWriteHelp(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	code := cli.Exec(context.Background(), newTestApp(), []string{"help", "greet"}, nil, &buf, &buf)
+	if code != 0 {
+		t.Fatalf("want exit code 0, got %d", code)
+	}
+	want := `Usage: app greet [flags] [target...]
+
+挨拶する
+
+Flags:
+  -count int
+    	回数 (default 1)
+  -loud
+    	大きな声で
+  -name string
+    	名前 (required) [$CLI_TEST_NAME]
+`
+	if buf.String() != want {
+		t.Errorf("want\n%s\ngot\n%s", want, buf.String())
+	}
+
+	// 隠しコマンドは一覧に出さない
+	buf.Reset()
+	cli.Exec(context.Background(), newTestApp(), []string{"help"}, nil, &buf, &buf)
+	if s := buf.String(); strings.Contains(s, "__complete") || !strings.Contains(s, "  completion  シェルの補完スクリプトを出力する\n") {
+		t.Errorf("unexpected command list: %s", s)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		words []string
+		want  []string
+	}{
+		"commands":     {[]string{"app", ""}, []string{"greet", "remote", "help", "completion"}},
+		"prefix":       {[]string{"app", "re"}, []string{"remote"}},
+		"nested":       {[]string{"app", "remote", ""}, []string{"add", "rm"}},
+		"flags":        {[]string{"app", "greet", "-"}, []string{"-count", "-loud", "-name"}},
+		"flag prefix":  {[]string{"app", "greet", "-l"}, []string{"-loud"}},
+		"flag value":   {[]string{"app", "greet", "-name", ""}, nil},
+		"after value":  {[]string{"app", "greet", "-name", "go", "-c"}, []string{"-count"}},
+		"after bool":   {[]string{"app", "greet", "-loud", "-n"}, []string{"-name"}},
+		"after arg":    {[]string{"app", "greet", "x", "-"}, nil},
+		"no word":      {[]string{"app"}, nil},
+		"unknown word": {[]string{"app", "nope", ""}, nil},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var stdout bytes.Buffer
+			args := append([]string{"__complete"}, tt.words...)
+			cli.Exec(context.Background(), newTestApp(), args, nil, &stdout, &stdout)
+			var got []string
+			if s := strings.TrimSuffix(stdout.String(), "\n"); s != "" {
+				got = strings.Split(s, "\n")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWriteCompletion(t *testing.T) {
+	t.Parallel()
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var buf bytes.Buffer
+		if err := cli.WriteCompletion(&buf, shell, "mytool"); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), "mytool __complete") {
+			t.Errorf("%s: want the script to call mytool __complete, got %s", shell, buf.String())
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// completeName は補完の候補を出力する隠しコマンドの名前
+// 補完スクリプトはカーソルまでの単語を渡してこのコマンドを呼ぶ
+const completeName = "__complete"
+
+// 補完スクリプト。%[1]sにはコマンド名が入る
+// 候補がないとき（フラグの値など）はシェルのファイル名の補完に任せる
+var completionScripts = map[string]string{
+	"bash": `# bash completion for %[1]s
+_%[1]s() {
+    local IFS=$'\n'
+    COMPREPLY=($(%[1]s ` + completeName + ` "${COMP_WORDS[@]:0:COMP_CWORD+1}" 2>/dev/null))
+}
+complete -o default -F _%[1]s %[1]s
+`,
+	"zsh": `#compdef %[1]s
+# zsh completion for %[1]s
+_%[1]s() {
+    local -a candidates
+    candidates=("${(@f)$(%[1]s ` + completeName + ` "${words[@]:0:$CURRENT}" 2>/dev/null)}")
+    if [[ -n "${candidates[1]}" ]]; then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+compdef _%[1]s %[1]s
+`,
+	"fish": `# fish completion for %[1]s
+complete -c %[1]s -f -a '(%[1]s ` + completeName + ` (commandline -opc) (commandline -ct) 2>/dev/null)'
+`,
+}
+
+// WriteCompletion はshell（bash、zsh、fish）の補完スクリプトをwに書き込む
+func WriteCompletion(w io.Writer, shell, name string) error {
+	script, ok := completionScripts[shell]
+	if !ok {
+		return Usagef("unsupported shell %q (want bash, zsh or fish)", shell)
+	}
+	_, err := fmt.Fprintf(w, script, name)
+	return err
+}
+
+// completionCommand は「completion bash|zsh|fish」で補完スクリプトを出力するコマンド
+func completionCommand(root *Command) *Command {
+	return &Command{
+		Name:  "completion",
+		Usage: "bash|zsh|fish",
+		Short: "シェルの補完スクリプトを出力する",
+		Long: fmt.Sprintf(`シェルの補完スクリプトを出力する
+
+  bash: source <(%[1]s completion bash)
+  zsh:  %[1]s completion zsh > "${fpath[1]}/_%[1]s"
+  fish: %[1]s completion fish > ~/.config/fish/completions/%[1]s.fish`, root.Name),
+		Run: func(ctx context.Context, c *Context) error {
+			if len(c.Args) != 1 {
+				return Usagef("want one shell name")
+			}
+			return WriteCompletion(c.Stdout, c.Args[0], root.Name)
+		},
+	}
+}
+
+// completeCommand は補完スクリプトから呼ばれ、候補を1行に1つ出力する
+// 引数の最初はコマンド名、最後は補完しようとしている単語
+func completeCommand(root *Command) *Command {
+	return &Command{
+		Name:   completeName,
+		Hidden: true,
+		Run: func(ctx context.Context, c *Context) error {
+			for _, s := range Complete(root, c.Args) {
+				fmt.Fprintln(c.Stdout, s)
+			}
+			return nil
+		},
+	}
+}
+
+// Complete はコマンド名から始まる単語の並びwordsの最後の単語の補完候補を返す
+func Complete(root *Command, words []string) []string {
+	if len(words) < 2 {
+		return nil
+	}
+	cur := words[len(words)-1]
+	cmd := root
+	fs := cmd.flagSet()
+	afterFlags := false
+	for i := 1; i < len(words)-1; i++ {
+		w := words[i]
+		switch {
+		case afterFlags:
+		case w == "--":
+			afterFlags = true
+		case strings.HasPrefix(w, "-"):
+			name := strings.TrimLeft(w, "-")
+			if strings.Contains(name, "=") {
+				continue
+			}
+			// 値を取るフラグは次の単語を値として読み飛ばす
+			if f := fs.Lookup(name); f != nil && !isBoolFlag(f) {
+				i++
+			}
+		default:
+			sub := cmd.Lookup(w)
+			if sub == nil {
+				// サブコマンドではない引数の後はフラグを解釈しない
+				afterFlags = true
+				continue
+			}
+			cmd, fs = sub, sub.flagSet()
+		}
+	}
+
+	// 直前の単語が値を取るフラグなら値はわからないので候補を出さない
+	if prev := words[len(words)-2]; len(words) > 2 && strings.HasPrefix(prev, "-") && !strings.Contains(prev, "=") {
+		if f := fs.Lookup(strings.TrimLeft(prev, "-")); f != nil && !isBoolFlag(f) {
+			return nil
+		}
+	}
+
+	var out []string
+	if strings.HasPrefix(cur, "-") {
+		if afterFlags {
+			return nil
+		}
+		fs.VisitAll(func(f *flag.Flag) {
+			if s := "-" + f.Name; strings.HasPrefix(s, cur) {
+				out = append(out, s)
+			}
+		})
+		return out
+	}
+	if afterFlags {
+		return nil
+	}
+	for _, sub := range cmd.visibleCommands() {
+		if strings.HasPrefix(sub.Name, cur) {
+			out = append(out, sub.Name)
+		}
+	}
+	return out
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang/recipe-golang/4.command-line-tool/cli"
+)
+
+// newApp はコマンドの全体を組み立てる
+// helpとcompletionはcli.Mainが加える
+func newApp() *cli.Command {
+	return &cli.Command{
+		Name:  "tool",
+		Short: "コマンドラインツールのレシピ",
+		Commands: []*cli.Command{
+			repeatCmd(),
+			pathCmd(),
+			findCmd(),
+			searchCmd(),
+			cpCmd(),
+			mvCmd(),
+			shellCmd(newApp),
+			{
+				Name:  "basics",
+				Short: "標準入出力・ファイル・deferなどの基本を実行する",
+				Run:   runBasics,
+			},
+		},
+	}
+}
+
+// repeatCmd は-msgを-n回繰り返して出力する
+//
+//	tool repeat > デフォルト値
+//	tool repeat -msg=こんにちは -n=2 > こんにちはこんにちは
+//	REPEAT_N=3 tool repeat -msg=や > ややや
+func repeatCmd() *cli.Command {
+	var msg string
+	var n int
+	return &cli.Command{
+		Name:  "repeat",
+		Short: "メッセージを繰り返して出力する",
+		Flags: func(fs *flag.FlagSet) {
+			// ポインタを指定して設定を予約。値はcli.Mainがパースしたときに入る
+			fs.StringVar(&msg, "msg", "デフォルト値", "説明")
+			fs.IntVar(&n, "n", 1, "回数")
+		},
+		// フラグがなければ環境変数の値を使う
+		Env: map[string]string{"msg": "REPEAT_MSG", "n": "REPEAT_N"},
+		Run: func(ctx context.Context, c *cli.Context) error {
+			// c.Argsはflag.Argsと同じくフラグの分が除外されている
+			if len(c.Args) > 0 {
+				return cli.Usagef("unexpected arguments %q", c.Args)
+			}
+			if n < 0 {
+				return cli.Usagef("-n must not be negative: %d", n)
+			}
+			fmt.Fprintln(c.Stdout, strings.Repeat(msg, n))
+			return nil
+		},
+	}
+}
+
+// pathCmd はpath/filepathの関数を呼ぶサブコマンドをまとめる
+//
+//	tool path ext dir/main.go > .go
+//	tool path rel -base /a /a/b/c > b/c
+func pathCmd() *cli.Command {
+	each := func(name, short string, fn func(string) string) *cli.Command {
+		return &cli.Command{
+			Name:  name,
+			Usage: "path...",
+			Short: short,
+			Run: func(ctx context.Context, c *cli.Context) error {
+				if len(c.Args) == 0 {
+					return cli.Usagef("want at least one path")
+				}
+				for _, p := range c.Args {
+					fmt.Fprintln(c.Stdout, fn(p))
+				}
+				return nil
+			},
+		}
+	}
+	var base string
+	return &cli.Command{
+		Name:  "path",
+		Short: "ファイルパスを扱う",
+		Commands: []*cli.Command{
+			each("ext", "拡張子を出力する", filepath.Ext),
+			each("base", "ファイル名を出力する", filepath.Base),
+			each("dir", "ディレクトリ名を出力する", filepath.Dir),
+			{
+				Name:  "join",
+				Usage: "elem...",
+				Short: "パスを結合する",
+				Run: func(ctx context.Context, c *cli.Context) error {
+					fmt.Fprintln(c.Stdout, filepath.Join(c.Args...))
+					return nil
+				},
+			},
+			{
+				Name:  "rel",
+				Usage: "path...",
+				Short: "-baseからの相対パスを出力する",
+				Flags: func(fs *flag.FlagSet) {
+					fs.StringVar(&base, "base", "", "基準のディレクトリ")
+				},
+				Env:      map[string]string{"base": "PATH_BASE"},
+				Required: []string{"base"},
+				Run: func(ctx context.Context, c *cli.Context) error {
+					for _, p := range c.Args {
+						rel, err := filepath.Rel(base, p)
+						if err != nil {
+							return err
+						}
+						fmt.Fprintln(c.Stdout, rel)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang/recipe-golang/4.command-line-tool/cli"
+	"golang/recipe-golang/4.command-line-tool/fileutil"
+)
+
+// transferFlags はcpとmvで共通のフラグ
+type transferFlags struct {
+	noClobber, force, update bool
+	dryRun, verbose          bool
+	lock                     string
+}
+
+func (t *transferFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&t.noClobber, "n", false, "コピー先があれば上書きしない")
+	fs.BoolVar(&t.force, "f", false, "コピー先があれば上書きする")
+	fs.BoolVar(&t.update, "u", false, "コピー元が新しいときだけ上書きする")
+	fs.BoolVar(&t.dryRun, "dry-run", false, "ファイルを変えずに行う操作を出力する")
+	fs.BoolVar(&t.verbose, "v", false, "行った操作を出力する")
+	fs.StringVar(&t.lock, "lock", "", "操作の間ロックする`file`（ほかのtoolと同時に動かさない）")
+}
+
+// run は引数を「コピー元... コピー先」として解釈し、コピー元ごとにfnを呼ぶ
+func (t *transferFlags) run(c *cli.Context, extra []fileutil.Option, fn func(src, dst string, opts ...fileutil.Option) error) error {
+	if len(c.Args) < 2 {
+		return cli.Usagef("want source and destination")
+	}
+	opts := append([]fileutil.Option{fileutil.WithDryRun(t.dryRun)}, extra...)
+	n := 0
+	for _, on := range []bool{t.noClobber, t.force, t.update} {
+		if on {
+			n++
+		}
+	}
+	switch {
+	case n > 1:
+		return cli.Usagef("-n, -f and -u are mutually exclusive")
+	case t.noClobber:
+		opts = append(opts, fileutil.WithConflict(fileutil.Skip))
+	case t.force:
+		opts = append(opts, fileutil.WithConflict(fileutil.Overwrite))
+	case t.update:
+		opts = append(opts, fileutil.WithConflict(fileutil.Newer))
+	}
+	if t.verbose || t.dryRun {
+		opts = append(opts, fileutil.WithLog(func(a fileutil.Action) {
+			fmt.Fprintln(c.Stdout, a)
+		}))
+	}
+
+	if t.lock != "" {
+		l, err := fileutil.Lock(t.lock)
+		if err != nil {
+			return err
+		}
+		defer l.Unlock()
+	}
+
+	srcs, dst := c.Args[:len(c.Args)-1], c.Args[len(c.Args)-1]
+	info, err := os.Stat(dst)
+	isDir := err == nil && info.IsDir()
+	if len(srcs) > 1 && !isDir {
+		return cli.Usagef("target %q is not a directory", dst)
+	}
+	for _, src := range srcs {
+		to := dst
+		if isDir {
+			to = filepath.Join(dst, filepath.Base(src))
+		}
+		if err := fn(src, to, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cpCmd はファイルをコピーする
+//
+//	tool cp -r -u src/ backup/
+//	tool cp -dry-run a.txt b.txt dir/
+func cpCmd() *cli.Command {
+	var (
+		t         transferFlags
+		recursive bool
+	)
+	return &cli.Command{
+		Name:  "cp",
+		Usage: "source... dest",
+		Short: "ファイルをコピーする",
+		Long: `ファイルをコピーする
+
+モードと更新時刻を引き継ぎ、一時ファイルに書いてから置き換えるので、途中で失敗してもコピー先は元のまま
+コピー先が既存のディレクトリなら、その中にコピーする
+コピー先があるときは、-n、-f、-uのどれかを指定しなければエラーにする`,
+		Flags: func(fs *flag.FlagSet) {
+			t = transferFlags{}
+			t.register(fs)
+			fs.BoolVar(&recursive, "r", false, "ディレクトリを中身ごとコピーする")
+		},
+		Run: func(ctx context.Context, c *cli.Context) error {
+			return t.run(c, []fileutil.Option{fileutil.WithRecursive(recursive)}, func(src, dst string, opts ...fileutil.Option) error {
+				return fileutil.Copy(ctx, src, dst, opts...)
+			})
+		},
+	}
+}
+
+// mvCmd はファイルを移動する
+//
+//	tool mv -n old.txt new.txt
+//	tool mv -v logs/ /mnt/archive/
+func mvCmd() *cli.Command {
+	var t transferFlags
+	return &cli.Command{
+		Name:  "mv",
+		Usage: "source... dest",
+		Short: "ファイルを移動する",
+		Long: `ファイルを移動する
+
+別のファイルシステムへはコピーしてから元を消す
+コピー先があるときは、-n、-f、-uのどれかを指定しなければエラーにする`,
+		Flags: func(fs *flag.FlagSet) {
+			t = transferFlags{}
+			t.register(fs)
+		},
+		Run: func(ctx context.Context, c *cli.Context) error {
+			return t.run(c, nil, func(src, dst string, opts ...fileutil.Option) error {
+				return fileutil.Move(ctx, src, dst, opts...)
+			})
+		},
+	}
+}
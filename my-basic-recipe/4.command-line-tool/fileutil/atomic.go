@@ -0,0 +1,116 @@
+// Package fileutil はファイルを安全に書き込み、コピー、移動するためのユーティリティ
+//
+// 書き込みは同じディレクトリの一時ファイルに書いてからfsyncしてrenameするので、
+// 途中で失敗したり電源が落ちたりしても、書きかけのファイルが残らない
+//
+//	err := fileutil.WriteFile("config.json", data, 0o644)
+//	err = fileutil.Copy(ctx, "src", "dst", fileutil.WithConflict(fileutil.Newer))
+//	l, err := fileutil.Lock("app.lock")
+//	defer l.Unlock()
+package fileutil
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// AtomicFile はCommitするまで元のファイルを変えないファイル
+// Commitしないで閉じると一時ファイルを消す
+type AtomicFile struct {
+	*os.File
+	name string
+	perm os.FileMode
+	done bool
+}
+
+// CreateAtomic はnameに書き込むAtomicFileを作る。書き込みはname.tmp-*という一時ファイルに対して行う
+func CreateAtomic(name string, perm os.FileMode) (*AtomicFile, error) {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &AtomicFile{File: f, name: name, perm: perm}, nil
+}
+
+// Commit は一時ファイルをfsyncして閉じ、nameに置き換える
+func (f *AtomicFile) Commit() error {
+	if f.done {
+		return os.ErrClosed
+	}
+	f.done = true
+	tmp := f.File.Name()
+	err := f.File.Chmod(f.perm)
+	if err == nil {
+		err = f.File.Sync()
+	}
+	if cerr := f.File.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, f.name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return syncDir(filepath.Dir(f.name))
+}
+
+// Close はCommitしていなければ一時ファイルを消す。deferで呼んでおくとよい
+//
+//	f, err := fileutil.CreateAtomic(name, 0o644)
+//	if err != nil {
+//		return err
+//	}
+//	defer f.Close()
+//	if _, err := f.Write(data); err != nil {
+//		return err
+//	}
+//	return f.Commit()
+func (f *AtomicFile) Close() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	err := f.File.Close()
+	if rerr := os.Remove(f.File.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// WriteFile はos.WriteFileと同じだが、書き終えるまで元のファイルを変えない
+func WriteFile(name string, data []byte, perm os.FileMode) error {
+	return WriteFrom(name, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// WriteFrom はwriteが書き込んだ内容でnameを置き換える。writeがエラーを返すとnameは変えない
+func WriteFrom(name string, perm os.FileMode, write func(w io.Writer) error) error {
+	f, err := CreateAtomic(name, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := write(f); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// syncDir はrenameをディスクに書き込むためにディレクトリをfsyncする
+// ディレクトリをfsyncできないOSでは何もしない
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !isSyncUnsupported(err) {
+		return err
+	}
+	return nil
+}
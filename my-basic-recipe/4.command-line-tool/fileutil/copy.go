@@ -0,0 +1,288 @@
+package fileutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrExist はコピー先がすでにあり、ConflictがFailのときのエラー
+	ErrExist = errors.New("fileutil: destination already exists")
+	// ErrIsDir はWithRecursiveを指定せずにディレクトリをコピーしようとしたときのエラー
+	ErrIsDir = errors.New("fileutil: source is a directory (use WithRecursive)")
+	// ErrSameFile はコピー元とコピー先が同じファイルか、コピー先がコピー元の中にあるときのエラー
+	ErrSameFile = errors.New("fileutil: source and destination are the same")
+)
+
+// Conflict はコピー先がすでにあるときの扱い
+type Conflict int
+
+const (
+	Fail      Conflict = iota // ErrExistを返す
+	Skip                      // コピーしない
+	Overwrite                 // 上書きする
+	Newer                     // コピー元の更新時刻が新しいときだけ上書きする
+)
+
+// Op はコピーや移動で行う1つの操作
+type Op string
+
+const (
+	OpCopy    Op = "copy"
+	OpMkdir   Op = "mkdir"
+	OpSymlink Op = "symlink"
+	OpSkip    Op = "skip"
+	OpRename  Op = "rename"
+	OpRemove  Op = "remove"
+)
+
+// Action はWithLogの関数に渡される操作の内容
+type Action struct {
+	Op  Op
+	Src string
+	Dst string
+}
+
+func (a Action) String() string {
+	if a.Dst == "" {
+		return fmt.Sprintf("%s %s", a.Op, a.Src)
+	}
+	return fmt.Sprintf("%s %s -> %s", a.Op, a.Src, a.Dst)
+}
+
+type options struct {
+	conflict  Conflict
+	recursive bool
+	dryRun    bool
+	log       func(Action)
+	skipped   bool // Conflictに従ってコピーしなかったファイルがあるか
+}
+
+// Option はコピーと移動の設定
+type Option func(*options)
+
+// WithConflict はコピー先がすでにあるときの扱いを設定する。初期値はFail
+// ディレクトリどうしは中身をまとめる
+func WithConflict(c Conflict) Option {
+	return func(o *options) {
+		o.conflict = c
+	}
+}
+
+// WithRecursive はディレクトリを中身ごとコピーするかを設定する
+func WithRecursive(on bool) Option {
+	return func(o *options) {
+		o.recursive = on
+	}
+}
+
+// WithDryRun はtrueのとき、ファイルを変えずにWithLogの関数だけを呼ぶ
+func WithDryRun(on bool) Option {
+	return func(o *options) {
+		o.dryRun = on
+	}
+}
+
+// WithLog は操作ごとに呼ぶ関数を設定する
+func WithLog(fn func(Action)) Option {
+	return func(o *options) {
+		o.log = fn
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{log: func(Action) {}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Copy はsrcをdstにコピーする。ファイルのモードと更新時刻を引き継ぐ
+// ファイルは一時ファイルに書いてから置き換えるので、途中で失敗してもdstは元のまま
+// シンボリックリンクはリンクとしてコピーする
+func Copy(ctx context.Context, src, dst string, opts ...Option) error {
+	o := newOptions(opts)
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() && !o.recursive {
+		return &fs.PathError{Op: "copy", Path: src, Err: ErrIsDir}
+	}
+	if err := checkSame(src, dst); err != nil {
+		return err
+	}
+	return o.copy(ctx, src, dst, info)
+}
+
+// checkSame はdstがsrcと同じか、srcの中にあればErrSameFileを返す
+func checkSame(src, dst string) error {
+	s, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+	d, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+	if s == d || strings.HasPrefix(d, s+string(filepath.Separator)) {
+		return &fs.PathError{Op: "copy", Path: dst, Err: ErrSameFile}
+	}
+	si, err := os.Stat(src)
+	if err != nil {
+		return nil
+	}
+	if di, err := os.Stat(dst); err == nil && os.SameFile(si, di) {
+		return &fs.PathError{Op: "copy", Path: dst, Err: ErrSameFile}
+	}
+	return nil
+}
+
+func (o *options) copy(ctx context.Context, src, dst string, info fs.FileInfo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	switch {
+	case info.IsDir():
+		return o.copyDir(ctx, src, dst, info)
+	case info.Mode()&fs.ModeSymlink != 0:
+		return o.copySymlink(src, dst)
+	case !info.Mode().IsRegular():
+		return &fs.PathError{Op: "copy", Path: src, Err: fmt.Errorf("unsupported file type %s", info.Mode().Type())}
+	}
+
+	ok, err := o.resolve(src, dst, info)
+	if err != nil || !ok {
+		return err
+	}
+	o.log(Action{Op: OpCopy, Src: src, Dst: dst})
+	if o.dryRun {
+		return nil
+	}
+	return copyFile(src, dst, info)
+}
+
+// resolve はdstがすでにあるときにConflictに従って、コピーするかを決める
+func (o *options) resolve(src, dst string, info fs.FileInfo) (bool, error) {
+	di, err := os.Lstat(dst)
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if di.IsDir() {
+		return false, &fs.PathError{Op: "copy", Path: dst, Err: fmt.Errorf("cannot overwrite directory with non-directory")}
+	}
+	switch o.conflict {
+	case Skip:
+	case Overwrite:
+		return true, nil
+	case Newer:
+		if info.ModTime().After(di.ModTime()) {
+			return true, nil
+		}
+	default:
+		return false, &fs.PathError{Op: "copy", Path: dst, Err: ErrExist}
+	}
+	o.skipped = true
+	o.log(Action{Op: OpSkip, Src: src, Dst: dst})
+	return false, nil
+}
+
+func copyFile(src, dst string, info fs.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	f, err := CreateAtomic(dst, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, in); err != nil {
+		return err
+	}
+	// Commitの前に更新時刻をそろえる。renameしても更新時刻は変わらない
+	if err := os.Chtimes(f.Name(), info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+func (o *options) copySymlink(src, dst string) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	ok, err := o.resolve(src, dst, info)
+	if err != nil || !ok {
+		return err
+	}
+	o.log(Action{Op: OpSymlink, Src: target, Dst: dst})
+	if o.dryRun {
+		return nil
+	}
+	// 上書きするときも一時的な名前で作ってから置き換える
+	tmp := dst + ".tmp-symlink"
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (o *options) copyDir(ctx context.Context, src, dst string, info fs.FileInfo) error {
+	di, err := os.Lstat(dst)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		o.log(Action{Op: OpMkdir, Src: src, Dst: dst})
+		if !o.dryRun {
+			// 中身を書き込めるように、モードは最後に設定する
+			if err := os.Mkdir(dst, 0o700); err != nil {
+				return err
+			}
+		}
+	case err != nil:
+		return err
+	case !di.IsDir():
+		return &fs.PathError{Op: "copy", Path: dst, Err: fmt.Errorf("cannot overwrite non-directory with directory")}
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		ei, err := e.Info()
+		if err != nil {
+			return err
+		}
+		if err := o.copy(ctx, filepath.Join(src, e.Name()), filepath.Join(dst, e.Name()), ei); err != nil {
+			return err
+		}
+	}
+	if o.dryRun || di != nil {
+		// もとからあったディレクトリのモードと更新時刻は変えない
+		return nil
+	}
+	if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
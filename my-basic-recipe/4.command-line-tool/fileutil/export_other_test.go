@@ -0,0 +1,6 @@
+//go:build !unix && !windows
+
+package fileutil
+
+// ErrCrossDevice はnil。別のファイルシステムへのrenameを判断できない
+var ErrCrossDevice error
@@ -0,0 +1,8 @@
+package fileutil
+
+// SetRename はrenameを差し替え、元に戻す関数を返す
+func SetRename(fn func(oldpath, newpath string) error) (restore func()) {
+	old := rename
+	rename = fn
+	return func() { rename = old }
+}
@@ -0,0 +1,8 @@
+//go:build unix
+
+package fileutil
+
+import "golang.org/x/sys/unix"
+
+// ErrCrossDevice は別のファイルシステムへのrenameで返るエラー
+var ErrCrossDevice error = unix.EXDEV
@@ -0,0 +1,8 @@
+//go:build windows
+
+package fileutil
+
+import "golang.org/x/sys/windows"
+
+// ErrCrossDevice は別のドライブへのrenameで返るエラー
+var ErrCrossDevice error = windows.ERROR_NOT_SAME_DEVICE
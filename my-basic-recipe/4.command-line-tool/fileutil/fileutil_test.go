@@ -0,0 +1,363 @@
+package fileutil_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/recipe-golang/4.command-line-tool/fileutil"
+)
+
+var mtime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func write(t *testing.T, name, body string, mode os.FileMode, tm time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(body), mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(name, mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(name, tm, tm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func read(t *testing.T, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// listDir はdirの中のファイルを"パス=中身"の形で並べて返す。ディレクトリは"パス/"、リンクは"パス->先"
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	var out []string
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || p == dir {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, _ := os.Readlink(p)
+			out = append(out, rel+"->"+target)
+		case info.IsDir():
+			out = append(out, rel+"/")
+		default:
+			out = append(out, rel+"="+read(t, p))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestWriteFile(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	name := filepath.Join(dir, "config.json")
+	write(t, name, "old", 0o600, mtime)
+
+	if err := fileutil.WriteFile(name, []byte("new"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(t, name); got != "new" {
+		t.Errorf("want %q, got %q", "new", got)
+	}
+	if info, _ := os.Stat(name); info.Mode().Perm() != 0o640 {
+		t.Errorf("want %v, got %v", os.FileMode(0o640), info.Mode().Perm())
+	}
+
+	// 書き込みに失敗したら元のファイルは変わらず、一時ファイルも残らない
+	fail := errors.New("fail")
+	err := fileutil.WriteFrom(name, 0o644, func(w io.Writer) error {
+		io.WriteString(w, "broken")
+		return fail
+	})
+	if !errors.Is(err, fail) {
+		t.Errorf("want %v, got %v", fail, err)
+	}
+	if got := read(t, name); got != "new" {
+		t.Errorf("want %q, got %q", "new", got)
+	}
+	if got := listDir(t, dir); !reflect.DeepEqual(got, []string{"config.json=new"}) {
+		t.Errorf("want no temporary files, got %v", got)
+	}
+
+	f, err := fileutil.CreateAtomic(name, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(f, "atomic")
+	if err := f.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("want Close after Commit to succeed, got %v", err)
+	}
+	if err := f.Commit(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("want %v, got %v", os.ErrClosed, err)
+	}
+}
+
+func TestCopy_File(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.sh")
+	write(t, src, "#!/bin/sh\n", 0o750, mtime)
+	dst := filepath.Join(dir, "dst.sh")
+	if err := fileutil.Copy(context.Background(), src, dst); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o750 || !info.ModTime().Equal(mtime) {
+		t.Errorf("want mode %v and mtime %v, got %v and %v", os.FileMode(0o750), mtime, info.Mode().Perm(), info.ModTime())
+	}
+	if got := read(t, dst); got != "#!/bin/sh\n" {
+		t.Errorf("want %q, got %q", "#!/bin/sh\n", got)
+	}
+}
+
+func TestCopy_Conflict(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		conflict fileutil.Conflict
+		dstTime  time.Time
+		want     string
+		err      error
+	}{
+		"fail":        {conflict: fileutil.Fail, dstTime: mtime, want: "dst", err: fileutil.ErrExist},
+		"skip":        {conflict: fileutil.Skip, dstTime: mtime, want: "dst"},
+		"overwrite":   {conflict: fileutil.Overwrite, dstTime: mtime.Add(time.Hour), want: "src"},
+		"newer":       {conflict: fileutil.Newer, dstTime: mtime.Add(-time.Hour), want: "src"},
+		"newer older": {conflict: fileutil.Newer, dstTime: mtime.Add(time.Hour), want: "dst"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+			write(t, src, "src", 0o644, mtime)
+			write(t, dst, "dst", 0o644, tt.dstTime)
+			err := fileutil.Copy(context.Background(), src, dst, fileutil.WithConflict(tt.conflict))
+			if !errors.Is(err, tt.err) {
+				t.Errorf("want %v, got %v", tt.err, err)
+			}
+			if got := read(t, dst); got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCopy_Dir(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	write(t, filepath.Join(src, "a.txt"), "a", 0o644, mtime)
+	write(t, filepath.Join(src, "sub", "b.txt"), "b", 0o600, mtime)
+	if err := os.Symlink("a.txt", filepath.Join(src, "link")); err != nil {
+		t.Skip("symlinks are not supported:", err)
+	}
+	if err := os.Chmod(filepath.Join(src, "sub"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(src, "sub"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "dst")
+	ctx := context.Background()
+
+	if err := fileutil.Copy(ctx, src, dst); !errors.Is(err, fileutil.ErrIsDir) {
+		t.Errorf("want %v, got %v", fileutil.ErrIsDir, err)
+	}
+	if err := fileutil.Copy(ctx, src, filepath.Join(src, "sub", "copy"), fileutil.WithRecursive(true)); !errors.Is(err, fileutil.ErrSameFile) {
+		t.Errorf("want %v for copying into itself, got %v", fileutil.ErrSameFile, err)
+	}
+
+	// dry-runでは何も作らずに操作だけを知らせる
+	var actions []string
+	err := fileutil.Copy(ctx, src, dst, fileutil.WithRecursive(true), fileutil.WithDryRun(true), fileutil.WithLog(func(a fileutil.Action) {
+		actions = append(actions, strings.ReplaceAll(a.String(), dir, ""))
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"mkdir /src -> /dst",
+		"copy /src/a.txt -> /dst/a.txt",
+		"symlink a.txt -> /dst/link",
+		"mkdir /src/sub -> /dst/sub",
+		"copy /src/sub/b.txt -> /dst/sub/b.txt",
+	}
+	if !reflect.DeepEqual(actions, want) {
+		t.Errorf("want %q, got %q", want, actions)
+	}
+	if _, err := os.Stat(dst); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("want nothing to be created, got %v", err)
+	}
+
+	if err := fileutil.Copy(ctx, src, dst, fileutil.WithRecursive(true)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := listDir(t, dst), []string{"a.txt=a", "link->a.txt", "sub/", "sub/b.txt=b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	info, _ := os.Stat(filepath.Join(dst, "sub"))
+	if info.Mode().Perm() != 0o750 || !info.ModTime().Equal(mtime) {
+		t.Errorf("want mode %v and mtime %v, got %v and %v", os.FileMode(0o750), mtime, info.Mode().Perm(), info.ModTime())
+	}
+
+	// 既存のディレクトリには中身をまとめる
+	write(t, filepath.Join(src, "c.txt"), "c", 0o644, mtime)
+	if err := fileutil.Copy(ctx, src, dst, fileutil.WithRecursive(true)); !errors.Is(err, fileutil.ErrExist) {
+		t.Errorf("want %v, got %v", fileutil.ErrExist, err)
+	}
+	if err := fileutil.Copy(ctx, src, dst, fileutil.WithRecursive(true), fileutil.WithConflict(fileutil.Skip)); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(t, filepath.Join(dst, "c.txt")); got != "c" {
+		t.Errorf("want %q, got %q", "c", got)
+	}
+}
+
+func TestMove(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	write(t, src, "src", 0o644, mtime)
+	write(t, dst, "dst", 0o644, mtime)
+	ctx := context.Background()
+
+	if err := fileutil.Move(ctx, src, dst); !errors.Is(err, fileutil.ErrExist) {
+		t.Errorf("want %v, got %v", fileutil.ErrExist, err)
+	}
+	if err := fileutil.Move(ctx, src, dst, fileutil.WithConflict(fileutil.Skip)); err != nil {
+		t.Fatal(err)
+	}
+	if got := listDir(t, dir); !reflect.DeepEqual(got, []string{"dst=dst", "src=src"}) {
+		t.Errorf("want nothing to be moved, got %v", got)
+	}
+	if err := fileutil.Move(ctx, src, dst, fileutil.WithConflict(fileutil.Overwrite)); err != nil {
+		t.Fatal(err)
+	}
+	if got := listDir(t, dir); !reflect.DeepEqual(got, []string{"dst=src"}) {
+		t.Errorf("want src to be moved, got %v", got)
+	}
+}
+
+// TestMove_CrossDevice はrenameが別のファイルシステムで失敗したときにコピーしてから消すことを確かめる
+// renameを差し替えるので並行して実行しない
+func TestMove_CrossDevice(t *testing.T) {
+	if fileutil.ErrCrossDevice == nil {
+		t.Skip("cross-device renames are not detected on " + runtime.GOOS)
+	}
+	exdev := &os.LinkError{Op: "rename", Err: fileutil.ErrCrossDevice}
+	defer fileutil.SetRename(func(string, string) error { return exdev })()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	write(t, filepath.Join(src, "a.txt"), "a", 0o644, mtime)
+	write(t, filepath.Join(src, "sub", "b.txt"), "b", 0o644, mtime)
+	dst := filepath.Join(dir, "dst")
+
+	var ops []fileutil.Op
+	err := fileutil.Move(context.Background(), src, dst, fileutil.WithLog(func(a fileutil.Action) {
+		ops = append(ops, a.Op)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := listDir(t, dir), []string{"dst/", "dst/a.txt=a", "dst/sub/", "dst/sub/b.txt=b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	want := []fileutil.Op{fileutil.OpRename, fileutil.OpMkdir, fileutil.OpCopy, fileutil.OpMkdir, fileutil.OpCopy, fileutil.OpRemove}
+	if !reflect.DeepEqual(ops, want) {
+		t.Errorf("want %v, got %v", want, ops)
+	}
+
+	// ほかのエラーではコピーしない
+	defer fileutil.SetRename(func(string, string) error { return os.ErrPermission })()
+	if err := fileutil.Move(context.Background(), dst, src); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("want %v, got %v", os.ErrPermission, err)
+	}
+}
+
+func TestLock(t *testing.T) {
+	t.Parallel()
+	name := filepath.Join(t.TempDir(), "app.lock")
+	l, err := fileutil.Lock(name)
+	if errors.Is(err, fileutil.ErrLockUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fileutil.TryLock(name); !errors.Is(err, fileutil.ErrLocked) {
+		t.Errorf("want %v, got %v", fileutil.ErrLocked, err)
+	}
+
+	// 解放されるまで待つ
+	locked := make(chan error, 1)
+	go func() {
+		l2, err := fileutil.Lock(name)
+		if err == nil {
+			err = l2.Unlock()
+		}
+		locked <- err
+	}()
+	select {
+	case err := <-locked:
+		t.Fatalf("want Lock to wait, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-locked:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the lock")
+	}
+
+	// 共有ロックどうしは同時に持てる
+	r1, err := fileutil.RLock(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := fileutil.RLock(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fileutil.TryLock(name); !errors.Is(err, fileutil.ErrLocked) {
+		t.Errorf("want %v, got %v", fileutil.ErrLocked, err)
+	}
+	r1.Unlock()
+	r2.Unlock()
+}
@@ -0,0 +1,56 @@
+package fileutil
+
+import (
+	"errors"
+	"os"
+)
+
+var (
+	// ErrLocked はTryLockでほかのプロセスがロックしていたときのエラー
+	ErrLocked = errors.New("fileutil: file is locked")
+	// ErrLockUnsupported はファイルのロックができないOSのときのエラー
+	ErrLockUnsupported = errors.New("fileutil: file locking is not supported on this platform")
+)
+
+// FileLock はプロセス間のアドバイザリロック
+// ロックはファイルを開いている間だけ有効で、ほかのプロセスもLockを使ったときにだけ効く
+type FileLock struct {
+	f *os.File
+}
+
+// Lock はnameのファイルを排他ロックする。ほかのプロセスがロックしていれば解放されるまで待つ
+// ファイルがなければ作る
+func Lock(name string) (*FileLock, error) {
+	return lockFile(name, false, true)
+}
+
+// RLock はnameのファイルを共有ロックする。共有ロックどうしは同時に持てる
+func RLock(name string) (*FileLock, error) {
+	return lockFile(name, true, true)
+}
+
+// TryLock はLockと同じだが、待たずにErrLockedを返す
+func TryLock(name string) (*FileLock, error) {
+	return lockFile(name, false, false)
+}
+
+func lockFile(name string, shared, wait bool) (*FileLock, error) {
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lock(f, shared, wait); err != nil {
+		f.Close()
+		return nil, &os.PathError{Op: "lock", Path: name, Err: err}
+	}
+	return &FileLock{f: f}, nil
+}
+
+// Unlock はロックを解放する。ロックのファイルは消さない
+func (l *FileLock) Unlock() error {
+	err := unlock(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
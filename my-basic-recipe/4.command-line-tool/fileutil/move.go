@@ -0,0 +1,65 @@
+package fileutil
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// rename はテストで別のファイルシステムへの移動を再現するために差し替えられる
+var rename = os.Rename
+
+// Move はsrcをdstに移動する。まずrenameを試し、別のファイルシステムで失敗したら
+// コピーしてからsrcを消す。コピーが終わるまでsrcは消さない
+// ディレクトリはWithRecursiveを指定しなくても移動できる
+func Move(ctx context.Context, src, dst string, opts ...Option) error {
+	o := newOptions(opts)
+	o.recursive = true
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if err := checkSame(src, dst); err != nil {
+		return err
+	}
+	if di, err := os.Lstat(dst); err == nil {
+		// renameは上書きしてしまうので、先にConflictに従う
+		// ディレクトリどうしはrenameできないので中身をまとめる
+		if info.IsDir() && di.IsDir() {
+			return o.moveByCopy(ctx, src, dst, info)
+		}
+		ok, err := o.resolve(src, dst, info)
+		if err != nil || !ok {
+			return err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	o.log(Action{Op: OpRename, Src: src, Dst: dst})
+	if o.dryRun {
+		return nil
+	}
+	err = rename(src, dst)
+	if err == nil || !isCrossDevice(err) {
+		return err
+	}
+	return o.moveByCopy(ctx, src, dst, info)
+}
+
+// moveByCopy はsrcをコピーしてから消す
+// Conflictに従ってコピーしなかったファイルがあるときは、なくさないようにsrcを残す
+func (o *options) moveByCopy(ctx context.Context, src, dst string, info fs.FileInfo) error {
+	if err := o.copy(ctx, src, dst, info); err != nil {
+		return err
+	}
+	if o.skipped {
+		return nil
+	}
+	o.log(Action{Op: OpRemove, Src: src})
+	if o.dryRun {
+		return nil
+	}
+	return os.RemoveAll(src)
+}
@@ -0,0 +1,24 @@
+//go:build !unix && !windows
+
+package fileutil
+
+import "os"
+
+func lock(f *os.File, shared, wait bool) error {
+	return ErrLockUnsupported
+}
+
+func unlock(f *os.File) error {
+	return ErrLockUnsupported
+}
+
+// isCrossDevice はrenameが別のファイルシステムへの移動で失敗したかを返す
+// 判断できないので、renameのエラーをそのまま返す
+func isCrossDevice(err error) bool {
+	return false
+}
+
+// isSyncUnsupported はディレクトリのfsyncができないかを返す
+func isSyncUnsupported(err error) bool {
+	return true
+}
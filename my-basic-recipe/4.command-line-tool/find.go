@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang/recipe-golang/4.command-line-tool/cli"
+	"golang/recipe-golang/4.command-line-tool/find"
+	"golang/recipe-golang/6.error/errs"
+)
+
+// stringsFlag は何度でも指定できるフラグ
+type stringsFlag []string
+
+func (s *stringsFlag) String() string { return strings.Join(*s, ",") }
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// findCmd はfindのようにファイルを探す
+//
+//	tool find -name '*.go' -exclude vendor/ .
+//	tool find -gitignore -type f -min-size 1M -newer 24h -format json
+//	tool find -regex '_test\.go$' -0 | xargs -0 wc -l
+func findCmd() *cli.Command {
+	var (
+		names, includes, excludes stringsFlag
+		regex, types, symlinks    string
+		minSize, maxSize          string
+		newer, older, format      string
+		gitignore, nul, sorted    bool
+		maxDepth, workers         int
+	)
+	return &cli.Command{
+		Name:  "find",
+		Usage: "[path...]",
+		Short: "ファイルを再帰的に探す",
+		Long: `ファイルを再帰的に探す
+
+-includeと-excludeは.gitignoreと同じ書き方のパターン
+-newerと-olderは期間（24h、7d）か日付（2006-01-02）
+読めないディレクトリがあっても最後までたどり、終了コードを1にする`,
+		Flags: func(fs *flag.FlagSet) {
+			names, includes, excludes = nil, nil, nil
+			fs.Var(&names, "name", "名前の`glob`（何度でも指定できる）")
+			fs.StringVar(&regex, "regex", "", "起点からの相対パスの正規表現")
+			fs.Var(&includes, "include", "含めるファイルの`pattern`（何度でも指定できる）")
+			fs.Var(&excludes, "exclude", "除く`pattern`（何度でも指定できる）")
+			fs.BoolVar(&gitignore, "gitignore", false, ".gitignoreに従って除く")
+			fs.StringVar(&types, "type", "", "種類 `f|d|l`（カンマ区切りで複数）")
+			fs.StringVar(&minSize, "min-size", "", "最小のサイズ（例: 10K、1.5MiB、2GB。K・KiBなどは1024倍、KB・MBなどは1000倍）")
+			fs.StringVar(&maxSize, "max-size", "", "最大のサイズ（単位は-min-sizeと同じ）")
+			fs.StringVar(&newer, "newer", "", "これより新しいもの")
+			fs.StringVar(&older, "older", "", "これより古いもの")
+			fs.IntVar(&maxDepth, "maxdepth", -1, "たどる深さ（起点の直下が1）")
+			fs.StringVar(&symlinks, "symlinks", "list", "シンボリックリンクの扱い `list|follow|skip`")
+			fs.IntVar(&workers, "j", 0, "並行してたどる数（0はCPUの数）")
+			fs.StringVar(&format, "format", "path", "出力形式 `path|json`")
+			fs.BoolVar(&nul, "0", false, "パスをNUL文字で区切って出力する")
+			fs.BoolVar(&sorted, "sort", false, "パスの順に並べて出力する")
+		},
+		Run: func(ctx context.Context, c *cli.Context) error {
+			opts := []find.Option{
+				find.WithName(names...),
+				find.WithGitignore(gitignore),
+				find.WithMaxDepth(maxDepth),
+				find.WithWorkers(workers),
+			}
+			if regex != "" {
+				re, err := regexp.Compile(regex)
+				if err != nil {
+					return cli.Usagef("-regex: %v", err)
+				}
+				opts = append(opts, find.WithRegexp(re))
+			}
+			if len(includes) > 0 {
+				opts = append(opts, find.WithInclude(includes...))
+			}
+			if len(excludes) > 0 {
+				opts = append(opts, find.WithExclude(excludes...))
+			}
+			if types != "" {
+				var ts []find.Type
+				for _, t := range strings.Split(types, ",") {
+					switch t {
+					case "f":
+						ts = append(ts, find.TypeFile)
+					case "d":
+						ts = append(ts, find.TypeDir)
+					case "l":
+						ts = append(ts, find.TypeSymlink)
+					default:
+						return cli.Usagef("-type: unknown type %q", t)
+					}
+				}
+				opts = append(opts, find.WithType(ts...))
+			}
+			min, err := parseSize(minSize)
+			if err != nil {
+				return cli.Usagef("-min-size: %v", err)
+			}
+			max, err := parseSize(maxSize)
+			if err != nil {
+				return cli.Usagef("-max-size: %v", err)
+			}
+			opts = append(opts, find.WithSize(min, max))
+			now := time.Now()
+			newerT, err := parseTime(newer, now)
+			if err != nil {
+				return cli.Usagef("-newer: %v", err)
+			}
+			olderT, err := parseTime(older, now)
+			if err != nil {
+				return cli.Usagef("-older: %v", err)
+			}
+			opts = append(opts, find.WithModTime(newerT, olderT))
+			switch symlinks {
+			case "list":
+				opts = append(opts, find.WithSymlinks(find.SymlinkList))
+			case "follow":
+				opts = append(opts, find.WithSymlinks(find.SymlinkFollow))
+			case "skip":
+				opts = append(opts, find.WithSymlinks(find.SymlinkSkip))
+			default:
+				return cli.Usagef("-symlinks: unknown policy %q", symlinks)
+			}
+			if format != "path" && format != "json" {
+				return cli.Usagef("-format: unknown format %q", format)
+			}
+
+			// 読めないディレクトリはその場で報告して続ける
+			failed := false
+			opts = append(opts, find.WithOnError(func(err error) {
+				failed = true
+				fmt.Fprintln(c.Stderr, "tool find:", err)
+			}))
+			f, err := find.New(opts...)
+			if err != nil {
+				return errs.Invalid.Wrap(err, "")
+			}
+
+			out := bufio.NewWriter(c.Stdout)
+			defer out.Flush()
+			write := func(e find.Entry) error { return writeEntry(out, e, format, nul) }
+			var found []find.Entry
+			if sorted {
+				write = func(e find.Entry) error {
+					found = append(found, e)
+					return nil
+				}
+			}
+
+			roots := c.Args
+			if len(roots) == 0 {
+				roots = []string{"."}
+			}
+			for _, root := range roots {
+				if err := f.Walk(ctx, root, write); err != nil {
+					return err
+				}
+			}
+			sort.Slice(found, func(i, j int) bool { return found[i].Path < found[j].Path })
+			for _, e := range found {
+				if err := writeEntry(out, e, format, nul); err != nil {
+					return err
+				}
+			}
+			if failed {
+				return errs.New("some paths could not be read")
+			}
+			return nil
+		},
+	}
+}
+
+func writeEntry(w io.Writer, e find.Entry, format string, nul bool) error {
+	if format == "json" {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	}
+	sep := "\n"
+	if nul {
+		sep = "\x00"
+	}
+	_, err := io.WriteString(w, e.Path+sep)
+	return err
+}
+
+// sizeUnits はparseSizeの単位。7.testのconfig.ParseSizeと同じく
+// KB・MB・GB・TBは1000倍ずつ、K・M・G・TとKiB・MiB・GiB・TiBは1024倍ずつ
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1e3,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1e6,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1e9,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1e12,
+	"tib": 1 << 40,
+}
+
+// parseSize は10K、1.5MiB、2GBのようなサイズをバイト数にする
+func parseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	t := strings.TrimSpace(s)
+	i := strings.IndexFunc(t, func(r rune) bool {
+		return !('0' <= r && r <= '9' || r == '.')
+	})
+	if i < 0 {
+		i = len(t)
+	}
+	num, unit := t[:i], strings.ToLower(strings.TrimSpace(t[i:]))
+	mul, ok := sizeUnits[unit]
+	if !ok || num == "" {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	n := f * mul
+	if n > math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return int64(n), nil
+}
+
+// parseTime は24hや7dのような期間ならnowからさかのぼった時刻、2006-01-02の形式ならその日の0時にする
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (want a duration like 24h or 7d, or a date like 2006-01-02)", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,533 @@
+// Package find はfindコマンドのようにディレクトリを再帰的にたどってファイルを探す
+//
+// fs.WalkDirでたどり、空いているワーカーがあればサブディレクトリを別のゴルーチンでたどる
+// 読めないディレクトリがあってもたどるのをやめず、エラーとして報告する
+//
+//	f, _ := find.New(find.WithName("*.go"), find.WithExclude("vendor/", ".*"))
+//	err := f.Walk(ctx, ".", func(e find.Entry) error {
+//		fmt.Println(e.Path)
+//		return nil
+//	})
+package find
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"golang/recipe-golang/6.error/errs"
+)
+
+// ErrSymlinkLoop はシンボリックリンクをたどると祖先のディレクトリに戻るときのエラー
+var ErrSymlinkLoop = errors.New("find: symlink loop")
+
+// SymlinkPolicy はシンボリックリンクの扱い
+type SymlinkPolicy int
+
+const (
+	// SymlinkList はリンク自体を結果に含め、リンク先はたどらない
+	SymlinkList SymlinkPolicy = iota
+	// SymlinkFollow はリンク先を結果に含め、ディレクトリならたどる
+	SymlinkFollow
+	// SymlinkSkip はリンクを無視する
+	SymlinkSkip
+)
+
+// Type はエントリの種類
+type Type string
+
+const (
+	TypeFile    Type = "file"
+	TypeDir     Type = "dir"
+	TypeSymlink Type = "symlink"
+	TypeOther   Type = "other"
+)
+
+func typeOf(mode fs.FileMode) Type {
+	switch {
+	case mode.IsRegular():
+		return TypeFile
+	case mode.IsDir():
+		return TypeDir
+	case mode&fs.ModeSymlink != 0:
+		return TypeSymlink
+	}
+	return TypeOther
+}
+
+// Entry は見つかったファイルやディレクトリ
+type Entry struct {
+	Path    string      `json:"path"`
+	Type    Type        `json:"type"`
+	Size    int64       `json:"size"`
+	ModTime time.Time   `json:"mod_time"`
+	Mode    fs.FileMode `json:"-"`
+	Depth   int         `json:"depth"`
+}
+
+// Finder はファイルを探す条件
+type Finder struct {
+	names     []string
+	regex     *regexp.Regexp
+	include   *Matcher
+	exclude   *Matcher
+	gitignore bool
+	types     map[Type]bool
+	minSize   int64
+	maxSize   int64
+	newer     time.Time
+	older     time.Time
+	maxDepth  int
+	symlinks  SymlinkPolicy
+	workers   int
+	onError   func(error)
+}
+
+// Option はFinderの設定
+type Option func(*Finder) error
+
+// WithName は名前がglobのどれかにマッチするものだけにする（filepath.Matchの書き方）
+func WithName(globs ...string) Option {
+	return func(f *Finder) error {
+		for _, g := range globs {
+			if _, err := filepath.Match(g, ""); err != nil {
+				return fmt.Errorf("find: name %q: %w", g, err)
+			}
+		}
+		f.names = append(f.names, globs...)
+		return nil
+	}
+}
+
+// WithRegexp は起点からのスラッシュ区切りの相対パスがreにマッチするものだけにする
+func WithRegexp(re *regexp.Regexp) Option {
+	return func(f *Finder) error {
+		f.regex = re
+		return nil
+	}
+}
+
+// WithInclude は.gitignoreの書き方のパターンにマッチするファイルだけにする
+// ディレクトリはパターンにかかわらずたどる
+func WithInclude(patterns ...string) Option {
+	return func(f *Finder) (err error) {
+		f.include, err = NewMatcher(patterns)
+		return err
+	}
+}
+
+// WithExclude は.gitignoreの書き方のパターンにマッチするものを除く。マッチしたディレクトリはたどらない
+func WithExclude(patterns ...string) Option {
+	return func(f *Finder) (err error) {
+		f.exclude, err = NewMatcher(patterns)
+		return err
+	}
+}
+
+// WithGitignore はtrueのとき、各ディレクトリの.gitignoreに従って除き、.gitディレクトリをたどらない
+func WithGitignore(enabled bool) Option {
+	return func(f *Finder) error {
+		f.gitignore = enabled
+		return nil
+	}
+}
+
+// WithType は種類がtypesのどれかのものだけにする
+func WithType(types ...Type) Option {
+	return func(f *Finder) error {
+		f.types = make(map[Type]bool)
+		for _, t := range types {
+			switch t {
+			case TypeFile, TypeDir, TypeSymlink, TypeOther:
+			default:
+				return fmt.Errorf("find: unknown type %q", t)
+			}
+			f.types[t] = true
+		}
+		return nil
+	}
+}
+
+// WithSize はサイズがmin以上max以下のファイルだけにする。0は制限なし
+// 指定するとディレクトリは結果に含まない
+func WithSize(min, max int64) Option {
+	return func(f *Finder) error {
+		if min < 0 || max < 0 || max > 0 && min > max {
+			return fmt.Errorf("find: invalid size range %d-%d", min, max)
+		}
+		f.minSize, f.maxSize = min, max
+		return nil
+	}
+}
+
+// WithModTime は更新時刻がnewerより後でolderより前のものだけにする。ゼロ値は制限なし
+func WithModTime(newer, older time.Time) Option {
+	return func(f *Finder) error {
+		f.newer, f.older = newer, older
+		return nil
+	}
+}
+
+// WithMaxDepth は起点からの深さがn以下のものだけにする。起点の直下が1。負の値は制限なし
+// 起点そのものは結果に含まないので0は指定できない
+func WithMaxDepth(n int) Option {
+	return func(f *Finder) error {
+		if n == 0 {
+			return errors.New("find: max depth must not be 0 (the start itself is not listed)")
+		}
+		f.maxDepth = n
+		return nil
+	}
+}
+
+// WithSymlinks はシンボリックリンクの扱いを設定する。初期値はSymlinkList
+func WithSymlinks(p SymlinkPolicy) Option {
+	return func(f *Finder) error {
+		f.symlinks = p
+		return nil
+	}
+}
+
+// WithWorkers は並行してたどるゴルーチンの数を設定する。初期値はCPUの数
+func WithWorkers(n int) Option {
+	return func(f *Finder) error {
+		if n > 0 {
+			f.workers = n
+		}
+		return nil
+	}
+}
+
+// WithOnError は読めないディレクトリなどのエラーを受け取る関数を設定する
+// 設定しないとエラーはまとめてWalkの戻り値になる
+func WithOnError(fn func(error)) Option {
+	return func(f *Finder) error {
+		f.onError = fn
+		return nil
+	}
+}
+
+// New はFinderを作る
+func New(opts ...Option) (*Finder, error) {
+	f := &Finder{maxDepth: -1, workers: runtime.NumCPU()}
+	for _, opt := range opts {
+		if err := opt(f); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// walker は1回のWalkの状態
+type walker struct {
+	*Finder
+	ctx    context.Context
+	cancel context.CancelFunc
+	root   string
+	fsys   fs.FS
+	fn     func(Entry) error
+	sem    chan struct{}
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex // fnとエラーの呼び出しを1つずつにする
+	err     error      // fnが返したエラー
+	walkErr error      // 読めなかったディレクトリなどのエラー
+
+	ignores sync.Map // ディレクトリ（fsのパス）→ *Matcher
+}
+
+// Walk はrootからたどり、条件に合うエントリごとにfnを呼ぶ
+// fnは1つずつ呼ばれるが、順番は決まらない。fnがエラーを返すとたどるのをやめてそのエラーを返す
+func (f *Finder) Walk(ctx context.Context, root string, fn func(Entry) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := &walker{
+		Finder: f,
+		ctx:    ctx,
+		cancel: cancel,
+		root:   root,
+		fsys:   os.DirFS(root),
+		fn:     fn,
+		sem:    make(chan struct{}, f.workers-1),
+	}
+	if f.gitignore {
+		w.loadIgnore(".")
+	}
+	w.walk(".")
+	w.wg.Wait()
+
+	if w.err != nil {
+		return w.err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return w.walkErr
+}
+
+// walk はstartからfs.WalkDirでたどる
+// 空いているワーカーがあればサブディレクトリを別のゴルーチンに任せる
+func (w *walker) walk(start string) {
+	fs.WalkDir(w.fsys, start, func(p string, d fs.DirEntry, err error) error {
+		if w.ctx.Err() != nil {
+			return fs.SkipAll
+		}
+		if err != nil {
+			w.report(&fs.PathError{Op: "walk", Path: w.osPath(p), Err: unwrapPathError(err)})
+			return nil
+		}
+		if p == start {
+			// 起点は呼び出し元で処理済み
+			return nil
+		}
+		depth := strings.Count(p, "/") + 1
+		isDir := d.IsDir()
+		if w.maxDepth >= 0 && depth > w.maxDepth {
+			if isDir {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if w.ignored(p, isDir) {
+			if isDir {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if d.Type()&fs.ModeSymlink != 0 {
+			return w.symlink(p, d, depth)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			w.report(err)
+			return nil
+		}
+		w.emit(p, info, depth)
+		if !isDir {
+			return nil
+		}
+		if w.maxDepth >= 0 && depth >= w.maxDepth {
+			return fs.SkipDir
+		}
+		if w.gitignore {
+			w.loadIgnore(p)
+		}
+		select {
+		case w.sem <- struct{}{}:
+			w.wg.Add(1)
+			go func() {
+				defer w.wg.Done()
+				defer func() { <-w.sem }()
+				w.walk(p)
+			}()
+			return fs.SkipDir
+		default:
+			return nil
+		}
+	})
+}
+
+// symlink はシンボリックリンクをSymlinkPolicyに従って扱う
+func (w *walker) symlink(p string, d fs.DirEntry, depth int) error {
+	if w.maxDepth >= 0 && depth > w.maxDepth {
+		return nil
+	}
+	switch w.symlinks {
+	case SymlinkSkip:
+		return nil
+	case SymlinkList:
+		info, err := d.Info()
+		if err != nil {
+			w.report(err)
+			return nil
+		}
+		w.emit(p, info, depth)
+		return nil
+	}
+
+	info, err := fs.Stat(w.fsys, p)
+	if err != nil {
+		// リンク切れ
+		w.report(&fs.PathError{Op: "stat", Path: w.osPath(p), Err: unwrapPathError(err)})
+		return nil
+	}
+	if !info.IsDir() {
+		w.emit(p, info, depth)
+		return nil
+	}
+	if err := w.checkLoop(p); err != nil {
+		w.report(err)
+		return nil
+	}
+	w.emit(p, info, depth)
+	if w.maxDepth >= 0 && depth >= w.maxDepth {
+		return nil
+	}
+	if w.gitignore {
+		w.loadIgnore(p)
+	}
+	w.walk(p)
+	return nil
+}
+
+// checkLoop はリンク先がpの祖先（起点を含む）のどれかと同じディレクトリならErrSymlinkLoopを返す
+// 祖先もリンクをたどった実際のパスで比べるので、複数のリンクでできたループも見つかる
+func (w *walker) checkLoop(p string) error {
+	target, err := filepath.EvalSymlinks(w.osPath(p))
+	if err != nil {
+		return err
+	}
+	for dir := path.Dir(p); ; dir = path.Dir(dir) {
+		real, err := filepath.EvalSymlinks(w.osPath(dir))
+		if err != nil {
+			return err
+		}
+		if real == target {
+			return &fs.PathError{Op: "walk", Path: w.osPath(p), Err: ErrSymlinkLoop}
+		}
+		if dir == "." {
+			return nil
+		}
+	}
+}
+
+// ignored は-excludeと.gitignoreで除かれるかを返す
+func (w *walker) ignored(p string, isDir bool) bool {
+	if w.exclude.Match(p, isDir) {
+		return true
+	}
+	if !w.gitignore {
+		return false
+	}
+	if isDir && path.Base(p) == ".git" {
+		return true
+	}
+	// 上のディレクトリの.gitignoreから順に見て、最後にマッチしたものに従う
+	ignored := false
+	dir := "."
+	rel := p
+	for {
+		if m, ok := w.ignores.Load(dir); ok {
+			if matched, decided := m.(*Matcher).match(rel, isDir); decided {
+				ignored = matched
+			}
+		}
+		i := strings.IndexByte(rel, '/')
+		if i < 0 {
+			return ignored
+		}
+		dir = path.Join(dir, rel[:i])
+		rel = rel[i+1:]
+	}
+}
+
+func (w *walker) loadIgnore(dir string) {
+	m, err := readIgnoreFile(filepath.Join(w.osPath(dir), ".gitignore"))
+	if err != nil {
+		w.report(err)
+		return
+	}
+	if m != nil {
+		w.ignores.Store(dir, m)
+	}
+}
+
+// emit は条件に合えばfnを呼ぶ
+func (w *walker) emit(p string, info fs.FileInfo, depth int) {
+	if !w.matches(p, info) {
+		return
+	}
+	e := Entry{
+		Path:    w.osPath(p),
+		Type:    typeOf(info.Mode()),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Mode:    info.Mode(),
+		Depth:   depth,
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return
+	}
+	if err := w.fn(e); err != nil {
+		w.err = err
+		w.cancel()
+	}
+}
+
+func (w *walker) matches(p string, info fs.FileInfo) bool {
+	t := typeOf(info.Mode())
+	if len(w.types) > 0 && !w.types[t] {
+		return false
+	}
+	if len(w.names) > 0 {
+		ok := false
+		for _, g := range w.names {
+			if m, _ := path.Match(g, path.Base(p)); m {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if w.regex != nil && !w.regex.MatchString(p) {
+		return false
+	}
+	if w.include != nil && t != TypeDir && !w.include.Match(p, false) {
+		return false
+	}
+	if w.minSize > 0 || w.maxSize > 0 {
+		if t == TypeDir {
+			return false
+		}
+		if info.Size() < w.minSize || w.maxSize > 0 && info.Size() > w.maxSize {
+			return false
+		}
+	}
+	if !w.newer.IsZero() && !info.ModTime().After(w.newer) {
+		return false
+	}
+	if !w.older.IsZero() && !info.ModTime().Before(w.older) {
+		return false
+	}
+	return true
+}
+
+// report はエラーをOnErrorに渡すか、まとめて後で返す
+func (w *walker) report(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.onError != nil {
+		w.onError(err)
+		return
+	}
+	w.walkErr = errs.Append(w.walkErr, err)
+}
+
+// osPath はfsのパスを起点を付けたOSのパスにする
+func (w *walker) osPath(p string) string {
+	return filepath.Join(w.root, filepath.FromSlash(p))
+}
+
+// unwrapPathError はos.DirFSのパスの入ったエラーから元のエラーを取り出す
+func unwrapPathError(err error) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		return pe.Err
+	}
+	return err
+}
@@ -0,0 +1,377 @@
+package find_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/recipe-golang/4.command-line-tool/find"
+)
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// tree はテスト用のディレクトリを作る
+//
+//	root/
+//	  .gitignore      (*.log, build/, !keep.log)
+//	  main.go         10バイト、1日前
+//	  main_test.go
+//	  app.log
+//	  keep.log
+//	  build/out.bin   2000バイト
+//	  pkg/.gitignore  (gen.go)
+//	  pkg/a.go        10日前
+//	  pkg/gen.go
+//	  pkg/sub/b.go
+//	  .git/HEAD
+func tree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":     "*.log\nbuild/\n!keep.log\n",
+		"main.go":        "package a\n",
+		"main_test.go":   "package a\n",
+		"app.log":        "log\n",
+		"keep.log":       "log\n",
+		"build/out.bin":  strings.Repeat("x", 2000),
+		"pkg/.gitignore": "gen.go\n",
+		"pkg/a.go":       "package a\n",
+		"pkg/gen.go":     "package a\n",
+		"pkg/sub/b.go":   "package a\n",
+		".git/HEAD":      "ref\n",
+	}
+	for name, body := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, now, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chtimes(t, filepath.Join(root, "main.go"), now.AddDate(0, 0, -1))
+	chtimes(t, filepath.Join(root, "pkg", "a.go"), now.AddDate(0, 0, -10))
+	return root
+}
+
+func chtimes(t *testing.T, name string, tm time.Time) {
+	t.Helper()
+	if err := os.Chtimes(name, tm, tm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// collect はWalkで見つかったパスを起点からの相対パスにして並べて返す
+func collect(t *testing.T, root string, opts ...find.Option) ([]string, error) {
+	t.Helper()
+	f, err := find.New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	err = f.Walk(context.Background(), root, func(e find.Entry) error {
+		rel, err := filepath.Rel(root, e.Path)
+		if err != nil {
+			return err
+		}
+		got = append(got, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(got)
+	return got, err
+}
+
+func TestWalk(t *testing.T) {
+	t.Parallel()
+	root := tree(t)
+	files := find.WithType(find.TypeFile)
+	cases := map[string]struct {
+		opts []find.Option
+		want []string
+	}{
+		"name": {
+			opts: []find.Option{find.WithName("*.go")},
+			want: []string{"main.go", "main_test.go", "pkg/a.go", "pkg/gen.go", "pkg/sub/b.go"},
+		},
+		"names": {
+			opts: []find.Option{find.WithName("*.bin", "HEAD")},
+			want: []string{".git/HEAD", "build/out.bin"},
+		},
+		"regexp": {
+			opts: []find.Option{find.WithRegexp(regexp.MustCompile(`^pkg/.*\.go$`))},
+			want: []string{"pkg/a.go", "pkg/gen.go", "pkg/sub/b.go"},
+		},
+		"include": {
+			opts: []find.Option{files, find.WithInclude("/pkg/**/*.go", "!gen.go")},
+			want: []string{"pkg/a.go", "pkg/sub/b.go"},
+		},
+		"exclude": {
+			opts: []find.Option{files, find.WithExclude(".git/", "pkg/", "*.log", "*_test.go")},
+			want: []string{".gitignore", "build/out.bin", "main.go"},
+		},
+		"gitignore": {
+			opts: []find.Option{files, find.WithGitignore(true)},
+			want: []string{".gitignore", "keep.log", "main.go", "main_test.go", "pkg/.gitignore", "pkg/a.go", "pkg/sub/b.go"},
+		},
+		"dirs": {
+			opts: []find.Option{find.WithType(find.TypeDir)},
+			want: []string{".git", "build", "pkg", "pkg/sub"},
+		},
+		"size": {
+			opts: []find.Option{find.WithSize(1000, 0)},
+			want: []string{"build/out.bin"},
+		},
+		"max size": {
+			opts: []find.Option{find.WithSize(0, 4), find.WithName("*.log")},
+			want: []string{"app.log", "keep.log"},
+		},
+		"newer": {
+			opts: []find.Option{find.WithName("*.go"), find.WithModTime(now.AddDate(0, 0, -5), time.Time{})},
+			want: []string{"main.go", "main_test.go", "pkg/gen.go", "pkg/sub/b.go"},
+		},
+		"older": {
+			opts: []find.Option{files, find.WithModTime(time.Time{}, now.Add(-time.Hour))},
+			want: []string{"main.go", "pkg/a.go"},
+		},
+		"max depth": {
+			opts: []find.Option{find.WithMaxDepth(1), find.WithType(find.TypeDir)},
+			want: []string{".git", "build", "pkg"},
+		},
+		"max depth 2": {
+			opts: []find.Option{find.WithMaxDepth(2), find.WithName("*.go")},
+			want: []string{"main.go", "main_test.go", "pkg/a.go", "pkg/gen.go"},
+		},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			for _, workers := range []int{1, 4} {
+				got, err := collect(t, root, append(tt.opts, find.WithWorkers(workers))...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("workers=%d: want %v, got %v", workers, tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWalk_Entry(t *testing.T) {
+	t.Parallel()
+	root := tree(t)
+	f, err := find.New(find.WithName("out.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []find.Entry
+	err = f.Walk(context.Background(), root, func(e find.Entry) error {
+		got = append(got, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := find.Entry{Path: filepath.Join(root, "build", "out.bin"), Type: find.TypeFile, Size: 2000, ModTime: now, Mode: 0o644, Depth: 2}
+	if len(got) != 1 || !got[0].ModTime.Equal(now) {
+		t.Fatalf("want [%+v], got %+v", want, got)
+	}
+	got[0].ModTime = now
+	if got[0] != want {
+		t.Errorf("want %+v, got %+v", want, got[0])
+	}
+}
+
+func TestWalk_Symlinks(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	for _, dir := range []string{"a/x", "b"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "b", "f.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"a/to-b":   "../b",
+		"a/x/up":   "..",   // 祖先へのループ
+		"b/to-a":   "../a", // a/to-b/to-a/to-b...のループ
+		"a/broken": "nothing",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Skip("symlinks are not supported:", err)
+		}
+	}
+
+	cases := map[string]struct {
+		policy find.SymlinkPolicy
+		want   []string
+		loops  int
+		errs   int
+	}{
+		"list": {
+			policy: find.SymlinkList,
+			want:   []string{"a", "a/broken", "a/to-b", "a/x", "a/x/up", "b", "b/f.txt", "b/to-a"},
+		},
+		"skip": {
+			policy: find.SymlinkSkip,
+			want:   []string{"a", "a/x", "b", "b/f.txt"},
+		},
+		"follow": {
+			policy: find.SymlinkFollow,
+			want:   []string{"a", "a/to-b", "a/to-b/f.txt", "a/x", "b", "b/f.txt", "b/to-a", "b/to-a/x"},
+			// ループはa/to-b/to-a、a/x/up、b/to-a/to-b、b/to-a/x/up。リンク切れはa/brokenとb/to-a/broken
+			loops: 4,
+			errs:  6,
+		},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var reported []error
+			got, err := collect(t, root, find.WithSymlinks(tt.policy), find.WithOnError(func(err error) {
+				reported = append(reported, err)
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+			if len(reported) != tt.errs {
+				t.Errorf("want %d errors, got %v", tt.errs, reported)
+			}
+			loops := 0
+			for _, err := range reported {
+				if errors.Is(err, find.ErrSymlinkLoop) {
+					loops++
+				}
+			}
+			if loops != tt.loops {
+				t.Errorf("want %d loop errors, got %v", tt.loops, reported)
+			}
+		})
+	}
+}
+
+func TestWalk_Unreadable(t *testing.T) {
+	t.Parallel()
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+	root := tree(t)
+	locked := filepath.Join(root, "pkg")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0o755) })
+
+	// OnErrorがなければ最後までたどってからまとめて返す
+	got, err := collect(t, root, find.WithName("*.go"))
+	if !errors.Is(err, os.ErrPermission) {
+		t.Errorf("want %v, got %v", os.ErrPermission, err)
+	}
+	if want := []string{"main.go", "main_test.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestWalk_Stop(t *testing.T) {
+	t.Parallel()
+	root := tree(t)
+	f, err := find.New(find.WithWorkers(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := errors.New("stop")
+	n := 0
+	err = f.Walk(context.Background(), root, func(find.Entry) error {
+		n++
+		return stop
+	})
+	if !errors.Is(err, stop) || n != 1 {
+		t.Errorf("want %v after 1 call, got %v after %d calls", stop, err, n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := f.Walk(ctx, root, func(find.Entry) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestNew_Error(t *testing.T) {
+	t.Parallel()
+	cases := map[string]find.Option{
+		"name":    find.WithName("[a-"),
+		"include": find.WithInclude("a", "[z-a]"),
+		"type":    find.WithType("pipe"),
+		"size":    find.WithSize(10, 5),
+		"depth 0": find.WithMaxDepth(0),
+	}
+	for name, opt := range cases {
+		name, opt := name, opt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := find.New(opt); err == nil {
+				t.Error("want error")
+			}
+		})
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	t.Parallel()
+	m, err := find.ReadMatcher(strings.NewReader(`# comment
+*.o
+/root.txt
+doc/**/*.md
+tmp/
+!important.o
+\#hash
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		"glob":          {path: "a/b/c.o", want: true},
+		"negate":        {path: "x/important.o", want: false},
+		"anchored":      {path: "root.txt", want: true},
+		"anchored deep": {path: "a/root.txt", want: false},
+		"double star":   {path: "doc/a/b/c.md", want: true},
+		"double star 0": {path: "doc/c.md", want: true},
+		"other md":      {path: "src/c.md", want: false},
+		"dir only":      {path: "a/tmp", isDir: true, want: true},
+		"dir only file": {path: "a/tmp", want: false},
+		"escaped":       {path: "#hash", want: true},
+		"comment":       {path: "# comment", want: false},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := m.Match(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package find
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Matcher は.gitignoreと同じ書き方のパターンでパスを判定する
+//
+//   - 空行と#で始まる行は無視する
+//   - !で始まるパターンは前のパターンの結果を打ち消す
+//   - /で終わるパターンはディレクトリにだけマッチする
+//   - 途中か先頭に/を含むパターンは基準のディレクトリからの相対パス、含まないパターンはどの階層の名前にもマッチする
+//   - *と?は/以外、**は/を含む任意の文字列にマッチする
+//
+// 最後にマッチしたパターンが優先される
+type Matcher struct {
+	patterns []pattern
+}
+
+type pattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// NewMatcher はパターンの並びからMatcherを作る
+func NewMatcher(lines []string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, line := range lines {
+		p, ok, err := parsePattern(line)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			m.patterns = append(m.patterns, p)
+		}
+	}
+	return m, nil
+}
+
+// ReadMatcher はrから.gitignoreの形式でパターンを読み込む
+func ReadMatcher(r io.Reader) (*Matcher, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return NewMatcher(lines)
+}
+
+// readIgnoreFile はnameの.gitignoreを読み込む。ファイルがなければnilを返す
+func readIgnoreFile(name string) (*Matcher, error) {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadMatcher(f)
+}
+
+// Match はスラッシュ区切りの相対パスがパターンにマッチするか（除外されるか）を返す
+func (m *Matcher) Match(path string, isDir bool) bool {
+	matched, _ := m.match(path, isDir)
+	return matched
+}
+
+// match はマッチしたかと、どれかのパターンが当てはまったか（否定も含む）を返す
+func (m *Matcher) match(path string, isDir bool) (matched, decided bool) {
+	if m == nil {
+		return false, false
+	}
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(path) {
+			matched, decided = !p.negate, true
+		}
+	}
+	return matched, decided
+}
+
+func parsePattern(line string) (pattern, bool, error) {
+	// 末尾の空白は\でエスケープしていなければ無視する
+	s := strings.TrimRight(line, " \t")
+	if strings.HasSuffix(s, "\\") && len(s) < len(line) {
+		s += " "
+	}
+	if s == "" || strings.HasPrefix(s, "#") {
+		return pattern{}, false, nil
+	}
+	var p pattern
+	switch {
+	case strings.HasPrefix(s, "!"):
+		p.negate = true
+		s = s[1:]
+	case strings.HasPrefix(s, `\!`), strings.HasPrefix(s, `\#`):
+		s = s[1:]
+	}
+	if strings.HasSuffix(s, "/") {
+		p.dirOnly = true
+		s = strings.TrimRight(s, "/")
+	}
+	if s == "" {
+		return pattern{}, false, nil
+	}
+	anchored := strings.Contains(s, "/")
+	s = strings.TrimPrefix(s, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '*' && strings.HasPrefix(s[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(s[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(s[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := s[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(s):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(s[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return pattern{}, false, err
+	}
+	p.re = re
+	return p, true, nil
+}
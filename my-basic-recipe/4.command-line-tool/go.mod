@@ -0,0 +1,12 @@
+module golang/recipe-golang/4.command-line-tool
+
+go 1.21
+
+require (
+	golang.org/x/sys v0.15.0
+	golang.org/x/term v0.15.0
+	golang.org/x/text v0.14.0
+	golang/recipe-golang/6.error v0.0.0
+)
+
+replace golang/recipe-golang/6.error => ../6.error
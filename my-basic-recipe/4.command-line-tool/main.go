@@ -2,41 +2,43 @@ package main
 
 import (
 	"bufio"
-	"flag"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"strings"
-)
 
-//** flagパッケージ
-// 設定される変数のポインタを取得
-var msg = flag.String("msg", "デフォルト値", "説明")
-var n int
+	"golang/recipe-golang/4.command-line-tool/cli"
+)
 
-func init() {
-	// ポインタを指定して設定を予約
-	flag.IntVar(&n, "n", 1, "回数")
+func main() {
+	//** サブコマンドを持つコマンド */ ・・cliパッケージを使う（commands.go）
+	// サブコマンドごとにflag.FlagSetを持つので、フラグはinitでグローバルに登録しない
+	// help・completionの生成、環境変数からのフラグの値、必須のフラグを扱う
+	// 終了コードはerrs.ExitCodeで決まる（使い方の誤りは64）
+	//go run . repeat -msg=こんにちは -n=2 > こんにちはこんにちは
+	//go run . help repeat > repeatのヘルプ
+	//go run . completion bash > bashの補完スクリプト
+	os.Exit(cli.Main(context.Background(), newApp(), os.Args[1:]))
 }
 
-func main() {
+// runBasics はbasicsサブコマンドの処理
+func runBasics(ctx context.Context, c *cli.Context) (rerr error) {
 	//** プログラム引数の取得 */ os.Args
 	// プログラム引数が入った文字列型のスライス
 	// 要素のひとつめはプログラム名
 	fmt.Println(os.Args) // go run main.go hello > [/var/folders/_q/t_p_01392kzd_wdkjv6tm9dc0000gp/T/go-build805512576/b001/exe/main hello]
 
 	//** フラグ（オプション）を便利に扱うパッケージ */ flagパッケージ
-	// ここで実際に設定される
-	flag.Parse()
-	fmt.Println(strings.Repeat(*msg, n))
-	//go run main.go > 'デフォルト値'
-	//go run main.go -msg=こんにちは > こんにちは
-	//go run main.go -msg=こんにちは -n=2 >  こんにちはこんにちは
+	// フラグはサブコマンドごとのflag.FlagSetでパースされる（repeatCmdを参照）
+	//go run . repeat > 'デフォルト値'
+	//go run . repeat -msg=こんにちは > こんにちは
+	//go run . repeat -msg=こんにちは -n=2 >  こんにちはこんにちは
 
 	//flagパッケージとプログラム引数 >> flag.Args関数を用いる
 	// os.Argsだとフラグも含まれる
-	// flag.Args関数はフラグの分は除外される
-	fmt.Println(flag.Args()) //go run main.go -msg=こんにちは -n=2 やぁ > [やぁ]
+	// flag.Args関数はフラグの分は除外される。cliではc.Argsに入る
+	fmt.Println(c.Args) //go run . basics やぁ > [やぁ]
 
 	// ** 標準入力と標準出力 */ osパッケージで提供されている*os.File型の変数
 	// さまざまな関数やメソッドの引数として渡せる
@@ -71,8 +73,7 @@ func main() {
 	if err != nil {
 		//もしsf, err := os.Open("./b.txt")として存在しないファイルを開こうとしたら
 		fmt.Println(err) //open ./b.txt: no such file or directory
-		return
-		//return err
+		return err
 	}
 	// 関数終了時に閉じる
 	defer sf.Close()
@@ -81,16 +82,21 @@ func main() {
 	df, err := os.Create("./b.txt") //./b.txtがつくられる
 	if err != nil {
 		fmt.Println(err)
-		return
-		//return err
+		return err
 	}
 	// 関数終了時に閉じる
+	// 書き込んだファイルはCloseでエラーになることがあるので、名前付きの戻り値で返す
 	defer func() {
-		if err := df.Close(); err != nil {
-			return
-			//rerr = err
+		if err := df.Close(); err != nil && rerr == nil {
+			rerr = err
 		}
 	}()
+	// 中身をコピーする
+	if _, err := io.Copy(df, sf); err != nil {
+		return err
+	}
+	// モードと更新時刻を引き継ぎ、書きかけのファイルを残さないならfileutil.Copyを使う（tool cpも同じ）
+	// fileutil.Copy(ctx, "./a.txt", "./b.txt", fileutil.WithConflict(fileutil.Overwrite))
 
 	// ** 関数の遅延実行 */ defer
 	// 	関数終了時に実行される
@@ -110,9 +116,9 @@ func main() {
 
 	// ** 入出力関連 */
 	//1行ずつ読み込む > bufio.Scannerを使用する
-	// 標準入力から読み込む
+	// 標準入力から読み込む（長い行や文字コードも扱って行を探すならtool searchを使う）
 	scanner := bufio.NewScanner(os.Stdin)
-	// 1行ずつ読み込んで繰り返す
+	// 1行ずつ読み込んで繰り返す（行の編集や履歴を使って対話的にコマンドを実行するならtool shellを使う）
 	// for scanner.Scan() {
 	// 	if err := scanner.Err(); err != nil {
 	// 		fmt.Fprintln(os.Stderr, err)
@@ -124,7 +130,7 @@ func main() {
 	// まとめてエラー処理をする
 	if err := scanner.Err(); err != nil {
 		fmt.Fprintln(os.Stderr, "読み込みに失敗しました:", err)
-		return
+		return err
 	}
 
 	//** ファイルパスを扱う */ ・・path/filepathパッケージを使う
@@ -143,6 +149,11 @@ func main() {
 	err2 := filepath.Walk(".",
 		func(path string, info os.FileInfo, err2 error) error {
 			//fmt.Println(path,info,err2)//dir, <nil>, lstat dir: no such file or directory
+			if err2 != nil {
+				// 読めないディレクトリがあっても止めずに続ける（並列に探すならtool findを使う）
+				fmt.Fprintln(c.Stderr, err2)
+				return nil
+			}
 			if filepath.Ext(path) == ".go" {
 				fmt.Println(path) //main.go
 			}
@@ -150,7 +161,7 @@ func main() {
 		})
 	if err2 != nil {
 		fmt.Print(err2)
-		return
+		return err2
 	}
-
+	return nil
 }
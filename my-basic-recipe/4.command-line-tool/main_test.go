@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/recipe-golang/4.command-line-tool/cli"
+	"golang/recipe-golang/6.error/errs"
+)
+
+func TestRepeat(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		args []string
+		code int
+		want string
+	}{
+		"default":  {args: []string{"repeat"}, want: "デフォルト値\n"},
+		"msg":      {args: []string{"repeat", "-msg=こんにちは"}, want: "こんにちは\n"},
+		"msg n":    {args: []string{"repeat", "-msg=こんにちは", "-n=2"}, want: "こんにちはこんにちは\n"},
+		"negative": {args: []string{"repeat", "-n=-1"}, code: errs.ExitUsage},
+		"args":     {args: []string{"repeat", "やぁ"}, code: errs.ExitUsage},
+		"path":     {args: []string{"path", "ext", "dir/main.go"}, want: ".go\n"},
+		"rel":      {args: []string{"path", "rel", "-base", "/a", "/a/b/c"}, want: "b/c\n"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var stdout, stderr bytes.Buffer
+			code := cli.Exec(context.Background(), newApp(), tt.args, nil, &stdout, &stderr)
+			if code != tt.code {
+				t.Fatalf("want exit code %d, got %d (%s)", tt.code, code, stderr.String())
+			}
+			if stdout.String() != tt.want {
+				t.Errorf("want %q, got %q", tt.want, stdout.String())
+			}
+		})
+	}
+}
+
+func TestRepeat_Env(t *testing.T) {
+	t.Setenv("REPEAT_N", "3")
+	var stdout, stderr bytes.Buffer
+	if code := cli.Exec(context.Background(), newApp(), []string{"repeat", "-msg=や"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("want exit code 0, got %d (%s)", code, stderr.String())
+	}
+	if got, want := stdout.String(), "ややや\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestFind(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	for name, body := range map[string]string{
+		"main.go":        "package main\n",
+		"big.bin":        strings.Repeat("x", 2048),
+		"sub/a.go":       "package sub\n",
+		"sub/.gitignore": "gen.go\n",
+		"sub/gen.go":     "package sub\n",
+	} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().AddDate(0, 0, -3)
+	if err := os.Chtimes(filepath.Join(root, "main.go"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	join := func(names ...string) string {
+		var b strings.Builder
+		for _, n := range names {
+			b.WriteString(filepath.Join(root, filepath.FromSlash(n)) + "\n")
+		}
+		return b.String()
+	}
+
+	cases := map[string]struct {
+		args []string
+		code int
+		want string
+	}{
+		"name":      {args: []string{"-name", "*.go", "-sort"}, want: join("main.go", "sub/a.go", "sub/gen.go")},
+		"gitignore": {args: []string{"-gitignore", "-type", "f", "-exclude", ".gitignore", "-sort"}, want: join("big.bin", "main.go", "sub/a.go")},
+		"size":      {args: []string{"-min-size", "1K"}, want: join("big.bin")},
+		"size kib":  {args: []string{"-min-size", "2KiB"}, want: join("big.bin")},
+		"size kb":   {args: []string{"-type", "f", "-min-size", "2KB", "-max-size", "2.1KB"}, want: join("big.bin")},
+		"size 1024": {args: []string{"-type", "f", "-min-size", "2.01K"}},
+		"newer":     {args: []string{"-name", "*.go", "-newer", "2d", "-sort"}, want: join("sub/a.go", "sub/gen.go")},
+		"older":     {args: []string{"-type", "f", "-older", "48h"}, want: join("main.go")},
+		"maxdepth":  {args: []string{"-maxdepth", "1", "-type", "d"}, want: join("sub")},
+		"depth 0":   {args: []string{"-maxdepth", "0"}, code: errs.ExitUsage},
+		"regex":     {args: []string{"-regex", `^sub/a`}, want: join("sub/a.go")},
+		"nul":       {args: []string{"-name", "*.go", "-maxdepth", "1", "-0"}, want: filepath.Join(root, "main.go") + "\x00"},
+		"bad type":  {args: []string{"-type", "x"}, code: errs.ExitUsage},
+		"bad size":  {args: []string{"-min-size", "big"}, code: errs.ExitUsage},
+		"bad time":  {args: []string{"-newer", "yesterday"}, code: errs.ExitUsage},
+		"bad name":  {args: []string{"-name", "[a-"}, code: errs.ExitUsage},
+		"bad links": {args: []string{"-symlinks", "maybe"}, code: errs.ExitUsage},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var stdout, stderr bytes.Buffer
+			args := append(append([]string{"find"}, tt.args...), root)
+			code := cli.Exec(context.Background(), newApp(), args, nil, &stdout, &stderr)
+			if code != tt.code {
+				t.Fatalf("want exit code %d, got %d (%s)", tt.code, code, stderr.String())
+			}
+			if stdout.String() != tt.want {
+				t.Errorf("want %q, got %q", tt.want, stdout.String())
+			}
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+		var stdout, stderr bytes.Buffer
+		code := cli.Exec(context.Background(), newApp(), []string{"find", "-name", "big.bin", "-format", "json", root}, nil, &stdout, &stderr)
+		if code != 0 {
+			t.Fatalf("want exit code 0, got %d (%s)", code, stderr.String())
+		}
+		var e struct {
+			Path  string `json:"path"`
+			Type  string `json:"type"`
+			Size  int64  `json:"size"`
+			Depth int    `json:"depth"`
+		}
+		if err := json.Unmarshal(stdout.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Path != filepath.Join(root, "big.bin") || e.Type != "file" || e.Size != 2048 || e.Depth != 1 {
+			t.Errorf("unexpected entry: %+v", e)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		t.Parallel()
+		var stdout, stderr bytes.Buffer
+		code := cli.Exec(context.Background(), newApp(), []string{"find", filepath.Join(root, "none"), root}, nil, &stdout, &stderr)
+		if code != 1 || !strings.Contains(stderr.String(), "none") {
+			t.Errorf("want exit code 1 with the error, got %d (%s)", code, stderr.String())
+		}
+		// 読めない起点があっても残りはたどる
+		if !strings.Contains(stdout.String(), filepath.Join(root, "main.go")) {
+			t.Errorf("want the other root to be walked, got %q", stdout.String())
+		}
+	})
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	for name, body := range map[string]string{
+		"a.txt":     "hello\nＨＥＬＬＯ world\nbye\n",
+		"sub/b.txt": "one\ntwo\nhello again\nthree\nfour\nfive\nhello end\n",
+		"sub/c.bin": "\x00\x01hello\n",
+		"skip.log":  "hello log\n",
+	} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, b, c := filepath.Join(root, "a.txt"), filepath.Join(root, "sub", "b.txt"), filepath.Join(root, "sub", "c.bin")
+
+	cases := map[string]struct {
+		args   []string
+		stdin  string
+		code   int
+		want   string
+		stderr string
+	}{
+		"dir": {
+			args: []string{"-n", "-exclude", "*.log", "hello", root},
+			want: a + ":1:hello\n" + b + ":3:hello again\n" + b + ":7:hello end\nBinary file " + c + " matches\n",
+		},
+		"ignore case": {args: []string{"-i", "hello", a}, want: "hello\nＨＥＬＬＯ world\n"},
+		"word":        {args: []string{"-w", "-F", "hello end", a, b}, want: b + ":hello end\n"},
+		"count":       {args: []string{"-c", "-name", "*.txt", "hello", root}, want: a + ":1\n" + b + ":2\n"},
+		"list":        {args: []string{"-l", "-i", "world", root}, want: a + "\n"},
+		"context": {
+			args: []string{"-C", "1", "-n", "hello", b, a},
+			want: b + "-2-two\n" + b + ":3:hello again\n" + b + "-4-three\n--\n" + b + "-6-five\n" + b + ":7:hello end\n--\n" + a + ":1:hello\n" + a + "-2-ＨＥＬＬＯ world\n",
+		},
+		"invert":   {args: []string{"-v", "-m", "1", "hello", a}, want: "ＨＥＬＬＯ world\n"},
+		"stdin":    {args: []string{"-H", "x+", "-"}, stdin: "axxb\nc\n", want: "(standard input):axxb\n"},
+		"color":    {args: []string{"-color", "always", "-n", "ll", a}, want: "\x1b[32m1\x1b[0m\x1b[36m:\x1b[0mhe\x1b[1;31mll\x1b[0mo\n"},
+		"text":     {args: []string{"-a", "hello", c}, want: "\x00\x01hello\n"},
+		"no match": {args: []string{"nothing", root}, code: errs.ExitFailure},
+		"missing":  {args: []string{"hello", filepath.Join(root, "none"), a}, code: errs.ExitFailure, want: a + ":hello\n", stderr: "no such file"},
+		"pattern":  {args: []string{}, code: errs.ExitUsage, stderr: "missing pattern"},
+		"bad re":   {args: []string{"(a"}, code: errs.ExitUsage, stderr: "missing closing )"},
+		"bad enc":  {args: []string{"-encoding", "x", "a"}, code: errs.ExitUsage, stderr: "unknown encoding"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"search"}, tt.args...)
+			code := cli.Exec(context.Background(), newApp(), args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.code {
+				t.Fatalf("want exit code %d, got %d (%s)", tt.code, code, stderr.String())
+			}
+			if stdout.String() != tt.want {
+				t.Errorf("want %q, got %q", tt.want, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("want %q in stderr, got %q", tt.stderr, stderr.String())
+			}
+			if tt.stderr == "" && stderr.Len() > 0 {
+				t.Errorf("want no message, got %q", stderr.String())
+			}
+		})
+	}
+}
+
+func TestCpMv(t *testing.T) {
+	t.Parallel()
+	setup := func(t *testing.T) string {
+		t.Helper()
+		dir := t.TempDir()
+		for name, body := range map[string]string{"a.txt": "a", "b.txt": "b", "src/c.txt": "c", "out/b.txt": "old"} {
+			p := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+	cases := map[string]struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+		files  map[string]string // 実行後のファイルの中身。""はファイルがないこと
+	}{
+		"cp":           {args: []string{"cp", "a.txt", "c.txt"}, files: map[string]string{"a.txt": "a", "c.txt": "a"}},
+		"cp into dir":  {args: []string{"cp", "a.txt", "out"}, files: map[string]string{"out/a.txt": "a"}},
+		"cp conflict":  {args: []string{"cp", "b.txt", "out"}, code: errs.ExitFailure, stderr: "destination already exists", files: map[string]string{"out/b.txt": "old"}},
+		"cp force":     {args: []string{"cp", "-f", "a.txt", "b.txt", "out"}, files: map[string]string{"out/a.txt": "a", "out/b.txt": "b"}},
+		"cp no clob":   {args: []string{"cp", "-n", "-v", "a.txt", "b.txt", "out"}, stdout: "copy a.txt -> out/a.txt\nskip b.txt -> out/b.txt\n", files: map[string]string{"out/b.txt": "old"}},
+		"cp dir":       {args: []string{"cp", "src", "dst"}, code: errs.ExitFailure, stderr: "use WithRecursive"},
+		"cp recursive": {args: []string{"cp", "-r", "src", "out"}, files: map[string]string{"out/src/c.txt": "c", "src/c.txt": "c"}},
+		"cp dry run":   {args: []string{"cp", "-dry-run", "-r", "src", "dst"}, stdout: "mkdir src -> dst\ncopy src/c.txt -> dst/c.txt\n", files: map[string]string{"dst/c.txt": ""}},
+		"cp flags":     {args: []string{"cp", "-n", "-f", "a.txt", "c.txt"}, code: errs.ExitUsage, stderr: "mutually exclusive"},
+		"cp not dir":   {args: []string{"cp", "a.txt", "b.txt", "c.txt"}, code: errs.ExitUsage, stderr: "is not a directory"},
+		"cp args":      {args: []string{"cp", "a.txt"}, code: errs.ExitUsage, stderr: "want source and destination"},
+		"mv":           {args: []string{"mv", "a.txt", "c.txt"}, files: map[string]string{"a.txt": "", "c.txt": "a"}},
+		"mv conflict":  {args: []string{"mv", "b.txt", "out"}, code: errs.ExitFailure, files: map[string]string{"b.txt": "b", "out/b.txt": "old"}},
+		"mv update":    {args: []string{"mv", "-u", "-lock", "tool.lock", "b.txt", "out"}, files: map[string]string{"b.txt": "b", "out/b.txt": "old"}},
+		"mv dir":       {args: []string{"mv", "-v", "src", "out"}, stdout: "rename src -> out/src\n", files: map[string]string{"src/c.txt": "", "out/src/c.txt": "c"}},
+		"mv dry run":   {args: []string{"mv", "-dry-run", "a.txt", "c.txt"}, stdout: "rename a.txt -> c.txt\n", files: map[string]string{"a.txt": "a", "c.txt": ""}},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			dir := setup(t)
+			// 更新時刻をそろえて-uでは上書きしないようにする
+			old := time.Now().Add(-time.Hour)
+			os.Chtimes(filepath.Join(dir, "b.txt"), old, old)
+			// 引数のパスはdirからの相対パスとして書いておく
+			args := append([]string(nil), tt.args...)
+			for i, a := range args {
+				if i > 0 && !strings.HasPrefix(a, "-") {
+					args[i] = filepath.Join(dir, a)
+				}
+			}
+			var stdout, stderr bytes.Buffer
+			code := cli.Exec(context.Background(), newApp(), args, nil, &stdout, &stderr)
+			if code != tt.code {
+				t.Fatalf("want exit code %d, got %d (%s)", tt.code, code, stderr.String())
+			}
+			if got := strings.ReplaceAll(stdout.String(), dir+string(filepath.Separator), ""); got != tt.stdout {
+				t.Errorf("want %q, got %q", tt.stdout, got)
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("want %q in stderr, got %q", tt.stderr, stderr.String())
+			}
+			for name, want := range tt.files {
+				b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+				if want == "" {
+					if !errors.Is(err, os.ErrNotExist) {
+						t.Errorf("%s: want no file, got %v", name, err)
+					}
+					continue
+				}
+				if string(b) != want {
+					t.Errorf("%s: want %q, got %q (%v)", name, want, b, err)
+				}
+			}
+		})
+	}
+}
+
+func TestShell(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		stdin  string
+		code   int
+		want   string
+		stderr string
+	}{
+		"commands":   {stdin: "repeat -msg=や -n 2\npath ext 'dir/a b.go'\n", want: "やや\n.go\n"},
+		"multi line": {stdin: "path join a \\\n  b\n", want: filepath.Join("a", "b") + "\n"},
+		"exit":       {stdin: "repeat -n=-1\nexit\n", code: errs.ExitUsage, stderr: "must not be negative"},
+		"no shell":   {stdin: "shell\n", code: errs.ExitUsage, stderr: `unknown command "shell"`},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var stdout, stderr bytes.Buffer
+			code := cli.Exec(context.Background(), newApp(), []string{"shell", "-history", ""}, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.code {
+				t.Fatalf("want exit code %d, got %d (%s)", tt.code, code, stderr.String())
+			}
+			if stdout.String() != tt.want {
+				t.Errorf("want %q, got %q", tt.want, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("want stderr containing %q, got %q", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
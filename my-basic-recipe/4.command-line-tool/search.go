@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang/recipe-golang/4.command-line-tool/cli"
+	"golang/recipe-golang/4.command-line-tool/find"
+	"golang/recipe-golang/4.command-line-tool/search"
+	"golang/recipe-golang/6.error/errs"
+)
+
+// エスケープシーケンスの色
+const (
+	colorPath  = "\x1b[35m"
+	colorNum   = "\x1b[32m"
+	colorSep   = "\x1b[36m"
+	colorMatch = "\x1b[1;31m"
+	colorReset = "\x1b[0m"
+)
+
+// searchCmd はgrepのようにファイルの中身を探す
+//
+//	tool search -i -n todo .
+//	tool search -F -w -C 2 -name '*.go' 'err != nil'
+//	tool search -encoding shift_jis 東京 data.csv
+//	tool search -c -gitignore TODO
+func searchCmd() *cli.Command {
+	var (
+		names, excludes                    stringsFlag
+		literal, word, ignoreCase, invert  bool
+		count, list, number, text, fname   bool
+		gitignore                          bool
+		before, after, around, maxCount, j int
+		encoding, color                    string
+	)
+	return &cli.Command{
+		Name:  "search",
+		Usage: "pattern [path...]",
+		Short: "ファイルの中身を探す",
+		Long: `ファイルの中身を行ごとに探す
+
+ディレクトリはたどってファイルを探す。pathを省くと.を、-を指定すると標準入力を探す
+-iは全角英数字と半角カナも同じ文字として扱う
+文字コードはファイルごとにUTF-8・UTF-16・Shift_JIS・EUC-JP・ISO-2022-JPから推測する
+ファイルは並行して探し、指定した順（ディレクトリの中はパスの順）に出力する
+一致した行があれば0、なければ1で終了する`,
+		Flags: func(fs *flag.FlagSet) {
+			names, excludes = nil, nil
+			fs.BoolVar(&literal, "F", false, "パターンを正規表現ではなく文字列として扱う")
+			fs.BoolVar(&word, "w", false, "単語として一致する行だけにする")
+			fs.BoolVar(&ignoreCase, "i", false, "大文字小文字と全角半角を区別しない")
+			fs.BoolVar(&invert, "v", false, "一致しない行を出力する")
+			fs.BoolVar(&count, "c", false, "ファイルごとに一致した行の数を出力する")
+			fs.BoolVar(&list, "l", false, "一致したファイルの名前だけを出力する")
+			fs.BoolVar(&number, "n", false, "行番号を出力する")
+			fs.BoolVar(&fname, "H", false, "ファイルが1つでもファイル名を出力する")
+			fs.BoolVar(&text, "a", false, "バイナリファイルもテキストとして出力する")
+			fs.IntVar(&before, "B", 0, "一致した行の前の`lines`行も出力する")
+			fs.IntVar(&after, "A", 0, "一致した行の後の`lines`行も出力する")
+			fs.IntVar(&around, "C", 0, "一致した行の前後の`lines`行も出力する")
+			fs.IntVar(&maxCount, "m", 0, "ファイルごとに`num`行見つけたらやめる")
+			fs.StringVar(&encoding, "encoding", "auto", "文字コード（autoは推測する）")
+			fs.StringVar(&color, "color", "auto", "色を付けるか `auto|always|never`")
+			fs.IntVar(&j, "j", runtime.NumCPU(), "並行して探すファイルの数")
+			fs.Var(&names, "name", "ディレクトリの中で探すファイルの名前の`glob`（何度でも指定できる）")
+			fs.Var(&excludes, "exclude", "除く`pattern`（.gitignoreの書き方、何度でも指定できる）")
+			fs.BoolVar(&gitignore, "gitignore", false, ".gitignoreに従って除く")
+		},
+		Env: map[string]string{"color": "SEARCH_COLOR"},
+		Run: func(ctx context.Context, c *cli.Context) error {
+			if len(c.Args) == 0 {
+				return cli.Usagef("missing pattern")
+			}
+			if around > 0 {
+				before, after = max(before, around), max(after, around)
+			}
+			mode := search.Regexp
+			if literal {
+				mode = search.Literal
+			}
+			s, err := search.New(c.Args[0],
+				search.WithMode(mode),
+				search.WithIgnoreCase(ignoreCase),
+				search.WithWord(word),
+				search.WithInvert(invert),
+				search.WithContext(before, after),
+				search.WithMaxCount(maxCount),
+				search.WithBinary(text),
+				search.WithEncoding(encoding),
+			)
+			if err != nil {
+				return cli.Usagef("%v", err)
+			}
+			p := &printer{
+				w:       bufio.NewWriter(c.Stdout),
+				number:  number,
+				context: before > 0 || after > 0,
+				invert:  invert,
+			}
+			defer p.w.Flush()
+			switch color {
+			case "auto":
+				p.color = isTerminal(c.Stdout) && os.Getenv("NO_COLOR") == ""
+			case "always":
+				p.color = true
+			case "never":
+			default:
+				return cli.Usagef("-color: unknown value %q", color)
+			}
+
+			paths := c.Args[1:]
+			if len(paths) == 0 {
+				paths = []string{"."}
+			}
+			failed := false
+			report := func(err error) {
+				failed = true
+				p.w.Flush()
+				fmt.Fprintln(c.Stderr, "tool search:", err)
+			}
+			files, dirs, err := searchTargets(ctx, paths, find.WithName(names...), find.WithExclude(excludes...), find.WithGitignore(gitignore), find.WithOnError(report))
+			if err != nil {
+				return errs.Invalid.Wrap(err, "")
+			}
+			p.filename = fname || dirs || len(files) > 1
+
+			matched := false
+			emit := func(res *search.Result) error {
+				if res.Err != nil {
+					report(res.Err)
+					return nil
+				}
+				if res.Count > 0 {
+					matched = true
+				}
+				return p.print(res, count, list)
+			}
+			start := 0
+			for i, name := range files {
+				if name != "-" {
+					continue
+				}
+				// 標準入力は並行して探すファイルとは別に、指定した位置で探す
+				if err := s.SearchFiles(ctx, files[start:i], j, emit); err != nil {
+					return err
+				}
+				in := c.Stdin
+				if in == nil {
+					in = strings.NewReader("")
+				}
+				res, err := s.Search(in)
+				res.Path, res.Err = "(standard input)", err
+				if err := emit(res); err != nil {
+					return err
+				}
+				start = i + 1
+			}
+			if err := s.SearchFiles(ctx, files[start:], j, emit); err != nil {
+				return err
+			}
+			if failed {
+				return errs.New("some files could not be read")
+			}
+			if !matched {
+				return cli.Exit(errs.ExitFailure)
+			}
+			return nil
+		},
+	}
+}
+
+// searchTargets はpathsから探すファイルを集める。ディレクトリはたどってパスの順に並べる
+// dirsはディレクトリを含んでいたかを返す
+func searchTargets(ctx context.Context, paths []string, opts ...find.Option) (files []string, dirs bool, err error) {
+	f, err := find.New(append(opts, find.WithType(find.TypeFile))...)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, p := range paths {
+		if info, err := os.Stat(p); p == "-" || err != nil || !info.IsDir() {
+			// 読めないファイルはSearchFileで報告する
+			files = append(files, p)
+			continue
+		}
+		dirs = true
+		var found []string
+		err := f.Walk(ctx, p, func(e find.Entry) error {
+			found = append(found, e.Path)
+			return nil
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, dirs, nil
+}
+
+// printer はgrepと同じ形式で結果を出力する
+type printer struct {
+	w        *bufio.Writer
+	filename bool
+	number   bool
+	context  bool
+	invert   bool
+	color    bool
+	printed  bool // 前後の行の区切りの--を出すため、何か出力したか
+}
+
+func (p *printer) print(res *search.Result, count, list bool) error {
+	switch {
+	case list:
+		if res.Count > 0 {
+			p.paint(colorPath, res.Path)
+			p.w.WriteByte('\n')
+		}
+	case count:
+		if p.filename {
+			p.paint(colorPath, res.Path)
+			p.paint(colorSep, ":")
+		}
+		fmt.Fprintln(p.w, res.Count)
+	case res.Binary && len(res.Lines) == 0:
+		if res.Count > 0 {
+			fmt.Fprintf(p.w, "Binary file %s matches\n", res.Path)
+		}
+	default:
+		last := -1
+		for _, l := range res.Lines {
+			if p.context && p.printed && l.Num != last+1 {
+				p.paint(colorSep, "--")
+				p.w.WriteByte('\n')
+			}
+			p.line(res.Path, l)
+			p.printed, last = true, l.Num
+		}
+	}
+	return p.w.Flush()
+}
+
+func (p *printer) line(path string, l search.Line) {
+	sep := ":"
+	if l.Context {
+		sep = "-"
+	}
+	if p.filename {
+		p.paint(colorPath, path)
+		p.paint(colorSep, sep)
+	}
+	if p.number {
+		p.paint(colorNum, strconv.Itoa(l.Num))
+		p.paint(colorSep, sep)
+	}
+	pos := 0
+	if !p.invert {
+		for _, m := range l.Matches {
+			if m[0] == m[1] {
+				continue
+			}
+			p.w.WriteString(l.Text[pos:m[0]])
+			p.paint(colorMatch, l.Text[m[0]:m[1]])
+			pos = m[1]
+		}
+	}
+	p.w.WriteString(l.Text[pos:])
+	p.w.WriteByte('\n')
+}
+
+func (p *printer) paint(color, s string) {
+	if p.color {
+		p.w.WriteString(color + s + colorReset)
+		return
+	}
+	p.w.WriteString(s)
+}
+
+// isTerminal はwが端末かを返す
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
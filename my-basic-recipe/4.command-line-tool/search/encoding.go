@@ -0,0 +1,97 @@
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// lookupEncoding は名前から文字コードを返す。"auto"のときはnilを返す
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	if name == "" || name == "auto" {
+		return nil, nil
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("search: unknown encoding %q", name)
+	}
+	return enc, nil
+}
+
+// detect はファイルの先頭のバイト列から文字コードを推測し、名前と一緒に返す
+// UTF-8のときはencはnilで、変換しない。バイナリファイルと判断したときはbinaryがtrue
+//
+//   - BOMがあればUTF-8かUTF-16
+//   - NUL文字を含めばバイナリ
+//   - ESC $ BなどのエスケープシーケンスがあればISO-2022-JP
+//   - UTF-8として正しければUTF-8
+//   - それ以外はShift_JISとEUC-JPで変換して、変換できない文字が少ないほう
+//   - どちらでも変換できない文字が多ければバイナリ
+func detect(head []byte) (enc encoding.Encoding, name string, binary bool) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		return unicode.UTF8BOM, "utf-8", false
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}), bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM), "utf-16", false
+	}
+	// UTF-16のBOMがないのにNUL文字を含むものはバイナリとして扱う
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, "", true
+	}
+	if isASCII(head) {
+		if bytes.Contains(head, []byte("\x1b$B")) || bytes.Contains(head, []byte("\x1b$@")) {
+			return japanese.ISO2022JP, "iso-2022-jp", false
+		}
+		return nil, "utf-8", false
+	}
+	if validUTF8(head) {
+		return nil, "utf-8", false
+	}
+	enc, name = japanese.ShiftJIS, "shift_jis"
+	bad, total := invalidRunes(japanese.ShiftJIS, head)
+	if euc, eucTotal := invalidRunes(japanese.EUCJP, head); euc < bad {
+		enc, name, bad, total = japanese.EUCJP, "euc-jp", euc, eucTotal
+	}
+	// 末尾で切れた1文字は許し、それ以上変換できない文字が5%を超えれば日本語のテキストではない
+	if bad > 1 && bad*20 > total {
+		return nil, "", true
+	}
+	return enc, name, false
+}
+
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// validUTF8 は途中で切れた末尾の文字を除いてUTF-8として正しいかを返す
+func validUTF8(b []byte) bool {
+	for i := 0; i < utf8.UTFMax && len(b) > 0; i++ {
+		if utf8.Valid(b) {
+			return true
+		}
+		if b[len(b)-1] < utf8.RuneSelf {
+			return false
+		}
+		b = b[:len(b)-1]
+	}
+	return utf8.Valid(b)
+}
+
+// invalidRunes はencで変換できなかった文字の数と、変換した文字の数を返す
+func invalidRunes(enc encoding.Encoding, b []byte) (bad, total int) {
+	out, err := enc.NewDecoder().Bytes(b)
+	if err != nil {
+		return len(b), len(b)
+	}
+	return bytes.Count(out, []byte(string(utf8.RuneError))), utf8.RuneCount(out)
+}
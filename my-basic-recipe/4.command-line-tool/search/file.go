@@ -0,0 +1,200 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/transform"
+)
+
+// binaryPeek はバイナリファイルかと文字コードを判定するために読む先頭のバイト数
+const binaryPeek = 8 << 10
+
+// maxLine は1行の最大のバイト数
+const maxLine = 16 << 20
+
+// Line は結果の1行
+type Line struct {
+	// Num は1から始まる行番号
+	Num  int
+	Text string
+	// Matches は一致した部分のバイト位置。前後の行では空
+	Matches [][2]int
+	// Context は一致した行ではなく前後の行のときtrue
+	Context bool
+}
+
+// Result は1つのファイルを探した結果
+type Result struct {
+	Path string
+	// Lines は一致した行と前後の行を行番号の順に並べたもの。バイナリファイルでは空
+	Lines []Line
+	// Count は一致した行の数
+	Count int
+	// Binary はNUL文字を含むか、どの文字コードとしても読めないバイナリファイルだったときtrue
+	Binary bool
+	// Encoding は読み込んだ文字コードの名前
+	Encoding string
+	// Err はファイルを読めなかったときのエラー
+	Err error
+}
+
+// Search はrを行ごとに探す
+func (s *Searcher) Search(r io.Reader) (*Result, error) {
+	res := &Result{}
+	br := bufio.NewReaderSize(r, binaryPeek)
+	head, err := br.Peek(binaryPeek)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return res, err
+	}
+
+	enc, err := lookupEncoding(s.encoding)
+	if err != nil {
+		return res, err
+	}
+	var in io.Reader = br
+	if enc == nil {
+		enc, res.Encoding, res.Binary = detect(head)
+	} else {
+		res.Encoding = s.encoding
+	}
+	if enc != nil {
+		in = transform.NewReader(br, enc.NewDecoder())
+	}
+	keep := !res.Binary || s.binary
+
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 64<<10), maxLine)
+	var (
+		before []Line // まだ出していない直前の行
+		after  int    // これから含める後ろの行の数
+		last   int    // Linesに入れた最後の行番号
+	)
+	for num := 1; sc.Scan(); num++ {
+		// WithMaxCountの数だけ見つけたら、後ろの行を含め終えたところでやめる
+		done := s.maxCount > 0 && res.Count >= s.maxCount
+		if done && (!keep || after == 0) {
+			break
+		}
+		text := strings.TrimSuffix(sc.Text(), "\r")
+		var matches [][2]int
+		if !done {
+			matches = s.Find(text)
+			if s.invert {
+				if matches != nil {
+					matches = nil
+				} else {
+					matches = [][2]int{}
+				}
+			}
+		}
+		if matches == nil {
+			if !keep {
+				continue
+			}
+			l := Line{Num: num, Text: text, Context: true}
+			if after > 0 {
+				res.Lines = append(res.Lines, l)
+				last = num
+				after--
+			} else if s.before > 0 {
+				before = append(before, l)
+				if len(before) > s.before {
+					before = before[1:]
+				}
+			}
+			continue
+		}
+
+		res.Count++
+		if keep {
+			for _, l := range before {
+				if l.Num > last {
+					res.Lines = append(res.Lines, l)
+				}
+			}
+			before = before[:0]
+			res.Lines = append(res.Lines, Line{Num: num, Text: text, Matches: matches})
+			last = num
+			after = s.after
+		}
+	}
+	if !keep {
+		res.Lines = nil
+	}
+	return res, sc.Err()
+}
+
+// SearchFile はnameのファイルを探す。読めなかったときはResult.Errにエラーが入る
+func (s *Searcher) SearchFile(name string) *Result {
+	f, err := os.Open(name)
+	if err != nil {
+		return &Result{Path: name, Err: err}
+	}
+	defer f.Close()
+	res, err := s.Search(f)
+	res.Path = name
+	var pe *os.PathError
+	if err != nil && !errors.As(err, &pe) {
+		err = &os.PathError{Op: "read", Path: name, Err: err}
+	}
+	res.Err = err
+	return res
+}
+
+// SearchFiles はpathsのファイルをworkers個のゴルーチンで並行して探し、pathsの順にfnを呼ぶ
+// fnがエラーを返すかctxが終わると、残りのファイルは探さずにそのエラーを返す
+func (s *Searcher) SearchFiles(ctx context.Context, paths []string, workers int, fn func(*Result) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	if workers < 1 {
+		workers = 1
+	}
+
+	// ファイルごとに結果を受け取るチャネルを用意しておき、終わった順ではなくpathsの順に受け取る
+	results := make([]chan *Result, len(paths))
+	for i := range results {
+		results[i] = make(chan *Result, 1)
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] <- s.SearchFile(paths[i])
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range paths {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	for _, ch := range results {
+		select {
+		case res := <-ch:
+			if err := fn(res); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
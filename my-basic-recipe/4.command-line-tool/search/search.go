@@ -0,0 +1,208 @@
+// Package search はファイルの中身を行ごとに探す
+//
+// 文字列・正規表現・単語の一致、全角英数字や半角カナも含めた大文字小文字の無視、
+// 前後の行、バイナリファイルの判定、Shift_JISやEUC-JPなどの文字コードを扱う
+//
+//	s, err := search.New("TODO", search.WithIgnoreCase(true), search.WithContext(1, 1))
+//	res := s.SearchFile("main.go")
+//	for _, l := range res.Lines {
+//		fmt.Println(l.Num, l.Text)
+//	}
+package search
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// ErrEmptyPattern はパターンが空のときのエラー
+var ErrEmptyPattern = errors.New("search: empty pattern")
+
+// Mode はパターンの解釈のしかた
+type Mode int
+
+const (
+	Regexp  Mode = iota // 正規表現（RE2の書き方）
+	Literal             // そのままの文字列
+)
+
+// Searcher は1つのパターンで探す。複数のゴルーチンから使える
+type Searcher struct {
+	re         *regexp.Regexp
+	mode       Mode
+	ignoreCase bool
+	word       bool
+	invert     bool
+	before     int
+	after      int
+	binary     bool
+	encoding   string
+	maxCount   int
+}
+
+// Option はSearcherの設定
+type Option func(*Searcher)
+
+// WithMode はパターンの解釈のしかたを設定する。初期値はRegexp
+func WithMode(m Mode) Option {
+	return func(s *Searcher) {
+		s.mode = m
+	}
+}
+
+// WithIgnoreCase は大文字小文字を区別しないかを設定する
+// 全角英数字は半角と、半角カナは全角と同じ文字として扱う（ＡＢＣ、abc、ａｂｃはどれも一致する）
+func WithIgnoreCase(on bool) Option {
+	return func(s *Searcher) {
+		s.ignoreCase = on
+	}
+}
+
+// WithWord は前後が文字・数字・_でない単語としての一致だけにするかを設定する
+func WithWord(on bool) Option {
+	return func(s *Searcher) {
+		s.word = on
+	}
+}
+
+// WithInvert は一致しない行を探すかを設定する
+func WithInvert(on bool) Option {
+	return func(s *Searcher) {
+		s.invert = on
+	}
+}
+
+// WithContext は一致した行の前後に含める行数を設定する
+func WithContext(before, after int) Option {
+	return func(s *Searcher) {
+		s.before, s.after = max(before, 0), max(after, 0)
+	}
+}
+
+// WithBinary はバイナリファイルもテキストとして行を返すかを設定する
+func WithBinary(on bool) Option {
+	return func(s *Searcher) {
+		s.binary = on
+	}
+}
+
+// WithEncoding は文字コードを設定する。初期値の"auto"はファイルごとに推測する
+// 名前はWHATWGのEncoding Standardのもの（"shift_jis"、"euc-jp"、"utf-16le"など）
+func WithEncoding(name string) Option {
+	return func(s *Searcher) {
+		s.encoding = name
+	}
+}
+
+// WithMaxCount はファイルごとにn行見つけたら探すのをやめる。0は制限なし
+func WithMaxCount(n int) Option {
+	return func(s *Searcher) {
+		s.maxCount = n
+	}
+}
+
+// New はpatternで探すSearcherを作る
+func New(pattern string, opts ...Option) (*Searcher, error) {
+	s := &Searcher{encoding: "auto"}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if pattern == "" {
+		return nil, ErrEmptyPattern
+	}
+	if _, err := lookupEncoding(s.encoding); err != nil {
+		return nil, err
+	}
+	expr := pattern
+	if s.ignoreCase {
+		expr, _ = fold(expr)
+	}
+	if s.mode == Literal {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if s.ignoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	s.re = re
+	return s, nil
+}
+
+// Find はlineの中で一致した部分のバイト位置を返す。一致しなければnilを返す
+// 空文字列に一致したときは長さ0の位置を返す
+func (s *Searcher) Find(line string) [][2]int {
+	text, offsets := line, []int(nil)
+	if s.ignoreCase {
+		text, offsets = fold(line)
+	}
+	var spans [][2]int
+	for _, loc := range s.re.FindAllStringIndex(text, -1) {
+		span := [2]int{loc[0], loc[1]}
+		if offsets != nil {
+			span = [2]int{offsets[loc[0]], offsets[loc[1]]}
+		}
+		if s.word && !isWord(line, span) {
+			continue
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// isWord はspanの前後が単語の文字でないかを返す
+func isWord(line string, span [2]int) bool {
+	if r, _ := utf8.DecodeLastRuneInString(line[:span[0]]); span[0] > 0 && isWordRune(r) {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(line[span[1]:]); span[1] < len(line) && isWordRune(r) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// fold は全角英数字を半角に、半角カナを全角にする
+// 戻り値のoffsetsは変換後のバイト位置から元のバイト位置への対応で、末尾の位置も含む
+// 記号は正規表現の意味が変わらないように変換しない
+func fold(s string) (string, []int) {
+	var b strings.Builder
+	offsets := make([]int, 0, len(s)+1)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		f := foldRune(r)
+		// 半角カナの濁点・半濁点は前の文字と合わせて1文字にする（ﾃﾞ→デ）
+		if next, nsize := utf8.DecodeRuneInString(s[i+size:]); next == 'ﾞ' || next == 'ﾟ' {
+			if c := []rune(norm.NFC.String(string(f) + string(width.Fold.String(string(next))))); len(c) == 1 && c[0] != f {
+				f = c[0]
+				size += nsize
+			}
+		}
+		n, _ := b.WriteRune(f)
+		for j := 0; j < n; j++ {
+			offsets = append(offsets, i)
+		}
+		i += size
+	}
+	offsets = append(offsets, len(s))
+	return b.String(), offsets
+}
+
+func foldRune(r rune) rune {
+	f := width.LookupRune(r).Folded()
+	if f != 0 && (unicode.IsLetter(f) || unicode.IsDigit(f)) {
+		return f
+	}
+	return r
+}
@@ -0,0 +1,269 @@
+package search_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+
+	"golang/recipe-golang/4.command-line-tool/search"
+)
+
+func TestFind(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		pattern string
+		opts    []search.Option
+		line    string
+		want    []string
+	}{
+		"regexp":        {pattern: `b+`, line: "abbcb", want: []string{"bb", "b"}},
+		"literal":       {pattern: `a.b`, opts: []search.Option{search.WithMode(search.Literal)}, line: "axb a.b", want: []string{"a.b"}},
+		"case":          {pattern: "go", line: "Go go GO", want: []string{"go"}},
+		"ignore case":   {pattern: "go", opts: []search.Option{search.WithIgnoreCase(true)}, line: "Go go GO", want: []string{"Go", "go", "GO"}},
+		"full width":    {pattern: "abc", opts: []search.Option{search.WithIgnoreCase(true)}, line: "ＡＢＣとａｂｃ", want: []string{"ＡＢＣ", "ａｂｃ"}},
+		"full pattern":  {pattern: "ＡＢＣ１", opts: []search.Option{search.WithIgnoreCase(true)}, line: "abc1", want: []string{"abc1"}},
+		"half kana":     {pattern: "データ", opts: []search.Option{search.WithIgnoreCase(true)}, line: "ﾃﾞｰﾀﾍﾞｰｽ", want: []string{"ﾃﾞｰﾀ"}},
+		"symbol":        {pattern: `a.`, opts: []search.Option{search.WithIgnoreCase(true)}, line: "Ａ．", want: []string{"Ａ．"}},
+		"word":          {pattern: "cat", opts: []search.Option{search.WithWord(true)}, line: "cat concat cat_ cat.", want: []string{"cat", "cat"}},
+		"word japanese": {pattern: "東京", opts: []search.Option{search.WithWord(true)}, line: "東京都 東京 ", want: []string{"東京"}},
+		"no match":      {pattern: "x", line: "abc"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s, err := search.New(tt.pattern, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, m := range s.Find(tt.line) {
+				got = append(got, tt.line[m[0]:m[1]])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNew_Error(t *testing.T) {
+	t.Parallel()
+	if _, err := search.New(""); !errors.Is(err, search.ErrEmptyPattern) {
+		t.Errorf("want %v, got %v", search.ErrEmptyPattern, err)
+	}
+	if _, err := search.New("(a"); err == nil {
+		t.Error("want error for an invalid regexp")
+	}
+	if _, err := search.New("a", search.WithEncoding("klingon")); err == nil {
+		t.Error("want error for an unknown encoding")
+	}
+}
+
+// lines はResult.Linesを"番号:本文"か"番号-本文"にする
+func lines(res *search.Result) []string {
+	var out []string
+	for _, l := range res.Lines {
+		sep := ":"
+		if l.Context {
+			sep = "-"
+		}
+		out = append(out, fmt.Sprintf("%d%s%s", l.Num, sep, l.Text))
+	}
+	return out
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+	const text = "a\nb\nmatch1\nc\nd\ne\nmatch2\nmatch3\nf\r\ng\n"
+	cases := map[string]struct {
+		opts  []search.Option
+		want  []string
+		count int
+	}{
+		"plain":   {want: []string{"3:match1", "7:match2", "8:match3"}, count: 3},
+		"context": {opts: []search.Option{search.WithContext(1, 1)}, want: []string{"2-b", "3:match1", "4-c", "6-e", "7:match2", "8:match3", "9-f"}, count: 3},
+		"overlap": {opts: []search.Option{search.WithContext(2, 2)}, want: []string{"1-a", "2-b", "3:match1", "4-c", "5-d", "6-e", "7:match2", "8:match3", "9-f", "10-g"}, count: 3},
+		"max":     {opts: []search.Option{search.WithMaxCount(2), search.WithContext(0, 1)}, want: []string{"3:match1", "4-c", "7:match2", "8-match3"}, count: 2},
+		"invert":  {opts: []search.Option{search.WithInvert(true), search.WithMaxCount(3)}, want: []string{"1:a", "2:b", "4:c"}, count: 3},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s, err := search.New("match", tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := s.Search(strings.NewReader(text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := lines(res); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+			if res.Count != tt.count {
+				t.Errorf("want %d, got %d", tt.count, res.Count)
+			}
+		})
+	}
+}
+
+func TestSearch_Binary(t *testing.T) {
+	t.Parallel()
+	const bin = "ELF\x00\x01key=1\nkey=2\n"
+	s, _ := search.New("key")
+	res, err := s.Search(strings.NewReader(bin))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Binary || res.Count != 2 || res.Lines != nil {
+		t.Errorf("want a binary result with 2 matches and no lines, got %+v", res)
+	}
+
+	s, _ = search.New("key", search.WithBinary(true))
+	res, _ = s.Search(strings.NewReader(bin))
+	if !res.Binary || len(res.Lines) != 2 {
+		t.Errorf("want 2 lines, got %+v", res)
+	}
+}
+
+// TestSearchFile_Binary は実際のバイナリファイルをShift_JISなどと推測せずにバイナリとして扱うことを確かめる
+func TestSearchFile_Binary(t *testing.T) {
+	t.Parallel()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	garbage := filepath.Join(t.TempDir(), "garbage.bin")
+	if err := os.WriteFile(garbage, bytes.Repeat([]byte{0x80, 0xA0, 0xFD, 'a'}, 256), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]struct {
+		path    string
+		pattern string
+	}{
+		"png":        {path: filepath.Join("testdata", "image.png"), pattern: "IHDR"},
+		"executable": {path: exe, pattern: "runtime"},
+		"no nul":     {path: garbage, pattern: "a"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s, err := search.New(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := s.SearchFile(tt.path)
+			if res.Err != nil {
+				t.Fatal(res.Err)
+			}
+			if !res.Binary || res.Count == 0 || res.Lines != nil {
+				t.Errorf("want a binary result with matches and no lines, got binary=%v count=%d encoding=%q", res.Binary, res.Count, res.Encoding)
+			}
+		})
+	}
+}
+
+func encode(t *testing.T, enc encoding.Encoding, s string) string {
+	t.Helper()
+	out, err := enc.NewEncoder().String(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestSearch_Encoding(t *testing.T) {
+	t.Parallel()
+	const text = "住所,電話\n東京都港区,03-1234-5678\n大阪府大阪市,06-1234-5678\n"
+	cases := map[string]struct {
+		in       string
+		encoding string
+		want     string
+	}{
+		"utf-8":       {in: text, want: "utf-8"},
+		"utf-8 bom":   {in: "\ufeff" + text, want: "utf-8"},
+		"shift_jis":   {in: encode(t, japanese.ShiftJIS, text), want: "shift_jis"},
+		"euc-jp":      {in: encode(t, japanese.EUCJP, text), want: "euc-jp"},
+		"iso-2022-jp": {in: encode(t, japanese.ISO2022JP, text), want: "iso-2022-jp"},
+		"utf-16le":    {in: encode(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), text), want: "utf-16"},
+		"utf-16be":    {in: encode(t, unicode.UTF16(unicode.BigEndian, unicode.UseBOM), text), want: "utf-16"},
+		"specified":   {in: encode(t, japanese.EUCJP, text), encoding: "euc-jp", want: "euc-jp"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			opts := []search.Option{}
+			if tt.encoding != "" {
+				opts = append(opts, search.WithEncoding(tt.encoding))
+			}
+			s, err := search.New("東京", opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := s.Search(strings.NewReader(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Encoding != tt.want {
+				t.Errorf("want %s, got %s", tt.want, res.Encoding)
+			}
+			if want := []string{"2:東京都港区,03-1234-5678"}; !reflect.DeepEqual(lines(res), want) {
+				t.Errorf("want %q, got %q", want, lines(res))
+			}
+		})
+	}
+}
+
+func TestSearchFiles(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < 50; i++ {
+		p := filepath.Join(dir, fmt.Sprintf("%02d.txt", i))
+		if err := os.WriteFile(p, []byte(strings.Repeat("x\n", i*100)+"hit\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	paths = append(paths, filepath.Join(dir, "none.txt"))
+
+	s, _ := search.New("hit")
+	var got []string
+	err := s.SearchFiles(context.Background(), paths, 8, func(res *search.Result) error {
+		got = append(got, res.Path)
+		if res.Path == paths[len(paths)-1] && !errors.Is(res.Err, os.ErrNotExist) {
+			t.Errorf("want %v, got %v", os.ErrNotExist, res.Err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, paths) {
+		t.Errorf("want results in order %v, got %v", paths, got)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	err = s.SearchFiles(context.Background(), paths, 8, func(*search.Result) error {
+		n++
+		return stop
+	})
+	if !errors.Is(err, stop) || n != 1 {
+		t.Errorf("want %v after 1 call, got %v after %d calls", stop, err, n)
+	}
+}
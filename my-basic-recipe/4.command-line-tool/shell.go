@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+
+	"golang/recipe-golang/4.command-line-tool/cli"
+	"golang/recipe-golang/4.command-line-tool/shell"
+)
+
+// shellCmd はサブコマンドを対話的に実行するシェルを起動する
+// newRootは実行できるコマンドの全体を作る
+//
+//	tool shell
+//	tool> find -name '*.go' .
+//	tool> exit
+//	echo 'repeat -n 2' | tool shell
+func shellCmd(newRoot func() *cli.Command) *cli.Command {
+	var history, prompt string
+	return &cli.Command{
+		Name:  "shell",
+		Short: "サブコマンドを対話的に実行する",
+		Long: `サブコマンドを対話的に実行する
+
+端末では行の編集（Ctrl-A/E/B/F/K/U/W、矢印キー）、履歴（↑↓、Ctrl-P/N）、Tabでの補完を使える
+引用符が閉じていないか行末が\のときは次の行も続けて読む
+Ctrl-Cは実行中のコマンドを取り消す。exitかCtrl-Dで終了する
+標準入力が端末でなければ1行ずつコマンドとして実行する`,
+		Flags: func(fs *flag.FlagSet) {
+			def := ""
+			if home, err := os.UserHomeDir(); err == nil {
+				def = filepath.Join(home, ".tool_history")
+			}
+			fs.StringVar(&history, "history", def, "履歴を保存する`file`（空なら保存しない）")
+			fs.StringVar(&prompt, "prompt", "tool> ", "プロンプト")
+		},
+		Env: map[string]string{"history": "TOOL_HISTORY"},
+		Run: func(ctx context.Context, c *cli.Context) error {
+			if len(c.Args) > 0 {
+				return cli.Usagef("unexpected arguments %q", c.Args)
+			}
+			// シェルの中でシェルは起動しない
+			root := newRoot()
+			cmds := root.Commands[:0]
+			for _, cmd := range root.Commands {
+				if cmd.Name != "shell" {
+					cmds = append(cmds, cmd)
+				}
+			}
+			root.Commands = cmds
+			sh := shell.New(root, shell.WithPrompt(prompt), shell.WithHistoryFile(history))
+			return sh.Run(ctx, c.Stdin, c.Stdout, c.Stderr)
+		},
+	}
+}
@@ -0,0 +1,292 @@
+package shell
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// ErrInterrupt は入力中にCtrl-Cが押されたときのエラー
+var ErrInterrupt = errors.New("shell: interrupted")
+
+// Completer はカーソルまでの行を受け取り、最後の単語を置き換える候補を返す
+// ディレクトリの候補は/で終える。候補が1つのときは後に空白を加えない
+type Completer func(line string) []string
+
+// Editor はrawモードの端末から1行を読む行エディタ
+//
+//	Ctrl-A/E 行頭/行末  Ctrl-B/F ←/→  Ctrl-P/N ↑/↓（履歴）
+//	Ctrl-K カーソルから行末まで削除  Ctrl-U 行頭からカーソルまで削除  Ctrl-W 前の単語を削除
+//	Ctrl-L 画面を消す  Ctrl-C 入力を取り消す  Ctrl-D 空の行なら終了  Tab 補完
+//
+// 端末の幅を超える行の表示は考えない
+type Editor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	history  *History
+	complete Completer
+
+	prompt string
+	line   []rune
+	pos    int
+}
+
+// NewEditor はrから読み、wに表示するEditorを作る。hとcompleteはnilでもよい
+func NewEditor(r io.Reader, w io.Writer, h *History, complete Completer) *Editor {
+	return &Editor{in: bufio.NewReader(r), out: w, history: h, complete: complete}
+}
+
+// ReadLine はpromptを表示して1行を読む。改行は含まない
+// Ctrl-CではErrInterrupt、空の行でのCtrl-Dと入力の終わりではio.EOFを返す
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	e.prompt, e.line, e.pos = prompt, nil, 0
+	var entries []string
+	if e.history != nil {
+		entries = e.history.Entries()
+	}
+	// 履歴をたどっている位置。len(entries)は編集中の行
+	hist, pending := len(entries), ""
+	e.refresh()
+	for {
+		r, _, err := e.in.ReadRune()
+		if err == io.EOF && len(e.line) > 0 {
+			e.write("\r\n")
+			return string(e.line), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			e.write("\r\n")
+			return string(e.line), nil
+		case ctrl('C'):
+			e.write("^C\r\n")
+			return "", ErrInterrupt
+		case ctrl('D'):
+			if len(e.line) == 0 {
+				e.write("\r\n")
+				return "", io.EOF
+			}
+			e.delete(e.pos, e.pos+1)
+		case 127, ctrl('H'):
+			e.delete(e.pos-1, e.pos)
+		case ctrl('A'):
+			e.pos = 0
+		case ctrl('E'):
+			e.pos = len(e.line)
+		case ctrl('B'):
+			e.pos = max(e.pos-1, 0)
+		case ctrl('F'):
+			e.pos = min(e.pos+1, len(e.line))
+		case ctrl('K'):
+			e.delete(e.pos, len(e.line))
+		case ctrl('U'):
+			e.delete(0, e.pos)
+		case ctrl('W'):
+			i := e.pos
+			for i > 0 && unicode.IsSpace(e.line[i-1]) {
+				i--
+			}
+			for i > 0 && !unicode.IsSpace(e.line[i-1]) {
+				i--
+			}
+			e.delete(i, e.pos)
+		case ctrl('L'):
+			e.write("\x1b[H\x1b[2J")
+		case ctrl('P'), ctrl('N'):
+			hist, pending = e.walkHistory(entries, hist, pending, r == ctrl('P'))
+		case '\t':
+			e.completeWord()
+		case 0x1b:
+			switch e.escape() {
+			case 'A':
+				hist, pending = e.walkHistory(entries, hist, pending, true)
+			case 'B':
+				hist, pending = e.walkHistory(entries, hist, pending, false)
+			case 'C':
+				e.pos = min(e.pos+1, len(e.line))
+			case 'D':
+				e.pos = max(e.pos-1, 0)
+			case 'H':
+				e.pos = 0
+			case 'F':
+				e.pos = len(e.line)
+			case '~':
+				e.delete(e.pos, e.pos+1)
+			}
+		default:
+			if r < 0x20 {
+				// ほかの制御文字は無視する
+				continue
+			}
+			e.insert(string(r))
+		}
+		e.refresh()
+	}
+}
+
+// ctrl はCtrlとcを同時に押したときの文字
+func ctrl(c rune) rune { return c & 0x1f }
+
+// escape はESCに続くシーケンスを読み、最後の文字を返す
+// Deleteの「ESC [ 3 ~」は~を返し、ほかの~で終わるものは0にする
+func (e *Editor) escape() rune {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != '[' && r != 'O' {
+		return 0
+	}
+	var params []rune
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return 0
+		}
+		if r >= 0x40 && r <= 0x7e {
+			if r == '~' && string(params) != "3" {
+				return 0
+			}
+			return r
+		}
+		params = append(params, r)
+	}
+}
+
+// walkHistory は履歴を1つたどる。編集中の行はpendingにとっておく
+func (e *Editor) walkHistory(entries []string, hist int, pending string, back bool) (int, string) {
+	switch {
+	case back && hist > 0:
+		if hist == len(entries) {
+			pending = string(e.line)
+		}
+		hist--
+		e.set(entries[hist])
+	case !back && hist < len(entries):
+		hist++
+		if hist == len(entries) {
+			e.set(pending)
+		} else {
+			e.set(entries[hist])
+		}
+	}
+	return hist, pending
+}
+
+func (e *Editor) set(s string) {
+	e.line = []rune(s)
+	e.pos = len(e.line)
+}
+
+func (e *Editor) insert(s string) {
+	rs := []rune(s)
+	e.line = append(e.line[:e.pos], append(rs, e.line[e.pos:]...)...)
+	e.pos += len(rs)
+}
+
+// delete は[from, to)の文字を消す
+func (e *Editor) delete(from, to int) {
+	from, to = max(from, 0), min(to, len(e.line))
+	if from >= to {
+		return
+	}
+	e.line = append(e.line[:from], e.line[to:]...)
+	e.pos = from
+}
+
+// completeWord はカーソルの前の単語を補完する
+// 候補が1つならそれにし、複数なら共通する先頭まで補い、それ以上補えなければ候補を表示する
+func (e *Editor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+	before := string(e.line[:e.pos])
+	cands := e.complete(before)
+	if len(cands) == 0 {
+		return
+	}
+	word := []rune(before[strings.LastIndexAny(before, " \t\n")+1:])
+	start := e.pos - len(word)
+	if len(cands) == 1 {
+		s := cands[0]
+		if !strings.HasSuffix(s, "/") {
+			s += " "
+		}
+		e.delete(start, e.pos)
+		e.insert(s)
+		return
+	}
+	if p := commonPrefix(cands); len([]rune(p)) > len(word) {
+		e.delete(start, e.pos)
+		e.insert(p)
+		return
+	}
+	sort.Strings(cands)
+	e.write("\r\n" + strings.Join(cands, "  ") + "\r\n")
+}
+
+func commonPrefix(ss []string) string {
+	p := []rune(ss[0])
+	for _, s := range ss[1:] {
+		rs := []rune(s)
+		n := 0
+		for n < len(p) && n < len(rs) && p[n] == rs[n] {
+			n++
+		}
+		p = p[:n]
+	}
+	return string(p)
+}
+
+// refresh は行を書き直してカーソルを置く
+func (e *Editor) refresh() {
+	var b strings.Builder
+	b.WriteString("\r" + e.prompt)
+	b.WriteString(display(e.line))
+	b.WriteString("\x1b[K")
+	if n := cells(e.line[e.pos:]); n > 0 {
+		fmt.Fprintf(&b, "\x1b[%dD", n)
+	}
+	e.write(b.String())
+}
+
+func (e *Editor) write(s string) {
+	io.WriteString(e.out, s)
+}
+
+// display は制御文字を^Xの形にした表示用の文字列
+func display(rs []rune) string {
+	var b strings.Builder
+	for _, r := range rs {
+		if r < 0x20 || r == 0x7f {
+			b.WriteString("^" + string(r^0x40))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// cells は端末で表示したときの幅。全角は2つ分
+func cells(rs []rune) int {
+	n := 0
+	for _, r := range rs {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			n += 2
+		default:
+			switch width.LookupRune(r).Kind() {
+			case width.EastAsianWide, width.EastAsianFullwidth:
+				n += 2
+			default:
+				n++
+			}
+		}
+	}
+	return n
+}
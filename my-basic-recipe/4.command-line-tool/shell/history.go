@@ -0,0 +1,123 @@
+package shell
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang/recipe-golang/4.command-line-tool/fileutil"
+)
+
+// History は入力した行の履歴。ファイルに1行ずつ追記して次に起動したときに読み込む
+// 複数行の入力は改行を\nにして1行で保存する
+type History struct {
+	name    string
+	max     int
+	entries []string
+}
+
+// LoadHistory はnameのファイルから最大max件の履歴を読み込む。ファイルがなければ空の履歴を返す
+// max件を超えていたらファイルを残した履歴だけに書き直す
+// nameが空のときはファイルに保存しない。先頭の~/はホームディレクトリにする
+func LoadHistory(name string, max int) (*History, error) {
+	name, err := expandHome(name)
+	if err != nil {
+		return nil, err
+	}
+	h := &History{name: name, max: max}
+	if name == "" {
+		return h, nil
+	}
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		h.entries = append(h.entries, unescape(sc.Text()))
+	}
+	f.Close()
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	n := len(h.entries)
+	h.trim()
+	if len(h.entries) < n {
+		if err := h.rewrite(); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// expandHome は~と~/で始まるパスをホームディレクトリからのパスにする
+func expandHome(name string) (string, error) {
+	rest, ok := strings.CutPrefix(name, "~")
+	if !ok || rest != "" && rest[0] != '/' && rest[0] != filepath.Separator {
+		return name, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, rest), nil
+}
+
+// Entries は古い順の履歴を返す
+func (h *History) Entries() []string {
+	return h.entries
+}
+
+// Add は履歴に加えてファイルに追記する。空の行と直前と同じ行は加えない
+func (h *History) Add(entry string) error {
+	if strings.TrimSpace(entry) == "" || len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry {
+		return nil
+	}
+	h.entries = append(h.entries, entry)
+	h.trim()
+	if h.name == "" {
+		return nil
+	}
+	f, err := os.OpenFile(h.name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(escape(entry) + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// trim はmax件を超えた古い履歴を捨てる。ファイルは次に読み込むときに切り詰める
+func (h *History) trim() {
+	if h.max > 0 && len(h.entries) > h.max {
+		h.entries = append([]string(nil), h.entries[len(h.entries)-h.max:]...)
+	}
+}
+
+// rewrite はファイルを今の履歴で置き換える。書き終えるまで元のファイルは変えない
+func (h *History) rewrite() error {
+	return fileutil.WriteFrom(h.name, 0o600, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		for _, entry := range h.entries {
+			bw.WriteString(escape(entry) + "\n")
+		}
+		return bw.Flush()
+	})
+}
+
+var (
+	escaper   = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	unescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+)
+
+func escape(s string) string   { return escaper.Replace(s) }
+func unescape(s string) string { return unescaper.Replace(s) }
@@ -0,0 +1,286 @@
+// Package shell はcli.Commandのサブコマンドを対話的に実行するシェル
+//
+// 端末では行の編集、履歴、Tabでの補完を使える。引用符や行末の\で続く入力は次の行も読む
+// Ctrl-Cは実行中のコマンドのcontextを取り消すだけで、シェルは終わらない
+//
+//	sh := shell.New(app, shell.WithHistoryFile("~/.tool_history"))
+//	err := sh.Run(ctx, os.Stdin, os.Stdout, os.Stderr)
+package shell
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+
+	"golang/recipe-golang/4.command-line-tool/cli"
+	"golang/recipe-golang/6.error/errs"
+)
+
+// builtins はシェルが自分で処理するコマンド。helpはcli.Execが加える
+var builtins = []string{"exit", "help", "history"}
+
+// Shell は対話的なシェル
+type Shell struct {
+	root        *cli.Command
+	prompt      string
+	prompt2     string
+	historyFile string
+	historySize int
+}
+
+// Option はShellの設定
+type Option func(*Shell)
+
+// WithPrompt はプロンプトを設定する。初期値は"ルートのコマンド名> "
+func WithPrompt(s string) Option {
+	return func(sh *Shell) {
+		sh.prompt = s
+	}
+}
+
+// WithContinuePrompt は入力が続くときのプロンプトを設定する。初期値は"> "
+func WithContinuePrompt(s string) Option {
+	return func(sh *Shell) {
+		sh.prompt2 = s
+	}
+}
+
+// WithHistoryFile は履歴を保存するファイルを設定する。空なら保存しない
+// 先頭の~/はホームディレクトリにする
+func WithHistoryFile(name string) Option {
+	return func(sh *Shell) {
+		sh.historyFile = name
+	}
+}
+
+// WithHistorySize は履歴の最大の件数を設定する。初期値は1000
+func WithHistorySize(n int) Option {
+	return func(sh *Shell) {
+		sh.historySize = n
+	}
+}
+
+// New はrootのサブコマンドを実行するShellを作る
+func New(root *cli.Command, opts ...Option) *Shell {
+	sh := &Shell{root: root, prompt: root.Name + "> ", prompt2: "> ", historySize: 1000}
+	for _, opt := range opts {
+		opt(sh)
+	}
+	return sh
+}
+
+// Run はstdinから1行ずつ読んでコマンドを実行する
+// exitか入力の終わりで、最後のコマンドの終了コードをcli.Exitにして返す（0ならnil）
+// stdinが端末でなければプロンプトを出さず、履歴もファイルに保存しない
+func (sh *Shell) Run(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer) error {
+	in := bufio.NewReader(stdin)
+	interactive := false
+	var fd int
+	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		interactive, fd = true, int(f.Fd())
+	}
+
+	var (
+		hist *History
+		err  error
+	)
+	if interactive {
+		hist, err = LoadHistory(sh.historyFile, sh.historySize)
+	} else {
+		hist, err = LoadHistory("", sh.historySize)
+	}
+	if err != nil {
+		return err
+	}
+
+	readLine := func(prompt string) (string, error) {
+		s, err := in.ReadString('\n')
+		if err == io.EOF && s != "" {
+			err = nil
+		}
+		return strings.TrimRight(s, "\r\n"), err
+	}
+	if interactive {
+		ed := NewEditor(in, stdout, hist, sh.complete)
+		readLine = func(prompt string) (string, error) {
+			// 1文字ずつ読むためにrawモードにする。コマンドの実行中は元に戻す
+			old, err := term.MakeRaw(fd)
+			if err != nil {
+				return "", err
+			}
+			defer term.Restore(fd, old)
+			return ed.ReadLine(prompt)
+		}
+	}
+
+	// Ctrl-Cのシグナルは実行中のコマンドだけを取り消す。入力を待っている間は無視する
+	var (
+		mu     sync.Mutex
+		cancel context.CancelFunc
+	)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-sig:
+				mu.Lock()
+				if cancel != nil {
+					cancel()
+				}
+				mu.Unlock()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	status := errs.ExitOK // 直前のコマンドの終了コード
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line, words, err := sh.read(readLine)
+		switch {
+		case errors.Is(err, ErrInterrupt):
+			status = 130
+			continue
+		case err == io.EOF:
+			return exit(status)
+		case err != nil:
+			return err
+		}
+		if err := hist.Add(line); err != nil {
+			fmt.Fprintf(stderr, "history: %v\n", err)
+		}
+		if len(words) == 0 {
+			continue
+		}
+
+		switch words[0] {
+		case "exit":
+			if len(words) > 2 {
+				fmt.Fprintln(stderr, "exit: too many arguments")
+				status = errs.ExitUsage
+				continue
+			}
+			if len(words) == 2 {
+				code, err := strconv.Atoi(words[1])
+				if err != nil {
+					fmt.Fprintf(stderr, "exit: %q is not a number\n", words[1])
+					status = errs.ExitUsage
+					continue
+				}
+				status = code
+			}
+			return exit(status)
+		case "history":
+			for i, h := range hist.Entries() {
+				fmt.Fprintf(stdout, "%5d  %s\n", i+1, strings.ReplaceAll(h, "\n", "\n       "))
+			}
+			status = errs.ExitOK
+			continue
+		}
+
+		cmdCtx, c := context.WithCancel(ctx)
+		mu.Lock()
+		cancel = c
+		mu.Unlock()
+		status = cli.Exec(cmdCtx, sh.root, words, in, stdout, stderr)
+		mu.Lock()
+		cancel = nil
+		mu.Unlock()
+		c()
+	}
+}
+
+// read は1つのコマンドを読む。引用符や\で終わっていなければ次の行も読む
+func (sh *Shell) read(readLine func(string) (string, error)) (string, []string, error) {
+	line, err := readLine(sh.prompt)
+	if err != nil {
+		return "", nil, err
+	}
+	for {
+		words, err := Split(line)
+		if !errors.Is(err, ErrIncomplete) {
+			return line, words, err
+		}
+		next, err := readLine(sh.prompt2)
+		if err == io.EOF {
+			return "", nil, fmt.Errorf("unexpected end of input: %w", ErrIncomplete)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		line += "\n" + next
+	}
+}
+
+// exit は終了コードをRunの戻り値にする
+func exit(code int) error {
+	if code == errs.ExitOK {
+		return nil
+	}
+	return cli.Exit(code)
+}
+
+// complete はEditorの補完。サブコマンドとフラグを補い、候補がなければファイル名を補う
+func (sh *Shell) complete(line string) []string {
+	i := strings.LastIndexAny(line, " \t\n") + 1
+	words, _ := Split(line[:i])
+	cur := line[i:]
+
+	cands := cli.Complete(sh.root, append(append([]string{sh.root.Name}, words...), cur))
+	if len(words) == 0 {
+		for _, b := range builtins {
+			if strings.HasPrefix(b, cur) {
+				cands = append(cands, b)
+			}
+		}
+		sort.Strings(cands)
+	}
+	if len(cands) == 0 && !strings.HasPrefix(cur, "-") {
+		cands = completeFile(cur)
+	}
+	return cands
+}
+
+// completeFile はcurで始まるファイル名の候補を返す。ディレクトリは/で終える
+// .で始まるファイルはcurの名前も.で始まるときだけ候補にする
+func completeFile(cur string) []string {
+	dir, base := cur[:strings.LastIndex(cur, "/")+1], cur[strings.LastIndex(cur, "/")+1:]
+	read := dir
+	if read == "" {
+		read = "."
+	}
+	entries, err := os.ReadDir(filepath.FromSlash(read))
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, base) || strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		if e.IsDir() {
+			name += "/"
+		}
+		out = append(out, dir+name)
+	}
+	return out
+}
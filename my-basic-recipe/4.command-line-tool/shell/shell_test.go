@@ -0,0 +1,343 @@
+package shell_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/recipe-golang/4.command-line-tool/cli"
+	"golang/recipe-golang/4.command-line-tool/shell"
+)
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		in   string
+		want []string
+		err  error
+	}{
+		"words":      {in: "  find -name  *.go ", want: []string{"find", "-name", "*.go"}},
+		"empty":      {in: "   "},
+		"single":     {in: `say 'a "b" \c'`, want: []string{"say", `a "b" \c`}},
+		"double":     {in: `say "a 'b' \"c\" \\ \n"`, want: []string{"say", `a 'b' "c" \ \n`}},
+		"join":       {in: `a'b'"c"d`, want: []string{"abcd"}},
+		"empty word": {in: `say '' ""`, want: []string{"say", "", ""}},
+		"escape":     {in: `a\ b \#c`, want: []string{"a b", "#c"}},
+		"newline":    {in: "a \\\nb", want: []string{"a", "b"}},
+		"multi line": {in: "say 'a\nb'", want: []string{"say", "a\nb"}},
+		"comment":    {in: "a b#c # d e", want: []string{"a", "b#c"}},
+		"open quote": {in: `say 'a`, want: []string{"say"}, err: shell.ErrIncomplete},
+		"open dq":    {in: `say "a`, want: []string{"say"}, err: shell.ErrIncomplete},
+		"backslash":  {in: `say a\`, want: []string{"say"}, err: shell.ErrIncomplete},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := shell.Split(tt.in)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("want %v, got %v", tt.err, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	t.Parallel()
+	for _, s := range []string{"a", "", "a b", "it's", `a"b\c`, "#x", "改\n行"} {
+		got, err := shell.Split("say " + shell.Quote(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"say", s}; !reflect.DeepEqual(got, want) {
+			t.Errorf("want %q, got %q", want, got)
+		}
+	}
+	if got := shell.Quote("plain"); got != "plain" {
+		t.Errorf("want %q, got %q", "plain", got)
+	}
+}
+
+func TestEditor(t *testing.T) {
+	t.Parallel()
+	complete := func(line string) []string {
+		var out []string
+		word := line[strings.LastIndex(line, " ")+1:]
+		for _, c := range []string{"find", "format", "repeat", "dir/"} {
+			if strings.HasPrefix(c, word) {
+				out = append(out, c)
+			}
+		}
+		return out
+	}
+	cases := map[string]struct {
+		history []string
+		in      string
+		want    []string
+		err     error // 最後に読んだときのエラー。nilならio.EOF
+	}{
+		"type":        {in: "ls -l\r", want: []string{"ls -l"}},
+		"japanese":    {in: "こんにちは\x7fばんは\r", want: []string{"こんにちばんは"}},
+		"move":        {in: "ac\x02b\x01>\x05<\r", want: []string{">abc<"}},
+		"arrows":      {in: "ac\x1b[Db\x1b[H>\x1b[F<\x1b[D\x1b[3~\r", want: []string{">abc"}},
+		"kill":        {in: "abc def\x02\x02\x0b\r", want: []string{"abc d"}},
+		"kill before": {in: "abc def\x02\x15\r", want: []string{"f"}},
+		"kill word":   {in: "abc def  \x17\r", want: []string{"abc "}},
+		"delete":      {in: "abc\x01\x04\r", want: []string{"bc"}},
+		"history":     {history: []string{"one", "two"}, in: "\x1b[A\r\x10\x10\r", want: []string{"two", "one"}},
+		"pending":     {history: []string{"one"}, in: "new\x1b[A\x1b[B!\r", want: []string{"new!"}},
+		"ctrl-c":      {in: "abc\x03", err: shell.ErrInterrupt},
+		"ctrl-d":      {in: "ab\x04\x04\x01\x04\x04\x04"},
+		"eof":         {in: "abc", want: []string{"abc"}},
+		"complete":    {in: "re\t-n\r", want: []string{"repeat -n"}},
+		"prefix":      {in: "f\tor\t\r", want: []string{"format "}},
+		"directory":   {in: "cd d\t\r", want: []string{"cd dir/"}},
+		"no match":    {in: "x\t\r", want: []string{"x"}},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			h, err := shell.LoadHistory("", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.history {
+				h.Add(s)
+			}
+			ed := shell.NewEditor(strings.NewReader(tt.in), io.Discard, h, complete)
+			if tt.err == nil {
+				tt.err = io.EOF
+			}
+			var got []string
+			for {
+				line, err := ed.ReadLine("> ")
+				if err != nil {
+					if !errors.Is(err, tt.err) {
+						t.Fatalf("want %v, got %v", tt.err, err)
+					}
+					break
+				}
+				got = append(got, line)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEditor_List(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	ed := shell.NewEditor(strings.NewReader("x\t\r"), &out, nil, func(string) []string {
+		return []string{"xb", "xa"}
+	})
+	if _, err := ed.ReadLine("> "); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "\r\nxa  xb\r\n") {
+		t.Errorf("want candidates listed, got %q", out.String())
+	}
+}
+
+func TestHistory(t *testing.T) {
+	t.Parallel()
+	name := filepath.Join(t.TempDir(), "history")
+	h, err := shell.LoadHistory(name, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"a", "", "b", "b", "say 'x\ny'", `c\n`, "d"} {
+		if err := h.Add(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"say 'x\ny'", `c\n`, "d"}
+	if got := h.Entries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	h, err = shell.LoadHistory(name, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Entries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestHistory_Trim(t *testing.T) {
+	t.Parallel()
+	name := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(name, []byte("a\nb\nsay 'x\\ny'\nd\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	h, err := shell.LoadHistory(name, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"say 'x\ny'", "d"}; !reflect.DeepEqual(h.Entries(), want) {
+		t.Errorf("want %q, got %q", want, h.Entries())
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "say 'x\\ny'\nd\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestHistory_Home(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("home", home)
+	h, err := shell.LoadHistory("~/.tool_history", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Add("say a"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(home, ".tool_history"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "say a\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+// newRoot はテスト用のコマンド。sleepは取り消されるまで待つ
+func newRoot(started chan<- struct{}) *cli.Command {
+	return &cli.Command{
+		Name: "tool",
+		Commands: []*cli.Command{
+			{
+				Name: "say",
+				Run: func(ctx context.Context, c *cli.Context) error {
+					_, err := io.WriteString(c.Stdout, strings.Join(c.Args, ",")+"\n")
+					return err
+				},
+			},
+			{
+				Name: "fail",
+				Run: func(ctx context.Context, c *cli.Context) error {
+					return errors.New("failed")
+				},
+			},
+			{
+				Name: "sleep",
+				Run: func(ctx context.Context, c *cli.Context) error {
+					close(started)
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			},
+		},
+	}
+}
+
+func TestShell_Run(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		in     string
+		code   int
+		stdout string
+		stderr string
+	}{
+		"commands":   {in: "say a b\n\n# comment\nsay 'c d'\n", stdout: "a,b\nc d\n"},
+		"multi line": {in: "say 'a\nb' \\\nc\n", stdout: "a\nb,c\n"},
+		"exit":       {in: "say a\nexit 3\nsay b\n", code: 3, stdout: "a\n"},
+		"status":     {in: "fail\n", code: 1, stderr: "tool fail: failed\n"},
+		"exit bad":   {in: "exit x\n", code: 64, stderr: `exit: "x" is not a number`},
+		"continue":   {in: "fail\nsay ok\n", stdout: "ok\n", stderr: "failed"},
+		"unknown":    {in: "nope\n", code: 64, stderr: `unknown command "nope"`},
+		"help":       {in: "help say\n", stdout: "tool say"},
+		"history":    {in: "say a\nhistory\n", stdout: "a\n    1  say a\n    2  history\n"},
+		"no newline": {in: "say a", stdout: "a\n"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var stdout, stderr bytes.Buffer
+			err := shell.New(newRoot(nil)).Run(context.Background(), strings.NewReader(tt.in), &stdout, &stderr)
+			code := 0
+			var ee *cli.ExitError
+			if errors.As(err, &ee) {
+				code = ee.Code
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.code {
+				t.Errorf("want exit code %d, got %d", tt.code, code)
+			}
+			if !strings.Contains(stdout.String(), tt.stdout) {
+				t.Errorf("want stdout containing %q, got %q", tt.stdout, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("want stderr containing %q, got %q", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestShell_Run_Incomplete(t *testing.T) {
+	t.Parallel()
+	err := shell.New(newRoot(nil)).Run(context.Background(), strings.NewReader("say 'a\n"), io.Discard, io.Discard)
+	if !errors.Is(err, shell.ErrIncomplete) {
+		t.Errorf("want %v, got %v", shell.ErrIncomplete, err)
+	}
+}
+
+// TestShell_Run_Interrupt はCtrl-Cで実行中のコマンドだけが取り消され、シェルは続くことを確かめる
+// プロセスにシグナルを送るので並列にしない
+func TestShell_Run_Interrupt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cannot send os.Interrupt on windows")
+	}
+	started := make(chan struct{})
+	r, w := io.Pipe()
+	var stdout, stderr bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- shell.New(newRoot(started)).Run(context.Background(), r, &stdout, &stderr)
+	}()
+	go func() {
+		io.WriteString(w, "sleep\n")
+		<-started
+		p, err := os.FindProcess(os.Getpid())
+		if err == nil {
+			p.Signal(os.Interrupt)
+		}
+		io.WriteString(w, "say after\n")
+		w.Close()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("shell did not finish after interrupt")
+	}
+	if got, want := stdout.String(), "after\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if !strings.Contains(stderr.String(), "context canceled") {
+		t.Errorf("want stderr containing %q, got %q", "context canceled", stderr.String())
+	}
+}
@@ -0,0 +1,96 @@
+package shell
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrIncomplete は引用符が閉じていないか、行末が\で続きがあるときのエラー
+var ErrIncomplete = errors.New("shell: incomplete input")
+
+// Split はシェルと同じように行を単語に分ける
+//
+//   - 空白で区切る
+//   - '...'の中はそのまま、"..."の中は\"と\\だけを解釈する
+//   - 引用符の外の\は次の文字をそのまま使う。\と改行の組は取り除く
+//   - 単語の先頭の#から行末まではコメント
+func Split(s string) ([]string, error) {
+	var (
+		words  []string
+		b      strings.Builder
+		inWord bool
+	)
+	flush := func() {
+		if inWord {
+			words = append(words, b.String())
+			b.Reset()
+			inWord = false
+		}
+	}
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			flush()
+		case r == '#' && !inWord:
+			// コメントは改行まで
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+		case r == '\\':
+			if i+1 == len(rs) {
+				return words, ErrIncomplete
+			}
+			i++
+			if rs[i] != '\n' {
+				b.WriteRune(rs[i])
+				inWord = true
+			}
+		case r == '\'':
+			end := indexRune(rs, i+1, '\'')
+			if end < 0 {
+				return words, ErrIncomplete
+			}
+			b.WriteString(string(rs[i+1 : end]))
+			inWord = true
+			i = end
+		case r == '"':
+			inWord = true
+			for i++; ; i++ {
+				if i == len(rs) {
+					return words, ErrIncomplete
+				}
+				if rs[i] == '"' {
+					break
+				}
+				if rs[i] == '\\' && i+1 < len(rs) && (rs[i+1] == '"' || rs[i+1] == '\\') {
+					i++
+				}
+				b.WriteRune(rs[i])
+			}
+		default:
+			b.WriteRune(r)
+			inWord = true
+		}
+	}
+	flush()
+	return words, nil
+}
+
+func indexRune(rs []rune, from int, r rune) int {
+	for i := from; i < len(rs); i++ {
+		if rs[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// Quote はSplitで元に戻せるように単語を引用符で囲む。囲まなくてよければそのまま返す
+func Quote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\#") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
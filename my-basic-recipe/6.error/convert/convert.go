@@ -0,0 +1,507 @@
+// Package convert は任意の値をstring、数値、bool、time.Time、time.Durationなどに変換する
+//
+// 変換は次の順に試す
+//  1. 変換先の型に登録されたConverter
+//  2. そのまま代入できる型ならそのまま返す
+//  3. 変換先がencoding.TextUnmarshalerを実装していれば、文字列にしてからUnmarshalText
+//  4. 変換先の種類ごとの変換（fmt.Stringer、encoding.TextMarshaler、strconvなど）
+//
+// 変換できない場合は、値と型と理由を持つ*Errorを返す
+// 設定ファイルの読み込みやリクエストのバインドで使う
+package convert
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang/recipe-golang/6.error/errs"
+)
+
+// ErrUnsupported は変換の方法がないときの原因
+var ErrUnsupported = errors.New("unsupported conversion")
+
+// Error は変換に失敗したときのエラー
+// errs.Invalidとして扱われる
+type Error struct {
+	Value interface{}  // 変換しようとした値
+	To    reflect.Type // 変換先の型
+	Err   error        // 理由
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("convert: cannot convert %#v (%T) to %s: %v", e.Value, e.Value, e.To, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// ErrorCode はerrs.CodeOfでerrs.Invalidを返すようにする
+func (e *Error) ErrorCode() errs.Code { return errs.Invalid }
+
+// Is はerrors.Is(err, errs.Invalid)をtrueにする
+func (e *Error) Is(target error) bool {
+	return target == errs.Invalid
+}
+
+// Converter は値を登録された型に変換する
+type Converter func(v interface{}) (interface{}, error)
+
+// Registry は型ごとのConverterを持つ
+// ゼロ値で使える
+type Registry struct {
+	mu    sync.RWMutex
+	convs map[reflect.Type]Converter
+}
+
+// Default はパッケージの関数が使うRegistry
+var Default = &Registry{}
+
+// Register はtへの変換にfnを使うように登録する
+func (r *Registry) Register(t reflect.Type, fn Converter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.convs == nil {
+		r.convs = map[reflect.Type]Converter{}
+	}
+	r.convs[t] = fn
+}
+
+func (r *Registry) lookup(t reflect.Type) (Converter, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn, ok := r.convs[t]
+	return fn, ok
+}
+
+// Register はTへの変換にfnを使うようにrに登録する
+//
+//	convert.Register(convert.Default, func(v interface{}) (Level, error) { ... })
+func Register[T any](r *Registry, fn func(v interface{}) (T, error)) {
+	r.Register(reflect.TypeOf((*T)(nil)).Elem(), func(v interface{}) (interface{}, error) {
+		return fn(v)
+	})
+}
+
+// To はvをTに変換する
+func To[T any](v interface{}) (T, error) {
+	return ToWith[T](Default, v)
+}
+
+// ToWith はrに登録されたConverterを使ってvをTに変換する
+func ToWith[T any](r *Registry, v interface{}) (T, error) {
+	var zero T
+	rv, err := r.Convert(v, reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return zero, err
+	}
+	return rv.Interface().(T), nil
+}
+
+// ToString はvを文字列に変換する
+func ToString(v interface{}) (string, error) { return To[string](v) }
+
+// ToInt はvをintに変換する
+func ToInt(v interface{}) (int, error) { return To[int](v) }
+
+// ToBool はvをboolに変換する
+func ToBool(v interface{}) (bool, error) { return To[bool](v) }
+
+// ToTime はvをtime.Timeに変換する
+func ToTime(v interface{}) (time.Time, error) { return To[time.Time](v) }
+
+// ToDuration はvをtime.Durationに変換する
+func ToDuration(v interface{}) (time.Duration, error) { return To[time.Duration](v) }
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Convert はvをtの値に変換する
+// reflectで構造体のフィールドに値を設定する場合に使う
+func (r *Registry) Convert(v interface{}, t reflect.Type) (reflect.Value, error) {
+	out, err := r.convert(v, t)
+	if err != nil {
+		var ce *Error
+		if errors.As(err, &ce) {
+			return reflect.Value{}, err
+		}
+		return reflect.Value{}, &Error{Value: v, To: t, Err: err}
+	}
+	return out, nil
+}
+
+func (r *Registry) convert(v interface{}, t reflect.Type) (reflect.Value, error) {
+	if fn, ok := r.lookup(t); ok {
+		out, err := fn(v)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		// 登録した関数がnilや別の型を返してもpanicしないようにする
+		if out == nil {
+			return reflect.Value{}, errors.New("converter returned nil")
+		}
+		ov := reflect.ValueOf(out)
+		if !ov.Type().AssignableTo(t) {
+			return reflect.Value{}, fmt.Errorf("converter returned %T", out)
+		}
+		res := reflect.New(t).Elem()
+		res.Set(ov)
+		return res, nil
+	}
+
+	if v == nil {
+		return reflect.Value{}, errors.New("nil value")
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Type().AssignableTo(t) {
+		out := reflect.New(t).Elem()
+		out.Set(rv)
+		return out, nil
+	}
+
+	// ポインタならポインタの先を変換する
+	if t.Kind() == reflect.Ptr && !reflect.PtrTo(t.Elem()).Implements(textUnmarshalerType) {
+		elem, err := r.convert(v, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out := reflect.New(t.Elem())
+		out.Elem().Set(elem)
+		return out, nil
+	}
+
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) && t != timeType {
+		return unmarshalText(v, t)
+	}
+
+	switch {
+	case t == timeType:
+		return toTime(v)
+	case t == durationType:
+		return toDuration(v)
+	}
+
+	var (
+		out interface{}
+		err error
+	)
+	switch t.Kind() {
+	case reflect.String:
+		out, err = toString(v)
+	case reflect.Bool:
+		out, err = toBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		out, err = toInt(v, t.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		out, err = toUint(v, t.Bits())
+	case reflect.Float32, reflect.Float64:
+		out, err = toFloat(v, t.Bits())
+	case reflect.Slice:
+		return r.toSlice(v, t)
+	default:
+		return reflect.Value{}, ErrUnsupported
+	}
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	// type Level intのような名前付きの型にも変換する
+	return reflect.ValueOf(out).Convert(t), nil
+}
+
+// toString はStringer、TextMarshaler、strconvの順に文字列にする
+func toString(v interface{}) (string, error) {
+	if isNilPtr(v) {
+		return "", ErrUnsupported
+	}
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case []byte:
+		return string(x), nil
+	case time.Time:
+		return x.Format(time.RFC3339Nano), nil
+	case fmt.Stringer:
+		return x.String(), nil
+	case encoding.TextMarshaler:
+		b, err := x.MarshalText()
+		return string(b), err
+	case error:
+		return x.Error(), nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, rv.Type().Bits()), nil
+	}
+	return "", ErrUnsupported
+}
+
+// stringOf は文字列として扱える値ならその文字列を返す
+func stringOf(v interface{}) (string, bool) {
+	if isNilPtr(v) {
+		return "", false
+	}
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case []byte:
+		return string(x), true
+	case fmt.Stringer:
+		return x.String(), true
+	case encoding.TextMarshaler:
+		b, err := x.MarshalText()
+		return string(b), err == nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.String {
+		return rv.String(), true
+	}
+	return "", false
+}
+
+// isNilPtr はvがnilのポインタか調べる
+// nilのポインタでStringやMarshalTextを呼ぶとほとんどの場合パニックになる
+func isNilPtr(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+func toBool(v interface{}) (bool, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intToBool(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > 1 {
+			return false, fmt.Errorf("%d is not 0 or 1", rv.Uint())
+		}
+		return rv.Uint() == 1, nil
+	}
+	s, ok := stringOf(v)
+	if !ok {
+		return false, ErrUnsupported
+	}
+	// 設定ファイルでよく使われる表記も受け付ける
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "y", "on":
+		return true, nil
+	case "no", "n", "off":
+		return false, nil
+	}
+	return strconv.ParseBool(strings.TrimSpace(s))
+}
+
+func intToBool(n int64) (bool, error) {
+	if n != 0 && n != 1 {
+		return false, fmt.Errorf("%d is not 0 or 1", n)
+	}
+	return n == 1, nil
+}
+
+func toInt(v interface{}, bits int) (int64, error) {
+	rv := reflect.ValueOf(v)
+	var n int64
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return 0, fmt.Errorf("%d overflows int%d: %w", u, bits, strconv.ErrRange)
+		}
+		n = int64(u)
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) {
+			return 0, fmt.Errorf("%v has a fractional part", f)
+		}
+		if f < math.MinInt64 || f >= math.MaxInt64 {
+			return 0, fmt.Errorf("%v overflows int%d: %w", f, bits, strconv.ErrRange)
+		}
+		n = int64(f)
+	case reflect.Bool:
+		return 0, fmt.Errorf("bool is not a number: %w", ErrUnsupported)
+	default:
+		s, ok := stringOf(v)
+		if !ok {
+			return 0, ErrUnsupported
+		}
+		// 0xや0bなどの接頭辞と_の区切りを受け付ける
+		return strconv.ParseInt(strings.TrimSpace(s), 0, bits)
+	}
+	if bits < 64 && (n < -1<<(bits-1) || n > 1<<(bits-1)-1) {
+		return 0, fmt.Errorf("%d overflows int%d: %w", n, bits, strconv.ErrRange)
+	}
+	return n, nil
+}
+
+func toUint(v interface{}, bits int) (uint64, error) {
+	rv := reflect.ValueOf(v)
+	var u uint64
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u = rv.Uint()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Int() < 0 {
+			return 0, fmt.Errorf("%d is negative: %w", rv.Int(), strconv.ErrRange)
+		}
+		u = uint64(rv.Int())
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) {
+			return 0, fmt.Errorf("%v has a fractional part", f)
+		}
+		if f < 0 || f >= math.MaxUint64 {
+			return 0, fmt.Errorf("%v overflows uint%d: %w", f, bits, strconv.ErrRange)
+		}
+		u = uint64(f)
+	case reflect.Bool:
+		return 0, fmt.Errorf("bool is not a number: %w", ErrUnsupported)
+	default:
+		s, ok := stringOf(v)
+		if !ok {
+			return 0, ErrUnsupported
+		}
+		return strconv.ParseUint(strings.TrimSpace(s), 0, bits)
+	}
+	if bits < 64 && u > 1<<bits-1 {
+		return 0, fmt.Errorf("%d overflows uint%d: %w", u, bits, strconv.ErrRange)
+	}
+	return u, nil
+}
+
+func toFloat(v interface{}, bits int) (float64, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if bits == 32 && math.Abs(f) > math.MaxFloat32 && !math.IsInf(f, 0) {
+			return 0, fmt.Errorf("%v overflows float32: %w", f, strconv.ErrRange)
+		}
+		return f, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), nil
+	case reflect.Bool:
+		return 0, fmt.Errorf("bool is not a number: %w", ErrUnsupported)
+	}
+	s, ok := stringOf(v)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+	return strconv.ParseFloat(strings.TrimSpace(s), bits)
+}
+
+// timeLayouts は文字列からtime.Timeに変換するときに試すレイアウト
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+}
+
+// toTime は文字列をtimeLayoutsで、整数をUnix時間（秒）としてtime.Timeにする
+func toTime(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.ValueOf(time.Unix(rv.Int(), 0)), nil
+	}
+	s, ok := stringOf(v)
+	if !ok {
+		return reflect.Value{}, ErrUnsupported
+	}
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return reflect.ValueOf(t), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("unknown time format %q (want RFC 3339 or 2006-01-02)", s)
+}
+
+// toDuration は文字列をtime.ParseDurationで、整数をナノ秒としてtime.Durationにする
+func toDuration(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.ValueOf(time.Duration(rv.Int())), nil
+	}
+	s, ok := stringOf(v)
+	if !ok {
+		return reflect.Value{}, ErrUnsupported
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return reflect.ValueOf(d), nil
+}
+
+// unmarshalText はvを文字列にしてtのUnmarshalTextを呼ぶ
+func unmarshalText(v interface{}, t reflect.Type) (reflect.Value, error) {
+	s, ok := stringOf(v)
+	if !ok {
+		var err error
+		if s, err = toString(v); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	out := reflect.New(t)
+	if err := out.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+		return reflect.Value{}, err
+	}
+	return out.Elem(), nil
+}
+
+// toSlice は文字列をカンマで区切るか、スライスの要素を1つずつ変換する
+func (r *Registry) toSlice(v interface{}, t reflect.Type) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		s, ok := stringOf(v)
+		if !ok {
+			return reflect.Value{}, ErrUnsupported
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			return reflect.ValueOf([]byte(s)).Convert(t), nil
+		}
+		var parts []string
+		if strings.TrimSpace(s) != "" {
+			parts = strings.Split(s, ",")
+		}
+		rv = reflect.ValueOf(parts)
+	}
+	out := reflect.MakeSlice(t, rv.Len(), rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		e := rv.Index(i).Interface()
+		if s, ok := e.(string); ok {
+			e = strings.TrimSpace(s)
+		}
+		ev, err := r.Convert(e, t.Elem())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("index %d: %w", i, err)
+		}
+		out.Index(i).Set(ev)
+	}
+	return out, nil
+}
@@ -0,0 +1,289 @@
+package convert_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/recipe-golang/6.error/convert"
+	"golang/recipe-golang/6.error/errs"
+)
+
+type Level int
+
+type Color struct{ R, G, B uint8 }
+
+func (c Color) String() string { return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B) }
+
+func TestToString(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		in   interface{}
+		want string
+	}{
+		"string":        {"gopher", "gopher"},
+		"int":           {100, "100"},
+		"uint8":         {uint8(255), "255"},
+		"float":         {1.5, "1.5"},
+		"bool":          {true, "true"},
+		"bytes":         {[]byte("abc"), "abc"},
+		"stringer":      {Color{255, 0, 128}, "#ff0080"},
+		"textmarshaler": {net.IPv4(127, 0, 0, 1), "127.0.0.1"},
+		"error":         {errors.New("boom"), "boom"},
+		"named":         {Level(3), "3"},
+		"duration":      {1500 * time.Millisecond, "1.5s"},
+		"time":          {time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), "2024-01-02T03:04:05Z"},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := convert.ToString(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestToInt(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		in      interface{}
+		want    int
+		wantErr error
+	}{
+		"int":       {42, 42, nil},
+		"int64":     {int64(-7), -7, nil},
+		"uint":      {uint(8), 8, nil},
+		"float":     {3.0, 3, nil},
+		"string":    {" 123 ", 123, nil},
+		"hex":       {"0x1f", 31, nil},
+		"fraction":  {3.5, 0, errs.Invalid},
+		"not num":   {"abc", 0, strconv.ErrSyntax},
+		"bool":      {true, 0, convert.ErrUnsupported},
+		"overflow":  {uint64(1 << 63), 0, strconv.ErrRange},
+		"unsupport": {struct{}{}, 0, convert.ErrUnsupported},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := convert.ToInt(tt.in)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("want %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTo_Range(t *testing.T) {
+	t.Parallel()
+	if _, err := convert.To[int8](200); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("want %v, got %v", strconv.ErrRange, err)
+	}
+	if _, err := convert.To[uint8]("-1"); !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("want %v, got %v", strconv.ErrSyntax, err)
+	}
+	if _, err := convert.To[uint16](-1); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("want %v, got %v", strconv.ErrRange, err)
+	}
+	if _, err := convert.To[float32](1e300); !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("want %v, got %v", strconv.ErrRange, err)
+	}
+	if got, err := convert.To[uint8]("255"); err != nil || got != 255 {
+		t.Errorf("want 255, got %v (%v)", got, err)
+	}
+}
+
+func TestToBool(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		in      interface{}
+		want    bool
+		wantErr bool
+	}{
+		"bool":   {true, true, false},
+		"string": {"false", false, false},
+		"yes":    {"Yes", true, false},
+		"off":    {"off", false, false},
+		"one":    {1, true, false},
+		"zero":   {uint(0), false, false},
+		"two":    {2, false, true},
+		"bad":    {"maybe", false, true},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := convert.ToBool(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("want error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestToTime(t *testing.T) {
+	t.Parallel()
+	want := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	cases := map[string]struct {
+		in   interface{}
+		want time.Time
+	}{
+		"rfc3339": {"2024-01-02T00:00:00Z", want},
+		"date":    {"2024-01-02", want},
+		"slash":   {"2024/01/02", want},
+		"unix":    {want.Unix(), want},
+		"time":    {want, want},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := convert.ToTime(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+	if _, err := convert.ToTime("yesterday"); err == nil {
+		t.Error("want error for unknown format")
+	}
+}
+
+func TestToDuration(t *testing.T) {
+	t.Parallel()
+	if got, err := convert.ToDuration("1m30s"); err != nil || got != 90*time.Second {
+		t.Errorf("want 1m30s, got %v (%v)", got, err)
+	}
+	if got, err := convert.ToDuration(int64(time.Second)); err != nil || got != time.Second {
+		t.Errorf("want 1s, got %v (%v)", got, err)
+	}
+	if _, err := convert.ToDuration("10"); err == nil {
+		t.Error("want error for missing unit")
+	}
+}
+
+func TestTo_TextUnmarshaler(t *testing.T) {
+	t.Parallel()
+	ip, err := convert.To[net.IP]("192.168.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ip.Equal(net.IPv4(192, 168, 0, 1)) {
+		t.Errorf("want 192.168.0.1, got %v", ip)
+	}
+	if _, err := convert.To[net.IP]("999.0.0.1"); err == nil {
+		t.Error("want error for invalid IP")
+	}
+}
+
+func TestTo_Named(t *testing.T) {
+	t.Parallel()
+	if got, err := convert.To[Level]("3"); err != nil || got != 3 {
+		t.Errorf("want 3, got %v (%v)", got, err)
+	}
+	if got, err := convert.To[[]int]("1, 2,3"); err != nil || !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("want [1 2 3], got %v (%v)", got, err)
+	}
+	if got, err := convert.To[*int]("5"); err != nil || *got != 5 {
+		t.Errorf("want 5, got %v (%v)", got, err)
+	}
+	if _, err := convert.To[[]int]("1,x"); !strings.Contains(fmt.Sprint(err), "index 1") {
+		t.Errorf("want index in error, got %v", err)
+	}
+}
+
+func TestTo_NilPointer(t *testing.T) {
+	t.Parallel()
+	var c *Color
+	if _, err := convert.ToString(c); !errors.Is(err, convert.ErrUnsupported) {
+		t.Errorf("want %v, got %v", convert.ErrUnsupported, err)
+	}
+	if _, err := convert.To[int](c); !errors.Is(err, convert.ErrUnsupported) {
+		t.Errorf("want %v, got %v", convert.ErrUnsupported, err)
+	}
+	if _, err := convert.To[bool](c); !errors.Is(err, convert.ErrUnsupported) {
+		t.Errorf("want %v, got %v", convert.ErrUnsupported, err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+	r := &convert.Registry{}
+	convert.Register(r, func(v interface{}) (Level, error) {
+		switch v {
+		case "debug":
+			return 0, nil
+		case "info":
+			return 1, nil
+		}
+		return 0, fmt.Errorf("unknown level %v", v)
+	})
+	if got, err := convert.ToWith[Level](r, "info"); err != nil || got != 1 {
+		t.Errorf("want 1, got %v (%v)", got, err)
+	}
+	// 登録したRegistryだけで使われる
+	if _, err := convert.To[Level]("info"); err == nil {
+		t.Error("want the default registry not to know the level names")
+	}
+}
+
+func TestRegister_BadConverter(t *testing.T) {
+	t.Parallel()
+	r := &convert.Registry{}
+	r.Register(reflect.TypeOf(Level(0)), func(v interface{}) (interface{}, error) {
+		if v == "nil" {
+			return nil, nil
+		}
+		return "not a level", nil
+	})
+	for _, v := range []string{"nil", "string"} {
+		_, err := convert.ToWith[Level](r, v)
+		if !errors.Is(err, errs.Invalid) {
+			t.Errorf("%s: want Invalid error, got %v", v, err)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	t.Parallel()
+	_, err := convert.To[time.Duration](true)
+	var ce *convert.Error
+	if !errors.As(err, &ce) {
+		t.Fatalf("want *convert.Error, got %v", err)
+	}
+	if ce.Value != true || ce.To != reflect.TypeOf(time.Duration(0)) {
+		t.Errorf("want value and type, got %+v", *ce)
+	}
+	if !errors.Is(err, errs.Invalid) || errs.HTTPStatus(err) != 400 {
+		t.Errorf("want Invalid, got %v", errs.CodeOf(err))
+	}
+	if want := "convert: cannot convert true (bool) to time.Duration: unsupported conversion"; err.Error() != want {
+		t.Errorf("want %q, got %q", want, err.Error())
+	}
+}
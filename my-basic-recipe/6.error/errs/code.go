@@ -0,0 +1,139 @@
+package errs
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Code はエラーの種類を表す
+// errorを実装しているのでerrors.Isの第2引数に渡せる
+type Code int
+
+const (
+	Unknown     Code = iota // コードなし
+	NotFound                // 対象が存在しない
+	Invalid                 // 入力が不正
+	Conflict                // 既に存在する・状態が合わない
+	Unavailable             // 一時的に利用できない（リトライで回復しうる）
+)
+
+func (c Code) String() string {
+	switch c {
+	case Unknown:
+		return "unknown"
+	case NotFound:
+		return "not found"
+	case Invalid:
+		return "invalid"
+	case Conflict:
+		return "conflict"
+	case Unavailable:
+		return "unavailable"
+	}
+	return fmt.Sprintf("Code(%d)", int(c))
+}
+
+func (c Code) Error() string { return c.String() }
+
+// New はコードつきのエラーを作る
+//
+//	return errs.NotFound.New("user not found")
+func (c Code) New(msg string) error {
+	return &wrapError{msg: msg, code: c, stack: callers(1)}
+}
+
+// Errorf は書式を指定してコードつきのエラーを作る
+func (c Code) Errorf(format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	return &wrapError{err: err, code: c, stack: stackIfNone(err, 1)}
+}
+
+// Wrap はerrにコードとメッセージを付ける
+// errがnilのときはnilを返す
+func (c Code) Wrap(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return &wrapError{msg: msg, err: err, code: c, stack: stackIfNone(err, 1)}
+}
+
+// Coder はコードを持つエラー型
+// 他のパッケージのエラー型もErrorCodeを実装するとCodeOfでコードを取り出せる
+type Coder interface {
+	ErrorCode() Code
+}
+
+// CodeOf はerrのチェーンで最も外側のコードを返す
+// コードがなければUnknownを返す
+func CodeOf(err error) Code {
+	for err != nil {
+		if e, ok := err.(interface{ errCode() Code }); ok && e.errCode() != Unknown {
+			return e.errCode()
+		}
+		if e, ok := err.(Coder); ok && e.ErrorCode() != Unknown {
+			return e.ErrorCode()
+		}
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			err = u.Unwrap()
+		case interface{ Unwrap() []error }:
+			// 複数エラーは最初に見つかったコードを使う
+			for _, e := range u.Unwrap() {
+				if c := CodeOf(e); c != Unknown {
+					return c
+				}
+			}
+			return Unknown
+		default:
+			return Unknown
+		}
+	}
+	return Unknown
+}
+
+// HTTPStatus はerrに対応するHTTPのステータスコードを返す
+// nilのときは200、コードがないときは500
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	switch CodeOf(err) {
+	case NotFound:
+		return http.StatusNotFound
+	case Invalid:
+		return http.StatusBadRequest
+	case Conflict:
+		return http.StatusConflict
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// 終了コードはsysexits.hに合わせる
+const (
+	ExitOK          = 0
+	ExitFailure     = 1  // その他のエラー
+	ExitUsage       = 64 // EX_USAGE
+	ExitNoInput     = 66 // EX_NOINPUT
+	ExitUnavailable = 69 // EX_UNAVAILABLE
+	ExitCantCreate  = 73 // EX_CANTCREAT
+)
+
+// ExitCode はCLIの終了コードを返す
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	switch CodeOf(err) {
+	case NotFound:
+		return ExitNoInput
+	case Invalid:
+		return ExitUsage
+	case Conflict:
+		return ExitCantCreate
+	case Unavailable:
+		return ExitUnavailable
+	}
+	return ExitFailure
+}
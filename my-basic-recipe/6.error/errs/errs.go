@@ -0,0 +1,129 @@
+// Package errs はスタックトレース、エラーコード、複数エラーのまとめを扱う
+//
+// pkg/errorsやmultierrと同じことを標準のerrors.Is/As/Unwrapと組み合わせて使える
+//
+//	if err := repo.Find(id); err != nil {
+//		return errs.Wrapf(err, "find user %d", id)
+//	}
+//	...
+//	if errors.Is(err, errs.NotFound) {
+//		// 見つからなかった場合の処理
+//	}
+//	fmt.Printf("%+v\n", err) // メッセージとスタックトレース
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// wrapError はメッセージ、元のエラー、コード、スタックを持つ
+type wrapError struct {
+	msg   string
+	err   error
+	code  Code
+	stack StackTrace
+}
+
+// New はスタックトレースつきのエラーを作る
+func New(msg string) error {
+	return &wrapError{msg: msg, stack: callers(1)}
+}
+
+// Errorf は書式を指定してスタックトレースつきのエラーを作る
+// fmt.Errorfと同じく%wでラップできる
+func Errorf(format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	return &wrapError{err: err, stack: stackIfNone(err, 1)}
+}
+
+// Wrap はerrにメッセージを付けてラップする
+// errがnilのときはnilを返す
+// チェーンにスタックがなければこの位置のスタックを記録する
+func Wrap(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return &wrapError{msg: msg, err: err, stack: stackIfNone(err, 1)}
+}
+
+// Wrapf は書式を指定してerrをラップする
+func Wrapf(err error, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return &wrapError{msg: fmt.Sprintf(format, args...), err: err, stack: stackIfNone(err, 1)}
+}
+
+// WithStack はメッセージを変えずにスタックだけを記録する
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &wrapError{err: err, stack: stackIfNone(err, 1)}
+}
+
+// stackIfNone はerrのチェーンにスタックがない場合だけ記録する
+// ラップするたびにスタックが重複して出力されないようにする
+func stackIfNone(err error, skip int) StackTrace {
+	if StackOf(err) != nil {
+		return nil
+	}
+	return callers(skip + 1)
+}
+
+func (e *wrapError) Error() string {
+	switch {
+	case e.err == nil:
+		return e.msg
+	case e.msg == "":
+		return e.err.Error()
+	}
+	return e.msg + ": " + e.err.Error()
+}
+
+func (e *wrapError) Unwrap() error { return e.err }
+
+// StackTrace はこのエラーで記録したスタックを返す
+func (e *wrapError) StackTrace() StackTrace { return e.stack }
+
+func (e *wrapError) errCode() Code { return e.code }
+
+// Is はerrors.Is(err, errs.NotFound)のようにコードで比較できるようにする
+func (e *wrapError) Is(target error) bool {
+	c, ok := target.(Code)
+	return ok && e.code != Unknown && e.code == c
+}
+
+// Temporary はUnavailableか、ラップしたエラーが一時的なときにtrueを返す
+// 振る舞いでエラーを判定するIsTemporaryのような関数で使える
+func (e *wrapError) Temporary() bool {
+	return CodeOf(e) == Unavailable || temporary(e.err)
+}
+
+// temporary はerrのチェーンにTemporary()がtrueを返すエラーがあるか調べる
+func temporary(err error) bool {
+	var te interface{ Temporary() bool }
+	return err != nil && errors.As(err, &te) && te.Temporary()
+}
+
+// Format は%+vでコードとスタックトレースも出力する
+func (e *wrapError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			io.WriteString(s, e.Error())
+			if c := CodeOf(e); c != Unknown {
+				fmt.Fprintf(s, " [%s]", c)
+			}
+			writeStack(s, StackOf(e))
+			return
+		}
+		io.WriteString(s, e.Error())
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	}
+}
@@ -0,0 +1,156 @@
+package errs_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"golang/recipe-golang/6.error/errs"
+)
+
+func TestWrap(t *testing.T) {
+	t.Parallel()
+	base := errors.New("disk full")
+	err := errs.Wrapf(errs.Wrap(base, "write"), "save %s", "a.txt")
+	if got, want := err.Error(), "save a.txt: write: disk full"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if !errors.Is(err, base) {
+		t.Error("want errors.Is to find the base error")
+	}
+	if errs.Wrap(nil, "x") != nil || errs.Wrapf(nil, "x") != nil || errs.WithStack(nil) != nil {
+		t.Error("want nil for nil error")
+	}
+}
+
+func TestStack(t *testing.T) {
+	t.Parallel()
+	err := errs.Wrap(errs.New("boom"), "outer")
+	st := errs.StackOf(err)
+	if len(st) == 0 {
+		t.Fatal("want stack trace")
+	}
+	if fn := st.Frames()[0].Function; !strings.HasSuffix(fn, "errs_test.TestStack") {
+		t.Errorf("want the stack to start at TestStack, got %s", fn)
+	}
+	detail := fmt.Sprintf("%+v", err)
+	if !strings.HasPrefix(detail, "outer: boom\n") || !strings.Contains(detail, "errs_test.go:") {
+		t.Errorf("want message and stack, got %q", detail)
+	}
+	// スタックは1回だけ出力する
+	if n := strings.Count(detail, "TestStack"); n != 1 {
+		t.Errorf("want 1 stack, got %d:\n%s", n, detail)
+	}
+	if got := fmt.Sprintf("%v", err); got != "outer: boom" {
+		t.Errorf("want %q, got %q", "outer: boom", got)
+	}
+	if errs.StackOf(errors.New("plain")) != nil {
+		t.Error("want no stack for plain errors")
+	}
+}
+
+func TestCode(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		err    error
+		code   errs.Code
+		status int
+		exit   int
+	}{
+		"nil":         {nil, errs.Unknown, http.StatusOK, 0},
+		"plain":       {errors.New("x"), errs.Unknown, http.StatusInternalServerError, 1},
+		"not found":   {errs.NotFound.New("user"), errs.NotFound, http.StatusNotFound, 66},
+		"invalid":     {errs.Invalid.Errorf("age %d", -1), errs.Invalid, http.StatusBadRequest, 64},
+		"conflict":    {errs.Conflict.Wrap(fs.ErrExist, "create"), errs.Conflict, http.StatusConflict, 73},
+		"unavailable": {errs.Wrap(errs.Unavailable.New("db"), "query"), errs.Unavailable, http.StatusServiceUnavailable, 69},
+		"outermost":   {errs.Conflict.Wrap(errs.NotFound.New("x"), "y"), errs.Conflict, http.StatusConflict, 73},
+		"fmt wrap":    {fmt.Errorf("ctx: %w", errs.NotFound.New("x")), errs.NotFound, http.StatusNotFound, 66},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := errs.CodeOf(tt.err); got != tt.code {
+				t.Errorf("want %v, got %v", tt.code, got)
+			}
+			if tt.code != errs.Unknown && !errors.Is(tt.err, tt.code) {
+				t.Errorf("want errors.Is(err, %v)", tt.code)
+			}
+			if got := errs.HTTPStatus(tt.err); got != tt.status {
+				t.Errorf("want status %d, got %d", tt.status, got)
+			}
+			if got := errs.ExitCode(tt.err); got != tt.exit {
+				t.Errorf("want exit code %d, got %d", tt.exit, got)
+			}
+		})
+	}
+}
+
+func TestCode_Is(t *testing.T) {
+	t.Parallel()
+	err := errs.Conflict.Wrap(fs.ErrExist, "create")
+	if errors.Is(err, errs.NotFound) {
+		t.Error("want Conflict not to match NotFound")
+	}
+	if !errors.Is(err, fs.ErrExist) {
+		t.Error("want the wrapped error to match")
+	}
+	if got := fmt.Sprintf("%+v", err); !strings.HasPrefix(got, "create: file already exists [conflict]") {
+		t.Errorf("want code in detail, got %q", got)
+	}
+}
+
+func TestTemporary(t *testing.T) {
+	t.Parallel()
+	isTemporary := func(err error) bool {
+		var te interface{ Temporary() bool }
+		return errors.As(err, &te) && te.Temporary()
+	}
+	if !isTemporary(errs.Wrap(errs.Unavailable.New("db"), "query")) {
+		t.Error("want Unavailable to be temporary")
+	}
+	if isTemporary(errs.NotFound.New("x")) {
+		t.Error("want NotFound not to be temporary")
+	}
+}
+
+func TestMulti(t *testing.T) {
+	t.Parallel()
+	var rerr error
+	rerr = errs.Append(rerr, nil)
+	if rerr != nil {
+		t.Fatalf("want nil, got %v", rerr)
+	}
+	first := errs.NotFound.New("user 1")
+	rerr = errs.Append(rerr, first)
+	if rerr != first {
+		t.Errorf("want a single error to be returned as is, got %v", rerr)
+	}
+	_, openErr := os.Open("/no/such/file")
+	rerr = errs.Append(rerr, openErr, errs.Combine(errors.New("a"), errors.New("b")))
+
+	if got := len(errs.Errors(rerr)); got != 4 {
+		t.Errorf("want 4 flattened errors, got %d", got)
+	}
+	if !errors.Is(rerr, errs.NotFound) || !errors.Is(rerr, fs.ErrNotExist) {
+		t.Error("want errors.Is to check every member")
+	}
+	var pathErr *fs.PathError
+	if !errors.As(rerr, &pathErr) || pathErr.Path != "/no/such/file" {
+		t.Errorf("want errors.As to find *fs.PathError, got %v", pathErr)
+	}
+	if got := errs.HTTPStatus(rerr); got != http.StatusNotFound {
+		t.Errorf("want %d, got %d", http.StatusNotFound, got)
+	}
+	if want := "user 1; open /no/such/file: no such file or directory; a; b"; rerr.Error() != want {
+		t.Errorf("want %q, got %q", want, rerr.Error())
+	}
+	detail := fmt.Sprintf("%+v", rerr)
+	if !strings.HasPrefix(detail, "4 errors occurred:\n  * user 1 [not found]\n    ") {
+		t.Errorf("want detailed list, got %q", detail)
+	}
+}
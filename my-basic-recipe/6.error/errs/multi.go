@@ -0,0 +1,85 @@
+package errs
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// multiError は複数のエラーをまとめたもの
+// Unwrap() []errorを実装しているのでerrors.Is/Asはすべてのエラーを調べる
+type multiError struct {
+	errs []error
+}
+
+// Append はerrにerrsを追加したエラーを返す
+// nilは無視し、すべてnilならnilを返す。1つだけならそのまま返す
+//
+//	var rerr error
+//	if err := step1(); err != nil {
+//		rerr = errs.Append(rerr, err)
+//	}
+//	if err := step2(); err != nil {
+//		rerr = errs.Append(rerr, err)
+//	}
+//	return rerr
+func Append(err error, errs ...error) error {
+	var all []error
+	for _, e := range append([]error{err}, errs...) {
+		all = append(all, Errors(e)...)
+	}
+	switch len(all) {
+	case 0:
+		return nil
+	case 1:
+		return all[0]
+	}
+	return &multiError{errs: all}
+}
+
+// Combine はerrsを1つのエラーにまとめる
+func Combine(errs ...error) error {
+	return Append(nil, errs...)
+}
+
+// Errors はAppendでまとめたエラーを1つずつ返す
+// まとめたものでなければerrだけを返す。nilのときはnil
+func Errors(err error) []error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *multiError:
+		return append([]error(nil), e.errs...)
+	}
+	return []error{err}
+}
+
+func (m *multiError) Error() string {
+	msgs := make([]string, len(m.errs))
+	for i, err := range m.errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (m *multiError) Unwrap() []error { return m.errs }
+
+// Format は%+vでそれぞれのエラーを詳細つきで出力する
+func (m *multiError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%d errors occurred:", len(m.errs))
+			for _, err := range m.errs {
+				detail := fmt.Sprintf("%+v", err)
+				io.WriteString(s, "\n  * "+strings.ReplaceAll(detail, "\n", "\n    "))
+			}
+			return
+		}
+		io.WriteString(s, m.Error())
+	case 's':
+		io.WriteString(s, m.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", m.Error())
+	}
+}
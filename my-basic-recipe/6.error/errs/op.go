@@ -0,0 +1,210 @@
+package errs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// OpError は失敗した操作と対象のリソース、パラメータ、原因を持つエラー
+// os.PathError（Op, Path, Err）をファイル以外のDBやHTTPにも使えるようにしたもの
+//
+//	var oe *errs.OpError
+//	if errors.As(err, &oe) {
+//		fmt.Println(oe.Resource) // 失敗したパス・テーブル・URL
+//	}
+type OpError struct {
+	Op       string                 // 操作（"open", "insert", "GET"など）
+	Kind     ResourceKind           // リソースの種類
+	Resource string                 // パス・テーブル名・URL
+	Params   map[string]interface{} // 操作のパラメータ（出力するときは秘密の値を隠す）
+	Code     Code                   // 指定しない場合は原因のコードを使う
+	Err      error                  // 原因
+	stack    StackTrace
+}
+
+// ResourceKind はOpErrorのリソースの種類
+type ResourceKind int
+
+const (
+	Other ResourceKind = iota
+	File               // ファイルのパス
+	Table              // DBのテーブル
+	URL                // HTTPのURL
+)
+
+func (k ResourceKind) String() string {
+	switch k {
+	case File:
+		return "file"
+	case Table:
+		return "table"
+	case URL:
+		return "url"
+	}
+	return "other"
+}
+
+// Op はOpErrorを作る。errがnilのときはnilを返す
+// kvはパラメータのキーと値を交互に並べる
+//
+//	return errs.Op("insert", errs.Table, "addressbook", err, "name", name, "phone", phone)
+func Op(op string, kind ResourceKind, resource string, err error, kv ...interface{}) error {
+	return newOp(Unknown, op, kind, resource, err, kv)
+}
+
+// Op はコードつきのOpErrorを作る。errがnilのときはnilを返す
+//
+//	return errs.NotFound.Op("select", errs.Table, "addressbook", sql.ErrNoRows, "id", id)
+func (c Code) Op(op string, kind ResourceKind, resource string, err error, kv ...interface{}) error {
+	return newOp(c, op, kind, resource, err, kv)
+}
+
+func newOp(c Code, op string, kind ResourceKind, resource string, err error, kv []interface{}) error {
+	if err == nil {
+		return nil
+	}
+	e := &OpError{Op: op, Kind: kind, Resource: resource, Code: c, Err: err, stack: stackIfNone(err, 2)}
+	for i := 0; i+1 < len(kv); i += 2 {
+		if e.Params == nil {
+			e.Params = map[string]interface{}{}
+		}
+		e.Params[fmt.Sprint(kv[i])] = kv[i+1]
+	}
+	return e
+}
+
+func (e *OpError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Op)
+	if e.Resource != "" {
+		b.WriteString(" " + e.Resource)
+	}
+	if e.Err != nil {
+		b.WriteString(": " + e.Err.Error())
+	}
+	return b.String()
+}
+
+func (e *OpError) Unwrap() error { return e.Err }
+
+// StackTrace はこのエラーで記録したスタックを返す
+func (e *OpError) StackTrace() StackTrace { return e.stack }
+
+func (e *OpError) errCode() Code { return e.Code }
+
+// Temporary はUnavailableか、原因のエラーが一時的なときにtrueを返す
+// Codeを指定しない場合は原因のコードで判定する
+func (e *OpError) Temporary() bool {
+	return CodeOf(e) == Unavailable || temporary(e.Err)
+}
+
+// Is はコードか、*OpErrorのパターンと比較する
+// パターンの空のフィールドは何にでも一致する
+//
+//	errors.Is(err, &errs.OpError{Op: "insert", Resource: "addressbook"})
+func (e *OpError) Is(target error) bool {
+	switch t := target.(type) {
+	case Code:
+		return e.Code != Unknown && e.Code == t
+	case *OpError:
+		return (t.Op == "" || t.Op == e.Op) &&
+			(t.Kind == Other || t.Kind == e.Kind) &&
+			(t.Resource == "" || t.Resource == e.Resource)
+	}
+	return false
+}
+
+// As は標準ライブラリのエラー型として取り出せるようにする
+// ファイルの操作は*fs.PathError、URLの操作は*url.Errorとして取り出せる
+func (e *OpError) As(target interface{}) bool {
+	switch t := target.(type) {
+	case **fs.PathError:
+		if e.Kind != File {
+			return false
+		}
+		*t = &fs.PathError{Op: e.Op, Path: e.Resource, Err: e.Err}
+		return true
+	case **url.Error:
+		if e.Kind != URL {
+			return false
+		}
+		*t = &url.Error{Op: e.Op, URL: e.Resource, Err: e.Err}
+		return true
+	}
+	return false
+}
+
+// Format は%+vでパラメータとスタックトレースも出力する
+// パラメータの秘密の値はRedactで隠す
+func (e *OpError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			io.WriteString(s, e.Op)
+			if e.Resource != "" {
+				fmt.Fprintf(s, " %s %s", e.Kind, e.Resource)
+			}
+			if len(e.Params) > 0 {
+				fmt.Fprintf(s, " {%s}", e.paramString())
+			}
+			if e.Err != nil {
+				io.WriteString(s, ": "+e.Err.Error())
+			}
+			if c := CodeOf(e); c != Unknown {
+				fmt.Fprintf(s, " [%s]", c)
+			}
+			writeStack(s, StackOf(e))
+			return
+		}
+		io.WriteString(s, e.Error())
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+func (e *OpError) paramString() string {
+	keys := make([]string, 0, len(e.Params))
+	for k := range e.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := make([]string, len(keys))
+	for i, k := range keys {
+		kvs[i] = fmt.Sprintf("%s=%v", k, Redact(k, e.Params[k]))
+	}
+	return strings.Join(kvs, " ")
+}
+
+// Redacted は出力するときに隠す値
+// パラメータのキーにかかわらず隠したい値を包む
+type Redacted struct {
+	Value interface{}
+}
+
+const redacted = "[REDACTED]"
+
+func (Redacted) String() string { return redacted }
+
+// SensitiveKeys はOpErrorを出力するときに値を隠すパラメータのキー
+// 大文字小文字を区別せず、キーにこれらの文字列を含むと隠す
+var SensitiveKeys = []string{"password", "passwd", "secret", "token", "apikey", "api_key", "authorization", "cookie", "phone"}
+
+// Redact はkeyが秘密のパラメータならvを隠した値を返す
+func Redact(key string, v interface{}) interface{} {
+	if _, ok := v.(Redacted); ok {
+		return redacted
+	}
+	k := strings.ToLower(key)
+	for _, s := range SensitiveKeys {
+		if strings.Contains(k, s) {
+			return redacted
+		}
+	}
+	return v
+}
@@ -0,0 +1,103 @@
+package errs_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang/recipe-golang/6.error/errs"
+)
+
+func TestOp(t *testing.T) {
+	t.Parallel()
+	if errs.Op("open", errs.File, "a.txt", nil) != nil {
+		t.Error("want nil for nil error")
+	}
+	err := fmt.Errorf("save: %w", errs.Op("insert", errs.Table, "addressbook", sql.ErrConnDone,
+		"name", "Gopher", "phone", "090-1234-5678", "note", errs.Redacted{Value: "private"}))
+
+	var oe *errs.OpError
+	if !errors.As(err, &oe) {
+		t.Fatalf("want *OpError, got %v", err)
+	}
+	if oe.Resource != "addressbook" || oe.Kind != errs.Table || oe.Params["name"] != "Gopher" {
+		t.Errorf("want table addressbook with params, got %+v", *oe)
+	}
+	if !errors.Is(err, sql.ErrConnDone) {
+		t.Error("want the cause to match")
+	}
+	if got, want := err.Error(), "save: insert addressbook: sql: connection is already closed"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	detail := fmt.Sprintf("%+v", oe)
+	if want := `insert table addressbook {name=Gopher note=[REDACTED] phone=[REDACTED]}: sql: connection is already closed`; !strings.HasPrefix(detail, want+"\n") {
+		t.Errorf("want %q, got %q", want, detail)
+	}
+	if strings.Contains(detail, "090") || strings.Contains(detail, "private") {
+		t.Errorf("want sensitive params to be redacted, got %q", detail)
+	}
+}
+
+func TestOpError_Is(t *testing.T) {
+	t.Parallel()
+	err := errs.Op("insert", errs.Table, "addressbook", sql.ErrTxDone)
+	cases := map[string]struct {
+		target error
+		want   bool
+	}{
+		"same op":        {&errs.OpError{Op: "insert"}, true},
+		"same resource":  {&errs.OpError{Resource: "addressbook", Kind: errs.Table}, true},
+		"other op":       {&errs.OpError{Op: "select"}, false},
+		"other kind":     {&errs.OpError{Kind: errs.File}, false},
+		"other resource": {&errs.OpError{Op: "insert", Resource: "user"}, false},
+		"cause":          {sql.ErrTxDone, true},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if got := errors.Is(err, tt.target); got != tt.want {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestOpError_Code(t *testing.T) {
+	t.Parallel()
+	err := errs.NotFound.Op("select", errs.Table, "addressbook", sql.ErrNoRows, "id", 1)
+	if !errors.Is(err, errs.NotFound) || errs.HTTPStatus(err) != 404 {
+		t.Errorf("want NotFound, got %v", errs.CodeOf(err))
+	}
+	if st := errs.StackOf(err); len(st) == 0 || !strings.HasSuffix(st.Frames()[0].Function, "TestOpError_Code") {
+		t.Errorf("want stack from the caller, got %+v", st)
+	}
+	// コードを指定しない場合は原因のコードを使う
+	inner := errs.Op("GET", errs.URL, "http://example.com", errs.Unavailable.New("503"))
+	if errs.CodeOf(inner) != errs.Unavailable {
+		t.Errorf("want Unavailable, got %v", errs.CodeOf(inner))
+	}
+}
+
+func TestOpError_As(t *testing.T) {
+	t.Parallel()
+	fileErr := errs.Op("open", errs.File, "/tmp/a.txt", fs.ErrNotExist)
+	var pe *fs.PathError
+	if !errors.As(fileErr, &pe) || pe.Path != "/tmp/a.txt" || pe.Op != "open" {
+		t.Errorf("want *fs.PathError, got %v", pe)
+	}
+	var ue *url.Error
+	if errors.As(fileErr, &ue) {
+		t.Error("want file errors not to be *url.Error")
+	}
+
+	urlErr := errs.Op("GET", errs.URL, "http://example.com/x", errors.New("refused"))
+	if !errors.As(urlErr, &ue) || ue.URL != "http://example.com/x" {
+		t.Errorf("want *url.Error, got %v", ue)
+	}
+}
@@ -0,0 +1,74 @@
+package errs
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// StackTrace はエラーを作った時点の呼び出し履歴
+type StackTrace []uintptr
+
+const maxDepth = 32
+
+// callers はskip個の呼び出し元を飛ばしてスタックを記録する
+func callers(skip int) StackTrace {
+	var pcs [maxDepth]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+	return StackTrace(pcs[:n])
+}
+
+// Frames はStackTraceを関数名・ファイル・行番号に変換する
+func (st StackTrace) Frames() []runtime.Frame {
+	if len(st) == 0 {
+		return nil
+	}
+	var frames []runtime.Frame
+	fs := runtime.CallersFrames(st)
+	for {
+		f, more := fs.Next()
+		frames = append(frames, f)
+		if !more {
+			return frames
+		}
+	}
+}
+
+// Format は%+vで関数名とファイルの位置を1行ずつ出力する
+//
+//	main.run
+//		/path/to/main.go:12
+func (st StackTrace) Format(s fmt.State, verb rune) {
+	if verb != 'v' || !s.Flag('+') {
+		fmt.Fprintf(s, "%v", []uintptr(st))
+		return
+	}
+	for _, f := range st.Frames() {
+		fmt.Fprintf(s, "\n%s\n\t%s:%d", f.Function, f.File, f.Line)
+	}
+}
+
+// StackOf はerrのチェーンで最も内側（最初に記録された）スタックを返す
+// 記録されていなければnilを返す
+func StackOf(err error) StackTrace {
+	var st StackTrace
+	for err != nil {
+		if e, ok := err.(interface{ StackTrace() StackTrace }); ok {
+			if s := e.StackTrace(); s != nil {
+				st = s
+			}
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			break
+		}
+		err = u.Unwrap()
+	}
+	return st
+}
+
+func writeStack(w io.Writer, st StackTrace) {
+	if st != nil {
+		fmt.Fprintf(w, "%+v", st)
+	}
+}
@@ -0,0 +1,8 @@
+module golang/recipe-golang/6.error
+
+go 1.21
+
+require (
+	github.com/rivo/uniseg v0.4.7
+	golang.org/x/text v0.14.0
+)
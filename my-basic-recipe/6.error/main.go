@@ -2,7 +2,6 @@ package main
 
 import (
 	"bufio"
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -10,7 +9,11 @@ import (
 	"os"
 	"regexp"
 	"strings"
-	"unicode/utf8"
+
+	"golang/recipe-golang/6.error/convert"
+	"golang/recipe-golang/6.error/errs"
+	"golang/recipe-golang/6.error/retry"
+	"golang/recipe-golang/6.error/runescan"
 )
 
 func main() {
@@ -64,6 +67,17 @@ func main() {
 		fmt.Println("s = ", s1.String()) //s1 =  test
 	}
 
+	// Stringerを実装していない値も変換したい場合はconvertパッケージを使う
+	// Stringer、TextMarshaler、strconvの順に試して文字列にする
+	if s3, err := convert.To[string](s); err == nil {
+		fmt.Println("s = ", s3) //s =  100
+	}
+	// 文字列から数値・bool・time.Durationなどにも変換できる
+	// 変換できない場合は値と型と理由を持つ*convert.Errorが返る
+	if _, err := convert.To[int8]("300"); err != nil {
+		fmt.Println(err) //convert: cannot convert "300" (string) to int8: strconv.ParseInt: parsing "300": value out of range
+	}
+
 	// ** エラー処理をまとめる */ ・・bufio.Scannerの実装が参考になる
 	// 途中でエラーが発生したらそれ以降の処理を飛ばす
 	// すべての処理が終わったらまとめてエラーを処理
@@ -80,7 +94,9 @@ func main() {
 	}
 
 	//コードポイント(rune)ずつ読み込むScannerを作る問題
-	s3 := NewRuneScanner(strings.NewReader("Hello, 世界"))
+	// runescanパッケージ: Readの境界で分割されたマルチバイト文字もデコードできる
+	// 不正なバイトは置き換え・読み飛ばし・エラー（位置つき）から選べる
+	s3 := runescan.New(strings.NewReader("Hello, 世界"))
 	for {
 		r, err := s3.Scan()
 		// fmt.Println("r = ", r)//r =  101 etc
@@ -95,6 +111,16 @@ func main() {
 		fmt.Printf("%c\n", r)
 	}
 
+	// 書記素クラスタ（見た目の1文字）ずつ読み込む
+	// Shift_JISなどはWithEncodingでデコードしてから読む
+	g3 := runescan.NewGraphemeScanner(strings.NewReader("が🇯🇵👍🏽"))
+	for g3.Scan() {
+		fmt.Println(g3.Text()) // が 🇯🇵 👍🏽
+	}
+	if err := g3.Err(); err != nil {
+		log.Fatal(err)
+	}
+
 	// ** エラーをまとめる */ ・・https://github.com/uber-go/multierrを使う
 	// 	成功したものは成功させたい
 	// 失敗したものだけエラーとして報告したい
@@ -110,6 +136,15 @@ func main() {
 	// 	for _, err := range multierr.Errors(rerr) {
 	// 		fmt.Println(err)
 	//  }
+	// errsパッケージのAppendでも同じことができる
+	// まとめたエラーもerrors.Is/Asですべてのエラーを調べられる
+	var rerr error
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if _, err := os.Open(name); err != nil {
+			rerr = errs.Append(rerr, err)
+		}
+	}
+	fmt.Println(len(errs.Errors(rerr)), errors.Is(rerr, os.ErrNotExist)) // 2 true
 
 	// **エラーに文脈を持たせる */ ・・github.com/pkg/errorsを使う
 	// 	エラーメッセージが「File Not Found」とかでは分かりづらい
@@ -120,6 +155,16 @@ func main() {
 	// if err := f(s); err != nil {
 	// 	return errors.Wrapf(err, "f() with %s", s)
 	// }
+	// errsパッケージのWrapfはスタックトレースを記録する
+	// %+vでメッセージとスタックトレースを出力する
+	err3 := errs.Wrapf(errs.NotFound.New("user not found"), "find user %d", 1)
+	fmt.Println(err3)                                       // find user 1: user not found
+	fmt.Println(errors.Is(err3, errs.NotFound))             // true
+	fmt.Println(errs.HTTPStatus(err3), errs.ExitCode(err3)) // 404 66
+	// fmt.Printf("%+v\n", err3)
+	// find user 1: user not found [not found]
+	// main.main
+	// 	/path/to/main.go:123
 
 	// ** エラーに文脈を持たせる（Go1.13） */・・fmt.Errorf関数の%wを使う
 	// 引数で指定したエラーをラップしてエラーを作る
@@ -148,6 +193,19 @@ func main() {
 		fmt.Println(err) // <nil>
 	}
 
+	// ** 振る舞いでリトライする */ ・・retryパッケージを使う
+	// IsTemporaryと同じくTemporary()やTimeout()メソッドでリトライするか決める
+	// errors.Isの対象やHTTPのステータスコードで決めることもできる
+	// （このファイルではerror型を定義し直しているためコメントにしている）
+	// err := retry.Do(ctx, func(ctx context.Context) error {
+	// 	return callAPI(ctx) // errs.Unavailableなど一時的なエラーならリトライする
+	// },
+	// 	retry.WithBackoff(retry.DecorrelatedJitter(100*time.Millisecond, 10*time.Second)),
+	// 	retry.WithMaxElapsed(time.Minute),
+	// )
+	// fmt.Printf("%+v\n", err) // 諦めた理由とすべての試行が出力される
+	fmt.Println(retry.HTTPStatus()(&retry.StatusError{StatusCode: 503}) == retry.Retry) // true
+
 	//** エラーとログ */
 	// ** エラーメッセージを工夫する
 	// ログに出すエラーメッセージに必要十分な情報を入れる
@@ -202,6 +260,17 @@ func main() {
 	// 	log.Fatal(err)//2022/05/01 19:59:49 error
 	// }
 
+	// ** ゴールーチンのパニック */ ・・safegoパッケージを使う
+	// ゴールーチンの中のパニックは呼び出し元でrecoverできずプロセスが終了する
+	// safego.Goやsafego.Groupで起動するとパニックを*safego.PanicErrorとして受け取れる
+	// WithLabelsでpprofのラベルを付けると、どのゴールーチンのパニックかわかる
+	// g, ctx := safego.WithContext(ctx, safego.WithReporter(safego.LogReporter(nil)))
+	// g.Go(ctx, export, safego.WithLabels("job", "export"))
+	// if err := g.Wait(); err != nil {
+	// 	fmt.Printf("%+v\n", err) // safego: panic in [job=export]: ... とスタック
+	// }
+	// 大域脱出の番兵のようにerror以外のパニックを通したい場合はWithRepanic(safego.NonError)
+
 	//** 大域脱出のテクニックとして使う */ ・・
 	defer func() {
 		if r := recover(); r != nil {
@@ -223,6 +292,11 @@ type error interface {
 
 // ** エラー型の定義 */ ・・Errorメソッドを実装している型を定義する
 //そのエラー特有の情報を保持する
+// ファイル以外（DBのテーブルやHTTPのURL）にも使えるようにしたものがerrs.OpError
+// 操作・リソース・パラメータ・原因を持ち、%+vでは秘密のパラメータを隠して出力する
+// err := errs.Op("insert", errs.Table, "addressbook", err, "name", name, "phone", phone)
+// var oe *errs.OpError
+// if errors.As(err, &oe) { fmt.Println(oe.Resource) } // addressbook
 type PathError struct {
 	Op   string
 	Path string
@@ -256,7 +330,6 @@ func f() (rerr error) {
 		}
 	}()
 	panic(errors.New("error"))
-	return nil
 }
 
 type Stringer interface {
@@ -283,35 +356,6 @@ func ToStringer(x interface{}) (Stringer, error) {
 	return nil, MyError("CastError")
 }
 
-//コードポイント(rune)ずつ読み込むScannerを作る問題
-type RuneScanner struct {
-	r   io.Reader
-	buf [16]byte
-}
-
-func NewRuneScanner(r io.Reader) *RuneScanner {
-	return &RuneScanner{r: r}
-}
-
-func (s *RuneScanner) Scan() (rune, error) {
-	n, err := s.r.Read(s.buf[:]) //与えられたバイトスライス p []byte を先頭から埋めていく。埋まったバイト数 n と、埋める過程で発生したエラー err を返す。
-	// fmt.Println("n=",n)
-	if err != nil {
-		return 0, err
-	}
-
-	r, size := utf8.DecodeRune(s.buf[:n])
-	// fmt.Println("r, size = ", r, size)
-	if r == utf8.RuneError {
-		return 0, errors.New("RuneError")
-	}
-
-	s.r = io.MultiReader(bytes.NewReader(s.buf[size:n]), s.r)
-	// fmt.Println("s.r = ",s.r)
-	// fmt.Println("r = ",r)
-	return r, nil
-}
-
 // 大域脱出のテクニックとして使う
 type escape struct{} //パッケージ内の型にする
 
@@ -0,0 +1,48 @@
+package retry
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff は次の試行までの待ち時間を決める
+// attemptは失敗した試行の回数（1から）、prevは前回の待ち時間（初回は0）
+type Backoff func(attempt int, prev time.Duration) time.Duration
+
+// Constant は常にdだけ待つ
+func Constant(d time.Duration) Backoff {
+	return func(int, time.Duration) time.Duration { return d }
+}
+
+// Exponential はbaseから倍々に増え、maxで頭打ちになる待ち時間を返す
+func Exponential(base, max time.Duration) Backoff {
+	return func(attempt int, _ time.Duration) time.Duration {
+		d := base
+		for i := 1; i < attempt; i++ {
+			d *= 2
+			if d >= max || d <= 0 {
+				return max
+			}
+		}
+		return d
+	}
+}
+
+// DecorrelatedJitter はbaseから前回の3倍までのランダムな待ち時間を返す（maxで頭打ち）
+// 多数のクライアントが同時にリトライして負荷が集中するのを避ける
+// https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+func DecorrelatedJitter(base, max time.Duration) Backoff {
+	return func(_ int, prev time.Duration) time.Duration {
+		if prev < base {
+			prev = base
+		}
+		upper := prev * 3
+		if upper > max || upper <= 0 {
+			upper = max
+		}
+		if upper <= base {
+			return base
+		}
+		return base + time.Duration(rand.Int63n(int64(upper-base)))
+	}
+}
@@ -0,0 +1,46 @@
+package retry
+
+import "sync"
+
+// Budget は複数の呼び出しで共有するリトライの予算
+// 障害時にすべてのクライアントがリトライし続けて負荷を増やすのを防ぐ
+// gRPCのリトライスロットリングと同じく、トークンがmaxの半分以下になるとリトライしない
+// リトライするたびに1減り、成功するたびにratioだけ回復する
+type Budget struct {
+	mu     sync.Mutex
+	tokens float64
+	max    float64
+	ratio  float64
+}
+
+// NewBudget はトークンの最大値がmaxのBudgetを作る
+func NewBudget(max, ratio float64) *Budget {
+	return &Budget{tokens: max, max: max, ratio: ratio}
+}
+
+// withdraw はリトライできる場合にトークンを1使ってtrueを返す
+func (b *Budget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens <= b.max/2 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *Budget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+// Tokens は残りのトークンを返す
+func (b *Budget) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens
+}
@@ -0,0 +1,143 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Decision はエラーをリトライするかどうかの判定
+type Decision int
+
+const (
+	Pass  Decision = iota // 判定しない（次のClassifierに任せる）
+	Retry                 // リトライする
+	Stop                  // リトライしない
+)
+
+// Classifier はエラーを見てリトライするかどうかを決める
+// 複数のClassifierは順番に評価し、最初にPass以外を返したものに従う
+// すべてPassのときはリトライしない
+type Classifier func(err error) Decision
+
+// DefaultClassifiers はWithClassifiersを指定しないときに使う
+// コンテキストのキャンセルではリトライせず、一時的なエラーとタイムアウトはリトライする
+var DefaultClassifiers = []Classifier{
+	StopOn(context.Canceled, context.DeadlineExceeded),
+	Temporary,
+	Timeout,
+}
+
+// Temporary はTemporary() boolメソッドがtrueを返すエラーをリトライする
+// 6.errorのIsTemporaryと同じように振る舞いで判定する
+func Temporary(err error) Decision {
+	var te interface{ Temporary() bool }
+	if errors.As(err, &te) && te.Temporary() {
+		return Retry
+	}
+	return Pass
+}
+
+// Timeout はTimeout() boolメソッドがtrueを返すエラーをリトライする
+// net.Errorのタイムアウトなどが当てはまる
+func Timeout(err error) Decision {
+	var te interface{ Timeout() bool }
+	if errors.As(err, &te) && te.Timeout() {
+		return Retry
+	}
+	return Pass
+}
+
+// RetryOn はerrors.Isでtargetsのどれかに当てはまるエラーをリトライする
+func RetryOn(targets ...error) Classifier {
+	return func(err error) Decision {
+		for _, target := range targets {
+			if errors.Is(err, target) {
+				return Retry
+			}
+		}
+		return Pass
+	}
+}
+
+// StopOn はerrors.Isでtargetsのどれかに当てはまるエラーをリトライしない
+func StopOn(targets ...error) Classifier {
+	return func(err error) Decision {
+		for _, target := range targets {
+			if errors.Is(err, target) {
+				return Stop
+			}
+		}
+		return Pass
+	}
+}
+
+// StatusError はHTTPのステータスコードを持つエラー
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("http status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// CheckResponse はステータスコードが400以上のときに*StatusErrorを返す
+func CheckResponse(resp *http.Response) error {
+	if resp.StatusCode >= 400 {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// RetryableStatus はHTTPStatusでcodesを省略したときにリトライするステータスコード
+var RetryableStatus = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// HTTPStatus はStatusCode() intメソッドかStatusErrorを持つエラーをステータスコードで判定する
+// codesに含まれればリトライし、それ以外のステータスではリトライしない
+// codesを省略するとRetryableStatusを使う
+func HTTPStatus(codes ...int) Classifier {
+	if len(codes) == 0 {
+		codes = RetryableStatus
+	}
+	return func(err error) Decision {
+		status, ok := statusOf(err)
+		if !ok {
+			return Pass
+		}
+		for _, c := range codes {
+			if status == c {
+				return Retry
+			}
+		}
+		return Stop
+	}
+}
+
+func statusOf(err error) (int, bool) {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode, true
+	}
+	var sc interface{ StatusCode() int }
+	if errors.As(err, &sc) {
+		return sc.StatusCode(), true
+	}
+	return 0, false
+}
+
+func classify(cs []Classifier, err error) Decision {
+	for _, c := range cs {
+		if d := c(err); d != Pass {
+			return d
+		}
+	}
+	return Stop
+}
@@ -0,0 +1,211 @@
+// Package retry はエラーの振る舞いを見て処理をリトライする
+//
+// リトライするかどうかはClassifierで決める
+// 6.errorのIsTemporaryのように、具象型ではなくTemporary()やTimeout()などのメソッドで判定する
+//
+//	err := retry.Do(ctx, func(ctx context.Context) error {
+//		return callAPI(ctx)
+//	}, retry.WithBackoff(retry.DecorrelatedJitter(100*time.Millisecond, 10*time.Second)))
+package retry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// StopReason はリトライをやめた理由
+type StopReason int
+
+const (
+	NotRetryable    StopReason = iota // Classifierがリトライしないと判定した
+	MaxAttempts                       // 最大試行回数に達した
+	MaxElapsed                        // 最大経過時間を過ぎる
+	Canceled                          // コンテキストが終わった
+	BudgetExhausted                   // リトライの予算を使い切った
+)
+
+func (r StopReason) String() string {
+	switch r {
+	case NotRetryable:
+		return "not retryable"
+	case MaxAttempts:
+		return "max attempts reached"
+	case MaxElapsed:
+		return "max elapsed time exceeded"
+	case Canceled:
+		return "canceled"
+	case BudgetExhausted:
+		return "retry budget exhausted"
+	}
+	return fmt.Sprintf("StopReason(%d)", int(r))
+}
+
+// Attempt は1回の試行の記録
+type Attempt struct {
+	Num      int           // 何回目の試行か（1から）
+	Err      error         // 試行の結果
+	Duration time.Duration // 試行にかかった時間
+	Wait     time.Duration // 次の試行までに待った時間（最後の試行では0）
+}
+
+// Error はリトライをあきらめたときに返すエラー
+// Unwrapで最後の試行のエラーを返すのでerrors.Is/Asで元のエラーを調べられる
+type Error struct {
+	Attempts []Attempt
+	Reason   StopReason
+	Elapsed  time.Duration
+	ctxErr   error
+}
+
+// Last は最後の試行のエラーを返す
+func (e *Error) Last() error {
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("retry: %s after %d attempt(s) in %s: %v", e.Reason, len(e.Attempts), e.Elapsed, e.Last())
+}
+
+// Unwrap は最後のエラーと、キャンセルされた場合はコンテキストのエラーを返す
+func (e *Error) Unwrap() []error {
+	if e.ctxErr != nil {
+		return []error{e.Last(), e.ctxErr}
+	}
+	return []error{e.Last()}
+}
+
+// Format は%+vですべての試行を出力する
+func (e *Error) Format(s fmt.State, verb rune) {
+	if verb != 'v' || !s.Flag('+') {
+		fmt.Fprint(s, e.Error())
+		return
+	}
+	var b strings.Builder
+	b.WriteString(e.Error())
+	for _, a := range e.Attempts {
+		fmt.Fprintf(&b, "\n  attempt %d (%s): %v", a.Num, a.Duration, a.Err)
+		if a.Wait > 0 {
+			fmt.Fprintf(&b, " (waited %s)", a.Wait)
+		}
+	}
+	fmt.Fprint(s, b.String())
+}
+
+type config struct {
+	maxAttempts int
+	maxElapsed  time.Duration
+	backoff     Backoff
+	classifiers []Classifier
+	budget      *Budget
+	onRetry     func(Attempt)
+}
+
+// Option はリトライの設定
+type Option func(*config)
+
+// WithMaxAttempts は最大試行回数を指定する（デフォルトは5、0以下で無制限）
+func WithMaxAttempts(n int) Option {
+	return func(c *config) { c.maxAttempts = n }
+}
+
+// WithMaxElapsed は最初の試行からの最大経過時間を指定する
+// 次の試行までに待つとこの時間を過ぎる場合はリトライしない
+func WithMaxElapsed(d time.Duration) Option {
+	return func(c *config) { c.maxElapsed = d }
+}
+
+// WithBackoff は待ち時間の決め方を指定する
+// デフォルトはExponential(100ms, 10s)
+func WithBackoff(b Backoff) Option {
+	return func(c *config) { c.backoff = b }
+}
+
+// WithClassifiers はリトライするかどうかの判定を差し替える
+// デフォルトはDefaultClassifiers
+func WithClassifiers(cs ...Classifier) Option {
+	return func(c *config) { c.classifiers = cs }
+}
+
+// WithBudget は複数の呼び出しで共有するリトライの予算を指定する
+func WithBudget(b *Budget) Option {
+	return func(c *config) { c.budget = b }
+}
+
+// WithOnRetry はリトライする前に呼ぶ関数を指定する（ログ出力など）
+func WithOnRetry(f func(Attempt)) Option {
+	return func(c *config) { c.onRetry = f }
+}
+
+// Do はfnが成功するか、リトライをやめるまで繰り返す
+// リトライをやめたときは試行の記録を持つ*Errorを返す
+func Do(ctx context.Context, fn func(ctx context.Context) error, opts ...Option) error {
+	_, err := DoValue(ctx, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	}, opts...)
+	return err
+}
+
+// DoValue は値を返す関数をリトライする
+func DoValue[T any](ctx context.Context, fn func(ctx context.Context) (T, error), opts ...Option) (T, error) {
+	c := config{
+		maxAttempts: 5,
+		backoff:     Exponential(100*time.Millisecond, 10*time.Second),
+		classifiers: DefaultClassifiers,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	var (
+		zero     T
+		attempts []Attempt
+		wait     time.Duration
+	)
+	start := time.Now()
+	giveUp := func(reason StopReason, ctxErr error) (T, error) {
+		return zero, &Error{Attempts: attempts, Reason: reason, Elapsed: time.Since(start), ctxErr: ctxErr}
+	}
+
+	for n := 1; ; n++ {
+		began := time.Now()
+		v, err := fn(ctx)
+		if err == nil {
+			if c.budget != nil {
+				c.budget.deposit()
+			}
+			return v, nil
+		}
+		attempts = append(attempts, Attempt{Num: n, Err: err, Duration: time.Since(began)})
+
+		switch {
+		case ctx.Err() != nil:
+			return giveUp(Canceled, ctx.Err())
+		case classify(c.classifiers, err) != Retry:
+			return giveUp(NotRetryable, nil)
+		case c.maxAttempts > 0 && n >= c.maxAttempts:
+			return giveUp(MaxAttempts, nil)
+		}
+
+		wait = c.backoff(n, wait)
+		if c.maxElapsed > 0 && time.Since(start)+wait > c.maxElapsed {
+			return giveUp(MaxElapsed, nil)
+		}
+		if c.budget != nil && !c.budget.withdraw() {
+			return giveUp(BudgetExhausted, nil)
+		}
+		attempts[n-1].Wait = wait
+		if c.onRetry != nil {
+			c.onRetry(attempts[n-1])
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return giveUp(Canceled, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/recipe-golang/6.error/errs"
+	"golang/recipe-golang/6.error/retry"
+)
+
+type tempErr struct{ temp bool }
+
+func (e tempErr) Error() string   { return fmt.Sprintf("temp=%v", e.temp) }
+func (e tempErr) Temporary() bool { return e.temp }
+
+// failN はn回失敗してから成功する関数を返す
+func failN(n int, err error) (func(context.Context) error, *int) {
+	calls := 0
+	return func(context.Context) error {
+		calls++
+		if calls <= n {
+			return err
+		}
+		return nil
+	}, &calls
+}
+
+var fast = retry.WithBackoff(retry.Constant(time.Millisecond))
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		fails     int
+		err       error
+		opts      []retry.Option
+		wantCalls int
+		reason    retry.StopReason // wantErrのときだけ見る
+		wantErr   bool
+	}{
+		"success":           {0, nil, nil, 1, 0, false},
+		"temporary":         {2, tempErr{true}, nil, 3, 0, false},
+		"not temporary":     {2, tempErr{false}, nil, 1, retry.NotRetryable, true},
+		"unknown error":     {2, io.ErrUnexpectedEOF, nil, 1, retry.NotRetryable, true},
+		"timeout":           {2, os.ErrDeadlineExceeded, nil, 3, 0, false},
+		"max attempts":      {5, tempErr{true}, []retry.Option{retry.WithMaxAttempts(3)}, 3, retry.MaxAttempts, true},
+		"errs unavailable":  {1, errs.Unavailable.New("db down"), nil, 2, 0, false},
+		"errs wrap":         {2, errs.Wrap(tempErr{true}, "call api"), nil, 3, 0, false},
+		"errs wrap not":     {2, errs.Wrap(tempErr{false}, "call api"), nil, 1, retry.NotRetryable, true},
+		"op unavailable":    {1, errs.Unavailable.Op("select", errs.Table, "addressbook", io.ErrUnexpectedEOF), nil, 2, 0, false},
+		"op cause":          {1, errs.Op("GET", errs.URL, "http://example.com", errs.Unavailable.New("503")), nil, 2, 0, false},
+		"op not found":      {1, errs.NotFound.Op("select", errs.Table, "addressbook", io.ErrUnexpectedEOF), nil, 1, retry.NotRetryable, true},
+		"op temporary":      {2, errs.Op("GET", errs.URL, "http://example.com", tempErr{true}), nil, 3, 0, false},
+		"op not temporary":  {2, errs.Op("GET", errs.URL, "http://example.com", tempErr{false}), nil, 1, retry.NotRetryable, true},
+		"errors.Is target":  {2, fmt.Errorf("read: %w", io.ErrUnexpectedEOF), []retry.Option{retry.WithClassifiers(retry.RetryOn(io.ErrUnexpectedEOF))}, 3, 0, false},
+		"stop before retry": {2, tempErr{true}, []retry.Option{retry.WithClassifiers(retry.StopOn(tempErr{true}), retry.Temporary)}, 1, retry.NotRetryable, true},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			fn, calls := failN(tt.fails, tt.err)
+			err := retry.Do(context.Background(), fn, append([]retry.Option{fast}, tt.opts...)...)
+			if *calls != tt.wantCalls {
+				t.Errorf("want %d calls, got %d", tt.wantCalls, *calls)
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("want <nil>, got %v", err)
+				}
+				return
+			}
+			var re *retry.Error
+			if !errors.As(err, &re) {
+				t.Fatalf("want *retry.Error, got %v", err)
+			}
+			if re.Reason != tt.reason {
+				t.Errorf("want %v, got %v", tt.reason, re.Reason)
+			}
+			if len(re.Attempts) != tt.wantCalls {
+				t.Errorf("want %d attempts recorded, got %d", tt.wantCalls, len(re.Attempts))
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("want errors.Is(err, %v)", tt.err)
+			}
+		})
+	}
+}
+
+func TestDo_HTTPStatus(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		status    int
+		wantCalls int
+	}{
+		"503 retried":     {http.StatusServiceUnavailable, 3},
+		"429 retried":     {http.StatusTooManyRequests, 3},
+		"404 not retried": {http.StatusNotFound, 1},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			calls := 0
+			err := retry.Do(context.Background(), func(context.Context) error {
+				calls++
+				return retry.CheckResponse(&http.Response{StatusCode: tt.status})
+			}, fast, retry.WithMaxAttempts(3), retry.WithClassifiers(retry.HTTPStatus()))
+			if calls != tt.wantCalls {
+				t.Errorf("want %d calls, got %d", tt.wantCalls, calls)
+			}
+			var se *retry.StatusError
+			if !errors.As(err, &se) || se.StatusCode != tt.status {
+				t.Errorf("want StatusError %d, got %v", tt.status, err)
+			}
+		})
+	}
+}
+
+func TestDoValue(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	v, err := retry.DoValue(context.Background(), func(context.Context) (string, error) {
+		if calls++; calls < 2 {
+			return "", tempErr{true}
+		}
+		return "ok", nil
+	}, fast)
+	if v != "ok" || err != nil {
+		t.Errorf("want ok, <nil>, got %q, %v", v, err)
+	}
+}
+
+func TestDo_Canceled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	var retries int
+	err := retry.Do(ctx, func(context.Context) error { return tempErr{true} },
+		retry.WithBackoff(retry.Constant(time.Hour)),
+		retry.WithOnRetry(func(retry.Attempt) {
+			retries++
+			cancel()
+		}))
+	var re *retry.Error
+	if !errors.As(err, &re) || re.Reason != retry.Canceled {
+		t.Fatalf("want canceled, got %v", err)
+	}
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, tempErr{true}) {
+		t.Errorf("want both the last error and context.Canceled, got %v", err)
+	}
+	if retries != 1 {
+		t.Errorf("want 1 retry, got %d", retries)
+	}
+}
+
+func TestDo_MaxElapsed(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+	err := retry.Do(context.Background(), func(context.Context) error { return tempErr{true} },
+		retry.WithMaxAttempts(0),
+		retry.WithBackoff(retry.Constant(20*time.Millisecond)),
+		retry.WithMaxElapsed(50*time.Millisecond))
+	var re *retry.Error
+	if !errors.As(err, &re) || re.Reason != retry.MaxElapsed {
+		t.Fatalf("want max elapsed, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond+40*time.Millisecond {
+		t.Errorf("want to stop around 50ms, took %s", elapsed)
+	}
+	if n := len(re.Attempts); n < 2 || n > 3 {
+		t.Errorf("want 2 or 3 attempts, got %d", n)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	t.Parallel()
+	b := retry.NewBudget(4, 0.5)
+	fail := func(context.Context) error { return tempErr{true} }
+	opts := []retry.Option{fast, retry.WithBudget(b), retry.WithMaxAttempts(10)}
+
+	// トークン4から2になるまでの2回だけリトライできる
+	err := retry.Do(context.Background(), fail, opts...)
+	var re *retry.Error
+	if !errors.As(err, &re) || re.Reason != retry.BudgetExhausted || len(re.Attempts) != 3 {
+		t.Fatalf("want budget exhausted after 3 attempts, got %v", err)
+	}
+	// 予算がない間は1回で諦める
+	err = retry.Do(context.Background(), fail, opts...)
+	if !errors.As(err, &re) || len(re.Attempts) != 1 {
+		t.Fatalf("want 1 attempt, got %v", err)
+	}
+	// 成功すると回復する
+	for i := 0; i < 2; i++ {
+		retry.Do(context.Background(), func(context.Context) error { return nil }, opts...)
+	}
+	if got := b.Tokens(); got != 3 {
+		t.Errorf("want 3 tokens, got %v", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+	exp := retry.Exponential(100*time.Millisecond, time.Second)
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 5: time.Second, 50: time.Second} {
+		if got := exp(attempt, 0); got != want {
+			t.Errorf("attempt %d: want %s, got %s", attempt, want, got)
+		}
+	}
+	dj := retry.DecorrelatedJitter(10*time.Millisecond, 100*time.Millisecond)
+	var prev time.Duration
+	for i := 1; i <= 100; i++ {
+		d := dj(i, prev)
+		upper := prev * 3
+		if upper < 30*time.Millisecond {
+			upper = 30 * time.Millisecond
+		}
+		if upper > 100*time.Millisecond {
+			upper = 100 * time.Millisecond
+		}
+		if d < 10*time.Millisecond || d > upper {
+			t.Fatalf("attempt %d: %s out of range [10ms, %s]", i, d, upper)
+		}
+		prev = d
+	}
+}
+
+func TestError_Format(t *testing.T) {
+	t.Parallel()
+	err := retry.Do(context.Background(), func(context.Context) error { return tempErr{true} }, fast, retry.WithMaxAttempts(2))
+	detail := fmt.Sprintf("%+v", err)
+	for _, want := range []string{"max attempts reached after 2 attempt(s)", "attempt 1 (", "(waited 1ms)", "attempt 2 ("} {
+		if !strings.Contains(detail, want) {
+			t.Errorf("want %q in %q", want, detail)
+		}
+	}
+}
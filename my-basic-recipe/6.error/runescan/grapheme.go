@@ -0,0 +1,78 @@
+package runescan
+
+import (
+	"io"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// GraphemeScanner は書記素クラスタ（見た目の1文字）ずつ読み込む
+// 「が」（か+濁点）や絵文字の肌の色、国旗などを1つにまとめて返す
+// 使い方はbufio.Scannerと同じ
+type GraphemeScanner struct {
+	s       *Scanner
+	buf     []byte
+	pending []byte // 境界が決まっていないデータ
+	token   []byte
+	state   int // uniseg.FirstGraphemeClusterの状態
+	eof     bool
+	err     error
+}
+
+// NewGraphemeScanner はrから書記素クラスタを読み込むGraphemeScannerを作る
+// optsはNewと同じ
+func NewGraphemeScanner(r io.Reader, opts ...Option) *GraphemeScanner {
+	return &GraphemeScanner{s: New(r, opts...), state: -1}
+}
+
+// Scan は次の書記素クラスタを読み込む
+// 入力の終わりかエラーのときはfalseを返す
+func (g *GraphemeScanner) Scan() bool {
+	g.token = nil
+	// 前回のトークンの領域を使い回す
+	g.buf = append(g.buf[:0], g.pending...)
+	g.pending = g.buf
+	for {
+		if len(g.pending) > 0 {
+			// 後ろに次のクラスタが始まっていれば境界が確定している
+			cluster, rest, _, state := uniseg.FirstGraphemeCluster(g.pending, g.state)
+			if len(rest) > 0 || g.eof {
+				g.buf = g.pending[:0]
+				g.token = cluster
+				g.pending = rest
+				g.state = state
+				return true
+			}
+		} else if g.eof {
+			return false
+		}
+
+		r, _, err := g.s.ReadRune()
+		if err == io.EOF {
+			g.eof = true
+			continue
+		}
+		if err != nil {
+			g.err = err
+			return false
+		}
+		g.pending = utf8.AppendRune(g.pending, r)
+	}
+}
+
+// Bytes は最後に読み込んだ書記素クラスタを返す
+// 次のScanで上書きされる
+func (g *GraphemeScanner) Bytes() []byte {
+	return g.token
+}
+
+// Text は最後に読み込んだ書記素クラスタを文字列で返す
+func (g *GraphemeScanner) Text() string {
+	return string(g.token)
+}
+
+// Err はio.EOF以外のエラーを返す
+func (g *GraphemeScanner) Err() error {
+	return g.err
+}
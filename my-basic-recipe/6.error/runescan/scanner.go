@@ -0,0 +1,203 @@
+// Package runescan はio.Readerからコードポイント(rune)や書記素クラスタを読み込む
+//
+// 内部でバッファを持ち、Readの境界でマルチバイト文字が分割されても正しくデコードする
+// 不正なバイトの扱いはPolicyで選べる
+package runescan
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+// Policy は不正なUTF-8のバイトを見つけたときの扱い
+type Policy int
+
+const (
+	// Replace は不正なバイトを1バイトずつutf8.RuneErrorとして返す
+	Replace Policy = iota
+	// Skip は不正なバイトを読み飛ばす
+	Skip
+	// Error は不正なバイトの位置を持つ*InvalidByteErrorを返す
+	Error
+)
+
+func (p Policy) String() string {
+	switch p {
+	case Replace:
+		return "replace"
+	case Skip:
+		return "skip"
+	case Error:
+		return "error"
+	}
+	return fmt.Sprintf("Policy(%d)", int(p))
+}
+
+// InvalidByteError はPolicyがErrorのときに返すエラー
+type InvalidByteError struct {
+	Offset int64 // 入力の先頭からのバイト位置
+	Byte   byte
+}
+
+func (e *InvalidByteError) Error() string {
+	return fmt.Sprintf("runescan: invalid UTF-8 byte %#02x at offset %d", e.Byte, e.Offset)
+}
+
+const defaultBufSize = 4096
+
+// Option はScannerの設定を変える
+type Option func(*Scanner)
+
+// WithPolicy は不正なバイトの扱いを指定する（デフォルトはReplace）
+func WithPolicy(p Policy) Option {
+	return func(s *Scanner) { s.policy = p }
+}
+
+// WithBufferSize はバッファのサイズを指定する
+// utf8.UTFMaxより小さい値はutf8.UTFMaxになる
+func WithBufferSize(n int) Option {
+	return func(s *Scanner) {
+		if n < utf8.UTFMax {
+			n = utf8.UTFMax
+		}
+		s.buf = make([]byte, n)
+	}
+}
+
+// WithEncoding は入力をencでUTF-8にデコードしてから読む
+// UTF-16やShift_JIS、EUC-JPのファイルを読む場合に使う
+// オフセットはデコード後のバイト位置になる
+// デコーダは不正なバイトをU+FFFDに置き換えるため、Policyは適用されない
+func WithEncoding(enc encoding.Encoding) Option {
+	return func(s *Scanner) { s.enc = enc }
+}
+
+// Scanner はio.Readerからruneを1つずつ読み込む
+// io.RuneReaderを実装する
+type Scanner struct {
+	r      io.Reader
+	buf    []byte
+	start  int   // 未読のデータの先頭
+	end    int   // 未読のデータの終端
+	offset int64 // buf[start]の入力上の位置
+	err    error // Readが返したエラー（バッファを読み切ってから返す）
+	policy Policy
+	enc    encoding.Encoding
+}
+
+// New はrから読み込むScannerを作る
+func New(r io.Reader, opts ...Option) *Scanner {
+	s := &Scanner{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.buf == nil {
+		s.buf = make([]byte, defaultBufSize)
+	}
+	if s.enc != nil {
+		r = transform.NewReader(r, s.enc.NewDecoder())
+	}
+	s.r = r
+	return s
+}
+
+// Scan は次のruneを返す
+// 入力の終わりではio.EOFを返す
+func (s *Scanner) Scan() (rune, error) {
+	r, _, err := s.ReadRune()
+	return r, err
+}
+
+// ReadRune は次のruneとそのバイト数を返す
+func (s *Scanner) ReadRune() (r rune, size int, err error) {
+	// ASCIIはデコードせずに返す
+	if s.start < s.end && s.buf[s.start] < utf8.RuneSelf {
+		r = rune(s.buf[s.start])
+		s.advance(1)
+		return r, 1, nil
+	}
+	for {
+		// 1文字分に満たない場合は読み足す
+		if !utf8.FullRune(s.buf[s.start:s.end]) {
+			s.fill()
+		}
+		if s.start == s.end {
+			return 0, 0, s.readErr()
+		}
+
+		r, size = utf8.DecodeRune(s.buf[s.start:s.end])
+		if r != utf8.RuneError || size != 1 {
+			s.advance(size)
+			return r, size, nil
+		}
+
+		// 不正なバイト（本物のU+FFFDはsizeが3になる）
+		b, off := s.buf[s.start], s.offset
+		s.advance(1)
+		switch s.policy {
+		case Skip:
+			continue
+		case Error:
+			return 0, 0, &InvalidByteError{Offset: off, Byte: b}
+		default:
+			return utf8.RuneError, 1, nil
+		}
+	}
+}
+
+// Offset は次に読むruneの入力上のバイト位置を返す
+func (s *Scanner) Offset() int64 {
+	return s.offset
+}
+
+func (s *Scanner) advance(n int) {
+	s.start += n
+	s.offset += int64(n)
+}
+
+// maxEmptyReads は0バイトでnilを返すReadを何回まで許すか（bufioと同じ）
+const maxEmptyReads = 100
+
+// fill は1文字分のデータがたまるかエラーになるまで読み込む
+// 端末からの入力でもブロックしないように、必要以上に待たない
+func (s *Scanner) fill() {
+	if s.err != nil {
+		return
+	}
+	// 残りを先頭に詰める
+	if s.start > 0 {
+		s.end = copy(s.buf, s.buf[s.start:s.end])
+		s.start = 0
+	}
+	for empty := 0; !utf8.FullRune(s.buf[:s.end]); {
+		n, err := s.r.Read(s.buf[s.end:])
+		if n < 0 {
+			panic("runescan: reader returned negative count from Read")
+		}
+		s.end += n
+		if err != nil {
+			s.err = err
+			return
+		}
+		if n > 0 {
+			empty = 0
+			continue
+		}
+		// 何も返さないReaderで無限ループにならないようにする
+		if empty++; empty >= maxEmptyReads {
+			s.err = io.ErrNoProgress
+			return
+		}
+	}
+}
+
+func (s *Scanner) readErr() error {
+	if s.err == nil {
+		return io.EOF
+	}
+	return s.err
+}
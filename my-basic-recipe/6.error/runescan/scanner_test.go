@@ -0,0 +1,264 @@
+package runescan_test
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+
+	"golang/recipe-golang/6.error/runescan"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func readAll(t *testing.T, s *runescan.Scanner) ([]rune, error) {
+	t.Helper()
+	var rs []rune
+	for {
+		r, err := s.Scan()
+		if err == io.EOF {
+			return rs, nil
+		}
+		if err != nil {
+			return rs, err
+		}
+		rs = append(rs, r)
+	}
+}
+
+func TestScanner_Split(t *testing.T) {
+	t.Parallel()
+	const input = "Hello, 世界🍣!"
+	cases := map[string]struct {
+		r       io.Reader
+		bufSize int
+	}{
+		"normal":     {strings.NewReader(input), 0},
+		"one byte":   {iotest.OneByteReader(strings.NewReader(input)), 0},
+		"half":       {iotest.HalfReader(strings.NewReader(input)), 0},
+		"small buf":  {strings.NewReader(input), 1},
+		"data error": {iotest.DataErrReader(strings.NewReader(input)), 5},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var opts []runescan.Option
+			if tt.bufSize > 0 {
+				opts = append(opts, runescan.WithBufferSize(tt.bufSize))
+			}
+			got, err := readAll(t, runescan.New(tt.r, opts...))
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if want := []rune(input); string(got) != string(want) {
+				t.Errorf("want %q, got %q", string(want), string(got))
+			}
+		})
+	}
+}
+
+func TestScanner_Policy(t *testing.T) {
+	t.Parallel()
+	// 0xffは不正なバイト、末尾は途中で切れた「界」
+	input := []byte("a\xffb�" + "\xe7\x95")
+	cases := map[string]struct {
+		policy runescan.Policy
+		want   string
+		offset int64
+	}{
+		"replace": {runescan.Replace, "a�b���", -1},
+		"skip":    {runescan.Skip, "ab�", -1},
+		"error":   {runescan.Error, "a", 1},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s := runescan.New(iotest.OneByteReader(bytes.NewReader(input)), runescan.WithPolicy(tt.policy))
+			got, err := readAll(t, s)
+			if string(got) != tt.want {
+				t.Errorf("want %q, got %q", tt.want, string(got))
+			}
+			var ibe *runescan.InvalidByteError
+			switch {
+			case tt.offset < 0 && err != nil:
+				t.Error("unexpected error:", err)
+			case tt.offset >= 0 && !errors.As(err, &ibe):
+				t.Errorf("want InvalidByteError, got %v", err)
+			case tt.offset >= 0 && (ibe.Offset != tt.offset || ibe.Byte != 0xff):
+				t.Errorf("want offset %d byte 0xff, got offset %d byte %#02x", tt.offset, ibe.Offset, ibe.Byte)
+			}
+		})
+	}
+}
+
+func TestScanner_ErrorContinue(t *testing.T) {
+	t.Parallel()
+	// エラーのあとも続きから読める
+	s := runescan.New(strings.NewReader("x\xe3\x81y"), runescan.WithPolicy(runescan.Error))
+	var offsets []int64
+	var got []rune
+	for {
+		r, err := s.Scan()
+		if err == io.EOF {
+			break
+		}
+		var ibe *runescan.InvalidByteError
+		if errors.As(err, &ibe) {
+			offsets = append(offsets, ibe.Offset)
+			continue
+		}
+		got = append(got, r)
+	}
+	if string(got) != "xy" {
+		t.Errorf("want %q, got %q", "xy", string(got))
+	}
+	if len(offsets) != 2 || offsets[0] != 1 || offsets[1] != 2 {
+		t.Errorf("want offsets [1 2], got %v", offsets)
+	}
+	if s.Offset() != 4 {
+		t.Errorf("want offset 4, got %d", s.Offset())
+	}
+}
+
+func TestScanner_ReadError(t *testing.T) {
+	t.Parallel()
+	errBoom := errors.New("boom")
+	r := io.MultiReader(strings.NewReader("あ"), iotest.ErrReader(errBoom))
+	got, err := readAll(t, runescan.New(r))
+	if string(got) != "あ" || !errors.Is(err, errBoom) {
+		t.Errorf("want あ, %v, got %q, %v", errBoom, string(got), err)
+	}
+}
+
+func TestScanner_Encoding(t *testing.T) {
+	t.Parallel()
+	const input = "こんにちは、世界！①"
+	cases := map[string]encoding.Encoding{
+		"UTF-16LE":  unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+		"UTF-16BE":  unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+		"Shift_JIS": japanese.ShiftJIS,
+		"EUC-JP":    japanese.EUCJP,
+	}
+	for name, enc := range cases {
+		enc := enc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			src, err := enc.NewEncoder().Bytes([]byte(input))
+			if err != nil {
+				t.Fatal("encode:", err)
+			}
+			s := runescan.New(iotest.OneByteReader(bytes.NewReader(src)), runescan.WithEncoding(enc))
+			got, err := readAll(t, s)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if string(got) != input {
+				t.Errorf("want %q, got %q", input, string(got))
+			}
+		})
+	}
+}
+
+func TestGraphemeScanner(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		input string
+		want  []string
+	}{
+		"ascii":      {"abc", []string{"a", "b", "c"}},
+		"dakuten":    {"がき", []string{"が", "き"}},
+		"flags":      {"🇯🇵🇺🇸", []string{"🇯🇵", "🇺🇸"}},
+		"skin tone":  {"👍🏽!", []string{"👍🏽", "!"}},
+		"zwj family": {"👨‍👩‍👧x", []string{"👨‍👩‍👧", "x"}},
+		"crlf":       {"a\r\nb", []string{"a", "\r\n", "b"}},
+		"empty":      {"", nil},
+		"invalid":    {"a\xffb", []string{"a", "�", "b"}},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			g := runescan.NewGraphemeScanner(iotest.OneByteReader(strings.NewReader(tt.input)))
+			var got []string
+			for g.Scan() {
+				got = append(got, g.Text())
+			}
+			if err := g.Err(); err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGraphemeScanner_Error(t *testing.T) {
+	t.Parallel()
+	g := runescan.NewGraphemeScanner(strings.NewReader("ab\xff"), runescan.WithPolicy(runescan.Error))
+	var n int
+	for g.Scan() {
+		n++
+	}
+	var ibe *runescan.InvalidByteError
+	if !errors.As(g.Err(), &ibe) || ibe.Offset != 2 {
+		t.Errorf("want InvalidByteError at 2, got %v", g.Err())
+	}
+	if n != 1 {
+		t.Errorf("want 1 grapheme before the error, got %d", n)
+	}
+}
+
+func TestScanner_Count(t *testing.T) {
+	t.Parallel()
+	got, err := readAll(t, runescan.New(strings.NewReader(benchText), runescan.WithBufferSize(7)))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if want := utf8.RuneCountInString(benchText); len(got) != want {
+		t.Errorf("want %d runes, got %d", want, len(got))
+	}
+}
+
+var benchText = strings.Repeat("Go言語でrune単位に読み込むベンチマーク。ASCII and 日本語 mixed text 🍣\n", 2000)
+
+func BenchmarkScanner_ReadRune(b *testing.B) {
+	b.SetBytes(int64(len(benchText)))
+	for i := 0; i < b.N; i++ {
+		s := runescan.New(strings.NewReader(benchText))
+		for {
+			if _, _, err := s.ReadRune(); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkBufioReader_ReadRune(b *testing.B) {
+	b.SetBytes(int64(len(benchText)))
+	for i := 0; i < b.N; i++ {
+		r := bufio.NewReader(strings.NewReader(benchText))
+		for {
+			if _, _, err := r.ReadRune(); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkGraphemeScanner(b *testing.B) {
+	b.SetBytes(int64(len(benchText)))
+	for i := 0; i < b.N; i++ {
+		g := runescan.NewGraphemeScanner(strings.NewReader(benchText))
+		for g.Scan() {
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package safego
+
+import (
+	"context"
+	"sync"
+)
+
+// Group はerrgroup.Groupと同じように複数のゴールーチンを待つ
+// ゴールーチンのパニックは*PanicErrorとしてWaitから返す
+// ゼロ値で使える
+type Group struct {
+	cancel func(error)
+	opts   []Option
+
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+// WithContext はGroupと、最初のエラーでキャンセルされるコンテキストを返す
+// optsはすべてのゴールーチンに適用する
+func WithContext(ctx context.Context, opts ...Option) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{cancel: cancel, opts: opts}, ctx
+}
+
+// Go はfnを新しいゴールーチンで実行する
+// optsはGroupのオプションに追加される（WithLabelsでゴールーチンごとのラベルを付ける）
+func (g *Group) Go(ctx context.Context, fn func(ctx context.Context) error, opts ...Option) {
+	c := newConfig(append(append([]Option(nil), g.opts...), opts...))
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := c.run(ctx, fn); err != nil {
+			g.errOnce.Do(func() {
+				g.err = err
+				if g.cancel != nil {
+					g.cancel(err)
+				}
+			})
+		}
+	}()
+}
+
+// Wait はすべてのゴールーチンの終了を待ち、最初のエラーを返す
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel(g.err)
+	}
+	return g.err
+}
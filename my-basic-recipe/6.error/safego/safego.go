@@ -0,0 +1,166 @@
+// Package safego はパニックしても落ちないゴールーチンを起動する
+//
+// ゴールーチンの中で起きたパニックは呼び出し元のrecoverでは捕まえられず、プロセスごと終了する
+// Goやgroupで起動したゴールーチンはパニックを*PanicErrorに変換して返す
+// pprofのラベルを付けて起動するので、プロファイルやパニックのエラーからどの処理かわかる
+package safego
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"runtime/pprof"
+	"sort"
+	"strings"
+)
+
+// PanicError はパニックを変換したエラー
+type PanicError struct {
+	Value  interface{}       // panicに渡された値
+	Stack  []byte            // パニックが起きたゴールーチンのスタック
+	Labels map[string]string // ゴールーチンのpprofラベル
+}
+
+func (e *PanicError) Error() string {
+	if len(e.Labels) == 0 {
+		return fmt.Sprintf("safego: panic: %v", e.Value)
+	}
+	return fmt.Sprintf("safego: panic in %s: %v", e.labelString(), e.Value)
+}
+
+// Unwrap はpanicにerrorが渡された場合にそのエラーを返す
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Format は%+vでスタックも出力する
+func (e *PanicError) Format(s fmt.State, verb rune) {
+	fmt.Fprint(s, e.Error())
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprintf(s, "\n%s", e.Stack)
+	}
+}
+
+func (e *PanicError) labelString() string {
+	kvs := make([]string, 0, len(e.Labels))
+	for k, v := range e.Labels {
+		kvs = append(kvs, k+"="+v)
+	}
+	sort.Strings(kvs)
+	return "[" + strings.Join(kvs, " ") + "]"
+}
+
+// Reporter はパニックを捕まえたときに呼ばれる
+// ログ出力やエラー監視サービスへの送信に使う
+type Reporter func(ctx context.Context, err *PanicError)
+
+// LogReporter はlに%+vでパニックを出力するReporterを返す
+// lがnilのときはlog.Default()を使う
+func LogReporter(l *log.Logger) Reporter {
+	if l == nil {
+		l = log.Default()
+	}
+	return func(_ context.Context, err *PanicError) {
+		l.Printf("%+v", err)
+	}
+}
+
+type config struct {
+	labels   []string
+	repanic  func(v interface{}) bool
+	reporter Reporter
+}
+
+// Option はゴールーチンの起動方法の設定
+type Option func(*config)
+
+// WithLabels はゴールーチンにpprofのラベルを付ける
+// kvはキーと値を交互に並べる（pprof.Labelsと同じ）
+func WithLabels(kv ...string) Option {
+	return func(c *config) { c.labels = append(c.labels, kv...) }
+}
+
+// WithRepanic はmatchがtrueを返す値のパニックをエラーにせず、そのままパニックさせる
+// 大域脱出用の番兵の値など、捕まえてはいけないパニックに使う
+func WithRepanic(match func(v interface{}) bool) Option {
+	return func(c *config) { c.repanic = match }
+}
+
+// NonError はerrorではない値のパニックのときにtrueを返す
+// WithRepanic(safego.NonError)でerror以外のパニックを再発生させる
+func NonError(v interface{}) bool {
+	_, ok := v.(error)
+	return !ok
+}
+
+// WithReporter はパニックを捕まえたときに呼ぶ関数を指定する
+func WithReporter(r Reporter) Option {
+	return func(c *config) { c.reporter = r }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if len(c.labels)%2 != 0 {
+		panic("safego: WithLabels needs key-value pairs")
+	}
+	return c
+}
+
+// Go はfnを新しいゴールーチンで実行し、結果を1つ送るチャネルを返す
+// fnがパニックした場合は*PanicErrorが送られる
+//
+//	errc := safego.Go(ctx, job, safego.WithLabels("job", "export"))
+//	if err := <-errc; err != nil { ... }
+func Go(ctx context.Context, fn func(ctx context.Context) error, opts ...Option) <-chan error {
+	c := newConfig(opts)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		errc <- c.run(ctx, fn)
+	}()
+	return errc
+}
+
+// Call はfnを同じゴールーチンで実行し、パニックを*PanicErrorに変換して返す
+// 名前付き戻り値とrecoverでパニックをエラーにするパターンを関数にしたもの
+func Call(ctx context.Context, fn func(ctx context.Context) error, opts ...Option) error {
+	return newConfig(opts).run(ctx, fn)
+}
+
+func (c *config) run(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	pprof.Do(ctx, pprof.Labels(c.labels...), func(ctx context.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			if c.repanic != nil && c.repanic(r) {
+				panic(r)
+			}
+			pe := &PanicError{Value: r, Stack: debug.Stack(), Labels: labelsOf(ctx)}
+			if c.reporter != nil {
+				c.reporter(ctx, pe)
+			}
+			err = pe
+		}()
+		err = fn(ctx)
+	})
+	return err
+}
+
+func labelsOf(ctx context.Context) map[string]string {
+	var labels map[string]string
+	pprof.ForLabels(ctx, func(k, v string) bool {
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[k] = v
+		return true
+	})
+	return labels
+}
@@ -0,0 +1,150 @@
+package safego_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang/recipe-golang/6.error/safego"
+)
+
+func TestGo(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		fn      func(context.Context) error
+		wantErr error
+		panic   interface{}
+	}{
+		"ok":          {func(context.Context) error { return nil }, nil, nil},
+		"error":       {func(context.Context) error { return io.EOF }, io.EOF, nil},
+		"panic value": {func(context.Context) error { panic("boom") }, nil, "boom"},
+		"panic error": {func(context.Context) error { panic(io.ErrUnexpectedEOF) }, io.ErrUnexpectedEOF, io.ErrUnexpectedEOF},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := <-safego.Go(context.Background(), tt.fn)
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("want %v, got %v", tt.wantErr, err)
+			}
+			var pe *safego.PanicError
+			switch {
+			case tt.panic == nil && errors.As(err, &pe):
+				t.Errorf("want no panic, got %v", err)
+			case tt.panic != nil && !errors.As(err, &pe):
+				t.Errorf("want PanicError, got %v", err)
+			case tt.panic != nil && pe.Value != tt.panic:
+				t.Errorf("want panic value %v, got %v", tt.panic, pe.Value)
+			}
+		})
+	}
+}
+
+func TestPanicError_Detail(t *testing.T) {
+	t.Parallel()
+	var reported *safego.PanicError
+	err := <-safego.Go(context.Background(), func(context.Context) error {
+		var m map[string]int
+		m["x"] = 1 // nilマップへの書き込みでパニック
+		return nil
+	},
+		safego.WithLabels("job", "export", "id", "42"),
+		safego.WithReporter(func(_ context.Context, pe *safego.PanicError) { reported = pe }))
+
+	var pe *safego.PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("want PanicError, got %v", err)
+	}
+	if reported != pe {
+		t.Error("want the reporter to receive the same error")
+	}
+	if got, want := pe.Labels, map[string]string{"job": "export", "id": "42"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("want labels %v, got %v", want, got)
+	}
+	if want := "safego: panic in [id=42 job=export]: assignment to entry in nil map"; err.Error() != want {
+		t.Errorf("want %q, got %q", want, err.Error())
+	}
+	if detail := fmt.Sprintf("%+v", err); !strings.Contains(detail, "safego_test.TestPanicError_Detail") {
+		t.Errorf("want stack in detail, got %q", detail)
+	}
+}
+
+type escape struct{}
+
+func TestRepanic(t *testing.T) {
+	t.Parallel()
+	defer func() {
+		if r := recover(); r != (escape{}) {
+			t.Errorf("want escape{} to be re-panicked, got %v", r)
+		}
+	}()
+	err := safego.Call(context.Background(), func(context.Context) error { panic(io.EOF) }, safego.WithRepanic(safego.NonError))
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("want error panics to be converted, got %v", err)
+	}
+	safego.Call(context.Background(), func(context.Context) error { panic(escape{}) }, safego.WithRepanic(safego.NonError))
+	t.Error("want panic")
+}
+
+func TestLogReporter(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	safego.Call(context.Background(), func(context.Context) error { panic("boom") },
+		safego.WithReporter(safego.LogReporter(log.New(&buf, "", 0))))
+	if got := buf.String(); !strings.HasPrefix(got, "safego: panic: boom\ngoroutine ") {
+		t.Errorf("want panic with stack in log, got %q", got)
+	}
+}
+
+func TestGroup(t *testing.T) {
+	t.Parallel()
+	var reports []string
+	var mu sync.Mutex
+	g, ctx := safego.WithContext(context.Background(), safego.WithReporter(func(_ context.Context, pe *safego.PanicError) {
+		mu.Lock()
+		defer mu.Unlock()
+		reports = append(reports, pe.Labels["worker"])
+	}))
+	g.Go(ctx, func(ctx context.Context) error { panic("worker crashed") }, safego.WithLabels("worker", "1"))
+	g.Go(ctx, func(ctx context.Context) error {
+		<-ctx.Done() // 他のゴールーチンのパニックでキャンセルされる
+		return ctx.Err()
+	}, safego.WithLabels("worker", "2"))
+
+	err := g.Wait()
+	var pe *safego.PanicError
+	if !errors.As(err, &pe) || pe.Labels["worker"] != "1" {
+		t.Fatalf("want panic from worker 1, got %v", err)
+	}
+	var cause *safego.PanicError
+	if !errors.As(context.Cause(ctx), &cause) {
+		t.Errorf("want the context to be canceled with the panic, got %v", context.Cause(ctx))
+	}
+	if len(reports) != 1 || reports[0] != "1" {
+		t.Errorf("want 1 report from worker 1, got %v", reports)
+	}
+}
+
+func TestGroup_Zero(t *testing.T) {
+	t.Parallel()
+	var g safego.Group
+	for i := 0; i < 3; i++ {
+		i := i
+		g.Go(context.Background(), func(context.Context) error {
+			if i == 2 {
+				return io.EOF
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); !errors.Is(err, io.EOF) {
+		t.Errorf("want %v, got %v", io.EOF, err)
+	}
+}
@@ -0,0 +1,439 @@
+// Package config は初期値・設定ファイル・環境変数・コマンドライン引数を重ねて構造体に読み込む
+//
+// 後のものほど優先される
+//
+//  1. defaultタグの初期値
+//  2. 設定ファイル（YAML・JSON・TOML。WithFilesで後に指定したものほど優先）
+//  3. 環境変数（envタグ。WithEnvPrefixの接頭辞が付く）
+//  4. コマンドライン引数（flagタグ）
+//
+// 構造体のタグで読み込む場所を指定する
+//
+//	type Config struct {
+//		Addr    string        `config:"addr" env:"ADDR" flag:"addr" default:":8080"`
+//		Timeout time.Duration `default:"30s"`
+//		MaxBody config.Size   `config:"max_body" default:"10MiB"`
+//		DB      struct {
+//			URL string `env:"URL" required:"true" secret:"true"`
+//		} `env:"DB_"`
+//	}
+//
+//	l := config.New(config.WithFiles("app.yaml"), config.WithEnvPrefix("APP_"), config.WithArgs(os.Args[1:]))
+//	var cfg Config
+//	err := l.Load(&cfg)
+//
+// 値の変換にはconvertパッケージを使うので、time.Durationやencoding.TextUnmarshalerを実装した型も使える
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"golang/recipe-golang/6.error/convert"
+	"golang/recipe-golang/6.error/errs"
+)
+
+var (
+	// ErrNotStruct はLoadに構造体へのポインタ以外が渡されたときのエラー
+	ErrNotStruct = errors.New("config: want a non-nil pointer to a struct")
+	// ErrUnknownFormat は設定ファイルの拡張子から形式がわからないときのエラー
+	ErrUnknownFormat = errors.New("config: unknown file format")
+)
+
+// RequiredError はrequiredタグのフィールドに値が設定されなかったときのエラー
+// errs.Invalidとして扱われる
+type RequiredError struct {
+	Fields []string // 値がないフィールドのキー
+}
+
+func (e *RequiredError) Error() string {
+	return "config: required fields are not set: " + strings.Join(e.Fields, ", ")
+}
+
+// ErrorCode はerrs.CodeOfでerrs.Invalidを返すようにする
+func (e *RequiredError) ErrorCode() errs.Code { return errs.Invalid }
+
+// Is はerrors.Is(err, errs.Invalid)をtrueにする
+func (e *RequiredError) Is(target error) bool { return target == errs.Invalid }
+
+// Validator を実装した構造体は、読み込んだ後にValidateが呼ばれる
+type Validator interface {
+	Validate() error
+}
+
+// Loader は設定を読み込む
+type Loader struct {
+	files     []string
+	envPrefix string
+	lookupEnv func(string) (string, bool)
+	args      []string
+	registry  *convert.Registry
+
+	mu      sync.Mutex
+	sources map[string]string
+}
+
+// Option はLoaderの設定
+type Option func(*Loader)
+
+// WithFiles は読み込む設定ファイルを追加する。後のファイルほど優先される
+// 形式は拡張子（.yaml、.yml、.json、.toml）で決まる
+func WithFiles(names ...string) Option {
+	return func(l *Loader) {
+		l.files = append(l.files, names...)
+	}
+}
+
+// WithEnvPrefix は環境変数の名前の前に付ける接頭辞（例: "APP_"）を設定する
+func WithEnvPrefix(prefix string) Option {
+	return func(l *Loader) {
+		l.envPrefix = prefix
+	}
+}
+
+// WithLookupEnv は環境変数を取得する関数を設定する。初期値はos.LookupEnv
+func WithLookupEnv(fn func(string) (string, bool)) Option {
+	return func(l *Loader) {
+		l.lookupEnv = fn
+	}
+}
+
+// WithArgs はflagタグのフィールドに使うコマンドライン引数を設定する
+func WithArgs(args []string) Option {
+	return func(l *Loader) {
+		l.args = args
+	}
+}
+
+// WithRegistry は値の変換に使うconvert.Registryを設定する。初期値はconvert.Default
+func WithRegistry(r *convert.Registry) Option {
+	return func(l *Loader) {
+		l.registry = r
+	}
+}
+
+// New はLoaderを作る
+func New(opts ...Option) *Loader {
+	l := &Loader{lookupEnv: os.LookupEnv, registry: convert.Default}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Files は読み込む設定ファイル
+func (l *Loader) Files() []string {
+	return append([]string(nil), l.files...)
+}
+
+// field は設定を入れる1つのフィールド
+type field struct {
+	key      string // ドット区切りのキー（例: db.url）
+	path     []string
+	index    []int
+	typ      reflect.Type
+	env      string
+	flag     string
+	usage    string
+	def      string
+	hasDef   bool
+	required bool
+	secret   bool
+}
+
+var textUnmarshalerType = reflect.TypeOf((*interface{ UnmarshalText([]byte) error })(nil)).Elem()
+
+// fieldsOf は構造体の値を入れるフィールドを順に返す
+// 入れ子の構造体はたどり、time.TimeなどUnmarshalTextを持つ型は1つの値として扱う
+func fieldsOf(t reflect.Type) []field {
+	var fields []field
+	var walk func(t reflect.Type, path []string, index []int, envPrefix string)
+	walk = func(t reflect.Type, path []string, index []int, envPrefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			name, ok := sf.Tag.Lookup("config")
+			if name == "-" {
+				continue
+			}
+			if !ok || name == "" {
+				name = snakeCase(sf.Name)
+			}
+			idx := append(append([]int(nil), index...), i)
+			env := sf.Tag.Get("env")
+
+			if sf.Type.Kind() == reflect.Struct && !reflect.PtrTo(sf.Type).Implements(textUnmarshalerType) {
+				p := append(append([]string(nil), path...), name)
+				// 埋め込みの構造体はキーを増やさずにたどる
+				if sf.Anonymous && !ok {
+					p = path
+				}
+				walk(sf.Type, p, idx, envPrefix+env)
+				continue
+			}
+
+			f := field{
+				path:  append(append([]string(nil), path...), name),
+				index: idx,
+				typ:   sf.Type,
+				flag:  sf.Tag.Get("flag"),
+				usage: sf.Tag.Get("usage"),
+			}
+			f.key = strings.Join(f.path, ".")
+			if env != "" && env != "-" {
+				f.env = envPrefix + env
+			}
+			f.def, f.hasDef = sf.Tag.Lookup("default")
+			f.required = sf.Tag.Get("required") == "true"
+			f.secret = sf.Tag.Get("secret") == "true"
+			fields = append(fields, f)
+		}
+	}
+	walk(t, nil, nil, "")
+	return fields
+}
+
+// snakeCase はMaxConnsをmax_connsにする
+func snakeCase(s string) string {
+	var b strings.Builder
+	rs := []rune(s)
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			// URLやIDのような連続した大文字は1つの単語にする
+			if i > 0 && (unicode.IsLower(rs[i-1]) || i+1 < len(rs) && unicode.IsLower(rs[i+1]) && unicode.IsUpper(rs[i-1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Load は設定を読み込んでdstに入れる
+// エラーのときdstは変更しない
+func (l *Loader) Load(dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrNotStruct
+	}
+	t := rv.Elem().Type()
+	fields := fieldsOf(t)
+
+	values, sources, err := l.resolve(fields)
+	if err != nil {
+		return err
+	}
+
+	// 前の値が残らないように新しい値に読み込む
+	out := reflect.New(t).Elem()
+	var err2 error
+	var missing []string
+	for _, f := range fields {
+		v, ok := values[f.key]
+		if !ok {
+			if f.required {
+				missing = append(missing, f.key)
+			}
+			continue
+		}
+		cv, err := l.registry.Convert(v, f.typ)
+		if err != nil {
+			err2 = errs.Append(err2, fmt.Errorf("config: %s (from %s): %w", f.key, sources[f.key], err))
+			continue
+		}
+		out.FieldByIndex(f.index).Set(cv)
+		if f.required && out.FieldByIndex(f.index).IsZero() {
+			missing = append(missing, f.key)
+		}
+	}
+	if len(missing) > 0 {
+		err2 = errs.Append(err2, &RequiredError{Fields: missing})
+	}
+	if err2 != nil {
+		return err2
+	}
+	if v, ok := out.Addr().Interface().(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return errs.Invalid.Wrap(err, "config: validate")
+		}
+	}
+
+	rv.Elem().Set(out)
+	l.mu.Lock()
+	l.sources = sources
+	l.mu.Unlock()
+	return nil
+}
+
+// resolve はフィールドごとに優先順位の最も高い値と、その出どころを返す
+func (l *Loader) resolve(fields []field) (map[string]interface{}, map[string]string, error) {
+	values := make(map[string]interface{})
+	sources := make(map[string]string)
+	set := func(f field, v interface{}, src string) {
+		values[f.key] = v
+		sources[f.key] = src
+	}
+
+	for _, f := range fields {
+		if f.hasDef {
+			set(f, f.def, "default")
+		}
+	}
+
+	for _, name := range l.files {
+		tree, err := readFile(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, f := range fields {
+			if v, ok := lookup(tree, f.path); ok {
+				set(f, v, "file:"+name)
+			}
+		}
+	}
+
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		name := l.envPrefix + f.env
+		if v, ok := l.lookupEnv(name); ok {
+			set(f, v, "env:"+name)
+		}
+	}
+
+	if l.args != nil {
+		flags, err := l.parseFlags(fields)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, f := range fields {
+			if v, ok := flags[f.flag]; ok && f.flag != "" {
+				set(f, v, "flag:-"+f.flag)
+			}
+		}
+	}
+	return values, sources, nil
+}
+
+// flagValue はフラグの文字列をそのまま持つ。変換はconvertで他の値と同じように行う
+type flagValue struct {
+	s      string
+	isBool bool
+}
+
+func (v *flagValue) String() string     { return v.s }
+func (v *flagValue) Set(s string) error { v.s = s; return nil }
+func (v *flagValue) IsBoolFlag() bool   { return v.isBool }
+
+// parseFlags はflagタグのフィールドのフラグを作ってl.argsをパースし、指定されたフラグの値を返す
+func (l *Loader) parseFlags(fields []field) (map[string]string, error) {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	for _, f := range fields {
+		if f.flag == "" {
+			continue
+		}
+		fs.Var(&flagValue{s: f.def, isBool: f.typ.Kind() == reflect.Bool}, f.flag, f.usage)
+	}
+	if err := fs.Parse(l.args); err != nil {
+		return nil, errs.Invalid.Wrap(err, "config: parse flags")
+	}
+	flags := make(map[string]string)
+	fs.Visit(func(fl *flag.Flag) {
+		flags[fl.Name] = fl.Value.String()
+	})
+	return flags, nil
+}
+
+// readFile は設定ファイルを拡張子の形式で読み込む
+func readFile(name string) (map[string]interface{}, error) {
+	var unmarshal func([]byte, interface{}) error
+	switch ext := strings.ToLower(filepath.Ext(name)); ext {
+	case ".yaml", ".yml":
+		unmarshal = yaml.Unmarshal
+	case ".json":
+		unmarshal = func(b []byte, v interface{}) error {
+			dec := json.NewDecoder(bytes.NewReader(b))
+			dec.UseNumber()
+			return dec.Decode(v)
+		}
+	case ".toml":
+		unmarshal = toml.Unmarshal
+	default:
+		return nil, fmt.Errorf("%w %q: %s", ErrUnknownFormat, ext, name)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	tree := make(map[string]interface{})
+	if err := unmarshal(b, &tree); err != nil {
+		return nil, errs.Invalid.Wrap(err, "config: "+name)
+	}
+	return tree, nil
+}
+
+// lookup は入れ子のmapからpathの値を探す
+// キーは大文字小文字と_・-を区別しない（max_conns、maxConns、max-connsは同じ）
+func lookup(tree map[string]interface{}, path []string) (interface{}, bool) {
+	var cur interface{} = tree
+	for _, key := range path {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		found := false
+		for k, v := range m {
+			if normalizeKey(k) == normalizeKey(key) {
+				cur, found = v, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	// JSONの数値は大きな整数が丸められないようUseNumberで読んでいるので、YAMLやTOMLと同じ型にそろえる
+	if n, ok := cur.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, true
+		}
+		if f, err := n.Float64(); err == nil {
+			return f, true
+		}
+		return n.String(), true
+	}
+	return cur, true
+}
+
+func normalizeKey(k string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(k))
+}
+
+// Sources は最後に読み込んだときの、キーごとの値の出どころ（default、file:名前、env:名前、flag:-名前）
+func (l *Loader) Sources() map[string]string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make(map[string]string, len(l.sources))
+	for k, v := range l.sources {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,325 @@
+package config_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang/recipe-golang/6.error/errs"
+	"golang/recipe-golang/config"
+)
+
+type DB struct {
+	URL      string `env:"URL" required:"true" secret:"true"`
+	MaxConns int    `env:"MAX_CONNS" flag:"max-conns" default:"5"`
+}
+
+type Config struct {
+	Addr    string        `env:"ADDR" flag:"addr" default:":80"`
+	Timeout time.Duration `default:"30s"`
+	MaxBody config.Size   `default:"1MiB"`
+	Tags    []string
+	Debug   bool   `flag:"debug"`
+	APIKey  string `env:"API_KEY"`
+	DB      DB     `env:"DB_"`
+}
+
+// env は環境変数の代わりに使うmap
+func env(m map[string]string) config.Option {
+	return config.WithLookupEnv(func(k string) (string, bool) {
+		v, ok := m[k]
+		return v, ok
+	})
+}
+
+func TestLoad_Formats(t *testing.T) {
+	t.Parallel()
+	want := Config{
+		Addr:    ":8080",
+		Timeout: 45 * time.Second,
+		MaxBody: 10 * config.MiB,
+		Tags:    []string{"web", "api"},
+		DB:      DB{URL: "postgres://localhost/app", MaxConns: 10},
+	}
+	for _, name := range []string{"app.yaml", "app.json", "app.toml"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var got Config
+			if err := config.New(config.WithFiles(filepath.Join("testdata", name)), env(nil)).Load(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("want %+v, got %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	t.Parallel()
+	l := config.New(
+		config.WithFiles("testdata/app.yaml", "testdata/local.json"),
+		config.WithEnvPrefix("APP_"),
+		env(map[string]string{"APP_ADDR": ":9090", "APP_DB_MAX_CONNS": "30", "ADDR": ":1"}),
+		config.WithArgs([]string{"-addr", ":7070", "-debug"}),
+	)
+	var cfg Config
+	if err := l.Load(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":7070" || cfg.DB.MaxConns != 30 || !cfg.Debug || cfg.Timeout != 45*time.Second {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	want := map[string]string{
+		"addr":         "flag:-addr",
+		"timeout":      "file:testdata/app.yaml",
+		"max_body":     "file:testdata/app.yaml",
+		"tags":         "file:testdata/app.yaml",
+		"debug":        "flag:-debug",
+		"db.url":       "file:testdata/app.yaml",
+		"db.max_conns": "env:APP_DB_MAX_CONNS",
+	}
+	if got := l.Sources(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	t.Parallel()
+	var cfg Config
+	err := config.New(env(map[string]string{"DB_URL": "x"})).Load(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{Addr: ":80", Timeout: 30 * time.Second, MaxBody: config.MiB, DB: DB{URL: "x", MaxConns: 5}}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("want %+v, got %+v", want, cfg)
+	}
+}
+
+// TestLoad_Env はテスト用のenvではなく本物の環境変数から読む
+// t.Setenvを使うのでt.Parallelにはしない
+func TestLoad_Env(t *testing.T) {
+	t.Setenv("DB", "postgres://localhost/test")
+	var cfg struct {
+		DB string `env:"DB" required:"true"`
+	}
+	if err := config.New().Load(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if want := "postgres://localhost/test"; cfg.DB != want {
+		t.Errorf("want %q, got %q", want, cfg.DB)
+	}
+}
+
+type Strict struct {
+	Name  string `env:"NAME" required:"true"`
+	Port  int    `env:"PORT" required:"true"`
+	Level string `env:"LEVEL" default:"info"`
+}
+
+func (s *Strict) Validate() error {
+	if s.Level != "info" && s.Level != "debug" {
+		return errors.New("level must be info or debug")
+	}
+	return nil
+}
+
+func TestLoad_Error(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		env  map[string]string
+		args []string
+		msg  string
+	}{
+		"required":     {env: map[string]string{"PORT": "80"}, msg: "required fields are not set: name"},
+		"required all": {env: map[string]string{"NAME": ""}, msg: "required fields are not set: name, port"},
+		"convert":      {env: map[string]string{"NAME": "a", "PORT": "http"}, msg: "port (from env:PORT)"},
+		"validate":     {env: map[string]string{"NAME": "a", "PORT": "80", "LEVEL": "trace"}, msg: "level must be info or debug"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			cfg := Strict{Name: "unchanged"}
+			err := config.New(env(tt.env)).Load(&cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.msg) {
+				t.Fatalf("want error containing %q, got %v", tt.msg, err)
+			}
+			if !errors.Is(err, errs.Invalid) {
+				t.Errorf("want %v, got %v", errs.Invalid, err)
+			}
+			if cfg.Name != "unchanged" {
+				t.Errorf("want cfg to be unchanged on error, got %+v", cfg)
+			}
+		})
+	}
+
+	var re *config.RequiredError
+	if err := config.New(env(nil)).Load(&Strict{}); !errors.As(err, &re) || len(re.Fields) != 2 {
+		t.Errorf("want *RequiredError with 2 fields, got %v", err)
+	}
+	if err := config.New().Load(Strict{}); !errors.Is(err, config.ErrNotStruct) {
+		t.Errorf("want %v, got %v", config.ErrNotStruct, err)
+	}
+	if err := config.New(config.WithFiles("testdata/app.ini")).Load(&Strict{}); !errors.Is(err, config.ErrUnknownFormat) {
+		t.Errorf("want %v, got %v", config.ErrUnknownFormat, err)
+	}
+	if err := config.New(config.WithFiles("testdata/none.yaml")).Load(&Strict{}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("want %v, got %v", os.ErrNotExist, err)
+	}
+	if err := config.New(config.WithArgs([]string{"-port"})).Load(&Config{}); !errors.Is(err, errs.Invalid) {
+		t.Errorf("want %v for an unknown flag, got %v", errs.Invalid, err)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		in   string
+		want config.Size
+		str  string
+	}{
+		"bytes":   {"512", 512, "512B"},
+		"b":       {"512B", 512, "512B"},
+		"kb":      {"2KB", 2000, "2000B"},
+		"kib":     {"2KiB", 2 * config.KiB, "2KiB"},
+		"k":       {"2k", 2 * config.KiB, "2KiB"},
+		"decimal": {"1.5 MiB", 1536 * config.KiB, "1536KiB"},
+		"gb":      {"1GB", 1e9, "1000000000B"},
+		"g":       {"3G", 3 * config.GiB, "3GiB"},
+		"zero":    {"0", 0, "0B"},
+	}
+	for name, tt := range cases {
+		name, tt := name, tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			got, err := config.ParseSize(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("want %d, got %d", tt.want, got)
+			}
+			if got.String() != tt.str {
+				t.Errorf("want %q, got %q", tt.str, got.String())
+			}
+		})
+	}
+	for _, in := range []string{"", "MB", "1XB", "-1", "1.2.3K", "99999999TiB"} {
+		if _, err := config.ParseSize(in); err == nil {
+			t.Errorf("%q: want error", in)
+		}
+	}
+}
+
+func TestDump(t *testing.T) {
+	t.Parallel()
+	l := config.New(
+		config.WithFiles("testdata/app.yaml"),
+		env(map[string]string{"API_KEY": "abc"}),
+	)
+	var cfg Config
+	if err := l.Load(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := l.Dump(&buf, cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := `addr = ":8080"  # file:testdata/app.yaml
+timeout = 45s  # file:testdata/app.yaml
+max_body = 10MiB  # file:testdata/app.yaml
+tags = [web api]  # file:testdata/app.yaml
+debug = false
+api_key = [REDACTED]  # env:API_KEY
+db.url = [REDACTED]  # file:testdata/app.yaml
+db.max_conns = 10  # file:testdata/app.yaml
+`
+	if buf.String() != want {
+		t.Errorf("want\n%s\ngot\n%s", want, buf.String())
+	}
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+	name := filepath.Join(t.TempDir(), "app.yaml")
+	write := func(s string) {
+		t.Helper()
+		if err := os.WriteFile(name, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("db:\n  url: a\n")
+
+	w, err := config.Watch[Config](config.New(config.WithFiles(name), env(nil)), config.WithInterval(5*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan config.Change[Config], 1)
+	errc := make(chan error, 1)
+	w.OnChange(func(c config.Change[Config]) { changes <- c })
+	w.OnError(func(err error) { errc <- err })
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	write("addr: :9000\ndb:\n  url: a\n  max_conns: 7\n")
+	select {
+	case c := <-changes:
+		if want := []string{"addr", "db.max_conns"}; !reflect.DeepEqual(c.Fields, want) {
+			t.Errorf("want %v, got %v", want, c.Fields)
+		}
+		if !c.Changed("addr") || c.Changed("db.url") || c.Old.Addr != ":80" || c.New.Addr != ":9000" {
+			t.Errorf("unexpected change: %+v", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a change")
+	}
+
+	// 読み込めない設定になっても前の設定を使い続ける
+	write("db:\n  url: ''\n  max_conns: 7\n")
+	select {
+	case err := <-errc:
+		var re *config.RequiredError
+		if !errors.As(err, &re) {
+			t.Errorf("want *RequiredError, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an error")
+	}
+	if got := w.Current(); got.Addr != ":9000" || got.DB.URL != "a" {
+		t.Errorf("want the previous config, got %+v", got)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestWatch_Interval(t *testing.T) {
+	t.Parallel()
+	name := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(name, []byte("db:\n  url: a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []time.Duration{0, -time.Second} {
+		w, err := config.Watch[Config](config.New(config.WithFiles(name), env(nil)), config.WithInterval(d))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := w.Run(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("WithInterval(%v): want %v, got %v", d, context.Canceled, err)
+		}
+	}
+}
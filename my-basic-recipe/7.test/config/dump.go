@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"golang/recipe-golang/6.error/errs"
+)
+
+// Dump はcfgの値を「キー = 値」の形で1行ずつ書き込む
+// secretタグのフィールドと、errs.SensitiveKeysを含むキーの値は隠す
+// 最後にLoadしたときの値の出どころがわかれば行末に書き込む
+//
+//	addr = ":8080"  # flag:-addr
+//	db.url = [REDACTED]  # env:APP_DB_URL
+func (l *Loader) Dump(w io.Writer, cfg interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(cfg))
+	if rv.Kind() != reflect.Struct {
+		return ErrNotStruct
+	}
+	sources := l.Sources()
+	for _, f := range fieldsOf(rv.Type()) {
+		line := f.key + " = " + formatValue(f, rv.FieldByIndex(f.index).Interface())
+		if src, ok := sources[f.key]; ok {
+			line += "  # " + src
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatValue は値を出力する形式にする。秘密の値は中身にかかわらず隠す
+func formatValue(f field, v interface{}) string {
+	// キーで隠すかどうかだけを知りたいので、値の代わりに目印を渡す
+	type probe struct{}
+	if f.secret || errs.Redact(f.key, probe{}) != (probe{}) {
+		return errs.Redacted{}.String()
+	}
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
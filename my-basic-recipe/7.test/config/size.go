@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Size はバイト数
+// 設定では"512"、"10KB"、"1.5MiB"、"2G"のように書ける
+//
+//	KB、MB、GB、TBは1000倍ずつ、KiB、MiB、GiB、TiBとK、M、G、Tは1024倍ずつ
+type Size int64
+
+// サイズの単位
+const (
+	Byte Size = 1
+	KiB       = 1024 * Byte
+	MiB       = 1024 * KiB
+	GiB       = 1024 * MiB
+	TiB       = 1024 * GiB
+)
+
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   float64(KiB),
+	"kb":  1e3,
+	"kib": float64(KiB),
+	"m":   float64(MiB),
+	"mb":  1e6,
+	"mib": float64(MiB),
+	"g":   float64(GiB),
+	"gb":  1e9,
+	"gib": float64(GiB),
+	"t":   float64(TiB),
+	"tb":  1e12,
+	"tib": float64(TiB),
+}
+
+// ParseSize は"10MiB"のような文字列をSizeにする
+func ParseSize(s string) (Size, error) {
+	t := strings.TrimSpace(s)
+	i := strings.IndexFunc(t, func(r rune) bool {
+		return !('0' <= r && r <= '9' || r == '.')
+	})
+	if i < 0 {
+		i = len(t)
+	}
+	num, unit := t[:i], strings.ToLower(strings.TrimSpace(t[i:]))
+	mul, ok := sizeUnits[unit]
+	if !ok || num == "" {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	n := f * mul
+	if n > math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return Size(n), nil
+}
+
+// String は割り切れる最も大きな単位（KiB、MiB、GiB、TiB）で表す
+func (s Size) String() string {
+	for _, u := range []struct {
+		size Size
+		name string
+	}{{TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"}} {
+		if s != 0 && s%u.size == 0 {
+			return strconv.FormatInt(int64(s/u.size), 10) + u.name
+		}
+	}
+	return strconv.FormatInt(int64(s), 10) + "B"
+}
+
+// MarshalText はStringの形式で書き出す
+func (s Size) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText はParseSizeで読み込む
+func (s *Size) UnmarshalText(b []byte) error {
+	v, err := ParseSize(string(b))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
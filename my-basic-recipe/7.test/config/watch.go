@@ -0,0 +1,189 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Change は設定が変わったときにOnChangeの関数に渡される
+type Change[T any] struct {
+	Old, New T
+	// Fields は値が変わったフィールドのキー
+	Fields []string
+}
+
+// Changed はkeyのフィールドが変わったかを返す
+func (c Change[T]) Changed(key string) bool {
+	for _, f := range c.Fields {
+		if f == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Watcher は設定ファイルの変更を監視して設定を読み込み直す
+// 変更はファイルの更新時刻とサイズをポーリングして見つける。環境変数と引数は読み込み直すときに読む
+type Watcher[T any] struct {
+	l        *Loader
+	interval time.Duration
+	reload   sync.Mutex // Reloadを1つずつ実行する
+
+	mu       sync.RWMutex
+	cur      T
+	stamps   map[string]fileStamp
+	onChange []func(Change[T])
+	onError  []func(error)
+}
+
+// WatchOption はWatcherの設定
+type WatchOption func(*watchOptions)
+
+type watchOptions struct {
+	interval time.Duration
+}
+
+// WithInterval はファイルを確かめる間隔を設定する。初期値は1秒
+// 0以下は無視して初期値のままにする
+func WithInterval(d time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		if d > 0 {
+			o.interval = d
+		}
+	}
+}
+
+// fileStamp はファイルが変わったかを判断するための情報
+type fileStamp struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+func stampOf(name string) fileStamp {
+	info, err := os.Stat(name)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{exists: true, modTime: info.ModTime(), size: info.Size()}
+}
+
+// Watch は設定を読み込み、lのファイルを監視するWatcherを作る
+// 監視はRunで始める
+//
+//	w, err := config.Watch[Config](l)
+//	w.OnChange(func(c config.Change[Config]) {
+//		if c.Changed("log.level") {
+//			setLevel(c.New.Log.Level)
+//		}
+//	})
+//	go w.Run(ctx)
+func Watch[T any](l *Loader, opts ...WatchOption) (*Watcher[T], error) {
+	o := watchOptions{interval: time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	w := &Watcher[T]{l: l, interval: o.interval}
+	// 読み込む前の状態を記録しておき、読み込み中の変更も次の確認で拾う
+	w.stamps = w.stat()
+	if err := l.Load(&w.cur); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Current は今の設定を返す
+func (w *Watcher[T]) Current() T {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.cur
+}
+
+// OnChange は設定が変わったときに呼ぶ関数を登録する
+func (w *Watcher[T]) OnChange(fn func(Change[T])) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onChange = append(w.onChange, fn)
+}
+
+// OnError は読み込み直しに失敗したときに呼ぶ関数を登録する
+// 失敗したときは前の設定を使い続ける
+func (w *Watcher[T]) OnError(fn func(error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onError = append(w.onError, fn)
+}
+
+// Run はctxが終わるまでファイルを監視する
+func (w *Watcher[T]) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			stamps := w.stat()
+			if reflect.DeepEqual(stamps, w.stamps) {
+				continue
+			}
+			w.stamps = stamps
+			if err := w.Reload(); err != nil {
+				w.mu.RLock()
+				fns := w.onError
+				w.mu.RUnlock()
+				for _, fn := range fns {
+					fn(err)
+				}
+			}
+		}
+	}
+}
+
+// Reload は設定を読み込み直し、変わったフィールドがあればOnChangeの関数を呼ぶ
+func (w *Watcher[T]) Reload() error {
+	w.reload.Lock()
+	defer w.reload.Unlock()
+	var next T
+	if err := w.l.Load(&next); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	old := w.cur
+	w.cur = next
+	fns := w.onChange
+	w.mu.Unlock()
+
+	fields := diffFields(old, next)
+	if len(fields) == 0 {
+		return nil
+	}
+	c := Change[T]{Old: old, New: next, Fields: fields}
+	for _, fn := range fns {
+		fn(c)
+	}
+	return nil
+}
+
+func (w *Watcher[T]) stat() map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(w.l.files))
+	for _, name := range w.l.files {
+		stamps[name] = stampOf(name)
+	}
+	return stamps
+}
+
+// diffFields は値が違うフィールドのキーを返す
+func diffFields[T any](a, b T) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	var keys []string
+	for _, f := range fieldsOf(va.Type()) {
+		if !reflect.DeepEqual(va.FieldByIndex(f.index).Interface(), vb.FieldByIndex(f.index).Interface()) {
+			keys = append(keys, f.key)
+		}
+	}
+	return keys
+}
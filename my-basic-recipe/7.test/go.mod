@@ -1,5 +1,11 @@
 module golang/recipe-golang
 
-go 1.16
+go 1.21
 
-require github.com/jinzhu/configor v1.2.1 // indirect
+require (
+	github.com/BurntSushi/toml v1.5.0
+	golang/recipe-golang/6.error v0.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace golang/recipe-golang/6.error => ../6.error
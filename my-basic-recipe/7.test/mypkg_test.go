@@ -4,8 +4,6 @@ import (
 	"fmt"
 	"io/ioutil"
 	"testing"
-
-	"github.com/jinzhu/configor"
 )
 
 type Hex int
@@ -183,15 +181,10 @@ func (db getTestDB) Get(key string) string { return "test" }
 // os.Getenvで取得できる
 // CIでテストを走らせるときに便利
 // DBの接続先など環境に依存する値を保存する
-// github.com/jinzhu/configorを使う
-var Config = struct {
-	DB string `env:"DB"`
-}{}
-
-func main() {
-	configor.Load(&Config)
-	fmt.Printf("config: %#v", Config)
-}
+// configパッケージ（config/）を使う
+// 初期値・設定ファイル・環境変数・コマンドライン引数の順に上書きされる
+// テストではt.Setenvで環境変数を設定する（テスト終了時に元に戻る）
+// 例はconfig/config_test.goのTestLoad_Env
 
 // ** テストデータを用意する */ ・・どの環境でも使用できるテストデータを用意する
 // - testdataというディレクトリに入れる（参考）https://pkg.go.dev/cmd/go#hdr-Test_packages
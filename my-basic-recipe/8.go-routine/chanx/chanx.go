@@ -0,0 +1,215 @@
+// Package chanx は型パラメタを使ったチャネルのユーティリティ
+//
+// すべての関数はctxが終わるとゴールーチンを終了して出力チャネルを閉じる
+// 受け取ったチャネルは受信専用（<-chan T）として扱い、閉じるのは送信側の責任とする
+package chanx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTimeout = errors.New("chanx: receive timed out")
+	ErrClosed  = errors.New("chanx: channel closed")
+)
+
+// Unbounded はBufferに渡すと上限のないキューになる
+const Unbounded = -1
+
+// OrDone はinから受信した値をそのまま送るチャネルを返す
+// ctxが終わるとinが閉じていなくても出力を閉じる
+func OrDone[T any](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if !Send(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Merge は複数のチャネルを1つにまとめる（ファンイン）
+// すべての入力が閉じると出力も閉じる
+func Merge[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	for _, in := range ins {
+		wg.Add(1)
+		go func(in <-chan T) {
+			defer wg.Done()
+			for v := range OrDone(ctx, in) {
+				if !Send(ctx, out, v) {
+					return
+				}
+			}
+		}(in)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Tee はinの値を2つのチャネルに複製する
+// 両方のチャネルが受信するまで次の値を読まない
+func Tee[T any](ctx context.Context, in <-chan T) (<-chan T, <-chan T) {
+	out1, out2 := make(chan T), make(chan T)
+	go func() {
+		defer close(out1)
+		defer close(out2)
+		for v := range OrDone(ctx, in) {
+			o1, o2 := out1, out2
+			for i := 0; i < 2; i++ {
+				select {
+				case <-ctx.Done():
+					return
+				case o1 <- v:
+					o1 = nil // 送信済みのチャネルはnilにしてselectで選ばれないようにする
+				case o2 <- v:
+					o2 = nil
+				}
+			}
+		}
+	}()
+	return out1, out2
+}
+
+// Take はinから最初のn個だけを送るチャネルを返す
+func Take[T any](ctx context.Context, in <-chan T, n int) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for i := 0; i < n; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok || !Send(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Bridge はチャネルのチャネルを1つのチャネルにする
+// 受信したチャネルを順番に読み切ってから次のチャネルに進む
+func Bridge[T any](ctx context.Context, chans <-chan <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			var stream <-chan T
+			select {
+			case <-ctx.Done():
+				return
+			case s, ok := <-chans:
+				if !ok {
+					return
+				}
+				stream = s
+			}
+			for v := range OrDone(ctx, stream) {
+				if !Send(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// Buffer はinとの間にバッファを挟んだチャネルを返す
+// 受信側が遅くても送信側をブロックしないようにする
+// sizeにUnboundedを指定すると上限なくメモリにためる
+func Buffer[T any](ctx context.Context, in <-chan T, size int) <-chan T {
+	if size >= 0 {
+		out := make(chan T, size)
+		go func() {
+			defer close(out)
+			for v := range OrDone(ctx, in) {
+				if !Send(ctx, out, v) {
+					return
+				}
+			}
+		}()
+		return out
+	}
+
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		var queue []T
+		for in != nil || len(queue) > 0 {
+			// キューが空のときはnilチャネルにして送信のcaseを無効にする
+			var (
+				sendCh chan<- T
+				next   T
+			)
+			if len(queue) > 0 {
+				sendCh, next = out, queue[0]
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					in = nil
+					continue
+				}
+				queue = append(queue, v)
+			case sendCh <- next:
+				var zero T
+				queue[0] = zero
+				queue = queue[1:]
+			}
+		}
+	}()
+	return out
+}
+
+// Timeout はinから1つ受信する。dを過ぎても受信できなければErrTimeoutを返す
+// inが閉じている場合はErrClosed、ctxが終わった場合はctx.Err()を返す
+func Timeout[T any](ctx context.Context, in <-chan T, d time.Duration) (T, error) {
+	var zero T
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case v, ok := <-in:
+		if !ok {
+			return zero, ErrClosed
+		}
+		return v, nil
+	case <-timer.C:
+		return zero, ErrTimeout
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// Send はctxが終わるまでoutへの送信を試みる
+// 送信できた場合にtrueを返す
+func Send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case out <- v:
+		return true
+	}
+}
@@ -0,0 +1,224 @@
+package chanx_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"golang/recipe-golang/chanx"
+
+	"go.uber.org/goleak"
+)
+
+// テスト終了時にゴールーチンが残っていないことを確認する
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+// gen はvsを順に送って閉じる送信専用のゴールーチンを作る
+func gen[T any](vs ...T) <-chan T {
+	ch := make(chan T)
+	go func() {
+		defer close(ch)
+		for _, v := range vs {
+			ch <- v
+		}
+	}()
+	return ch
+}
+
+// infinite はctxが終わるまでnを送り続ける
+func infinite(ctx context.Context, n int) <-chan int {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for chanx.Send(ctx, ch, n) {
+		}
+	}()
+	return ch
+}
+
+func collect[T any](ch <-chan T) []T {
+	var vs []T
+	for v := range ch {
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+func equal[T comparable](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMerge(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ctx := context.Background()
+	got := collect(chanx.Merge(ctx, gen(1, 2, 3), gen(4, 5), gen[int]()))
+	sort.Ints(got)
+	if want := []int{1, 2, 3, 4, 5}; !equal(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestMerge_Cancel(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	src1, src2 := infinite(ctx, 1), infinite(ctx, 2)
+	out := chanx.Merge(ctx, src1, src2)
+	<-out
+	cancel()
+	collect(out) // 閉じられるまで読む
+}
+
+func TestTee(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ctx := context.Background()
+	a, b := chanx.Tee(ctx, gen("a", "b", "c"))
+	var gotA, gotB []string
+	for a != nil || b != nil {
+		select {
+		case v, ok := <-a:
+			if !ok {
+				a = nil
+				continue
+			}
+			gotA = append(gotA, v)
+		case v, ok := <-b:
+			if !ok {
+				b = nil
+				continue
+			}
+			gotB = append(gotB, v)
+		}
+	}
+	want := []string{"a", "b", "c"}
+	if !equal(gotA, want) || !equal(gotB, want) {
+		t.Errorf("want %v twice, got %v and %v", want, gotA, gotB)
+	}
+}
+
+func TestTee_Cancel(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	a, b := chanx.Tee(ctx, infinite(ctx, 1))
+	<-a // bが受信しないまま止める
+	cancel()
+	collect(a)
+	collect(b)
+}
+
+func TestTake(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	src := infinite(ctx, 7)
+	if got, want := collect(chanx.Take(ctx, src, 3)), []int{7, 7, 7}; !equal(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if got := collect(chanx.Take(ctx, gen(1), 3)); !equal(got, []int{1}) {
+		t.Errorf("want [1], got %v", got)
+	}
+	cancel()
+	collect(src)
+}
+
+func TestOrDone(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	never := make(chan int) // 誰も閉じないチャネル
+	out := chanx.OrDone(ctx, never)
+	cancel()
+	if _, ok := <-out; ok {
+		t.Error("want closed channel after cancel")
+	}
+}
+
+func TestBridge(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ctx := context.Background()
+	chans := make(chan (<-chan int))
+	go func() {
+		defer close(chans)
+		for i := 0; i < 3; i++ {
+			chans <- gen(i*10, i*10+1)
+		}
+	}()
+	if got, want := collect(chanx.Bridge(ctx, chans)), []int{0, 1, 10, 11, 20, 21}; !equal(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	cases := map[string]int{"bounded": 2, "unbuffered": 0, "unbounded": chanx.Unbounded}
+	for name, size := range cases {
+		size := size
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if got, want := collect(chanx.Buffer(ctx, gen(1, 2, 3, 4), size)), []int{1, 2, 3, 4}; !equal(got, want) {
+				t.Errorf("want %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestBuffer_UnboundedDoesNotBlockSender(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	out := chanx.Buffer(ctx, in, chanx.Unbounded)
+	for i := 0; i < 1000; i++ {
+		select {
+		case in <- i:
+		case <-time.After(time.Second):
+			t.Fatalf("send %d blocked", i)
+		}
+	}
+	close(in)
+	if v := <-out; v != 0 {
+		t.Errorf("want 0, got %d", v)
+	}
+	cancel()
+	collect(out)
+}
+
+func TestTimeout(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	ctx := context.Background()
+	if v, err := chanx.Timeout(ctx, gen(1), time.Second); v != 1 || err != nil {
+		t.Errorf("want 1, <nil>, got %d, %v", v, err)
+	}
+	if _, err := chanx.Timeout(ctx, make(chan int), 10*time.Millisecond); !errors.Is(err, chanx.ErrTimeout) {
+		t.Errorf("want %v, got %v", chanx.ErrTimeout, err)
+	}
+	if _, err := chanx.Timeout(ctx, gen[int](), time.Second); !errors.Is(err, chanx.ErrClosed) {
+		t.Errorf("want %v, got %v", chanx.ErrClosed, err)
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := chanx.Timeout(canceled, make(chan int), time.Second); !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestDirection(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	// 双方向のチャネルも受信専用として渡せる
+	ch := make(chan int, 1)
+	ch <- 1
+	close(ch)
+	var recv <-chan int = chanx.OrDone(context.Background(), ch)
+	if got := collect(recv); !equal(got, []int{1}) {
+		t.Errorf("want [1], got %v", got)
+	}
+}
@@ -1,5 +1,11 @@
 module golang/recipe-golang
 
-go 1.16
+go 1.21
 
-require golang.org/x/sync v0.0.0-20210220032951-036812b2e83c // indirect
+require golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
+
+require go.uber.org/goleak v1.3.0
+
+require golang/recipe-golang/6.error v0.0.0
+
+replace golang/recipe-golang/6.error => ../6.error
@@ -36,6 +36,10 @@ import (
 	"sync"
 	"time"
 
+	"golang/recipe-golang/6.error/safego"
+	"golang/recipe-golang/chanx"
+	"golang/recipe-golang/syncx"
+
 	"golang.org/x/sync/errgroup"
 )
 
@@ -199,19 +203,41 @@ func main() {
 	//<-time.After(0 * time.Minute) //5分たったら現在時刻が送られてくるチャネルを返す
 
 	// ** チャネルを引数や戻り値にする
-	ch5 := makeCh()
+	// 型パラメタで要素の型ごとに関数を作らなくてよい
+	ch5 := makeCh[int]()
 	go func() { ch5 <- 100 }()
 	fmt.Println(recvCh(ch5)) //100
 
 	// ** 双方向チャネル */
-	ch6 := makeCh2()
-	go func() { ch6 <- 100 }()
-	fmt.Println(recvCh2(ch6)) //200
+	// 引数をchan Tにすると受け取った側でも送信できてしまう
+	// func recvCh(recv chan int) int {
+	// 	go func() { recv <- 200 }() //間違った使い方ができる
+	// 	return <-recv
+	// }
 
 	// ** 単方向チャネル */
-	ch7 := makeCh3()
-	go func(ch7 chan<- int) { ch7 <- 100 }(ch7) //送信専用のチャネル
-	fmt.Println(recvCh3(ch7))                   // 100
+	// recvChは受信専用（<-chan T）なので中で送信するとコンパイルエラー
+	ch6 := makeCh[string]()
+	go func(ch6 chan<- string) { ch6 <- "hello" }(ch6) //送信専用のチャネル
+	fmt.Println(recvCh(ch6))                           // hello
+
+	// ** チャネルのユーティリティ */
+	// chanxパッケージ: Merge, Tee, Take, OrDone, Bridge, Buffer, Timeout
+	// ctxが終わるとゴールーチンを終了して出力チャネルを閉じる
+	cctx, ccancel := context.WithCancel(context.Background())
+	nums := make(chan int)
+	go func() {
+		defer close(nums)
+		for i := 0; chanx.Send(cctx, nums, i); i++ {
+		}
+	}()
+	for v := range chanx.Take(cctx, nums, 3) {
+		fmt.Println(v) // 0 1 2
+	}
+	ccancel()
+	if _, err := chanx.Timeout(context.Background(), make(chan int), 10*time.Millisecond); err != nil {
+		fmt.Println(err) // chanx: receive timed out
+	}
 
 	// ** Concurrencyの実現
 	// - 複数のゴールーチンで分業する
@@ -312,6 +338,19 @@ func main() {
 		fmt.Println("エラーを返すゴールーチンの待機") // エラーを返すゴールーチンの待機
 	}
 
+	// ** パニックするゴールーチンの待機・・safegoパッケージを使う（6.error）
+	// errgroupのゴールーチンでパニックが起きるとプロセスごと終了する
+	// safego.Groupはパニックをエラーに変換してWaitから返す
+	sg, sctx := safego.WithContext(context.Background())
+	sg.Go(sctx, func(ctx context.Context) error {
+		var m map[string]int
+		m["a"] = 1 // パニック
+		return nil
+	}, safego.WithLabels("job", "import"))
+	if err := sg.Wait(); err != nil {
+		fmt.Println(err) // safego: panic in [job=import]: assignment to entry in nil map
+	}
+
 	// ** 1度しか実行しない関数・・sync.Onceを使う
 	// 1回以上Doメソッドを呼んでも意味がない
 	// 複数のゴールーチンから1回しか呼ばないようにするために利用する
@@ -320,6 +359,15 @@ func main() {
 	once.Do(f) //2回目は実行されない
 	fmt.Println("done")
 
+	// ** syncパッケージで足りない場合 */ ・・syncxパッケージを使う
+	// - syncx.KeyedMutex: レコードIDごとにロックを取る。使われなくなったキーは解放される
+	// - syncx.Semaphore: 重み付きのセマフォ。Acquire(ctx, n)はctxでキャンセルできる
+	// - syncx.Group: 同じキーの同時呼び出しを1回にまとめて結果を共有する（singleflight）
+	// - syncx.Lazy / syncx.OnceValue: sync.Onceと違い、初期化に失敗したら次回に再実行する
+	var km syncx.KeyedMutex[int64]
+	km.Lock(1) // ID=1のレコードだけをロック
+	km.Unlock(1)
+
 	// ** ゴルーチンとチャネルを深く理解する
 	// ** ゴルーチンのスケジューラの挙動・・ゴルーチンが切り替わるタイミング
 	// - チャネルへの読み書き
@@ -335,7 +383,7 @@ func main() {
 	// runtime.GOMAXPROCSで設定が可能
 	// 環境変数のGOMAXPROCSでも設定ができる
 	// runtime.NumCPUで論理CPUの数が返ってくる
-	fmt.Println(runtime.NumCPU) //0x1006600
+	fmt.Println(runtime.NumCPU()) // 8
 	// デフォルトはruntime.NumCPUの数
 	// - 並列度が1の場合
 	// 並列に動かないだけでうまく使えば有効
@@ -396,30 +444,25 @@ func main() {
 	// WithValueで値を持たせる
 	// 例：キャッシュを充てない
 
-}
+	// ** 定期実行 */ ・・schedulerパッケージを使う
+	// cron式（秒・タイムゾーン指定あり）や一定間隔でジョブを実行する
+	// 前回の実行が終わっていない場合の振る舞いをジョブごとに選べる（skip / queue / cancel-previous）
+	// Stopは実行中のジョブの終了を待つ。ctxが終わったらジョブをキャンセルする
+	// s := scheduler.New()
+	// s.Add("export", scheduler.MustParseCron("CRON_TZ=Asia/Tokyo 0 0 3 * * *"), export,
+	// 	scheduler.WithOverlap(scheduler.OverlapSkip))
+	// s.Start()
+	// defer s.Stop(ctx)
 
-// ** チャネルを引数や戻り値にする
-func makeCh() chan int {
-	return make(chan int)
-}
-func recvCh(recv chan int) int {
-	return <-recv
 }
 
-// ** 双方向チャネル */
-func makeCh2() chan int {
-	return make(chan int)
-}
-func recvCh2(recv chan int) int {
-	go func() { recv <- 200 }() //間違った使い方ができる
-	return <-recv
+// ** チャネルを引数や戻り値にする
+func makeCh[T any]() chan T {
+	return make(chan T)
 }
 
 // ** 単方向チャネル */
-func makeCh3() chan int {
-	return make(chan int)
-}
-func recvCh3(recv <-chan int) int { //受信専用のチャネル
+func recvCh[T any](recv <-chan T) T { //受信専用のチャネル
 	return <-recv
 }
 
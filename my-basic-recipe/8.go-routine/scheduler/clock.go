@@ -0,0 +1,127 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock は現在時刻とタイマーを提供する
+// テストではFakeClockに差し替えて時間を進める
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer はtime.Timerを抽象化したもの
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// RealClock はtimeパッケージをそのまま使うClock
+type RealClock struct{}
+
+func (RealClock) Now() time.Time { return time.Now() }
+
+func (RealClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.t.C }
+func (t realTimer) Stop() bool          { return t.t.Stop() }
+
+// FakeClock はAdvanceを呼んだときだけ進むClock
+// ゼロ値では使えないのでNewFakeClockで作る
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	changed chan struct{} // タイマーの増減があるとcloseされる
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now, changed: make(chan struct{})}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1), at: c.now.Add(d)}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	c.notify()
+	return t
+}
+
+// Advance は時刻をdだけ進め、期限が来たタイマーを発火させる
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	rest := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			rest = append(rest, t)
+			continue
+		}
+		t.c <- c.now
+	}
+	for i := len(rest); i < len(c.timers); i++ {
+		c.timers[i] = nil
+	}
+	c.timers = rest
+	c.notify()
+}
+
+// BlockUntil は待機中のタイマーがn個になるまでブロックする
+// ゴールーチンが次のタイマーを仕掛けるのを待つために使う
+func (c *FakeClock) BlockUntil(n int) {
+	for {
+		c.mu.Lock()
+		if len(c.timers) == n {
+			c.mu.Unlock()
+			return
+		}
+		ch := c.changed
+		c.mu.Unlock()
+		<-ch
+	}
+}
+
+func (c *FakeClock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	c     chan time.Time
+	at    time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, ft := range c.timers {
+		if ft == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.notify()
+			return true
+		}
+	}
+	return false
+}
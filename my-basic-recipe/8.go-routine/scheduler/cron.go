@@ -0,0 +1,285 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule はcron式で表されたスケジュール
+// 秒 分 時 日 月 曜日 の6フィールド（秒を省略した5フィールドも可）
+type CronSchedule struct {
+	Second, Minute, Hour, Dom, Month, Dow uint64
+	// Location はcron式を評価するタイムゾーン
+	Location *time.Location
+
+	domStar, dowStar bool
+}
+
+// cronの各フィールドの範囲
+type bounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	seconds = bounds{0, 59, nil}
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	doms    = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dows = bounds{0, 7, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// ParseCron はcron式をパースしてScheduleを返す
+// 先頭に CRON_TZ=Asia/Tokyo や TZ=UTC をつけるとタイムゾーンを指定できる
+// @daily などの記述子と @every 1h30m も使える
+func ParseCron(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	loc := time.Local
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		i := strings.IndexAny(spec, " \t")
+		if i < 0 {
+			return nil, fmt.Errorf("cron %q: missing fields after timezone", spec)
+		}
+		name := spec[strings.Index(spec, "=")+1 : i]
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %w", spec, err)
+		}
+		loc = l
+		spec = strings.TrimSpace(spec[i:])
+	}
+
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(spec[len("@every "):]))
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %w", spec, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("cron %q: interval must be positive", spec)
+		}
+		return Interval{Every: d}, nil
+	}
+	if d, ok := descriptors[spec]; ok {
+		spec = d
+	} else if strings.HasPrefix(spec, "@") {
+		return nil, fmt.Errorf("cron %q: unknown descriptor", spec)
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron %q: expected 5 or 6 fields, found %d", spec, len(fields))
+	}
+
+	s := &CronSchedule{Location: loc}
+	var star bool
+	for i, f := range []struct {
+		bits *uint64
+		star *bool
+		b    bounds
+	}{
+		{&s.Second, &star, seconds},
+		{&s.Minute, &star, minutes},
+		{&s.Hour, &star, hours},
+		{&s.Dom, &s.domStar, doms},
+		{&s.Month, &star, months},
+		{&s.Dow, &s.dowStar, dows},
+	} {
+		var err error
+		if *f.bits, *f.star, err = parseField(fields[i], f.b); err != nil {
+			return nil, fmt.Errorf("cron %q: %w", spec, err)
+		}
+	}
+	// 7も日曜日として扱う
+	if s.Dow&(1<<7) != 0 {
+		s.Dow = s.Dow&^(1<<7) | 1
+	}
+	return s, nil
+}
+
+// MustParseCron はParseCronに失敗するとパニックを起こす
+// パッケージ変数の初期化で使う
+func MustParseCron(spec string) Schedule {
+	s, err := ParseCron(spec)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// parseField は 1,2,5-10,*/15 のようなフィールドをビット列にする
+func parseField(field string, b bounds) (bits uint64, star bool, err error) {
+	for _, part := range strings.Split(field, ",") {
+		lo, hi, step := b.min, b.max, uint(1)
+		rng := part
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.ParseUint(part[i+1:], 10, 0)
+			if err != nil || n == 0 {
+				return 0, false, fmt.Errorf("invalid step in %q", part)
+			}
+			step = uint(n)
+			rng = part[:i]
+		}
+		switch {
+		case rng == "*" || rng == "?":
+			if step == 1 {
+				star = true
+			}
+		case strings.Contains(rng, "-"):
+			i := strings.Index(rng, "-")
+			if lo, err = parseValue(rng[:i], b); err != nil {
+				return 0, false, err
+			}
+			if hi, err = parseValue(rng[i+1:], b); err != nil {
+				return 0, false, err
+			}
+		default:
+			if lo, err = parseValue(rng, b); err != nil {
+				return 0, false, err
+			}
+			// 5/10 は5から最大値まで10おき
+			if step == 1 {
+				hi = lo
+			}
+		}
+		if lo > hi {
+			return 0, false, fmt.Errorf("invalid range %q", part)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, star, nil
+}
+
+func parseValue(s string, b bounds) (uint, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 0)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if uint(n) < b.min || uint(n) > b.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", n, b.min, b.max)
+	}
+	return uint(n), nil
+}
+
+// Next はtより後で最初にcron式にマッチする時刻を返す
+// 5年先までに見つからない場合はゼロ値を返す
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	loc := s.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	orig := t.Location()
+	t = t.In(loc)
+
+	// 次の秒に切り上げる
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + 5
+	added := false
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for 1<<uint(t.Month())&s.Month == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		// 夏時間の切り替えで0時がずれた場合の補正
+		if h := t.Hour(); h != 0 {
+			if h > 12 {
+				t = t.Add(time.Duration(24-h) * time.Hour)
+			} else {
+				t = t.Add(-time.Duration(h) * time.Hour)
+			}
+		}
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Hour())&s.Hour == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Minute())&s.Minute == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Second())&s.Second == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t.In(orig)
+}
+
+// dayMatches は日と曜日の条件を判定する
+// どちらも指定されている場合はcronの慣習どおりORになる
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	dom := 1<<uint(t.Day())&s.Dom != 0
+	dow := 1<<uint(t.Weekday())&s.Dow != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,106 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"golang/recipe-golang/scheduler"
+)
+
+func TestParseCron_Next(t *testing.T) {
+	t.Parallel()
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2022, 5, 1, 10, 30, 15, 0, time.UTC) // 日曜日
+	cases := map[string]struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		"every second":  {"TZ=UTC * * * * * *", base, base.Add(time.Second)},
+		"five fields":   {"TZ=UTC */15 * * * *", base, time.Date(2022, 5, 1, 10, 45, 0, 0, time.UTC)},
+		"seconds step":  {"TZ=UTC */20 * * * * *", base, time.Date(2022, 5, 1, 10, 30, 20, 0, time.UTC)},
+		"range":         {"TZ=UTC 0 0 9-17 * * *", base, time.Date(2022, 5, 1, 11, 0, 0, 0, time.UTC)},
+		"list":          {"TZ=UTC 0 0 1,23 * * *", base, time.Date(2022, 5, 1, 23, 0, 0, 0, time.UTC)},
+		"month name":    {"TZ=UTC 0 0 0 1 jan *", base, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		"weekday name":  {"TZ=UTC 0 0 0 * * MON-FRI", base, time.Date(2022, 5, 2, 0, 0, 0, 0, time.UTC)},
+		"sunday as 7":   {"TZ=UTC 0 0 12 * * 7", base, time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)},
+		"dom or dow":    {"TZ=UTC 0 0 0 15 * sat", base, time.Date(2022, 5, 7, 0, 0, 0, 0, time.UTC)},
+		"leap day":      {"TZ=UTC 0 0 0 29 2 *", base, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		"daily":         {"TZ=UTC @daily", base, time.Date(2022, 5, 2, 0, 0, 0, 0, time.UTC)},
+		"hourly":        {"TZ=UTC @hourly", base, time.Date(2022, 5, 1, 11, 0, 0, 0, time.UTC)},
+		"every":         {"@every 90m", base, base.Add(90 * time.Minute)},
+		"tokyo 3am":     {"CRON_TZ=Asia/Tokyo 0 0 3 * * *", base, time.Date(2022, 5, 2, 3, 0, 0, 0, tokyo)},
+		"never matches": {"TZ=UTC 0 0 0 31 2 *", base, time.Time{}},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s, err := scheduler.ParseCron(tt.spec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := s.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseCron_DST(t *testing.T) {
+	t.Parallel()
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	s := scheduler.MustParseCron("CRON_TZ=America/New_York 0 30 2 * * *")
+	// 2022-03-13 は2:00〜3:00が存在しないので翌日になる
+	from := time.Date(2022, 3, 12, 12, 0, 0, 0, ny)
+	want := time.Date(2022, 3, 14, 2, 30, 0, 0, ny)
+	if got := s.Next(from); !got.Equal(want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestParseCron_Error(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"too few fields": "* * *",
+		"out of range":   "60 * * * * *",
+		"bad step":       "*/0 * * * * *",
+		"bad range":      "0 0 10-5 * * *",
+		"bad name":       "0 0 0 * foo *",
+		"bad descriptor": "@sometimes",
+		"bad timezone":   "CRON_TZ=Mars/Olympus * * * * *",
+		"bad every":      "@every soon",
+	}
+	for name, spec := range cases {
+		spec := spec
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := scheduler.ParseCron(spec); err == nil {
+				t.Errorf("want error for %q", spec)
+			}
+		})
+	}
+}
+
+func TestInterval_Jitter(t *testing.T) {
+	t.Parallel()
+	s := scheduler.Interval{
+		Every:  time.Minute,
+		Jitter: 10 * time.Second,
+		Rand:   func(n int64) int64 { return n - 1 },
+	}
+	base := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	want := base.Add(time.Minute + 10*time.Second - 1)
+	if got := s.Next(base); !got.Equal(want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if got := (scheduler.Interval{}).Next(base); !got.IsZero() {
+		t.Errorf("want zero time for no interval, got %v", got)
+	}
+}
@@ -0,0 +1,43 @@
+package scheduler
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Schedule は次の実行時刻を決める
+// ゼロ値のtime.Timeを返すとそれ以降は実行されない
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// ScheduleFunc は関数をScheduleとして扱う
+type ScheduleFunc func(t time.Time) time.Time
+
+func (f ScheduleFunc) Next(t time.Time) time.Time { return f(t) }
+
+// Interval は一定間隔で実行するスケジュール
+// Jitterを指定すると0からJitterまでのランダムな遅延が加わる
+// 複数のプロセスで同じジョブが同時に走るのを避けるのに使う
+type Interval struct {
+	Every  time.Duration
+	Jitter time.Duration
+	// Rand は[0, n)の乱数を返す。nilのときはmath/randを使う
+	Rand func(n int64) int64
+}
+
+// Next はtのEvery後を返す。Everyが0以下のときは実行し続けないようにゼロ値を返す
+func (s Interval) Next(t time.Time) time.Time {
+	if s.Every <= 0 {
+		return time.Time{}
+	}
+	next := t.Add(s.Every)
+	if s.Jitter > 0 {
+		rnd := s.Rand
+		if rnd == nil {
+			rnd = rand.Int63n
+		}
+		next = next.Add(time.Duration(rnd(int64(s.Jitter))))
+	}
+	return next
+}
@@ -0,0 +1,367 @@
+// Package scheduler はcron式や一定間隔でジョブを定期実行する
+//
+//	s := scheduler.New()
+//	s.Add("export", scheduler.MustParseCron("CRON_TZ=Asia/Tokyo 0 0 3 * * *"), export)
+//	s.Start()
+//	defer s.Stop(ctx)
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrDuplicateJob = errors.New("scheduler: duplicate job name")
+	ErrUnknownJob   = errors.New("scheduler: unknown job")
+	ErrStopped      = errors.New("scheduler: stopped")
+	ErrBadSchedule  = errors.New("scheduler: bad schedule")
+)
+
+// JobFunc はスケジューラから呼ばれる処理
+// ctxはStopのタイムアウトやOverlapCancelPreviousでキャンセルされる
+type JobFunc func(ctx context.Context) error
+
+// OverlapPolicy は前回の実行が終わっていないときの振る舞い
+type OverlapPolicy int
+
+const (
+	// OverlapSkip は今回の実行を飛ばす
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue は前回の実行が終わってから実行する
+	OverlapQueue
+	// OverlapCancelPrevious は前回の実行をキャンセルしてから実行する
+	OverlapCancelPrevious
+)
+
+func (p OverlapPolicy) String() string {
+	switch p {
+	case OverlapSkip:
+		return "skip"
+	case OverlapQueue:
+		return "queue"
+	case OverlapCancelPrevious:
+		return "cancel-previous"
+	}
+	return fmt.Sprintf("OverlapPolicy(%d)", int(p))
+}
+
+// RunStatus はジョブの実行結果
+type RunStatus int
+
+const (
+	StatusSucceeded RunStatus = iota
+	StatusFailed
+	StatusSkipped
+	StatusCanceled
+)
+
+func (s RunStatus) String() string {
+	switch s {
+	case StatusSucceeded:
+		return "succeeded"
+	case StatusFailed:
+		return "failed"
+	case StatusSkipped:
+		return "skipped"
+	case StatusCanceled:
+		return "canceled"
+	}
+	return fmt.Sprintf("RunStatus(%d)", int(s))
+}
+
+// Run は1回分の実行履歴
+type Run struct {
+	Job       string
+	Scheduled time.Time // 本来の実行予定時刻
+	Started   time.Time
+	Finished  time.Time
+	Status    RunStatus
+	Err       error
+}
+
+// Option はSchedulerの設定
+type Option func(*Scheduler)
+
+// WithClock はClockを差し替える
+func WithClock(c Clock) Option {
+	return func(s *Scheduler) { s.clock = c }
+}
+
+// WithHistoryLimit はジョブごとに保持する履歴の件数を指定する
+func WithHistoryLimit(n int) Option {
+	return func(s *Scheduler) { s.historyLimit = n }
+}
+
+// JobOption はジョブごとの設定
+type JobOption func(*job)
+
+// WithOverlap は実行が重なったときの振る舞いを指定する
+// デフォルトはOverlapSkip
+func WithOverlap(p OverlapPolicy) JobOption {
+	return func(j *job) { j.overlap = p }
+}
+
+// Scheduler は登録されたジョブをスケジュールどおりに実行する
+type Scheduler struct {
+	clock        Clock
+	historyLimit int
+
+	mu         sync.Mutex
+	jobs       map[string]*job
+	started    bool
+	stopped    bool
+	loopCtx    context.Context // Stopが呼ばれるとキャンセル
+	stopLoops  context.CancelFunc
+	runCtx     context.Context // Stopがタイムアウトするとキャンセル
+	cancelRuns context.CancelFunc
+
+	loops sync.WaitGroup
+	runs  sync.WaitGroup
+}
+
+// New はSchedulerを作る
+func New(opts ...Option) *Scheduler {
+	s := &Scheduler{
+		clock:        RealClock{},
+		historyLimit: 100,
+		jobs:         map[string]*job{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.loopCtx, s.stopLoops = context.WithCancel(context.Background())
+	s.runCtx, s.cancelRuns = context.WithCancel(context.Background())
+	return s
+}
+
+// Add はジョブを登録する
+// Start後に登録した場合はすぐにスケジュールされる
+func (s *Scheduler) Add(name string, sched Schedule, fn JobFunc, opts ...JobOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return ErrStopped
+	}
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("%w: %q", ErrDuplicateJob, name)
+	}
+	if iv, ok := sched.(Interval); ok && iv.Every <= 0 {
+		return fmt.Errorf("%w: %q: interval %v must be positive", ErrBadSchedule, name, iv.Every)
+	}
+	j := &job{s: s, name: name, sched: sched, fn: fn}
+	for _, opt := range opts {
+		opt(j)
+	}
+	s.jobs[name] = j
+	if s.started {
+		s.startLoop(j)
+	}
+	return nil
+}
+
+// Remove はジョブの登録を解除する
+// 実行中の処理はそのまま最後まで実行される
+func (s *Scheduler) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownJob, name)
+	}
+	delete(s.jobs, name)
+	if j.stopLoop != nil {
+		j.stopLoop()
+	}
+	return nil
+}
+
+// Start はジョブのスケジュールを開始する
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started || s.stopped {
+		return
+	}
+	s.started = true
+	for _, j := range s.jobs {
+		s.startLoop(j)
+	}
+}
+
+// Stop は新しい実行を止め、実行中のジョブの終了を待つ
+// ctxが先に終わった場合は実行中のジョブをキャンセルし、終了を待ってctx.Err()を返す
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return nil
+	}
+	s.stopped = true
+	s.stopLoops()
+	s.mu.Unlock()
+
+	s.loops.Wait()
+	done := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.cancelRuns()
+		return nil
+	case <-ctx.Done():
+		s.cancelRuns()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// History はジョブの実行履歴を古い順に返す
+func (s *Scheduler) History(name string) []Run {
+	s.mu.Lock()
+	j, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]Run(nil), j.history...)
+}
+
+// startLoop はs.muを保持した状態で呼ぶ
+func (s *Scheduler) startLoop(j *job) {
+	ctx, cancel := context.WithCancel(s.loopCtx)
+	j.stopLoop = cancel
+	s.loops.Add(1)
+	go func() {
+		defer s.loops.Done()
+		j.loop(ctx)
+	}()
+}
+
+type job struct {
+	s        *Scheduler
+	name     string
+	sched    Schedule
+	fn       JobFunc
+	overlap  OverlapPolicy
+	stopLoop context.CancelFunc
+
+	mu        sync.Mutex
+	running   bool
+	cancelRun context.CancelFunc
+	done      chan struct{} // 実行中の処理が終わるとcloseされる
+	queue     []time.Time
+	history   []Run
+}
+
+func (j *job) loop(ctx context.Context) {
+	clock := j.s.clock
+	next := j.sched.Next(clock.Now())
+	for !next.IsZero() {
+		timer := clock.NewTimer(next.Sub(clock.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		j.trigger(next)
+		next = j.sched.Next(clock.Now())
+	}
+}
+
+// trigger は予定時刻scheduledの実行を開始する
+func (j *job) trigger(scheduled time.Time) {
+	j.mu.Lock()
+	if j.running {
+		switch j.overlap {
+		case OverlapQueue:
+			j.queue = append(j.queue, scheduled)
+			j.mu.Unlock()
+			return
+		case OverlapCancelPrevious:
+			cancel, done := j.cancelRun, j.done
+			j.mu.Unlock()
+			cancel()
+			<-done
+			j.mu.Lock()
+		default:
+			now := j.s.clock.Now()
+			j.record(Run{Scheduled: scheduled, Started: now, Finished: now, Status: StatusSkipped})
+			j.mu.Unlock()
+			return
+		}
+	}
+	j.running = true
+	j.done = make(chan struct{})
+	// goroutineが動き出す前に次のtriggerが来ても取り消せるように、ここでcontextを作る
+	ctx := j.newRunContext()
+	j.s.runs.Add(1)
+	j.mu.Unlock()
+
+	go func() {
+		defer j.s.runs.Done()
+		j.run(ctx, scheduled)
+	}()
+}
+
+// newRunContext は1回の実行のcontextを作り、j.cancelRunに設定する。j.muを保持した状態で呼ぶ
+func (j *job) newRunContext() context.Context {
+	ctx, cancel := context.WithCancel(j.s.runCtx)
+	j.cancelRun = cancel
+	return ctx
+}
+
+// run はキューが空になるまで順に実行する
+func (j *job) run(ctx context.Context, scheduled time.Time) {
+	for {
+		r := Run{Scheduled: scheduled, Started: j.s.clock.Now()}
+		r.Err = j.fn(ctx)
+		r.Finished = j.s.clock.Now()
+		switch {
+		case r.Err == nil:
+			r.Status = StatusSucceeded
+		case ctx.Err() != nil && errors.Is(r.Err, ctx.Err()):
+			r.Status = StatusCanceled
+		default:
+			r.Status = StatusFailed
+		}
+
+		j.mu.Lock()
+		j.cancelRun()
+		j.record(r)
+		if len(j.queue) > 0 && j.s.loopCtx.Err() == nil {
+			scheduled = j.queue[0]
+			j.queue = j.queue[1:]
+			ctx = j.newRunContext()
+			j.mu.Unlock()
+			continue
+		}
+		// 停止中であれば待っている実行は捨てる
+		for _, t := range j.queue {
+			now := j.s.clock.Now()
+			j.record(Run{Scheduled: t, Started: now, Finished: now, Status: StatusCanceled, Err: ErrStopped})
+		}
+		j.queue = nil
+		j.running = false
+		close(j.done)
+		j.mu.Unlock()
+		return
+	}
+}
+
+// record はj.muを保持した状態で呼ぶ
+func (j *job) record(r Run) {
+	r.Job = j.name
+	j.history = append(j.history, r)
+	if n := j.s.historyLimit; n > 0 && len(j.history) > n {
+		j.history = append(j.history[:0], j.history[len(j.history)-n:]...)
+	}
+}
@@ -0,0 +1,282 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang/recipe-golang/scheduler"
+)
+
+var epoch = time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+
+// newTestScheduler はFakeClockを使うSchedulerを作る
+func newTestScheduler(t *testing.T) (*scheduler.Scheduler, *scheduler.FakeClock) {
+	t.Helper()
+	clock := scheduler.NewFakeClock(epoch)
+	s := scheduler.New(scheduler.WithClock(clock))
+	t.Cleanup(func() { s.Stop(context.Background()) })
+	return s, clock
+}
+
+func statuses(runs []scheduler.Run) []scheduler.RunStatus {
+	ss := make([]scheduler.RunStatus, len(runs))
+	for i, r := range runs {
+		ss[i] = r.Status
+	}
+	return ss
+}
+
+func equalStatuses(a, b []scheduler.RunStatus) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestScheduler_Interval(t *testing.T) {
+	t.Parallel()
+	s, clock := newTestScheduler(t)
+	ran := make(chan time.Time)
+	err := s.Add("tick", scheduler.Interval{Every: time.Minute}, func(ctx context.Context) error {
+		ran <- clock.Now()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	for i := 1; i <= 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		want := epoch.Add(time.Duration(i) * time.Minute)
+		if got := <-ran; !got.Equal(want) {
+			t.Errorf("run %d: want %v, got %v", i, want, got)
+		}
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	h := s.History("tick")
+	if len(h) != 3 {
+		t.Fatalf("want 3 runs, got %d", len(h))
+	}
+	if !h[2].Scheduled.Equal(epoch.Add(3 * time.Minute)) {
+		t.Errorf("want scheduled %v, got %v", epoch.Add(3*time.Minute), h[2].Scheduled)
+	}
+}
+
+func TestScheduler_Overlap(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		policy scheduler.OverlapPolicy
+		want   []scheduler.RunStatus
+	}{
+		"skip":   {scheduler.OverlapSkip, []scheduler.RunStatus{scheduler.StatusSkipped, scheduler.StatusSucceeded}},
+		"queue":  {scheduler.OverlapQueue, []scheduler.RunStatus{scheduler.StatusSucceeded, scheduler.StatusSucceeded}},
+		"cancel": {scheduler.OverlapCancelPrevious, []scheduler.RunStatus{scheduler.StatusCanceled, scheduler.StatusSucceeded}},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s, clock := newTestScheduler(t)
+			started := make(chan struct{}, 2)
+			release := make(chan struct{})
+			calls := 0
+			job := func(ctx context.Context) error {
+				calls++
+				started <- struct{}{}
+				if calls > 1 {
+					return nil
+				}
+				select {
+				case <-release:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if err := s.Add("job", scheduler.Interval{Every: time.Minute}, job, scheduler.WithOverlap(tt.policy)); err != nil {
+				t.Fatal(err)
+			}
+			s.Start()
+			clock.BlockUntil(1)
+			clock.Advance(time.Minute)
+			<-started
+			clock.BlockUntil(1)
+			clock.Advance(time.Minute)
+			switch tt.policy {
+			case scheduler.OverlapCancelPrevious:
+				<-started
+			case scheduler.OverlapQueue:
+				clock.BlockUntil(1)
+				close(release)
+				<-started
+			default:
+				clock.BlockUntil(1)
+				close(release)
+			}
+			if err := s.Stop(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := statuses(s.History("job")); !equalStatuses(got, tt.want) {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestScheduler_CancelPreviousBeforeStart は前の実行のgoroutineが動き出す前に次の予定が来ても
+// 前の実行が取り消されることを確かめる
+func TestScheduler_CancelPreviousBeforeStart(t *testing.T) {
+	t.Parallel()
+	s, clock := newTestScheduler(t)
+	calls := 0
+	job := func(ctx context.Context) error {
+		calls++
+		if calls > 1 {
+			return nil
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if err := s.Add("job", scheduler.Interval{Every: time.Minute}, job, scheduler.WithOverlap(scheduler.OverlapCancelPrevious)); err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	// 1回目の実行を待たずに次の予定を進める
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+	}
+	clock.BlockUntil(1)
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []scheduler.RunStatus{scheduler.StatusCanceled, scheduler.StatusSucceeded}
+	if got := statuses(s.History("job")); !equalStatuses(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestScheduler_StopWaitsForRunningJob(t *testing.T) {
+	t.Parallel()
+	s, clock := newTestScheduler(t)
+	started := make(chan struct{})
+	finished := false
+	s.Add("slow", scheduler.Interval{Every: time.Second}, func(ctx context.Context) error {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		finished = true
+		return nil
+	})
+	s.Start()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	<-started
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !finished {
+		t.Error("Stop returned before the job finished")
+	}
+}
+
+func TestScheduler_StopTimeout(t *testing.T) {
+	t.Parallel()
+	s, clock := newTestScheduler(t)
+	started := make(chan struct{})
+	s.Add("stuck", scheduler.Interval{Every: time.Second}, func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	s.Start()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want %v, got %v", context.DeadlineExceeded, err)
+	}
+	h := s.History("stuck")
+	if len(h) != 1 || h[0].Status != scheduler.StatusCanceled {
+		t.Errorf("want one canceled run, got %+v", h)
+	}
+}
+
+func TestScheduler_FailedRun(t *testing.T) {
+	t.Parallel()
+	s, clock := newTestScheduler(t)
+	boom := errors.New("boom")
+	done := make(chan struct{})
+	s.Add("fail", scheduler.Interval{Every: time.Second}, func(ctx context.Context) error {
+		defer close(done)
+		return boom
+	})
+	s.Start()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	<-done
+	s.Stop(context.Background())
+	h := s.History("fail")
+	if len(h) != 1 || h[0].Status != scheduler.StatusFailed || !errors.Is(h[0].Err, boom) {
+		t.Errorf("want one failed run with %v, got %+v", boom, h)
+	}
+}
+
+func TestScheduler_Add(t *testing.T) {
+	t.Parallel()
+	s, _ := newTestScheduler(t)
+	nop := func(ctx context.Context) error { return nil }
+	if err := s.Add("a", scheduler.Interval{Every: time.Second}, nop); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("a", scheduler.Interval{Every: time.Second}, nop); !errors.Is(err, scheduler.ErrDuplicateJob) {
+		t.Errorf("want %v, got %v", scheduler.ErrDuplicateJob, err)
+	}
+	if err := s.Add("b", scheduler.Interval{}, nop); !errors.Is(err, scheduler.ErrBadSchedule) {
+		t.Errorf("want %v, got %v", scheduler.ErrBadSchedule, err)
+	}
+	if err := s.Remove("b"); !errors.Is(err, scheduler.ErrUnknownJob) {
+		t.Errorf("want %v, got %v", scheduler.ErrUnknownJob, err)
+	}
+	s.Stop(context.Background())
+	if err := s.Add("c", scheduler.Interval{Every: time.Second}, nop); !errors.Is(err, scheduler.ErrStopped) {
+		t.Errorf("want %v, got %v", scheduler.ErrStopped, err)
+	}
+}
+
+func TestScheduler_HistoryLimit(t *testing.T) {
+	t.Parallel()
+	clock := scheduler.NewFakeClock(epoch)
+	s := scheduler.New(scheduler.WithClock(clock), scheduler.WithHistoryLimit(2))
+	ran := make(chan struct{})
+	s.Add("job", scheduler.Interval{Every: time.Second}, func(ctx context.Context) error {
+		ran <- struct{}{}
+		return nil
+	})
+	s.Start()
+	for i := 0; i < 5; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+		<-ran
+	}
+	s.Stop(context.Background())
+	h := s.History("job")
+	if len(h) != 2 {
+		t.Fatalf("want 2 runs, got %d", len(h))
+	}
+	if want := epoch.Add(5 * time.Second); !h[1].Scheduled.Equal(want) {
+		t.Errorf("want %v, got %v", want, h[1].Scheduled)
+	}
+}
@@ -0,0 +1,75 @@
+// Package syncx はsyncパッケージだけでは足りないロックや同期の仕組みを提供する
+package syncx
+
+import "sync"
+
+// KeyedMutex はキーごとに独立したロックを提供する
+// レコードIDごとにロックを取りたい場合に使う
+// 誰もロックしていないキーはマップから削除されるのでキーが増え続けてもメモリを使い続けない
+// ゼロ値で使える
+type KeyedMutex[K comparable] struct {
+	mu    sync.Mutex
+	locks map[K]*keyedLock
+}
+
+type keyedLock struct {
+	mu   sync.Mutex
+	refs int // ロック中とロック待ちの数
+}
+
+// Lock はkeyのロックを取る
+// 同じキーのロックが取られている場合はUnlockされるまでブロックする
+func (m *KeyedMutex[K]) Lock(key K) {
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = map[K]*keyedLock{}
+	}
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	l.mu.Lock()
+}
+
+// TryLock はブロックせずにロックを試みる
+func (m *KeyedMutex[K]) TryLock(key K) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.locks == nil {
+		m.locks = map[K]*keyedLock{}
+	}
+	if _, ok := m.locks[key]; ok {
+		return false
+	}
+	l := &keyedLock{refs: 1}
+	l.mu.Lock()
+	m.locks[key] = l
+	return true
+}
+
+// Unlock はkeyのロックを解放する
+// ロックされていないキーをUnlockするとパニックを起こす
+func (m *KeyedMutex[K]) Unlock(key K) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, ok := m.locks[key]
+	if !ok {
+		panic("syncx: unlock of unlocked key")
+	}
+	l.refs--
+	if l.refs == 0 {
+		delete(m.locks, key)
+	}
+	l.mu.Unlock()
+}
+
+// Len は使用中のキーの数を返す
+func (m *KeyedMutex[K]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.locks)
+}
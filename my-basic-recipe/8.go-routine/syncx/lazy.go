@@ -0,0 +1,48 @@
+package syncx
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Lazy は初めて必要になったときに値を初期化する
+// sync.OnceValueと違い、初期化がエラーになった場合は結果を保持せず次のGetで再実行する
+// 初期化は同時に1つしか実行されない
+type Lazy[T any] struct {
+	init func() (T, error)
+	done atomic.Bool
+	mu   sync.Mutex
+	val  T
+}
+
+// NewLazy はinitで初期化するLazyを作る
+func NewLazy[T any](init func() (T, error)) *Lazy[T] {
+	return &Lazy[T]{init: init}
+}
+
+// Get は初期化済みの値を返す
+// まだ初期化に成功していなければinitを呼ぶ
+func (l *Lazy[T]) Get() (T, error) {
+	if l.done.Load() {
+		return l.val, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done.Load() {
+		return l.val, nil
+	}
+	v, err := l.init()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	l.val = v
+	l.done.Store(true)
+	return v, nil
+}
+
+// OnceValue はLazyを関数として返す
+// sync.OnceValueのエラーでリトライする版
+func OnceValue[T any](init func() (T, error)) func() (T, error) {
+	return NewLazy(init).Get
+}
@@ -0,0 +1,88 @@
+package syncx
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Group は同じキーに対する同時の呼び出しを1回にまとめる（singleflight）
+// キャッシュが切れた瞬間に同じデータを何度も読み込むのを防ぐ
+// ゼロ値で使える
+type Group[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*call[V]
+}
+
+type call[V any] struct {
+	wg    sync.WaitGroup
+	val   V
+	err   error
+	dups  int
+	panic any
+}
+
+// PanicError はDoに渡した関数がパニックを起こしたときに待っていた呼び出し元に返すエラー
+type PanicError struct {
+	Value any
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("syncx: function panicked: %v", e.Value)
+}
+
+// Do はkeyに対してfnを実行して結果を返す
+// 同じキーで実行中の呼び出しがあれば、その終了を待って同じ結果を返す
+// sharedは結果が他の呼び出し元と共有されたかどうか
+func (g *Group[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[K]*call[V]{}
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		c.wg.Wait()
+		if c.panic != nil {
+			return v, &PanicError{c.panic}, true
+		}
+		return c.val, c.err, true
+	}
+	c := &call[V]{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	g.doCall(c, key, fn)
+	g.mu.Lock()
+	shared = c.dups > 0
+	g.mu.Unlock()
+	return c.val, c.err, shared
+}
+
+func (g *Group[K, V]) doCall(c *call[V], key K, fn func() (V, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.panic = r
+		}
+		g.mu.Lock()
+		// Forgetで別の呼び出しに置き換わっている場合は消さない
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+		c.wg.Done()
+		// 待っている呼び出し元にはPanicErrorを返し、実行した呼び出し元ではパニックを続ける
+		if c.panic != nil {
+			panic(c.panic)
+		}
+	}()
+	c.val, c.err = fn()
+}
+
+// Forget はkeyの実行中の呼び出しを忘れる
+// 以降のDoは実行中の呼び出しを待たずに新しくfnを実行する
+func (g *Group[K, V]) Forget(key K) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.calls, key)
+}
@@ -0,0 +1,255 @@
+package syncx_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang/recipe-golang/syncx"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	t.Parallel()
+	var m syncx.KeyedMutex[int]
+	var wg sync.WaitGroup
+	counts := make([]int, 3) // キーごとのカウンタ（同じキーのロック内でだけ触る）
+	for i := 0; i < 300; i++ {
+		wg.Add(1)
+		go func(key int) {
+			defer wg.Done()
+			m.Lock(key)
+			defer m.Unlock(key)
+			v := counts[key]
+			time.Sleep(time.Microsecond)
+			counts[key] = v + 1
+		}(i % 3)
+	}
+	wg.Wait()
+	for key, n := range counts {
+		if n != 100 {
+			t.Errorf("key %d: want 100, got %d", key, n)
+		}
+	}
+	if n := m.Len(); n != 0 {
+		t.Errorf("want unused keys to be freed, got %d keys", n)
+	}
+}
+
+func TestKeyedMutex_TryLock(t *testing.T) {
+	t.Parallel()
+	var m syncx.KeyedMutex[string]
+	if !m.TryLock("a") {
+		t.Fatal("want TryLock to succeed")
+	}
+	if m.TryLock("a") {
+		t.Error("want TryLock on a locked key to fail")
+	}
+	if !m.TryLock("b") {
+		t.Error("want TryLock on another key to succeed")
+	}
+	m.Unlock("a")
+	m.Unlock("b")
+	if n := m.Len(); n != 0 {
+		t.Errorf("want 0 keys, got %d", n)
+	}
+}
+
+func TestSemaphore_Weighted(t *testing.T) {
+	t.Parallel()
+	s := syncx.NewSemaphore(3)
+	ctx := context.Background()
+	var cur, max int64
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		n := int64(i%3 + 1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Acquire(ctx, n); err != nil {
+				t.Error(err)
+				return
+			}
+			defer s.Release(n)
+			c := atomic.AddInt64(&cur, n)
+			for {
+				m := atomic.LoadInt64(&max)
+				if c <= m || atomic.CompareAndSwapInt64(&max, m, c) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt64(&cur, -n)
+		}()
+	}
+	wg.Wait()
+	if max > 3 {
+		t.Errorf("want at most 3 in use, got %d", max)
+	}
+	if !s.TryAcquire(3) {
+		t.Error("want all weight to be released")
+	}
+}
+
+func TestSemaphore_Cancel(t *testing.T) {
+	t.Parallel()
+	s := syncx.NewSemaphore(2)
+	if !s.TryAcquire(2) {
+		t.Fatal("want TryAcquire to succeed")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want %v, got %v", context.DeadlineExceeded, err)
+	}
+	if err := s.Acquire(context.Background(), 3); !errors.Is(err, syncx.ErrTooLarge) {
+		t.Errorf("want %v, got %v", syncx.ErrTooLarge, err)
+	}
+	s.Release(2)
+	if !s.TryAcquire(2) {
+		t.Error("want canceled acquire to leave nothing held")
+	}
+}
+
+func TestSemaphore_FIFO(t *testing.T) {
+	t.Parallel()
+	s := syncx.NewSemaphore(2)
+	s.TryAcquire(1)
+	// 重み2の待ちがいる間は重み1の要求も後ろに並ぶ
+	big := make(chan struct{})
+	go func() {
+		s.Acquire(context.Background(), 2)
+		close(big)
+	}()
+	for s.TryAcquire(0) {
+		time.Sleep(time.Millisecond)
+	}
+	if s.TryAcquire(1) {
+		t.Fatal("want TryAcquire to respect the waiting acquirer")
+	}
+	s.Release(1)
+	<-big
+	s.Release(2)
+}
+
+func TestGroup_Do(t *testing.T) {
+	t.Parallel()
+	var g syncx.Group[string, int]
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return 42, nil
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	var sharedCount int32
+	started := make(chan struct{}, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started <- struct{}{}
+			v, err, shared := g.Do("user:1", fn)
+			if v != 42 || err != nil {
+				t.Errorf("want 42, <nil>, got %d, %v", v, err)
+			}
+			if shared {
+				atomic.AddInt32(&sharedCount, 1)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		<-started
+	}
+	// 全員がDoの中で待つまで少し待つ
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("want 1 call, got %d", calls)
+	}
+	if sharedCount != n {
+		t.Errorf("want all %d callers to share the result, got %d", n, sharedCount)
+	}
+}
+
+func TestGroup_Forget(t *testing.T) {
+	t.Parallel()
+	var g syncx.Group[int, string]
+	release := make(chan struct{})
+	go g.Do(1, func() (string, error) {
+		<-release
+		return "old", nil
+	})
+	time.Sleep(10 * time.Millisecond)
+	g.Forget(1)
+	v, _, shared := g.Do(1, func() (string, error) { return "new", nil })
+	close(release)
+	if v != "new" || shared {
+		t.Errorf("want new, false, got %s, %v", v, shared)
+	}
+}
+
+func TestGroup_Panic(t *testing.T) {
+	t.Parallel()
+	var g syncx.Group[int, int]
+	release := make(chan struct{})
+	done := make(chan interface{})
+	go func() {
+		defer func() { done <- recover() }()
+		g.Do(1, func() (int, error) {
+			<-release
+			panic("boom")
+		})
+	}()
+	time.Sleep(10 * time.Millisecond)
+	errc := make(chan error)
+	go func() {
+		_, err, _ := g.Do(1, func() (int, error) { return 0, nil })
+		errc <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if r := <-done; r != "boom" {
+		t.Errorf("want panic boom, got %v", r)
+	}
+	var pe *syncx.PanicError
+	if err := <-errc; !errors.As(err, &pe) {
+		t.Errorf("want PanicError, got %v", err)
+	}
+}
+
+func TestLazy(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	get := syncx.OnceValue(func() (string, error) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return "", errors.New("not ready")
+		}
+		return "ready", nil
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := get(); err == nil {
+			t.Fatalf("call %d: want error", i+1)
+		}
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := get(); v != "ready" || err != nil {
+				t.Errorf("want ready, <nil>, got %q, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 3 {
+		t.Errorf("want 3 calls, got %d", calls)
+	}
+}
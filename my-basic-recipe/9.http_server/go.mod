@@ -0,0 +1,7 @@
+module golang/recipe-golang/9.http_server
+
+go 1.21
+
+require golang/recipe-golang/6.error v0.0.0
+
+replace golang/recipe-golang/6.error => ../6.error
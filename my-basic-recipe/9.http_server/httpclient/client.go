@@ -0,0 +1,151 @@
+// Package httpclient はJSONのAPIを呼び出すHTTPクライアント
+//
+// エラーはすべて*errs.OpErrorで返すので、errors.Asで失敗したメソッドとURLがわかる
+// クエリパラメータはURLから外してParamsに入れるため、tokenなどの秘密の値は%+vで隠される
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang/recipe-golang/6.error/errs"
+)
+
+// StatusError はステータスコードが400以上のレスポンスを表すエラー
+type StatusError struct {
+	Code int
+	Body string // レスポンスボディの先頭
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("http status %d %s", e.Code, http.StatusText(e.Code))
+}
+
+// StatusCode はステータスコードを返す
+// retry.HTTPStatusでリトライするかどうかの判定に使える
+func (e *StatusError) StatusCode() int { return e.Code }
+
+// maxErrorBody はStatusErrorに入れるレスポンスボディの最大バイト数
+const maxErrorBody = 512
+
+// Client はベースURLからの相対パスでAPIを呼び出す
+type Client struct {
+	base *url.URL
+	hc   *http.Client
+}
+
+// Option はClientの設定
+type Option func(*Client)
+
+// WithHTTPClient は使用する*http.Clientを指定する（デフォルトはhttp.DefaultClient）
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.hc = hc }
+}
+
+// New はbaseURLに対するClientを作る
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, errs.Invalid.Op("parse", errs.URL, baseURL, err)
+	}
+	c := &Client{base: u, hc: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// GetJSON はpathにGETリクエストを送り、レスポンスのJSONをvにデコードする
+func (c *Client) GetJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+	return c.do(ctx, http.MethodGet, path, query, nil, v)
+}
+
+// PostJSON はbodyをJSONにしてpathにPOSTし、レスポンスのJSONをvにデコードする
+// vがnilの場合はレスポンスボディを読み捨てる
+func (c *Client) PostJSON(ctx context.Context, path string, body, v interface{}) error {
+	return c.do(ctx, http.MethodPost, path, nil, body, v)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, v interface{}) error {
+	u := c.base.JoinPath(path)
+	u.RawQuery = query.Encode()
+	// エラーのリソースにはクエリとパスワードを含めない
+	resource := (&url.URL{Scheme: u.Scheme, User: u.User, Host: u.Host, Path: u.Path}).Redacted()
+	kv := queryParams(query)
+	fail := func(code errs.Code, err error) error {
+		return code.Op(method, errs.URL, resource, err, kv...)
+	}
+
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fail(errs.Invalid, err)
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), r)
+	if err != nil {
+		return fail(errs.Invalid, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		// *url.Errorはクエリつきのurlを含むので原因だけを使う
+		if ue, ok := err.(*url.Error); ok {
+			err = ue.Err
+		}
+		if ctx.Err() != nil {
+			return fail(errs.Unknown, err)
+		}
+		return fail(errs.Unavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fail(statusCode(resp.StatusCode), &StatusError{Code: resp.StatusCode, Body: strings.TrimSpace(string(b))})
+	}
+	if v == nil {
+		_, err := io.Copy(io.Discard, resp.Body)
+		return fail(errs.Unknown, err)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fail(errs.Unknown, fmt.Errorf("decode response: %w", err))
+	}
+	return nil
+}
+
+// statusCode はHTTPのステータスコードをerrs.Codeにする
+func statusCode(status int) errs.Code {
+	switch status {
+	case http.StatusNotFound:
+		return errs.NotFound
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return errs.Invalid
+	case http.StatusConflict:
+		return errs.Conflict
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return errs.Unavailable
+	}
+	return errs.Unknown
+}
+
+func queryParams(query url.Values) []interface{} {
+	var kv []interface{}
+	for k, vs := range query {
+		v := strings.Join(vs, ",")
+		kv = append(kv, k, v)
+	}
+	return kv
+}
@@ -0,0 +1,127 @@
+package httpclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang/recipe-golang/6.error/errs"
+	"golang/recipe-golang/9.http_server/httpclient"
+)
+
+type msg struct {
+	Msg string `json:"msg"`
+}
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(msg{Msg: "hello " + r.FormValue("name")})
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		var m msg
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(m)
+	})
+	mux.HandleFunc("/busy", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "try again later", http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "{")
+	})
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	c, err := httpclient.New(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	var got msg
+	if err := c.GetJSON(ctx, "/hello", url.Values{"name": {"Gopher"}}, &got); err != nil || got.Msg != "hello Gopher" {
+		t.Errorf("want hello Gopher, got %q, %v", got.Msg, err)
+	}
+	if err := c.PostJSON(ctx, "/echo", msg{Msg: "ping"}, &got); err != nil || got.Msg != "ping" {
+		t.Errorf("want ping, got %q, %v", got.Msg, err)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	c, err := httpclient.New(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]struct {
+		path   string
+		code   errs.Code
+		status int
+	}{
+		"not found":   {"/nothing", errs.NotFound, http.StatusNotFound},
+		"unavailable": {"/busy", errs.Unavailable, http.StatusServiceUnavailable},
+		"bad json":    {"/broken", errs.Unknown, 0},
+	}
+	for name, tt := range cases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var v msg
+			err := c.GetJSON(context.Background(), tt.path, url.Values{"token": {"s3cret"}}, &v)
+			var oe *errs.OpError
+			if !errors.As(err, &oe) {
+				t.Fatalf("want *errs.OpError, got %v", err)
+			}
+			if oe.Op != http.MethodGet || oe.Resource != s.URL+tt.path {
+				t.Errorf("want GET %s, got %s %s", s.URL+tt.path, oe.Op, oe.Resource)
+			}
+			if got := errs.CodeOf(err); got != tt.code {
+				t.Errorf("want %v, got %v", tt.code, got)
+			}
+			var se *httpclient.StatusError
+			if tt.status != 0 && (!errors.As(err, &se) || se.StatusCode() != tt.status) {
+				t.Errorf("want status %d, got %v", tt.status, err)
+			}
+			var ue *url.Error
+			if !errors.As(err, &ue) || ue.URL != oe.Resource {
+				t.Errorf("want *url.Error for the resource, got %v", ue)
+			}
+			if detail := fmt.Sprintf("%+v", err); strings.Contains(detail, "s3cret") || strings.Contains(err.Error(), "s3cret") {
+				t.Errorf("want token to be redacted, got %q", detail)
+			}
+		})
+	}
+}
+
+func TestClient_Unreachable(t *testing.T) {
+	t.Parallel()
+	s := httptest.NewServer(http.NotFoundHandler())
+	s.Close()
+	c, err := httpclient.New("http://user:pass@" + strings.TrimPrefix(s.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.GetJSON(context.Background(), "/x", nil, nil)
+	if !errors.Is(err, errs.Unavailable) {
+		t.Errorf("want Unavailable, got %v", err)
+	}
+	if strings.Contains(err.Error(), "pass") {
+		t.Errorf("want password to be redacted, got %q", err.Error())
+	}
+}
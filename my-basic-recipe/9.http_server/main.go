@@ -2,7 +2,9 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io/ioutil"
@@ -11,8 +13,12 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
+
+	"golang/recipe-golang/6.error/errs"
+	"golang/recipe-golang/9.http_server/httpclient"
 )
 
 // 単純なhttp_server
@@ -168,6 +174,7 @@ func main() {
 	rand.Seed(t)
 
 	http.HandleFunc("/omikuji", handler3)
+	http.HandleFunc("/json", handler2) // {"msg":"hello"}
 
 	// **10.4. HTTPクライアント **/
 	// **HTTPリクエストを送る //・・http.DefaultClientを用いる
@@ -258,6 +265,25 @@ func main() {
 	}
 	fmt.Println(&resp4)
 
+	// ** エラーから失敗したリクエストを知る */ ・・httpclientパッケージを使う
+	// エラーは*errs.OpErrorで、メソッド・URL・クエリパラメータ・原因を持つ
+	// ステータスコードが400以上の場合は*httpclient.StatusErrorが原因になる
+	// tokenなどの秘密のパラメータは%+vで出力しても隠される
+	hc, err := httpclient.New("http://localhost:8080")
+	if err != nil {
+		log.Fatal(err)
+	}
+	var msg struct {
+		Msg string `json:"msg"`
+	}
+	if err := hc.GetJSON(context.Background(), "/json", url.Values{"token": {"secret"}}, &msg); err != nil {
+		var oe *errs.OpError
+		if errors.As(err, &oe) {
+			fmt.Println(oe.Op, oe.Resource) // GET http://localhost:8080/json
+		}
+		fmt.Printf("%+v\n", err) // GET url http://localhost:8080/json {token=[REDACTED]}: ...
+	}
+
 	// ** HTTPサーバの起動 */ ・・http.ListenAndServeを使う
 	// - 第1引数でホスト名とポート番号を指定
 	// ホスト名を省略した場合localhost